@@ -31,4 +31,7 @@ var (
 	WrongPartitionNameFormat  = Errno{Code: 52032, Message: "wrong partition name format ", CNMessage: "分区名格式错误"}
 	DownloadDbactorFail       = Errno{Code: 52033, Message: "download dbactor fail", CNMessage: "下载dbactor失败"}
 	DownloadFileFail          = Errno{Code: 52034, Message: "download file fail", CNMessage: "下载文件失败"}
+	ArchivePartitionFail      = Errno{Code: 52035, Message: "archive partition fail", CNMessage: "归档分区失败"}
+	ArchiveNotExisted         = Errno{Code: 52036, Message: "partition archive not existed", CNMessage: "分区归档不存在"}
+	RestoreArchiveFail        = Errno{Code: 52037, Message: "restore partition archive fail", CNMessage: "恢复分区归档失败"}
 )
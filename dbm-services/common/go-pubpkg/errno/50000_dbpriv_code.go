@@ -98,4 +98,9 @@ var (
 	QueryPrivilegesFail           = Errno{Code: 51042, Message: "query privileges fail", CNMessage: "查询权限失败"}
 	InternalAccountNameNotAllowed = Errno{Code: 51043, Message: "internal account name is not allowed",
 		CNMessage: "不允许使用内部账号名称"}
+	ExpireTimeInvalid = Errno{Code: 51044, Message: "expire time is invalid or earlier than now",
+		CNMessage: "过期时间格式错误或者早于当前时间"}
+	TemporaryPrivIdNull = Errno{Code: 51045, Message: "temporary privilege id should not be empty",
+		CNMessage: "临时授权id不能为空"}
+	RevokePrivilegesFail = Errno{Code: 51046, Message: "revoke privileges fail", CNMessage: "回收权限失败"}
 )
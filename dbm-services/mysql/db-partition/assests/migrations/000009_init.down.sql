@@ -0,0 +1,5 @@
+SET NAMES utf8;
+DROP TABLE IF EXISTS mysql_partition_archive_log;
+DROP TABLE IF EXISTS spider_partition_archive_log;
+ALTER TABLE mysql_partition_config DROP COLUMN archive_enable, DROP COLUMN archive_keep_days;
+ALTER TABLE spider_partition_config DROP COLUMN archive_enable, DROP COLUMN archive_keep_days;
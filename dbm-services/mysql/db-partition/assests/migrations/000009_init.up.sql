@@ -0,0 +1,57 @@
+SET NAMES utf8;
+ALTER TABLE mysql_partition_config ADD COLUMN archive_enable tinyint(1) NOT NULL DEFAULT 0 COMMENT '删除过期分区前是否归档',
+    ADD COLUMN archive_keep_days int NOT NULL DEFAULT 0 COMMENT '归档文件保留天数';
+ALTER TABLE spider_partition_config ADD COLUMN archive_enable tinyint(1) NOT NULL DEFAULT 0 COMMENT '删除过期分区前是否归档',
+    ADD COLUMN archive_keep_days int NOT NULL DEFAULT 0 COMMENT '归档文件保留天数';
+
+CREATE TABLE IF NOT EXISTS `mysql_partition_archive_log` (
+    `id` bigint NOT NULL AUTO_INCREMENT,
+    `config_id` int NOT NULL,
+    `bk_biz_id` int NOT NULL COMMENT '业务的 cmdb id',
+    `immute_domain` varchar(200) NOT NULL,
+    `address` varchar(64) NOT NULL COMMENT '归档的实例 ip:port',
+    `bk_cloud_id` int NOT NULL,
+    `dbname` varchar(100) NOT NULL,
+    `tbname` varchar(100) NOT NULL,
+    `partition_name` varchar(64) NOT NULL,
+    `staging_table` varchar(64) NOT NULL COMMENT 'exchange partition 使用的中转表',
+    `table_rows` bigint NOT NULL DEFAULT 0,
+    `file_name` varchar(255) NOT NULL DEFAULT '',
+    `file_size` bigint NOT NULL DEFAULT 0,
+    `file_md5` varchar(32) NOT NULL DEFAULT '',
+    `location` varchar(1024) NOT NULL DEFAULT '' COMMENT '归档文件在介质中心的路径',
+    `expire_date` date DEFAULT NULL COMMENT '归档文件过期日期',
+    `status` varchar(32) NOT NULL COMMENT 'archiving, archived, failed',
+    `check_info` text COMMENT '失败原因',
+    `create_time` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `update_time` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    KEY `idx_config_id` (`config_id`),
+    KEY `idx_domain_db_tb` (`immute_domain`,`dbname`,`tbname`,`partition_name`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+CREATE TABLE IF NOT EXISTS `spider_partition_archive_log` (
+    `id` bigint NOT NULL AUTO_INCREMENT,
+    `config_id` int NOT NULL,
+    `bk_biz_id` int NOT NULL COMMENT '业务的 cmdb id',
+    `immute_domain` varchar(200) NOT NULL,
+    `address` varchar(64) NOT NULL COMMENT '归档的实例 ip:port',
+    `bk_cloud_id` int NOT NULL,
+    `dbname` varchar(100) NOT NULL,
+    `tbname` varchar(100) NOT NULL,
+    `partition_name` varchar(64) NOT NULL,
+    `staging_table` varchar(64) NOT NULL COMMENT 'exchange partition 使用的中转表',
+    `table_rows` bigint NOT NULL DEFAULT 0,
+    `file_name` varchar(255) NOT NULL DEFAULT '',
+    `file_size` bigint NOT NULL DEFAULT 0,
+    `file_md5` varchar(32) NOT NULL DEFAULT '',
+    `location` varchar(1024) NOT NULL DEFAULT '' COMMENT '归档文件在介质中心的路径',
+    `expire_date` date DEFAULT NULL COMMENT '归档文件过期日期',
+    `status` varchar(32) NOT NULL COMMENT 'archiving, archived, failed',
+    `check_info` text COMMENT '失败原因',
+    `create_time` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `update_time` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    KEY `idx_config_id` (`config_id`),
+    KEY `idx_domain_db_tb` (`immute_domain`,`dbname`,`tbname`,`partition_name`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
@@ -0,0 +1,3 @@
+SET NAMES utf8;
+ALTER TABLE mysql_partition_config DROP COLUMN range_interval, DROP COLUMN expire_size;
+ALTER TABLE spider_partition_config DROP COLUMN range_interval, DROP COLUMN expire_size;
@@ -0,0 +1,5 @@
+SET NAMES utf8;
+ALTER TABLE mysql_partition_config ADD COLUMN range_interval bigint NOT NULL DEFAULT 0 COMMENT '按id范围分区时每个分区的id范围',
+    ADD COLUMN expire_size bigint NOT NULL DEFAULT 0 COMMENT '按id范围分区时分区总大小上限，单位字节';
+ALTER TABLE spider_partition_config ADD COLUMN range_interval bigint NOT NULL DEFAULT 0 COMMENT '按id范围分区时每个分区的id范围',
+    ADD COLUMN expire_size bigint NOT NULL DEFAULT 0 COMMENT '按id范围分区时分区总大小上限，单位字节';
@@ -35,6 +35,58 @@ func DryRun(r *gin.Context) {
 
 }
 
+// ArchivePartition 手动归档过期分区
+func ArchivePartition(r *gin.Context) {
+	var input service.Checker
+	if err := r.ShouldBind(&input); err != nil {
+		slog.Error("msg", "bind error", err)
+		SendResponse(r, errno.ErrBind, nil)
+		return
+	}
+	logs, err := input.Archive()
+	SendResponse(r, err, logs)
+	return
+}
+
+// GetArchiveLog 查询分区归档日志
+func GetArchiveLog(r *gin.Context) {
+	var input service.QueryArchiveInput
+	if err := r.ShouldBind(&input); err != nil {
+		slog.Error(err.Error())
+		SendResponse(r, errno.ErrBind, nil)
+		return
+	}
+	lists, count, err := input.GetArchiveLog()
+	// ListResponse 返回信息
+	type ListResponse struct {
+		Count int64       `json:"count"`
+		Items interface{} `json:"items"`
+	}
+	if err != nil {
+		slog.Error(err.Error())
+		SendResponse(r, err, nil)
+		return
+	}
+	SendResponse(r, err, ListResponse{
+		Count: count,
+		Items: lists,
+	})
+	return
+}
+
+// RestoreArchive 把归档的分区恢复为一张表
+func RestoreArchive(r *gin.Context) {
+	var input service.RestoreArchiveInput
+	if err := r.ShouldBind(&input); err != nil {
+		slog.Error(err.Error())
+		SendResponse(r, errno.ErrBind, nil)
+		return
+	}
+	result, err := input.RestoreArchive()
+	SendResponse(r, err, result)
+	return
+}
+
 // GetPartitionsConfig TODO
 func GetPartitionsConfig(r *gin.Context) {
 	var input service.QueryParititionsInput
@@ -38,6 +38,9 @@ func InitEnv() {
 	viper.BindEnv("pt.max_size", "PT_MAX_SIZE")
 	viper.BindEnv("pt.max_rows", "PT_MAX_ROWS")
 
+	// 分区归档参数
+	viper.BindEnv("archive.max_rows", "ARCHIVE_MAX_ROWS")
+
 	viper.BindEnv("dba.bk_biz_id", "DBA_APP_BK_BIZ_ID")
 
 	// 程序日志参数, 可选参数
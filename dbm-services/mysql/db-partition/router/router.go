@@ -33,6 +33,10 @@ func RegisterRouter(engine *gin.Engine) {
 	p.POST("/init_monitor", handler.InitMonitor)
 	// 迁移分区配置
 	p.POST("/migrate_config", handler.MigrateConfig)
+	// 分区归档
+	p.POST("/archive_partition", handler.ArchivePartition)
+	p.POST("/query_archive", handler.GetArchiveLog)
+	p.POST("/restore_archive", handler.RestoreArchive)
 	// 巡检
 	p.POST("/check_log", handler.CheckLog)
 }
@@ -0,0 +1,873 @@
+package service
+
+import (
+	"bufio"
+	"compress/gzip"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"dbm-services/common/go-pubpkg/errno"
+	"dbm-services/mysql/db-partition/model"
+
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+)
+
+// Archive 手动归档分区规则已过期的分区，归档后的分区为空分区，由分区任务删除
+func (m *Checker) Archive() ([]*PartitionArchiveLog, error) {
+	slog.Info("do service Archive")
+	if m.BkBizId == 0 {
+		return nil, errno.BkBizIdIsEmpty
+	}
+	if m.ClusterId == 0 {
+		return nil, errno.ClusterIdIsEmpty
+	}
+	if m.BkCloudId == nil {
+		return nil, errno.CloudIdRequired
+	}
+	if m.ConfigId == 0 {
+		return nil, errno.RuleIdNull
+	}
+	var configs []*PartitionConfig
+	var tbName string
+	switch m.ClusterType {
+	case Tendbha, Tendbsingle:
+		tbName = MysqlPartitionConfig
+	case Tendbcluster:
+		tbName = SpiderPartitionConfig
+	default:
+		return nil, errno.NotSupportedClusterType
+	}
+	err := model.DB.Self.Table(tbName).Where("bk_biz_id = ? and cluster_id = ? and id = ?", m.BkBizId, m.ClusterId,
+		m.ConfigId).Scan(&configs).Error
+	if err != nil {
+		slog.Error("msg", fmt.Sprintf("query %s err", tbName), err)
+		return nil, err
+	}
+	if len(configs) == 0 {
+		return nil, errno.PartitionConfigNotExisted
+	}
+	if !configs[0].ArchiveEnable {
+		return nil, errno.ArchivePartitionFail.Add("archive is not enabled in partition config")
+	}
+
+	var logs []*PartitionArchiveLog
+	var fails []IdLog
+	switch m.ClusterType {
+	case Tendbha, Tendbsingle:
+		ins, errInner := GetMaster(m.ImmuteDomain, m.ClusterType)
+		if errInner != nil {
+			slog.Error("msg", "GetClusterMasterError", errInner)
+			return nil, errInner
+		}
+		logs, fails = ArchivePartitionConfigs(configs, m.ClusterType, ins)
+	case Tendbcluster:
+		cluster := fmt.Sprintf("%s|%d|%d", m.ImmuteDomain, m.Port, *m.BkCloudId)
+		hostNodes, _, errInner := GetTendbclusterInstances(cluster)
+		if errInner != nil {
+			slog.Error("msg", "GetTendbclusterInstances", errInner)
+			return nil, errInner
+		}
+		for _, instances := range hostNodes {
+			for _, ins := range instances {
+				// 中控节点上的表没有数据，不需要归档
+				if ins.Wrapper != "mysql" {
+					continue
+				}
+				newconfig := *configs[0]
+				newconfig.DbLike = fmt.Sprintf("%s_%s", newconfig.DbLike, ins.SplitNum)
+				insLogs, insFails := ArchivePartitionConfigs([]*PartitionConfig{&newconfig}, m.ClusterType,
+					Host{Ip: ins.Ip, Port: ins.Port, BkCloudId: ins.Cloud})
+				logs = append(logs, insLogs...)
+				fails = append(fails, insFails...)
+			}
+		}
+	}
+	if len(fails) > 0 {
+		var msgs []string
+		for _, fail := range fails {
+			msgs = append(msgs, fail.Log)
+		}
+		return logs, errno.ArchivePartitionFail.Add(strings.Join(msgs, "\n"))
+	}
+	if len(logs) == 0 {
+		return logs, errno.NothingToDo
+	}
+	return logs, nil
+}
+
+// ArchivePartitionConfigs 归档实例上开启归档的分区规则已过期的分区，返回归档日志以及归档失败的分区规则
+func ArchivePartitionConfigs(configs []*PartitionConfig, clusterType string, host Host) ([]*PartitionArchiveLog,
+	[]IdLog) {
+	var logs []*PartitionArchiveLog
+	var fails []IdLog
+	for _, config := range configs {
+		if !config.ArchiveEnable || config.Phase != online {
+			continue
+		}
+		tbs, err := config.GetDbTableInfo(true, host)
+		if err != nil {
+			// 没有匹配的表、分区规则与实际不一致，在生成分区语句时会记录日志
+			slog.Warn("archive partition get table info", "config_id", config.ID, "error", err)
+			continue
+		}
+		var errs []string
+		for _, tb := range tbs {
+			if !tb.Partitioned {
+				continue
+			}
+			expired, errInner := tb.GetExpiredPartitions(host)
+			if errInner != nil {
+				errs = append(errs, errInner.Error())
+				continue
+			}
+			// 上次归档中断残留的中转表，对应的分区可能已经不在过期分区中，需要继续归档
+			leftover, errInner := tb.leftoverStagingTables(host)
+			if errInner != nil {
+				errs = append(errs, errInner.Error())
+				continue
+			}
+			for partitionName := range leftover {
+				if !slices.Contains(expired, partitionName) {
+					expired = append(expired, partitionName)
+				}
+			}
+			for _, partitionName := range expired {
+				log, errArchive := tb.ArchivePartition(clusterType, partitionName, host)
+				if errArchive != nil {
+					errs = append(errs, fmt.Sprintf("archive %s.%s partition %s on %s:%d error: %s",
+						tb.DbName, tb.TbName, partitionName, host.Ip, host.Port, errArchive.Error()))
+					continue
+				}
+				if log != nil {
+					logs = append(logs, log)
+				}
+			}
+		}
+		if len(errs) > 0 {
+			fails = append(fails, IdLog{ConfigId: config.ID, Log: strings.Join(errs, "\n")})
+		}
+	}
+	return logs, fails
+}
+
+// ArchiveBeforeDrop 定时任务生成分区语句前归档过期分区，归档失败记录到分区日志，不影响添加分区
+func ArchiveBeforeDrop(configs []*PartitionConfig, clusterType string, host Host, cronDate string) {
+	_, fails := ArchivePartitionConfigs(configs, clusterType, host)
+	if len(fails) == 0 {
+		return
+	}
+	msg := fmt.Sprintf("archive partition on %s:%d fail", host.Ip, host.Port)
+	SendMonitor(msg, fmt.Errorf("%d partition configs archive fail", len(fails)))
+	slog.Error("msg", msg, fails)
+	if err := AddLogBatch(fails, cronDate, Scheduler, Fail, clusterType, ""); err != nil {
+		SendMonitor("add log fail", err)
+		slog.Error("msg", "add log fail", err)
+	}
+}
+
+// ArchivePartition 把分区 exchange 到中转表，中转表导出为 sql 文件上传到介质中心，
+// 成功后删除中转表，原分区变为空分区。返回 nil 表示分区没有数据，不需要归档
+func (m *ConfigDetail) ArchivePartition(clusterType string, partitionName string, host Host) (*PartitionArchiveLog,
+	error) {
+	logTb, err := archiveLogTable(clusterType)
+	if err != nil {
+		return nil, err
+	}
+	address := fmt.Sprintf("%s:%d", host.Ip, host.Port)
+	staging := stagingTableName(m.TbName, partitionName)
+	partitionExists, err := countRows(fmt.Sprintf("select count(*) as CNT from information_schema.PARTITIONS "+
+		"where TABLE_SCHEMA='%s' and TABLE_NAME='%s' and PARTITION_NAME='%s'", m.DbName, m.TbName, partitionName), host)
+	if err != nil {
+		return nil, err
+	}
+	// 只剩中转表时分区已经被删除，只归档中转表
+	var partitionRows int64
+	if partitionExists > 0 {
+		partitionRows, err = countRows(fmt.Sprintf("select count(*) as CNT from %s.%s partition (%s)",
+			quoteName(m.DbName), quoteName(m.TbName), quoteName(partitionName)), host)
+		if err != nil {
+			return nil, err
+		}
+	}
+	stagingExists, err := countRows(fmt.Sprintf("select count(*) as CNT from information_schema.tables "+
+		"where TABLE_SCHEMA='%s' and TABLE_NAME='%s'", m.DbName, staging), host)
+	if err != nil {
+		return nil, err
+	}
+	var stagingRows int64
+	if stagingExists > 0 {
+		stagingRows, err = countRows(fmt.Sprintf("select count(*) as CNT from %s.%s",
+			quoteName(m.DbName), quoteName(staging)), host)
+		if err != nil {
+			return nil, err
+		}
+	}
+	dropStaging := fmt.Sprintf("drop table if exists %s.%s", quoteName(m.DbName), quoteName(staging))
+
+	switch {
+	case stagingRows > 0 && partitionRows > 0:
+		// 分区与中转表都有数据，可能是中转表残留或者 exchange 之后分区又写入了数据，需要人工确认
+		return nil, fmt.Errorf("both partition and staging table %s have data, please check manually", staging)
+	case stagingRows > 0:
+		// 上次归档在 exchange 之后中断，如果已经归档成功只需要删除中转表，否则继续归档中转表的数据
+		var done []*PartitionArchiveLog
+		err = model.DB.Self.Table(logTb).Where(
+			"address = ? and dbname = ? and tbname = ? and partition_name = ? and status = ? and table_rows = ?",
+			address, m.DbName, m.TbName, partitionName, archived, stagingRows).Find(&done).Error
+		if err != nil {
+			return nil, err
+		}
+		if len(done) > 0 {
+			return nil, executeSqls([]string{dropStaging}, host)
+		}
+	case partitionRows > 0:
+		maxRows := viper.GetInt64("archive.max_rows")
+		if maxRows <= 0 {
+			maxRows = defaultArchiveMaxRows
+		}
+		if partitionRows > maxRows {
+			return nil, fmt.Errorf("partition has %d rows, more than archive.max_rows %d", partitionRows, maxRows)
+		}
+		// 中转表与原表结构一致且不分区，exchange 只修改元数据
+		err = executeSqls([]string{
+			dropStaging,
+			fmt.Sprintf("create table %s.%s like %s.%s", quoteName(m.DbName), quoteName(staging),
+				quoteName(m.DbName), quoteName(m.TbName)),
+			fmt.Sprintf("alter table %s.%s remove partitioning", quoteName(m.DbName), quoteName(staging)),
+			fmt.Sprintf("alter table %s.%s exchange partition %s with table %s.%s", quoteName(m.DbName),
+				quoteName(m.TbName), quoteName(partitionName), quoteName(m.DbName), quoteName(staging)),
+		}, host)
+		if err != nil {
+			return nil, err
+		}
+		// exchange 前后分区可能有写入，以中转表的实际行数为准
+		stagingRows, err = countRows(fmt.Sprintf("select count(*) as CNT from %s.%s",
+			quoteName(m.DbName), quoteName(staging)), host)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		// 分区没有数据，删除可能残留的空中转表
+		if stagingExists > 0 {
+			return nil, executeSqls([]string{dropStaging}, host)
+		}
+		return nil, nil
+	}
+
+	log := &PartitionArchiveLog{
+		ConfigId:      m.ID,
+		BkBizId:       m.BkBizId,
+		ImmuteDomain:  m.ImmuteDomain,
+		Address:       address,
+		BkCloudId:     host.BkCloudId,
+		DbName:        m.DbName,
+		TbName:        m.TbName,
+		PartitionName: partitionName,
+		StagingTable:  staging,
+		TableRows:     stagingRows,
+		Status:        archiving,
+		CreateTime:    time.Now(),
+		UpdateTime:    time.Now(),
+	}
+	if err = model.DB.Self.Table(logTb).Create(log).Error; err != nil {
+		slog.Error("msg", "add archive log failed", err)
+		return nil, err
+	}
+	err = m.archiveStagingTable(log, host)
+	if err != nil {
+		log.Status = archiveFailed
+		log.CheckInfo = err.Error()
+	}
+	errUpdate := model.DB.Self.Table(logTb).Where("id = ?", log.Id).Updates(map[string]interface{}{
+		"file_name":   log.FileName,
+		"file_size":   log.FileSize,
+		"file_md5":    log.FileMd5,
+		"location":    log.Location,
+		"expire_date": log.ExpireDate,
+		"status":      log.Status,
+		"check_info":  log.CheckInfo,
+		"update_time": time.Now(),
+	}).Error
+	if err != nil {
+		return log, err
+	}
+	if errUpdate != nil {
+		slog.Error("msg", "update archive log failed", errUpdate)
+		return log, errUpdate
+	}
+	// 归档记录保存成功后才删除中转表，删除失败下次归档时会再次删除
+	if err = executeSqls([]string{dropStaging}, host); err != nil {
+		slog.Warn("drop staging table", "address", address, "table", staging, "error", err)
+	}
+	return log, nil
+}
+
+// archiveStagingTable 导出中转表上传到介质中心，结果记录到 log 中
+func (m *ConfigDetail) archiveStagingTable(log *PartitionArchiveLog, host Host) error {
+	if err := os.MkdirAll(archiveWorkDir, 0755); err != nil {
+		return err
+	}
+	log.FileName = fmt.Sprintf("%s_%s_%s_%s_%s.sql.gz", strings.ReplaceAll(log.Address, ":", "_"),
+		log.DbName, log.TbName, log.PartitionName, time.Now().Format("20060102150405"))
+	filename := filepath.Join(archiveWorkDir, log.FileName)
+	defer func() {
+		_ = os.Remove(filename)
+	}()
+	rows, err := exportTable(m.DbName, log.StagingTable, filename, host)
+	if err != nil {
+		return err
+	}
+	if rows != log.TableRows {
+		return fmt.Errorf("exported %d rows, but staging table has %d rows", rows, log.TableRows)
+	}
+	log.FileMd5, log.FileSize, err = fileMd5(filename)
+	if err != nil {
+		return err
+	}
+	dir := path.Join("mysql", "partition", "archive", log.ImmuteDomain)
+	resp, err := UploadToBkRepo(filename, dir, m.ArchiveKeepDays)
+	if err != nil {
+		return err
+	}
+	if resp.Code != 0 {
+		return fmt.Errorf("upload %s to bkrepo respone error. respone code is %d,respone msg:%s,traceId:%s",
+			filename, resp.Code, resp.Message, resp.RequestId)
+	}
+	log.Location = path.Join(dir, log.FileName)
+	if m.ArchiveKeepDays > 0 {
+		expireDate := time.Now().AddDate(0, 0, m.ArchiveKeepDays)
+		log.ExpireDate = &expireDate
+	}
+	log.Status = archived
+	return nil
+}
+
+// exportTable 导出表为 gzip 压缩的 sql 文件，第一条语句为建表语句，之后每行一条 insert 语句，返回导出的行数。
+// 字段值使用 hex 导出，避免二进制数据在 json 传输中被破坏。有主键时按主键分页，没有主键时一次导出，
+// 中转表的行数不超过 archive.max_rows
+func exportTable(dbName string, tbName string, filename string, host Host) (int64, error) {
+	address := fmt.Sprintf("%s:%d", host.Ip, host.Port)
+	queryRequest := QueryRequest{Addresses: []string{address}, Cmds: []string{
+		fmt.Sprintf("show create table %s.%s", quoteName(dbName), quoteName(tbName)),
+		fmt.Sprintf("select COLUMN_NAME as COLUMN_NAME,DATA_TYPE as DATA_TYPE,"+
+			"ifnull(CHARACTER_SET_NAME,'') as CHARACTER_SET_NAME,ifnull(COLLATION_NAME,'') as COLLATION_NAME "+
+			"from information_schema.COLUMNS where TABLE_SCHEMA='%s' and TABLE_NAME='%s' "+
+			"and EXTRA not like '%%GENERATED%%' order by ORDINAL_POSITION", dbName, tbName),
+		fmt.Sprintf("select COLUMN_NAME as COLUMN_NAME from information_schema.STATISTICS "+
+			"where TABLE_SCHEMA='%s' and TABLE_NAME='%s' and INDEX_NAME='PRIMARY' order by SEQ_IN_INDEX",
+			dbName, tbName),
+	}, Force: false, QueryTimeout: 30, BkCloudId: host.BkCloudId}
+	output, err := OneAddressExecuteSql(queryRequest)
+	if err != nil {
+		return 0, err
+	}
+	if len(output.CmdResults[0].TableData) == 0 || len(output.CmdResults[1].TableData) == 0 {
+		return 0, fmt.Errorf("table %s.%s not existed", dbName, tbName)
+	}
+	createTable, _ := output.CmdResults[0].TableData[0]["Create Table"].(string)
+	var columns, selects []string
+	var exportColumns []exportColumn
+	for i, row := range output.CmdResults[1].TableData {
+		c := exportColumn{
+			name:      row["COLUMN_NAME"].(string),
+			alias:     fmt.Sprintf("c%d", i),
+			dataType:  strings.ToLower(row["DATA_TYPE"].(string)),
+			charset:   fmt.Sprintf("%v", row["CHARACTER_SET_NAME"]),
+			collation: fmt.Sprintf("%v", row["COLLATION_NAME"]),
+		}
+		exportColumns = append(exportColumns, c)
+		columns = append(columns, quoteName(c.name))
+		selects = append(selects, fmt.Sprintf("hex(%s) as %s", c.hexExpr(), c.alias))
+	}
+	var keys []exportColumn
+	for _, row := range output.CmdResults[2].TableData {
+		name := row["COLUMN_NAME"].(string)
+		idx := slices.IndexFunc(exportColumns, func(c exportColumn) bool { return c.name == name })
+		if idx < 0 {
+			return 0, fmt.Errorf("primary key column %s of %s.%s is not exported", name, dbName, tbName)
+		}
+		keys = append(keys, exportColumns[idx])
+	}
+	var keyNames []string
+	for _, k := range keys {
+		keyNames = append(keyNames, quoteName(k.name))
+	}
+
+	fh, err := os.Create(filename)
+	if err != nil {
+		return 0, err
+	}
+	defer fh.Close()
+	gz := gzip.NewWriter(fh)
+	w := bufio.NewWriter(gz)
+	// show create table 中注释里的换行会被转义，去掉换行后建表语句为一行
+	_, _ = fmt.Fprintf(w, "-- archive of %s.%s from %s at %s\n", dbName, tbName, address,
+		time.Now().Format(time.RFC3339))
+	_, _ = fmt.Fprintf(w, "%s;\n", strings.ReplaceAll(createTable, "\n", " "))
+
+	var total int64
+	insertPrefix := fmt.Sprintf("INSERT INTO %s (%s) VALUES ", quoteName(tbName), strings.Join(columns, ","))
+	// 上一页最后一行的主键，下一页从它之后开始
+	var lastKey []string
+	for {
+		sql := fmt.Sprintf("select %s from %s.%s", strings.Join(selects, ","), quoteName(dbName), quoteName(tbName))
+		if len(keys) > 0 {
+			if lastKey != nil {
+				sql += fmt.Sprintf(" where (%s) > (%s)", strings.Join(keyNames, ","), strings.Join(lastKey, ","))
+			}
+			sql += fmt.Sprintf(" order by %s limit %d", strings.Join(keyNames, ","), archiveBatchRows)
+		}
+		queryRequest = QueryRequest{Addresses: []string{address}, Cmds: []string{sql}, Force: false,
+			QueryTimeout: 60, BkCloudId: host.BkCloudId}
+		output, err = OneAddressExecuteSql(queryRequest)
+		if err != nil {
+			return total, err
+		}
+		data := output.CmdResults[0].TableData
+		if len(data) == 0 {
+			break
+		}
+		for start := 0; start < len(data); start += archiveBatchRows {
+			batch := data[start:min(start+archiveBatchRows, len(data))]
+			var values []string
+			for _, row := range batch {
+				var fields []string
+				for _, c := range exportColumns {
+					fields = append(fields, c.valueExpr(row[c.alias]))
+				}
+				values = append(values, fmt.Sprintf("(%s)", strings.Join(fields, ",")))
+			}
+			if _, err = fmt.Fprintf(w, "%s%s;\n", insertPrefix, strings.Join(values, ",")); err != nil {
+				return total, err
+			}
+		}
+		total += int64(len(data))
+		if len(keys) == 0 || len(data) < archiveBatchRows {
+			break
+		}
+		last := data[len(data)-1]
+		lastKey = nil
+		for _, k := range keys {
+			lit, errKey := k.keyLiteral(last[k.alias])
+			if errKey != nil {
+				return total, errKey
+			}
+			lastKey = append(lastKey, lit)
+		}
+	}
+	if err = w.Flush(); err != nil {
+		return total, err
+	}
+	if err = gz.Close(); err != nil {
+		return total, err
+	}
+	return total, nil
+}
+
+// exportColumn 导出的字段
+type exportColumn struct {
+	name      string
+	alias     string
+	dataType  string
+	charset   string
+	collation string
+}
+
+var numericTypes = []string{"tinyint", "smallint", "mediumint", "int", "integer", "bigint", "decimal", "float",
+	"double", "year"}
+
+// hexExpr 数字类型的 hex 返回数值的十六进制，需要先转换为字符串
+func (c exportColumn) hexExpr() string {
+	if slices.Contains(numericTypes, c.dataType) {
+		return fmt.Sprintf("concat(%s)", quoteName(c.name))
+	}
+	return quoteName(c.name)
+}
+
+// valueExpr 归档文件中 insert 语句的字段值
+func (c exportColumn) valueExpr(v interface{}) string {
+	hexValue, ok := v.(string)
+	switch {
+	case !ok:
+		return "NULL"
+	case c.dataType == "json":
+		return fmt.Sprintf("convert(unhex('%s') using utf8mb4)", hexValue)
+	default:
+		return fmt.Sprintf("unhex('%s')", hexValue)
+	}
+}
+
+// numericLiteral concat 后的数字
+var numericLiteral = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?([eE][-+]?[0-9]+)?$`)
+
+// keyLiteral 分页条件中主键字段的值。字符串按字段的字符集和排序规则比较，与 order by 的顺序一致。
+// 数字类型直接使用数值，X'..' 与数字比较时会被当成整数
+func (c exportColumn) keyLiteral(v interface{}) (string, error) {
+	hexValue, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("primary key column %s is null", c.name)
+	}
+	value, err := hex.DecodeString(hexValue)
+	if err != nil {
+		return "", fmt.Errorf("primary key column %s: invalid hex value %s", c.name, hexValue)
+	}
+	if slices.Contains(numericTypes, c.dataType) {
+		if !numericLiteral.Match(value) {
+			return "", fmt.Errorf("primary key column %s: invalid %s value %s", c.name, c.dataType, value)
+		}
+		return string(value), nil
+	}
+	if c.charset != "" && c.collation != "" {
+		return fmt.Sprintf("_%s X'%s' collate %s", c.charset, hexValue, c.collation), nil
+	}
+	return fmt.Sprintf("X'%s'", hexValue), nil
+}
+
+// GetArchiveLog 查询分区归档日志
+func (m *QueryArchiveInput) GetArchiveLog() ([]*PartitionArchiveLog, int64, error) {
+	allResults := make([]*PartitionArchiveLog, 0)
+	logTb, err := archiveLogTable(strings.ToLower(m.ClusterType))
+	if err != nil {
+		return nil, 0, err
+	}
+	tx := model.DB.Self.Session(&gorm.Session{}).Table(logTb).Where("bk_biz_id = ?", m.BkBizId)
+	if m.ConfigId != 0 {
+		tx = tx.Where("config_id = ?", m.ConfigId)
+	}
+	if m.ImmuteDomain != "" {
+		tx = tx.Where("immute_domain = ?", m.ImmuteDomain)
+	}
+	if m.DbName != "" {
+		tx = tx.Where("dbname = ?", m.DbName)
+	}
+	if m.TbName != "" {
+		tx = tx.Where("tbname = ?", m.TbName)
+	}
+	if m.Status != "" {
+		tx = tx.Where("status = ?", m.Status)
+	}
+	var count int64
+	if err = tx.Session(&gorm.Session{}).Count(&count).Error; err != nil {
+		slog.Error("cnt sql execute error", "error", err)
+		return nil, 0, err
+	}
+	if m.Limit == 0 {
+		m.Limit = 20
+	}
+	err = tx.Session(&gorm.Session{}).Order("id desc").Limit(m.Limit).Offset(m.Offset).Find(&allResults).Error
+	if err != nil {
+		slog.Error("sql execute error", "error", err)
+		return nil, 0, err
+	}
+	return allResults, count, nil
+}
+
+// RestoreArchive 从介质中心下载归档文件，在归档的实例上恢复为一张新表
+func (m *RestoreArchiveInput) RestoreArchive() (*RestoreArchiveResult, error) {
+	var configTb, manageLogTb string
+	switch strings.ToLower(m.ClusterType) {
+	case Tendbha, Tendbsingle:
+		configTb, manageLogTb = MysqlPartitionConfig, MysqlManageLogsTable
+	case Tendbcluster:
+		configTb, manageLogTb = SpiderPartitionConfig, SpiderManageLogsTable
+	default:
+		return nil, errno.NotSupportedClusterType
+	}
+	logTb, _ := archiveLogTable(strings.ToLower(m.ClusterType))
+	var logs []*PartitionArchiveLog
+	err := model.DB.Self.Table(logTb).Where("id = ? and bk_biz_id = ? and status = ?", m.Id, m.BkBizId, archived).
+		Find(&logs).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(logs) == 0 {
+		return nil, errno.ArchiveNotExisted
+	}
+	log := logs[0]
+	target := m.TargetTable
+	if target == "" {
+		target = fmt.Sprintf("%s_%s_restore", log.TbName, log.PartitionName)
+	}
+	if len(target) > 64 {
+		return nil, errno.RestoreArchiveFail.Add(fmt.Sprintf("table name %s is too long, please set target_table",
+			target))
+	}
+	ip, portStr, _ := strings.Cut(log.Address, ":")
+	port, _ := strconv.Atoi(portStr)
+	host := Host{Ip: ip, Port: port, BkCloudId: log.BkCloudId}
+	exists, err := countRows(fmt.Sprintf("select count(*) as CNT from information_schema.tables "+
+		"where TABLE_SCHEMA='%s' and TABLE_NAME='%s'", log.DbName, target), host)
+	if err != nil {
+		return nil, err
+	}
+	if exists > 0 {
+		return nil, errno.RestoreArchiveFail.Add(fmt.Sprintf("table %s.%s already exists", log.DbName, target))
+	}
+
+	if err = os.MkdirAll(archiveWorkDir, 0755); err != nil {
+		return nil, err
+	}
+	filename := filepath.Join(archiveWorkDir, fmt.Sprintf("restore_%d_%s", log.Id, log.FileName))
+	defer func() {
+		_ = os.Remove(filename)
+	}()
+	if err = DownloadFromBkRepo(log.Location, filename); err != nil {
+		return nil, errno.DownloadFileFail.Add(err.Error())
+	}
+	md5sum, _, err := fileMd5(filename)
+	if err != nil {
+		return nil, err
+	}
+	if md5sum != log.FileMd5 {
+		return nil, errno.RestoreArchiveFail.Add(fmt.Sprintf("md5 of %s is %s, expected %s", log.Location, md5sum,
+			log.FileMd5))
+	}
+	if err = restoreFile(filename, log.DbName, log.StagingTable, target, host); err != nil {
+		return nil, errno.RestoreArchiveFail.Add(err.Error())
+	}
+	rows, err := countRows(fmt.Sprintf("select count(*) as CNT from %s.%s", quoteName(log.DbName),
+		quoteName(target)), host)
+	if err != nil {
+		return nil, err
+	}
+	if rows != log.TableRows {
+		return nil, errno.RestoreArchiveFail.Add(fmt.Sprintf("restored %d rows, but archived %d rows", rows,
+			log.TableRows))
+	}
+	CreateManageLog(configTb, manageLogTb, log.ConfigId, fmt.Sprintf("RestoreArchive %d to %s.%s", log.Id,
+		log.DbName, target), m.Operator)
+	return &RestoreArchiveResult{Address: log.Address, DbName: log.DbName, TbName: target, TableRows: rows}, nil
+}
+
+// restoreFile 执行归档文件中的语句，表名由中转表替换为 target
+func restoreFile(filename string, dbName string, staging string, target string, host Host) error {
+	fh, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer fh.Close()
+	gz, err := gzip.NewReader(fh)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+	r := bufio.NewReader(gz)
+	createPrefix := fmt.Sprintf("CREATE TABLE %s ", quoteName(staging))
+	insertPrefix := fmt.Sprintf("INSERT INTO %s ", quoteName(staging))
+	newTable := fmt.Sprintf("%s.%s ", quoteName(dbName), quoteName(target))
+	var batch []string
+	for {
+		line, errRead := r.ReadString('\n')
+		if errRead != nil && !errors.Is(errRead, io.EOF) {
+			return errRead
+		}
+		line = strings.TrimSuffix(strings.TrimSpace(line), ";")
+		switch {
+		case line == "" || strings.HasPrefix(line, "--"):
+		case strings.HasPrefix(line, createPrefix):
+			if err = executeSqls([]string{"CREATE TABLE " + newTable + strings.TrimPrefix(line, createPrefix)},
+				host); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, insertPrefix):
+			batch = append(batch, "INSERT INTO "+newTable+strings.TrimPrefix(line, insertPrefix))
+		default:
+			return fmt.Errorf("unexpected statement in archive file: %.100s", line)
+		}
+		if len(batch) >= restoreBatchSqls || (errors.Is(errRead, io.EOF) && len(batch) > 0) {
+			if err = executeSqls(batch, host); err != nil {
+				return err
+			}
+			batch = nil
+		}
+		if errors.Is(errRead, io.EOF) {
+			return nil
+		}
+	}
+}
+
+// droppablePartitions 过滤出可以删除的分区。开启归档的分区规则只删除没有数据、没有残留中转表，
+// 并且最近一次归档成功的分区；没有归档记录的空分区从来没有数据，可以直接删除。
+// 归档失败的分区等下次定时任务重新归档后再删除
+func (m *ConfigDetail) droppablePartitions(partitions []string, host Host) ([]string, error) {
+	if len(partitions) == 0 {
+		return partitions, nil
+	}
+	var cmds []string
+	for _, name := range partitions {
+		cmds = append(cmds, fmt.Sprintf("select count(*) as CNT from %s.%s partition (%s)",
+			quoteName(m.DbName), quoteName(m.TbName), quoteName(name)))
+	}
+	address := fmt.Sprintf("%s:%d", host.Ip, host.Port)
+	queryRequest := QueryRequest{Addresses: []string{address}, Cmds: cmds,
+		Force: false, QueryTimeout: 30, BkCloudId: host.BkCloudId}
+	output, err := OneAddressExecuteSql(queryRequest)
+	if err != nil {
+		return nil, err
+	}
+	leftover, err := m.leftoverStagingTables(host)
+	if err != nil {
+		return nil, err
+	}
+	var droppable []string
+	for i, name := range partitions {
+		cnt, _ := strconv.ParseInt(fmt.Sprintf("%v", output.CmdResults[i].TableData[0]["CNT"]), 10, 64)
+		if cnt > 0 {
+			slog.Info("partition not archived, skip drop", "db", m.DbName, "tb", m.TbName, "partition", name,
+				"rows", cnt)
+			continue
+		}
+		if staging, ok := leftover[name]; ok {
+			slog.Info("staging table left, skip drop", "db", m.DbName, "tb", m.TbName, "partition", name,
+				"staging", staging)
+			continue
+		}
+		status, errInner := latestArchiveStatus(address, m.DbName, m.TbName, name)
+		if errInner != nil {
+			return nil, errInner
+		}
+		if status != "" && status != archived {
+			slog.Info("partition archive not finished, skip drop", "db", m.DbName, "tb", m.TbName,
+				"partition", name, "status", status)
+			continue
+		}
+		droppable = append(droppable, name)
+	}
+	return droppable, nil
+}
+
+// leftoverStagingTables 表上残留的中转表，返回分区名到中转表名的映射
+func (m *ConfigDetail) leftoverStagingTables(host Host) (map[string]string, error) {
+	queryRequest := QueryRequest{Addresses: []string{fmt.Sprintf("%s:%d", host.Ip, host.Port)},
+		Cmds: []string{fmt.Sprintf("select TABLE_NAME as TABLE_NAME from information_schema.tables "+
+			"where TABLE_SCHEMA='%s' and TABLE_NAME like '%%\\_archive'", m.DbName)},
+		Force: false, QueryTimeout: 30, BkCloudId: host.BkCloudId}
+	output, err := OneAddressExecuteSql(queryRequest)
+	if err != nil {
+		return nil, err
+	}
+	leftover := make(map[string]string)
+	for _, row := range output.CmdResults[0].TableData {
+		name, _ := row["TABLE_NAME"].(string)
+		if partitionName, ok := stagingPartitionName(m.TbName, name); ok {
+			leftover[partitionName] = name
+		}
+	}
+	return leftover, nil
+}
+
+// latestArchiveStatus 分区最近一次归档的状态，没有归档记录时返回空。
+// 同一个实例只属于一种集群，两张日志表中最多只有一张有这个实例的记录
+func latestArchiveStatus(address string, dbName string, tbName string, partitionName string) (string, error) {
+	for _, logTb := range []string{MysqlPartitionArchiveLogTable, SpiderPartitionArchiveLogTable} {
+		var logs []*PartitionArchiveLog
+		err := model.DB.Self.Table(logTb).Where("address = ? and dbname = ? and tbname = ? and partition_name = ?",
+			address, dbName, tbName, partitionName).Order("id desc").Limit(1).Find(&logs).Error
+		if err != nil {
+			return "", err
+		}
+		if len(logs) > 0 {
+			return logs[0].Status, nil
+		}
+	}
+	return "", nil
+}
+
+// archiveLogTable 集群类型对应的归档日志表
+func archiveLogTable(clusterType string) (string, error) {
+	switch clusterType {
+	case Tendbha, Tendbsingle:
+		return MysqlPartitionArchiveLogTable, nil
+	case Tendbcluster:
+		return SpiderPartitionArchiveLogTable, nil
+	default:
+		return "", errno.NotSupportedClusterType
+	}
+}
+
+// stagingTableName exchange partition 使用的中转表，表名超过64个字符时截断并加上原表名的校验值
+func stagingTableName(tbName string, partitionName string) string {
+	name := fmt.Sprintf("%s_%s_archive", tbName, partitionName)
+	if len(name) <= 64 {
+		return name
+	}
+	return fmt.Sprintf("%.30s_%08x_%s_archive", tbName, crc32.ChecksumIEEE([]byte(tbName)), partitionName)
+}
+
+// managedPartitionName 分区任务创建的分区名，按日期分区为 p20240101，按id范围分区为 p_100
+var managedPartitionName = regexp.MustCompile(`^(p[0-9]{8}|p_[0-9]+)$`)
+
+// stagingPartitionName 从中转表名反推分区名，不是该表的中转表时返回 false
+func stagingPartitionName(tbName string, staging string) (string, bool) {
+	if !strings.HasSuffix(staging, "_archive") {
+		return "", false
+	}
+	for _, prefix := range []string{
+		tbName + "_",
+		fmt.Sprintf("%.30s_%08x_", tbName, crc32.ChecksumIEEE([]byte(tbName))),
+	} {
+		partitionName, ok := strings.CutPrefix(strings.TrimSuffix(staging, "_archive"), prefix)
+		// 只认分区任务生成的分区名，避免把以 表名_ 开头的其他表的中转表当成这张表的
+		if ok && managedPartitionName.MatchString(partitionName) &&
+			stagingTableName(tbName, partitionName) == staging {
+			return partitionName, true
+		}
+	}
+	return "", false
+}
+
+// countRows 执行返回 CNT 字段的查询
+func countRows(sql string, host Host) (int64, error) {
+	queryRequest := QueryRequest{Addresses: []string{fmt.Sprintf("%s:%d", host.Ip, host.Port)},
+		Cmds: []string{sql}, Force: false, QueryTimeout: 60, BkCloudId: host.BkCloudId}
+	output, err := OneAddressExecuteSql(queryRequest)
+	if err != nil {
+		return 0, err
+	}
+	if len(output.CmdResults) == 0 || len(output.CmdResults[0].TableData) == 0 {
+		return 0, fmt.Errorf("no result for %s", sql)
+	}
+	return strconv.ParseInt(fmt.Sprintf("%v", output.CmdResults[0].TableData[0]["CNT"]), 10, 64)
+}
+
+// executeSqls 按顺序执行语句，一条失败后不再执行后面的语句
+func executeSqls(sqls []string, host Host) error {
+	queryRequest := QueryRequest{Addresses: []string{fmt.Sprintf("%s:%d", host.Ip, host.Port)},
+		Cmds: sqls, Force: false, QueryTimeout: 300, BkCloudId: host.BkCloudId}
+	_, err := OneAddressExecuteSql(queryRequest)
+	return err
+}
+
+// fileMd5 文件的 md5 以及大小
+func fileMd5(filename string) (string, int64, error) {
+	fh, err := os.Open(filename)
+	if err != nil {
+		return "", 0, err
+	}
+	defer fh.Close()
+	h := md5.New()
+	size, err := io.Copy(h, fh)
+	if err != nil {
+		return "", 0, err
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), size, nil
+}
+
+// quoteName 库表名加上反引号
+func quoteName(name string) string {
+	return fmt.Sprintf("`%s`", strings.ReplaceAll(name, "`", "``"))
+}
@@ -0,0 +1,87 @@
+package service
+
+import "time"
+
+// MysqlPartitionArchiveLogTable tendbha、tendbsingle 分区归档日志表
+const MysqlPartitionArchiveLogTable = "mysql_partition_archive_log"
+
+// SpiderPartitionArchiveLogTable tendbcluster 分区归档日志表
+const SpiderPartitionArchiveLogTable = "spider_partition_archive_log"
+
+// 分区归档的状态
+const (
+	archiving     = "archiving"
+	archived      = "archived"
+	archiveFailed = "failed"
+)
+
+// defaultArchiveKeepDays 开启归档但未指定保留天数时，归档文件保留一年
+const defaultArchiveKeepDays = 365
+
+// defaultArchiveMaxRows 单个分区超过该行数不归档，可通过 archive.max_rows 修改
+const defaultArchiveMaxRows = 5000000
+
+// archiveBatchRows 每次从中转表导出的行数，也是归档文件中每条 insert 语句的行数
+const archiveBatchRows = 1000
+
+// restoreBatchSqls 恢复归档时每次通过 db-remote-service 执行的 insert 语句条数
+const restoreBatchSqls = 10
+
+// archiveWorkDir 归档文件的本地临时目录
+const archiveWorkDir = "archive"
+
+// PartitionArchiveLog 分区归档日志，记录归档文件在介质中心的位置
+type PartitionArchiveLog struct {
+	Id            int64      `json:"id" gorm:"column:id;primary_key;auto_increment"`
+	ConfigId      int        `json:"config_id" gorm:"column:config_id"`
+	BkBizId       int64      `json:"bk_biz_id" gorm:"column:bk_biz_id"`
+	ImmuteDomain  string     `json:"immute_domain" gorm:"column:immute_domain"`
+	Address       string     `json:"address" gorm:"column:address"`
+	BkCloudId     int        `json:"bk_cloud_id" gorm:"column:bk_cloud_id"`
+	DbName        string     `json:"dbname" gorm:"column:dbname"`
+	TbName        string     `json:"tbname" gorm:"column:tbname"`
+	PartitionName string     `json:"partition_name" gorm:"column:partition_name"`
+	StagingTable  string     `json:"staging_table" gorm:"column:staging_table"`
+	TableRows     int64      `json:"table_rows" gorm:"column:table_rows"`
+	FileName      string     `json:"file_name" gorm:"column:file_name"`
+	FileSize      int64      `json:"file_size" gorm:"column:file_size"`
+	FileMd5       string     `json:"file_md5" gorm:"column:file_md5"`
+	Location      string     `json:"location" gorm:"column:location"`
+	ExpireDate    *time.Time `json:"expire_date" gorm:"column:expire_date"`
+	Status        string     `json:"status" gorm:"column:status"`
+	CheckInfo     string     `json:"check_info" gorm:"column:check_info"`
+	CreateTime    time.Time  `json:"create_time" gorm:"column:create_time"`
+	UpdateTime    time.Time  `json:"update_time" gorm:"column:update_time"`
+}
+
+// QueryArchiveInput 查询分区归档日志
+type QueryArchiveInput struct {
+	ClusterType  string `json:"cluster_type"`
+	BkBizId      int64  `json:"bk_biz_id"`
+	ConfigId     int    `json:"config_id"`
+	ImmuteDomain string `json:"immute_domain"`
+	DbName       string `json:"dbname"`
+	TbName       string `json:"tbname"`
+	Status       string `json:"status"`
+	Limit        int    `json:"limit"`
+	Offset       int    `json:"offset"`
+}
+
+// RestoreArchiveInput 把归档的分区恢复为一张表
+type RestoreArchiveInput struct {
+	ClusterType string `json:"cluster_type"`
+	BkBizId     int64  `json:"bk_biz_id"`
+	// 分区归档日志的id
+	Id int64 `json:"id"`
+	// 恢复到归档实例上的表名，库名与被归档的表相同，为空时使用 表名_分区名_restore
+	TargetTable string `json:"target_table"`
+	Operator    string `json:"operator"`
+}
+
+// RestoreArchiveResult 归档恢复的结果
+type RestoreArchiveResult struct {
+	Address   string `json:"address"`
+	DbName    string `json:"dbname"`
+	TbName    string `json:"tbname"`
+	TableRows int64  `json:"table_rows"`
+}
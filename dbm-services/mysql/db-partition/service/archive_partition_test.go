@@ -0,0 +1,110 @@
+package service
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestKeyLiteral(t *testing.T) {
+	cases := []struct {
+		column  exportColumn
+		value   interface{}
+		want    string
+		wantErr bool
+	}{
+		{column: exportColumn{name: "id", dataType: "int"}, value: "3130", want: "10"},
+		{column: exportColumn{name: "id", dataType: "bigint"}, value: "2D35", want: "-5"},
+		{column: exportColumn{name: "id", dataType: "decimal"}, value: "312E3530", want: "1.50"},
+		{column: exportColumn{name: "id", dataType: "double"}, value: "312E35652B3230", want: "1.5e+20"},
+		{column: exportColumn{name: "id", dataType: "int"}, value: "616263", wantErr: true},
+		{column: exportColumn{name: "id", dataType: "int"}, value: nil, wantErr: true},
+		{column: exportColumn{name: "id", dataType: "int"}, value: "zz", wantErr: true},
+		{
+			column: exportColumn{name: "name", dataType: "varchar", charset: "utf8mb4",
+				collation: "utf8mb4_general_ci"},
+			value: "616263",
+			want:  "_utf8mb4 X'616263' collate utf8mb4_general_ci",
+		},
+		{column: exportColumn{name: "b", dataType: "varbinary"}, value: "00FF", want: "X'00FF'"},
+		{
+			column: exportColumn{name: "d", dataType: "date"},
+			value:  "323032342D30312D3031",
+			want:   "X'323032342D30312D3031'",
+		},
+	}
+	for _, c := range cases {
+		got, err := c.column.keyLiteral(c.value)
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("keyLiteral(%s %v) = %s, want error", c.column.dataType, c.value, got)
+			}
+			continue
+		}
+		if err != nil || got != c.want {
+			t.Errorf("keyLiteral(%s %v) = %s, %v, want %s", c.column.dataType, c.value, got, err, c.want)
+		}
+	}
+}
+
+func TestValueExpr(t *testing.T) {
+	cases := []struct {
+		dataType string
+		value    interface{}
+		want     string
+	}{
+		{"int", nil, "NULL"},
+		{"int", "3130", "unhex('3130')"},
+		{"varchar", "", "unhex('')"},
+		{"json", "7B7D", "convert(unhex('7B7D') using utf8mb4)"},
+	}
+	for _, c := range cases {
+		if got := (exportColumn{name: "c", dataType: c.dataType}).valueExpr(c.value); got != c.want {
+			t.Errorf("valueExpr(%s %v) = %s, want %s", c.dataType, c.value, got, c.want)
+		}
+	}
+}
+
+func TestStagingTableName(t *testing.T) {
+	if got := stagingTableName("t1", "p20240101"); got != "t1_p20240101_archive" {
+		t.Errorf("stagingTableName short = %s", got)
+	}
+
+	long := strings.Repeat("a", 60)
+	got := stagingTableName(long, "p20240101")
+	if len(got) > 64 || !strings.HasPrefix(got, strings.Repeat("a", 30)+"_") ||
+		!strings.HasSuffix(got, "_p20240101_archive") {
+		t.Errorf("stagingTableName long = %s", got)
+	}
+	// 前 30 个字符相同的表使用不同的中转表
+	if other := stagingTableName(long+"b", "p20240101"); other == got {
+		t.Errorf("stagingTableName of different tables both %s", got)
+	}
+}
+
+func TestStagingPartitionName(t *testing.T) {
+	long := strings.Repeat("a", 60)
+	cases := []struct {
+		tbName  string
+		staging string
+		want    string
+		wantOk  bool
+	}{
+		{"t1", "t1_p20240101_archive", "p20240101", true},
+		{"t1", "t1_p_100_archive", "p_100", true},
+		{long, stagingTableName(long, "p20240101"), "p20240101", true},
+		{"t1", "t1_p20240101", "", false},
+		{"t1", "t2_p20240101_archive", "", false},
+		// t1_x 的中转表不是 t1 的
+		{"t1", "t1_x_p20240101_archive", "", false},
+		{"t1", "t1_other_archive", "", false},
+		// 截断后的名字只认自己的校验值
+		{long + "b", stagingTableName(long, "p20240101"), "", false},
+	}
+	for _, c := range cases {
+		got, ok := stagingPartitionName(c.tbName, c.staging)
+		if got != c.want || ok != c.wantOk {
+			t.Errorf("stagingPartitionName(%s, %s) = %s, %v, want %s, %v", c.tbName, c.staging, got, ok,
+				c.want, c.wantOk)
+		}
+	}
+}
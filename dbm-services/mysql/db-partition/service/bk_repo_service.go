@@ -12,13 +12,20 @@ import (
 	"net/url"
 	"os"
 	"path"
+	"strconv"
 )
 
 // UploadDirectToBkRepo 上传文件到介质中心
 func UploadDirectToBkRepo(filename string) (*BkRepoRespone, error) {
+	// 文件默认保留半年
+	return UploadToBkRepo(filename, path.Join("mysql", "partition"), 15)
+}
+
+// UploadToBkRepo 上传文件到介质中心的 dir 目录下，expireDays 为文件保留天数，0 表示永久保留
+func UploadToBkRepo(filename string, dir string, expireDays int) (*BkRepoRespone, error) {
 	// 路径需要包含文件名称
 	targetURL, err := url.JoinPath(model.BkRepo.EndPointUrl,
-		path.Join("generic", model.BkRepo.Project, model.BkRepo.PublicBucket, "mysql", "partition", filename))
+		path.Join("generic", model.BkRepo.Project, model.BkRepo.PublicBucket, dir, path.Base(filename)))
 	if err != nil {
 		slog.Error("get url fail")
 		return nil, err
@@ -49,8 +56,7 @@ func UploadDirectToBkRepo(filename string) (*BkRepoRespone, error) {
 	req.Header.Set("Content-Type", "multipart/form-data; boundary="+boundary)
 	// 文件是否可以被覆盖，默认false
 	req.Header.Set("X-BKREPO-OVERWRITE", "True")
-	// 文件默认保留半年
-	req.Header.Set("X-BKREPO-EXPIRES", "15")
+	req.Header.Set("X-BKREPO-EXPIRES", strconv.Itoa(expireDays))
 	req.ContentLength = fi.Size() + int64(bodyBuf.Len()) + int64(closeBuf.Len())
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
@@ -67,6 +73,41 @@ func UploadDirectToBkRepo(filename string) (*BkRepoRespone, error) {
 	return &baseResp, err
 }
 
+// DownloadFromBkRepo 从介质中心下载 location 路径的文件到本地 filename
+func DownloadFromBkRepo(location string, filename string) error {
+	sourceURL, err := url.JoinPath(model.BkRepo.EndPointUrl,
+		path.Join("generic", model.BkRepo.Project, model.BkRepo.PublicBucket, location))
+	if err != nil {
+		slog.Error("get url fail")
+		return err
+	}
+	slog.Info(fmt.Sprintf("start download file from %s to %s", sourceURL, filename))
+	req, err := http.NewRequest("GET", sourceURL, nil)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(model.BkRepo.User, model.BkRepo.Pwd)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("返回码非200 %d", resp.StatusCode)
+	}
+	fh, err := os.Create(filename)
+	if err != nil {
+		slog.Error("create file error", "file", filename, "err", err)
+		return err
+	}
+	defer fh.Close()
+	if _, err = io.Copy(fh, resp.Body); err != nil {
+		slog.Error("write file error", "file", filename, "err", err)
+		return err
+	}
+	return nil
+}
+
 // BkRepoRespone 响应
 type BkRepoRespone struct {
 	Code      int             `json:"code"`
@@ -174,9 +174,17 @@ func (config *PartitionConfig) GetDbTableInfo(fromCron bool, host Host) (ptlist
 					}
 				}
 			}
-			if partitioned == true && len(output.CmdResults[0].TableData) == 2 {
-				ok, errInner := CalculateInterval(output.CmdResults[0].TableData[0]["PARTITION_NAME"].(string),
-					output.CmdResults[0].TableData[1]["PARTITION_NAME"].(string), config.PartitionTimeInterval)
+			if partitioned == true && (config.PartitionType == IdRangePartitionType ||
+				len(output.CmdResults[0].TableData) == 2) {
+				var ok bool
+				var errInner error
+				if config.PartitionType == IdRangePartitionType {
+					// 按id范围分区的分区名不是日期，检查分区描述的间隔
+					ok, errInner = config.CheckIdRangeInterval(db, tb, host)
+				} else {
+					ok, errInner = CalculateInterval(output.CmdResults[0].TableData[0]["PARTITION_NAME"].(string),
+						output.CmdResults[0].TableData[1]["PARTITION_NAME"].(string), config.PartitionTimeInterval)
+				}
 				if errInner != nil {
 					slog.Error("CalculateInterval", "error", errInner.Error())
 					return nil, errInner
@@ -242,7 +250,7 @@ func CheckPartitionExpression(expression, method, column string, partitionType i
 		if (expression == column || expression == columnWithBackquote) && method == "LIST" {
 			return true, nil
 		}
-	case 101:
+	case 101, IdRangePartitionType:
 		if (expression == column || expression == columnWithBackquote) && method == "RANGE" {
 			return true, nil
 		}
@@ -296,7 +304,29 @@ func CalculateInterval(firstName, secondName string, interval int) (bool, error)
 
 // GetDropPartitionSql 生成删除分区的sql
 func (m *ConfigDetail) GetDropPartitionSql(host Host) (string, error) {
-	var sql, dropSql, fx string
+	var dropSql string
+	expired, err := m.GetExpiredPartitions(host)
+	if err != nil {
+		return dropSql, err
+	}
+	if m.ArchiveEnable {
+		expired, err = m.droppablePartitions(expired, host)
+		if err != nil {
+			return dropSql, err
+		}
+	}
+	if len(expired) != 0 {
+		dropSql = fmt.Sprintf("alter table `%s`.`%s` drop partition %s", m.DbName, m.TbName, strings.Join(expired, ","))
+	}
+	return dropSql, nil
+}
+
+// GetExpiredPartitions 获取已经过期的分区
+func (m *ConfigDetail) GetExpiredPartitions(host Host) ([]string, error) {
+	if m.PartitionType == IdRangePartitionType {
+		return m.GetIdRangeExpiredPartitions(host)
+	}
+	var fx string
 	// 保留时间+1天，考虑时区差异引起的时间计算不稳定
 	reserve := m.ReservedPartition*m.PartitionTimeInterval + 1
 	address := fmt.Sprintf("%s:%d", host.Ip, host.Port)
@@ -318,14 +348,14 @@ func (m *ConfigDetail) GetDropPartitionSql(host Host) (string, error) {
 	case 5:
 		fx = fmt.Sprintf(`UNIX_TIMESTAMP(date_sub(curdate(),INTERVAL %d DAY))`, reserve-DiffOneDay)
 	default:
-		return dropSql, errno.NotSupportedPartitionType
+		return nil, errno.NotSupportedPartitionType
 	}
-	sql = fmt.Sprintf("%s %s %s", base0, fx, base1)
+	sql := fmt.Sprintf("%s %s %s", base0, fx, base1)
 	var queryRequest = QueryRequest{Addresses: []string{address}, Cmds: []string{sql}, Force: true, QueryTimeout: 30,
 		BkCloudId: int(host.BkCloudId)}
 	output, err := OneAddressExecuteSql(queryRequest)
 	if err != nil {
-		return dropSql, err
+		return nil, err
 	}
 	reg := regexp.MustCompile(fmt.Sprintf("^%s$", "p[0-9]{8}"))
 
@@ -335,14 +365,11 @@ func (m *ConfigDetail) GetDropPartitionSql(host Host) (string, error) {
 		if reg.MatchString(name) {
 			expired = append(expired, name)
 		} else {
-			return dropSql, fmt.Errorf("partition_name [%s] not like 'p20130101', "+
+			return nil, fmt.Errorf("partition_name [%s] not like 'p20130101', "+
 				"not created by partition system, can't be dropped", name)
 		}
 	}
-	if len(expired) != 0 {
-		dropSql = fmt.Sprintf("alter table `%s`.`%s` drop partition %s", m.DbName, m.TbName, strings.Join(expired, ","))
-	}
-	return dropSql, nil
+	return expired, nil
 }
 
 // GetInitPartitionSql 首次分区,自动分区
@@ -350,8 +377,10 @@ func (m *ConfigDetail) GetInitPartitionSql(dbtype string, splitCnt int, host Hos
 	var sqlPartitionDesc []string
 	var pkey, descKey, descFormat, initSql string
 	var needSize, diff int
-	var err error
 	slog.Info(fmt.Sprintf("GetInitPartitionSql ConfigDetail: %v", m))
+	if m.PartitionType == IdRangePartitionType {
+		return m.GetIdRangeInitPartitionSql(dbtype, splitCnt, host)
+	}
 	switch m.PartitionType {
 	case 0:
 		pkey = fmt.Sprintf("RANGE (TO_DAYS(%s))", m.PartitionColumn)
@@ -406,7 +435,15 @@ func (m *ConfigDetail) GetInitPartitionSql(dbtype string, splitCnt int, host Hos
 	// --charset=utf8 --recursion-method=NONE --alter-foreign-keys-method=auto --alter "partition by xxx"
 	// D=leagues_server_HN1,t=league_audit --max-load Threads_running=100 --critical-load=Threads_running:80 --no-drop-old-table
 	// --pause-file=/tmp/partition_osc_pause_xxxx --set-vars lock_wait_timeout=5 --execute >> /data/dbbak/xxx.out 2>&1 &
+	return m.partitionBySql(dbtype, pkey, sqlPartitionDesc, splitCnt, host)
+}
 
+// partitionBySql 生成把表改为分区表的语句，有唯一键的表使用pt-osc
+func (m *ConfigDetail) partitionBySql(dbtype string, pkey string, sqlPartitionDesc []string, splitCnt int,
+	host Host) (string, int, error) {
+	var initSql string
+	var needSize int
+	var err error
 	if dbtype == "TDBCTL" {
 		initSql = fmt.Sprintf("alter table `%s`.`%s` partition by %s (%s)", m.DbName, m.TbName, pkey,
 			strings.Join(sqlPartitionDesc, ","))
@@ -434,17 +471,10 @@ func (m *ConfigDetail) GetInitPartitionSql(dbtype string, splitCnt int, host Hos
 // CheckTableSize TODO
 func (m *ConfigDetail) CheckTableSize(splitCnt int, host Host) (int, error) {
 	var needSize int
-	address := fmt.Sprintf("%s:%d", host.Ip, host.Port)
-	sql := fmt.Sprintf(
-		"select TABLE_ROWS,(DATA_LENGTH+INDEX_LENGTH) as BYTES from information_schema.tables where TABLE_SCHEMA='%s' and TABLE_NAME='%s'", m.DbName, m.TbName)
-	var queryRequest = QueryRequest{Addresses: []string{address}, Cmds: []string{sql}, Force: true, QueryTimeout: 30,
-		BkCloudId: m.BkCloudId}
-	output, err := OneAddressExecuteSql(queryRequest)
+	rows, bytes, err := m.GetTableSize(host)
 	if err != nil {
 		return needSize, err
 	}
-	rows, _ := strconv.Atoi(output.CmdResults[0].TableData[0]["TABLE_ROWS"].(string))
-	bytes, _ := strconv.Atoi(output.CmdResults[0].TableData[0]["BYTES"].(string))
 	if bytes < viper.GetInt("pt.max_size") && rows < viper.GetInt("pt.max_rows") {
 		needSize = 3 * bytes * splitCnt // 预留空间：3倍于表大小的空间用于做pt-osc，如果是spider remote，再乘以这台机器上的分片数量
 		return needSize, nil
@@ -456,6 +486,22 @@ func (m *ConfigDetail) CheckTableSize(splitCnt int, host Host) (int, error) {
 	}
 }
 
+// GetTableSize 获取表的行数以及数据和索引的大小
+func (m *ConfigDetail) GetTableSize(host Host) (int, int, error) {
+	address := fmt.Sprintf("%s:%d", host.Ip, host.Port)
+	sql := fmt.Sprintf(
+		"select TABLE_ROWS,(DATA_LENGTH+INDEX_LENGTH) as BYTES from information_schema.tables where TABLE_SCHEMA='%s' and TABLE_NAME='%s'", m.DbName, m.TbName)
+	var queryRequest = QueryRequest{Addresses: []string{address}, Cmds: []string{sql}, Force: true, QueryTimeout: 30,
+		BkCloudId: m.BkCloudId}
+	output, err := OneAddressExecuteSql(queryRequest)
+	if err != nil {
+		return 0, 0, err
+	}
+	rows, _ := strconv.Atoi(output.CmdResults[0].TableData[0]["TABLE_ROWS"].(string))
+	bytes, _ := strconv.Atoi(output.CmdResults[0].TableData[0]["BYTES"].(string))
+	return rows, bytes, nil
+}
+
 // GetAddPartitionSql 生成增加分区的sql
 func (m *ConfigDetail) GetAddPartitionSql(host Host) (string, error) {
 	var vsql, addSql, descKey, name, fx string
@@ -463,6 +509,9 @@ func (m *ConfigDetail) GetAddPartitionSql(host Host) (string, error) {
 	var diff, desc int
 	var begin int
 	address := fmt.Sprintf("%s:%d", host.Ip, host.Port)
+	if m.PartitionType == IdRangePartitionType {
+		return m.GetIdRangeAddPartitionSql(host)
+	}
 	switch m.PartitionType {
 	case 0:
 		diff = DiffOneDay
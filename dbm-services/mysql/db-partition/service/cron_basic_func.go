@@ -93,6 +93,9 @@ func (m PartitionJob) ExecuteTendbhaPartition() {
 		var objects []PartitionObject
 		for _, cluster := range clusters {
 			port := master[cluster].Port
+			// 开启归档的分区规则先归档过期分区，归档后的空分区才会生成删除语句
+			ArchiveBeforeDrop(clusterConfigs[int(cluster)], Tendbha, Host{Ip: ip, Port: port, BkCloudId: cloud},
+				m.CronDate)
 			// 获取需要执行的分区语句，哪些分区规则不需要执行
 			sqls, nothingToDo, checkFail, _ := CheckPartitionConfigs(clusterConfigs[int(cluster)], "mysql",
 				1, true, Host{Ip: ip, Port: port, BkCloudId: cloud})
@@ -185,6 +188,11 @@ func (m PartitionJob) ExecuteTendbclusterPartition() {
 					}
 					newconfigs[k] = &newconfig
 				}
+				// 中控节点上的表没有数据，只在remote上归档
+				if ins.Wrapper == "mysql" {
+					ArchiveBeforeDrop(newconfigs, Tendbcluster, Host{Ip: ins.Ip, Port: ins.Port, BkCloudId: ins.Cloud},
+						m.CronDate)
+				}
 				// 在这个实例上，不需要执行的、需要执行的、检查失败的分区规则
 				sqls, nothingToDo, fail, _ := CheckPartitionConfigs(newconfigs, ins.Wrapper,
 					splitCnt, true, Host{Ip: ins.Ip, Port: ins.Port, BkCloudId: ins.Cloud})
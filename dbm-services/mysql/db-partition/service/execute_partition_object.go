@@ -20,8 +20,14 @@ type PartitionConfig struct {
 	PartitionColumn     string `json:"partition_columns" gorm:"column:partition_column"`
 	PartitionColumnType string `json:"partition_column_type" gorm:"column:partition_column_type"`
 	// 保留的分区个数 ReservedPartition := ExpireTime / PartitionTimeInterval
+	// 按id范围分区时为保留的有数据的分区个数，0表示不按个数删除分区
 	ReservedPartition int `json:"reserved_partition" gorm:"column:reserved_partition"`
-	ExtraPartition    int `json:"extra_partition" gorm:"column:extra_partition"`
+	// 预先创建的分区个数，按id范围分区时为当前最大id之后预留的空分区个数
+	ExtraPartition int `json:"extra_partition" gorm:"column:extra_partition"`
+	// 按id范围分区时每个分区的id范围
+	RangeInterval int64 `json:"range_interval" gorm:"column:range_interval"`
+	// 按id范围分区时，分区总大小超过该值后删除最早的分区，单位字节，0表示不按大小删除分区
+	ExpireSize int64 `json:"expire_size" gorm:"column:expire_size"`
 	// 分区间隔
 	PartitionTimeInterval int `json:"partition_time_interval" gorm:"column:partition_time_interval"`
 	PartitionType         int `json:"partition_type" gorm:"column:partition_type"`
@@ -29,6 +35,10 @@ type PartitionConfig struct {
 	ExpireTime int `json:"expire_time"`
 	// 集群所在的时区
 	TimeZone string `json:"time_zone"`
+	// 删除过期分区前先归档到介质中心
+	ArchiveEnable bool `json:"archive_enable" gorm:"column:archive_enable"`
+	// 归档文件在介质中心保留的天数
+	ArchiveKeepDays int `json:"archive_keep_days" gorm:"column:archive_keep_days"`
 	// 分区规则启用或者禁用
 	Phase      string    `json:"phase" gorm:"column:phase"`
 	Creator    string    `json:"creator" gorm:"column:creator"`
@@ -0,0 +1,202 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// IdRangePolicy 按整型字段(通常是自增id)的范围分区
+const IdRangePolicy = "id_range"
+
+// IdRangePartitionType 按id范围分区的分区类型，PARTITION BY RANGE (id)，分区名为 p_分区上界
+const IdRangePartitionType = 201
+
+var idRangePartitionName = regexp.MustCompile(`^p_[0-9]+$`)
+
+// idRangePartition 按id范围分区的一个分区
+type idRangePartition struct {
+	Name string
+	// values less than 的值
+	Desc  int64
+	Bytes int64
+}
+
+// checkIdRangePolicy 检查按id范围分区的配置
+func (m *CreatePartitionsInput) checkIdRangePolicy() error {
+	// spider 中控节点上没有数据，无法按照最大id预留分区
+	if strings.ToLower(m.ClusterType) == Tendbcluster {
+		return errors.New("tendbcluster 不支持按id范围分区")
+	}
+	if m.PartitionColumnType != "int" && m.PartitionColumnType != "bigint" {
+		return errors.New("按id范围分区，分区字段类型必须为 int、bigint")
+	}
+	if m.RangeInterval < 1 {
+		return errors.New("每个分区的id范围不能小于1")
+	}
+	if m.RangeHeadroom < 1 {
+		return errors.New("预留的空分区个数不能小于1")
+	}
+	if m.ExpireCount < 0 || m.ExpireSize < 0 {
+		return errors.New("保留的分区个数、分区总大小不能小于0")
+	}
+	return nil
+}
+
+// GetIdRangeInitPartitionSql 按id范围首次分区，当前最大id所在的分区保存所有已有数据，之后预留 ExtraPartition 个空分区
+func (m *ConfigDetail) GetIdRangeInitPartitionSql(dbtype string, splitCnt int, host Host) (string, int, error) {
+	maxId, err := m.getMaxId(host)
+	if err != nil {
+		return "", 0, err
+	}
+	current := (maxId/m.RangeInterval + 1) * m.RangeInterval
+	var sqlPartitionDesc []string
+	for i := 0; i <= m.ExtraPartition; i++ {
+		desc := current + int64(i)*m.RangeInterval
+		sqlPartitionDesc = append(sqlPartitionDesc, fmt.Sprintf(" partition p_%d values less than (%d)", desc, desc))
+	}
+	return m.partitionBySql(dbtype, fmt.Sprintf("RANGE (%s)", m.PartitionColumn), sqlPartitionDesc, splitCnt, host)
+}
+
+// GetIdRangeAddPartitionSql 当前最大id之后的空分区不足 ExtraPartition 个时，生成增加分区的sql
+func (m *ConfigDetail) GetIdRangeAddPartitionSql(host Host) (string, error) {
+	partitions, err := getIdRangePartitions(m.DbName, m.TbName, host)
+	if err != nil {
+		return "", err
+	}
+	if len(partitions) == 0 {
+		return "", fmt.Errorf("%s.%s has no partition", m.DbName, m.TbName)
+	}
+	maxId, err := m.getMaxId(host)
+	if err != nil {
+		return "", err
+	}
+	// 分区的下界大于最大id，是预留的空分区
+	var ahead int
+	lower := int64(math.MinInt64)
+	for _, p := range partitions {
+		if lower > maxId {
+			ahead++
+		}
+		lower = p.Desc
+	}
+	need := m.ExtraPartition - ahead
+	if need <= 0 {
+		return "", nil
+	}
+	last := partitions[len(partitions)-1].Desc
+	var adds []string
+	for i := 1; i <= need; i++ {
+		desc := last + int64(i)*m.RangeInterval
+		adds = append(adds, fmt.Sprintf("partition `p_%d` values less than (%d)", desc, desc))
+	}
+	return fmt.Sprintf("alter table `%s`.`%s` add partition (%s)", m.DbName, m.TbName, strings.Join(adds, ",")), nil
+}
+
+// GetIdRangeExpiredPartitions 按保留的分区个数、分区总大小获取需要删除的分区。
+// 只删除最大id所在分区之前的分区，从最早的分区开始删除
+func (m *ConfigDetail) GetIdRangeExpiredPartitions(host Host) ([]string, error) {
+	if m.ReservedPartition <= 0 && m.ExpireSize <= 0 {
+		return nil, nil
+	}
+	partitions, err := getIdRangePartitions(m.DbName, m.TbName, host)
+	if err != nil {
+		return nil, err
+	}
+	maxId, err := m.getMaxId(host)
+	if err != nil {
+		return nil, err
+	}
+	var old []idRangePartition
+	for _, p := range partitions {
+		if p.Desc <= maxId {
+			old = append(old, p)
+		}
+	}
+	var drop int
+	if m.ReservedPartition > 0 {
+		// 最大id所在的分区也计入保留的分区个数
+		drop = len(old) - (m.ReservedPartition - 1)
+	}
+	if m.ExpireSize > 0 {
+		_, bytes, errInner := m.GetTableSize(host)
+		if errInner != nil {
+			return nil, errInner
+		}
+		total := int64(bytes)
+		var n int
+		for n < len(old) && total > m.ExpireSize {
+			total -= old[n].Bytes
+			n++
+		}
+		if n > drop {
+			drop = n
+		}
+	}
+	var expired []string
+	for i := 0; i < drop && i < len(old); i++ {
+		if !idRangePartitionName.MatchString(old[i].Name) {
+			return nil, fmt.Errorf("partition_name [%s] not like 'p_1000000', "+
+				"not created by partition system, can't be dropped", old[i].Name)
+		}
+		expired = append(expired, old[i].Name)
+	}
+	return expired, nil
+}
+
+// CheckIdRangeInterval 检查已有分区的id范围与配置是否一致
+func (config *PartitionConfig) CheckIdRangeInterval(db string, tb string, host Host) (bool, error) {
+	partitions, err := getIdRangePartitions(db, tb, host)
+	if err != nil {
+		return false, err
+	}
+	for i := 1; i < len(partitions); i++ {
+		if partitions[i].Desc-partitions[i-1].Desc != config.RangeInterval {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// getMaxId 分区字段当前的最大值，空表为0
+func (m *ConfigDetail) getMaxId(host Host) (int64, error) {
+	sql := fmt.Sprintf("select ifnull(max(`%s`),0) as MAX_ID from `%s`.`%s`", m.PartitionColumn, m.DbName, m.TbName)
+	var queryRequest = QueryRequest{Addresses: []string{fmt.Sprintf("%s:%d", host.Ip, host.Port)},
+		Cmds: []string{sql}, Force: true, QueryTimeout: 30, BkCloudId: host.BkCloudId}
+	output, err := OneAddressExecuteSql(queryRequest)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(fmt.Sprintf("%v", output.CmdResults[0].TableData[0]["MAX_ID"]), 10, 64)
+}
+
+// getIdRangePartitions 按分区顺序获取分区的上界以及大小
+func getIdRangePartitions(db string, tb string, host Host) ([]idRangePartition, error) {
+	sql := fmt.Sprintf("select PARTITION_NAME as PARTITION_NAME,PARTITION_DESCRIPTION as PARTITION_DESCRIPTION,"+
+		"(DATA_LENGTH+INDEX_LENGTH) as BYTES from information_schema.PARTITIONS "+
+		"where TABLE_SCHEMA='%s' and TABLE_NAME='%s' and PARTITION_NAME is not null "+
+		"order by PARTITION_ORDINAL_POSITION", db, tb)
+	var queryRequest = QueryRequest{Addresses: []string{fmt.Sprintf("%s:%d", host.Ip, host.Port)},
+		Cmds: []string{sql}, Force: true, QueryTimeout: 30, BkCloudId: host.BkCloudId}
+	output, err := OneAddressExecuteSql(queryRequest)
+	if err != nil {
+		return nil, err
+	}
+	var partitions []idRangePartition
+	for _, row := range output.CmdResults[0].TableData {
+		name, _ := row["PARTITION_NAME"].(string)
+		descStr, _ := row["PARTITION_DESCRIPTION"].(string)
+		desc, errParse := strconv.ParseInt(descStr, 10, 64)
+		if errParse != nil {
+			// MAXVALUE 分区之后不能再增加分区
+			return nil, fmt.Errorf("%s.%s partition %s values less than (%s) is not an id range partition",
+				db, tb, name, descStr)
+		}
+		bytes, _ := strconv.ParseInt(fmt.Sprintf("%v", row["BYTES"]), 10, 64)
+		partitions = append(partitions, idRangePartition{Name: name, Desc: desc, Bytes: bytes})
+	}
+	return partitions, nil
+}
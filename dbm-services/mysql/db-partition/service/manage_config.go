@@ -270,36 +270,46 @@ func (m *CreatePartitionsInput) CreatePartitionsConfig() (error, []int) {
 		return errors.New("库表名不能为空！"), []int{}
 	}
 
-	if m.PartitionTimeInterval < 1 {
-		return errors.New("分区间隔不能小于1"), []int{}
-	}
+	var reservedPartition, extraPartition, partitionType int
+	archiveKeepDays := m.getArchiveKeepDays()
+	if m.PartitionPolicy == IdRangePolicy {
+		if err := m.checkIdRangePolicy(); err != nil {
+			return err, []int{}
+		}
+		// 按id范围分区，保留的分区个数与预留的分区个数由页面指定
+		reservedPartition, extraPartition, partitionType = m.ExpireCount, m.RangeHeadroom, IdRangePartitionType
+	} else {
+		if m.PartitionTimeInterval < 1 {
+			return errors.New("分区间隔不能小于1"), []int{}
+		}
 
-	if m.ExpireTime < m.PartitionTimeInterval {
-		return errors.New("过期时间必须不小于分区间隔"), []int{}
-	}
-	if m.ExpireTime%m.PartitionTimeInterval != 0 {
-		return errors.New("过期时间必须是分区间隔的整数倍"), []int{}
-	}
-	reservedPartition := m.ExpireTime / m.PartitionTimeInterval
-	partitionType := 0
-	// 普通分区类型0 5 101
-	switch m.PartitionColumnType {
-	case "datetime", "date":
-		if strings.EqualFold(m.RemoteHashAlgorithm, "range") {
-			partitionType = 4
-		} else {
-			partitionType = 0
+		if m.ExpireTime < m.PartitionTimeInterval {
+			return errors.New("过期时间必须不小于分区间隔"), []int{}
 		}
-	case "timestamp":
-		partitionType = 5
-	case "int", "bigint":
-		if strings.EqualFold(m.RemoteHashAlgorithm, "list") {
-			partitionType = 3
-		} else {
-			partitionType = 101
+		if m.ExpireTime%m.PartitionTimeInterval != 0 {
+			return errors.New("过期时间必须是分区间隔的整数倍"), []int{}
 		}
-	default:
-		return errors.New("请选择分区字段类型：datetime、date、timestamp、int、bigint"), []int{}
+		reservedPartition = m.ExpireTime / m.PartitionTimeInterval
+		// 普通分区类型0 5 101
+		switch m.PartitionColumnType {
+		case "datetime", "date":
+			if strings.EqualFold(m.RemoteHashAlgorithm, "range") {
+				partitionType = 4
+			} else {
+				partitionType = 0
+			}
+		case "timestamp":
+			partitionType = 5
+		case "int", "bigint":
+			if strings.EqualFold(m.RemoteHashAlgorithm, "list") {
+				partitionType = 3
+			} else {
+				partitionType = 101
+			}
+		default:
+			return errors.New("请选择分区字段类型：datetime、date、timestamp、int、bigint"), []int{}
+		}
+		extraPartition = extraTime
 	}
 	var errs []string
 	warnings1, err := m.compareWithSameArray()
@@ -331,11 +341,15 @@ func (m *CreatePartitionsInput) CreatePartitionsConfig() (error, []int) {
 				PartitionColumn:       m.PartitionColumn,
 				PartitionColumnType:   m.PartitionColumnType,
 				ReservedPartition:     reservedPartition,
-				ExtraPartition:        extraTime,
+				ExtraPartition:        extraPartition,
+				RangeInterval:         m.RangeInterval,
+				ExpireSize:            m.ExpireSize,
 				PartitionTimeInterval: m.PartitionTimeInterval,
 				PartitionType:         partitionType,
 				ExpireTime:            m.ExpireTime,
 				TimeZone:              m.TimeZone,
+				ArchiveEnable:         m.ArchiveEnable,
+				ArchiveKeepDays:       archiveKeepDays,
 				Creator:               m.Creator,
 				Updator:               m.Updator,
 				Phase:                 online,
@@ -381,29 +395,39 @@ func (m *CreatePartitionsInput) UpdatePartitionsConfig() error {
 		return errors.New("库表名不能为空！")
 	}
 
-	if m.PartitionTimeInterval < 1 {
-		return errors.New("分区间隔不能小于1")
-	}
+	var reservedPartition, extraPartition, partitionType int
+	archiveKeepDays := m.getArchiveKeepDays()
+	if m.PartitionPolicy == IdRangePolicy {
+		if err := m.checkIdRangePolicy(); err != nil {
+			return err
+		}
+		// 按id范围分区，保留的分区个数与预留的分区个数由页面指定
+		reservedPartition, extraPartition, partitionType = m.ExpireCount, m.RangeHeadroom, IdRangePartitionType
+	} else {
+		if m.PartitionTimeInterval < 1 {
+			return errors.New("分区间隔不能小于1")
+		}
 
-	if m.ExpireTime < m.PartitionTimeInterval {
-		return errors.New("过期时间必须不小于分区间隔")
-	}
-	if m.ExpireTime%m.PartitionTimeInterval != 0 {
-		return errors.New("过期时间必须是分区间隔的整数倍")
-	}
+		if m.ExpireTime < m.PartitionTimeInterval {
+			return errors.New("过期时间必须不小于分区间隔")
+		}
+		if m.ExpireTime%m.PartitionTimeInterval != 0 {
+			return errors.New("过期时间必须是分区间隔的整数倍")
+		}
 
-	reservedPartition := m.ExpireTime / m.PartitionTimeInterval
-	partitionType := 0
+		reservedPartition = m.ExpireTime / m.PartitionTimeInterval
 
-	switch m.PartitionColumnType {
-	case "datetime", "date":
-		partitionType = 0
-	case "timestamp":
-		partitionType = 5
-	case "int", "bigint":
-		partitionType = 101
-	default:
-		return errors.New("请选择分区字段类型：datetime、date、timestamp、int、bigint")
+		switch m.PartitionColumnType {
+		case "datetime", "date":
+			partitionType = 0
+		case "timestamp":
+			partitionType = 5
+		case "int", "bigint":
+			partitionType = 101
+		default:
+			return errors.New("请选择分区字段类型：datetime、date、timestamp、int、bigint")
+		}
+		extraPartition = extraTime
 	}
 	var errs []string
 	for _, dblike := range m.DbLikes {
@@ -439,10 +463,14 @@ func (m *CreatePartitionsInput) UpdatePartitionsConfig() error {
 				"partition_column":        m.PartitionColumn,
 				"partition_column_type":   m.PartitionColumnType,
 				"reserved_partition":      reservedPartition,
-				"extra_partition":         extraTime,
+				"extra_partition":         extraPartition,
+				"range_interval":          m.RangeInterval,
+				"expire_size":             m.ExpireSize,
 				"partition_time_interval": m.PartitionTimeInterval,
 				"partition_type":          partitionType,
 				"expire_time":             m.ExpireTime,
+				"archive_enable":          m.ArchiveEnable,
+				"archive_keep_days":       archiveKeepDays,
 				"updator":                 m.Updator,
 				"update_time":             time.Now(),
 			}
@@ -464,6 +492,14 @@ func (m *CreatePartitionsInput) UpdatePartitionsConfig() error {
 	return nil
 }
 
+// getArchiveKeepDays 开启归档时，未指定保留天数使用默认值
+func (m *CreatePartitionsInput) getArchiveKeepDays() int {
+	if m.ArchiveEnable && m.ArchiveKeepDays <= 0 {
+		return defaultArchiveKeepDays
+	}
+	return m.ArchiveKeepDays
+}
+
 // DisablePartitionConfig TODO
 func (m *DisablePartitionInput) DisablePartitionConfig() error {
 	if len(m.Ids) == 0 {
@@ -81,6 +81,14 @@ type CreatePartitionsInput struct {
 	Creator               string   `json:"creator"`
 	Updator               string   `json:"updator"`
 	RemoteHashAlgorithm   string   `json:"remote_hash_algorithm"`
+	ArchiveEnable         bool     `json:"archive_enable"`    // 删除过期分区前先归档
+	ArchiveKeepDays       int      `json:"archive_keep_days"` // 归档文件保留天数
+	// 分区策略，为空时按时间分区，id_range 按整型字段的范围分区
+	PartitionPolicy string `json:"partition_policy"`
+	RangeInterval   int64  `json:"range_interval"` // 每个分区的id范围
+	RangeHeadroom   int    `json:"range_headroom"` // 当前最大id之后预留的空分区个数
+	ExpireCount     int    `json:"expire_count"`   // 保留的有数据的分区个数，0表示不按个数删除
+	ExpireSize      int64  `json:"expire_size"`    // 分区总大小上限，单位字节，0表示不按大小删除
 }
 
 // DeletePartitionConfigByIds Ids 是分区配置的主键id
@@ -0,0 +1,13 @@
+SET NAMES utf8;
+CREATE TABLE IF NOT EXISTS `tb_priv_drift_reports` (
+    `id` bigint NOT NULL AUTO_INCREMENT,
+    `bk_biz_id` int(11) NOT NULL COMMENT '业务的 cmdb id',
+    `cluster_type` varchar(100) NOT NULL COMMENT '集群类型',
+    `immute_domain` varchar(255) NOT NULL COMMENT '集群域名',
+    `drift_count` int NOT NULL DEFAULT 0 COMMENT '漂移的权限条数',
+    `report` longtext NOT NULL COMMENT '检查结果以及修复语句',
+    `operator` varchar(200) DEFAULT NULL COMMENT '检查发起者',
+    `create_time` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '检查时间',
+    PRIMARY KEY (`id`),
+    KEY `idx_biz_domain` (`bk_biz_id`, `immute_domain`),
+    KEY `idx_create_time` (`create_time`)) ENGINE=InnoDB DEFAULT CHARSET=utf8;
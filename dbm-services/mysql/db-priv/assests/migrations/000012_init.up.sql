@@ -0,0 +1,21 @@
+SET NAMES utf8;
+CREATE TABLE IF NOT EXISTS `tb_temporary_privs` (
+    `id` bigint NOT NULL AUTO_INCREMENT,
+    `bk_biz_id` int(11) NOT NULL COMMENT '业务的 cmdb id',
+    `cluster_type` varchar(100) NOT NULL COMMENT '集群类型',
+    `user` varchar(200) NOT NULL COMMENT '账号',
+    `priv_log_id` bigint NOT NULL COMMENT 'priv_logs 中授权记录的 id',
+    `para` longtext NOT NULL COMMENT '授权参数以及授权时的账号规则',
+    `expire_time` timestamp NOT NULL COMMENT '过期时间',
+    `status` varchar(50) NOT NULL COMMENT 'active, revoking, revoked, revoke_failed',
+    `retries` int NOT NULL DEFAULT 0 COMMENT '回收失败次数',
+    `err_msg` text COMMENT '回收失败的原因',
+    `ticket` varchar(200) DEFAULT NULL COMMENT '授权接口',
+    `creator` varchar(200) NOT NULL COMMENT '创建者',
+    `create_time` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    `operator` varchar(200) DEFAULT NULL COMMENT '最后一次变更者',
+    `update_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP COMMENT '最后一次变更时间',
+    `revoke_time` timestamp NULL DEFAULT NULL COMMENT '回收时间',
+    PRIMARY KEY (`id`),
+    KEY `idx_status_expire_time` (`status`, `expire_time`),
+    KEY `idx_biz_user` (`bk_biz_id`, `user`)) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE utf8_bin;
@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log/slog"
+
+	"dbm-services/common/go-pubpkg/errno"
+	"dbm-services/mysql/priv-service/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CheckPrivDrift 检查集群实例上的权限与账号规则、授权记录是否一致，可生成修复语句
+// 只读接口，不会在实例上执行修复语句
+func (m *PrivService) CheckPrivDrift(c *gin.Context) {
+	slog.Info("do CheckPrivDrift!")
+	var input service.DriftCheckPara
+
+	body, err := ioutil.ReadAll(c.Request.Body)
+	if err != nil {
+		slog.Error("msg", "error", err)
+		SendResponse(c, errno.ErrBind, err)
+		return
+	}
+
+	if err = json.Unmarshal(body, &input); err != nil {
+		slog.Error("msg", "error", err)
+		SendResponse(c, errno.ErrBind, err)
+		return
+	}
+
+	reports, err := input.CheckPrivDrift()
+	SendResponse(c, err, ListResponse{
+		Count: len(reports),
+		Items: reports,
+	})
+	return
+}
+
+// GetPrivDriftReport 查询权限漂移检查结果，报告中的修复语句需要人工确认后另行执行
+func (m *PrivService) GetPrivDriftReport(c *gin.Context) {
+	slog.Info("do GetPrivDriftReport!")
+	var input service.GetDriftReportPara
+
+	body, err := ioutil.ReadAll(c.Request.Body)
+	if err != nil {
+		slog.Error("msg", "error", err)
+		SendResponse(c, errno.ErrBind, err)
+		return
+	}
+
+	if err = json.Unmarshal(body, &input); err != nil {
+		slog.Error("msg", "error", err)
+		SendResponse(c, errno.ErrBind, err)
+		return
+	}
+
+	reports, count, err := input.GetPrivDriftReport()
+	SendResponse(c, err, ListResponse{
+		Count: count,
+		Items: reports,
+	})
+	return
+}
@@ -43,6 +43,15 @@ func (m *PrivService) Routes() []*gin.RouteInfo {
 		{Method: http.MethodPost, Path: "get_priv", HandlerFunc: m.GetPriv},
 		{Method: http.MethodPost, Path: "get_user_list", HandlerFunc: m.GetUserList},
 
+		// 临时授权，add_priv 指定 expire_time 时为临时授权，到期自动回收
+		{Method: http.MethodPost, Path: "get_temporary_priv", HandlerFunc: m.GetTemporaryPriv},
+		{Method: http.MethodPost, Path: "extend_temporary_priv", HandlerFunc: m.ExtendTemporaryPriv},
+		{Method: http.MethodPost, Path: "revoke_temporary_priv", HandlerFunc: m.RevokeTemporaryPriv},
+
+		// 权限漂移检查
+		{Method: http.MethodPost, Path: "check_priv_drift", HandlerFunc: m.CheckPrivDrift},
+		{Method: http.MethodPost, Path: "get_priv_drift_report", HandlerFunc: m.GetPrivDriftReport},
+
 		// 实例间权限克隆
 		{Method: http.MethodPost, Path: "clone_instance_priv_dry_run", HandlerFunc: m.CloneInstancePrivDryRun},
 		{Method: http.MethodPost, Path: "clone_instance_priv", HandlerFunc: m.CloneInstancePriv},
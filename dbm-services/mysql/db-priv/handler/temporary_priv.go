@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log/slog"
+	"strings"
+
+	"dbm-services/common/go-pubpkg/errno"
+	"dbm-services/mysql/priv-service/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetTemporaryPriv 查询临时授权
+func (m *PrivService) GetTemporaryPriv(c *gin.Context) {
+	slog.Info("do GetTemporaryPriv!")
+	var input service.GetTemporaryPrivPara
+
+	body, err := ioutil.ReadAll(c.Request.Body)
+	if err != nil {
+		slog.Error("msg", "error", err)
+		SendResponse(c, errno.ErrBind, err)
+		return
+	}
+
+	if err = json.Unmarshal(body, &input); err != nil {
+		slog.Error("msg", "error", err)
+		SendResponse(c, errno.ErrBind, err)
+		return
+	}
+
+	privs, count, err := input.GetTemporaryPriv()
+	SendResponse(c, err, ListResponse{
+		Count: count,
+		Items: privs,
+	})
+	return
+}
+
+// ExtendTemporaryPriv 临时授权延期
+func (m *PrivService) ExtendTemporaryPriv(c *gin.Context) {
+	slog.Info("do ExtendTemporaryPriv!")
+	var input service.ModifyTemporaryPrivPara
+	ticket := strings.TrimPrefix(c.FullPath(), "/priv/")
+
+	body, err := ioutil.ReadAll(c.Request.Body)
+	if err != nil {
+		slog.Error("msg", "error", err)
+		SendResponse(c, errno.ErrBind, err)
+		return
+	}
+
+	if err = json.Unmarshal(body, &input); err != nil {
+		slog.Error("msg", "error", err)
+		SendResponse(c, errno.ErrBind, err)
+		return
+	}
+
+	err = input.ExtendTemporaryPriv(string(body), ticket)
+	SendResponse(c, err, nil)
+	return
+}
+
+// RevokeTemporaryPriv 提前回收临时授权
+func (m *PrivService) RevokeTemporaryPriv(c *gin.Context) {
+	slog.Info("do RevokeTemporaryPriv!")
+	var input service.ModifyTemporaryPrivPara
+	ticket := strings.TrimPrefix(c.FullPath(), "/priv/")
+
+	body, err := ioutil.ReadAll(c.Request.Body)
+	if err != nil {
+		slog.Error("msg", "error", err)
+		SendResponse(c, errno.ErrBind, err)
+		return
+	}
+
+	if err = json.Unmarshal(body, &input); err != nil {
+		slog.Error("msg", "error", err)
+		SendResponse(c, errno.ErrBind, err)
+		return
+	}
+
+	err = input.RevokeTemporaryPriv(string(body), ticket)
+	SendResponse(c, err, nil)
+	return
+}
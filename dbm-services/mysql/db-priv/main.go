@@ -45,6 +45,14 @@ func main() {
 	util.DbmetaClient = util.NewClientByHosts(viper.GetString("dbmeta"))
 	util.DrsClient = util.NewClientByHosts(viper.GetString("dbRemoteService"))
 
+	// 定时回收过期的临时授权
+	go service.RunTemporaryPrivJob(viper.GetDuration("temporaryPriv.interval"))
+
+	// 定时检查权限漂移
+	if viper.GetBool("privDrift.enable") {
+		go service.RunPrivDriftJob(viper.GetDuration("privDrift.interval"), viper.GetInt("privDrift.keepDays"))
+	}
+
 	// 注册服务
 	gin.SetMode(gin.ReleaseMode)
 	engine := gin.New()
@@ -51,8 +51,7 @@ func (m *AccountPara) AddAccount(jsonPara string, ticket string) (TbAccounts, er
 	innerAccount := make(map[string][]string)
 	innerAccount[sqlserver] = []string{"mssql_exporter", "dbm_admin", "sa", "sqlserver"}
 	innerAccount[mongodb] = []string{"dba", "apppdba", "monitor", "appmonitor"}
-	innerAccount[mysql] = []string{"gcs_admin", "gcs_dba", "monitor", "gm", "admin", "repl", "dba_bak_all_sel",
-		"yw", "partition_yw", "spider", "mysql.session", "mysql.sys", "gcs_spider", "sync"}
+	innerAccount[mysql] = mysqlInnerAccounts
 	innerAccount[tendbcluster] = innerAccount[mysql]
 	if !m.MigrateFlag {
 		if util.HasElem(strings.ToLower(m.User), innerAccount[*m.ClusterType]) {
@@ -301,12 +300,18 @@ func EncryptPswInDb(psw string) (string, error) {
 	return jsonString, nil
 }
 
-// AddPrivLog 记录操作日志，日志不对外
-func AddPrivLog(log PrivLog) {
+// mysqlInnerAccounts mysql、tendbcluster 的内置账号，不允许作为账号规则的账号
+var mysqlInnerAccounts = []string{"gcs_admin", "gcs_dba", "monitor", "gm", "admin", "repl", "dba_bak_all_sel",
+	"yw", "partition_yw", "spider", "mysql.session", "mysql.sys", "gcs_spider", "sync"}
+
+// AddPrivLog 记录操作日志，日志不对外，返回日志 id，记录失败时返回 0
+func AddPrivLog(log PrivLog) int64 {
 	log.Para = strings.Replace(log.Para, viper.GetString("bk_app_code"), "", -1)
 	log.Para = strings.Replace(log.Para, viper.GetString("bk_app_secret"), "", -1)
 	err := DB.Self.Create(&log).Error
 	if err != nil {
 		slog.Error("add log err", err)
+		return 0
 	}
+	return log.Id
 }
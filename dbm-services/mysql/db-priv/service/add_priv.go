@@ -45,6 +45,12 @@ func (m *PrivTaskPara) AddPrivDryRun() (PrivTaskPara, error) {
 		}
 	}
 
+	if m.ExpireTime != "" {
+		if _, err := ParseExpireTime(m.ExpireTime); err != nil {
+			errMsg = append(errMsg, err.Error())
+		}
+	}
+
 	if len(errMsg) > 0 {
 		return taskPara, errno.GrantPrivilegesParameterCheckFail.Add("\n" + strings.Join(errMsg, "\n"))
 	}
@@ -54,6 +60,7 @@ func (m *PrivTaskPara) AddPrivDryRun() (PrivTaskPara, error) {
 	taskPara.AccoutRules = m.AccoutRules
 	taskPara.ClusterType = m.ClusterType
 	taskPara.User = m.User
+	taskPara.ExpireTime = m.ExpireTime
 
 	return taskPara, nil
 }
@@ -77,7 +84,14 @@ func (m *PrivTaskPara) AddPriv(jsonPara string, ticket string) error {
 	if m.ClusterType == "" {
 		return errno.ClusterTypeIsEmpty
 	}
-	AddPrivLog(PrivLog{BkBizId: m.BkBizId, Ticket: ticket, Operator: m.Operator, Para: jsonPara, Time: time.Now()})
+	privLogId := AddPrivLog(PrivLog{BkBizId: m.BkBizId, Ticket: ticket, Operator: m.Operator, Para: jsonPara,
+		Time: time.Now()})
+	// 临时授权在授权前记录，授权部分失败时已授予的权限也会到期回收
+	if m.ExpireTime != "" {
+		if err := m.AddTemporaryPriv(privLogId, ticket); err != nil {
+			return err
+		}
+	}
 	limit := rate.Every(time.Millisecond * 100) // QPS：10
 	burst := 10                                 // 桶容量 10
 	limiter := rate.NewLimiter(limit, burst)
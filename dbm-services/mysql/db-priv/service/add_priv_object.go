@@ -18,6 +18,8 @@ type PrivTaskPara struct {
 	AccoutRules     []TbAccountRules `json:"account_rules"`
 	SourceIPs       []string         `json:"source_ips"`
 	TargetInstances []string         `json:"target_instances"`
+	// 临时授权的过期时间，格式 2006-01-02 15:04:05，为空时是永久授权，到期后自动回收
+	ExpireTime string `json:"expire_time"`
 }
 
 // Instance GetCluster 函数返回的结构体
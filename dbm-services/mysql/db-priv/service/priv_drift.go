@@ -0,0 +1,766 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"dbm-services/common/go-pubpkg/errno"
+	"dbm-services/mysql/priv-service/util"
+)
+
+const allPrivileges = "ALL PRIVILEGES"
+const grantOption = "GRANT OPTION"
+
+// 授权接口记录到 priv_logs 的 ticket，v2 接口的 ticket 带有 "/" 前缀
+var driftPrivLogTickets = []string{"add_priv", "/add_priv", "add_priv_without_account_rule"}
+
+// allDbPrivs 5.7 及以上版本 ALL PRIVILEGES 包含的库级别权限，8.0 show grants 会展开 ALL PRIVILEGES
+var allDbPrivs = []string{"SELECT", "INSERT", "UPDATE", "DELETE", "CREATE", "DROP", "REFERENCES", "INDEX", "ALTER",
+	"CREATE TEMPORARY TABLES", "LOCK TABLES", "EXECUTE", "CREATE VIEW", "SHOW VIEW", "CREATE ROUTINE",
+	"ALTER ROUTINE", "EVENT", "TRIGGER"}
+
+// grantRegexp 匹配 show grants 的结果，比如 GRANT SELECT, INSERT ON `db`.* TO 'user'@'host' WITH GRANT OPTION
+var grantRegexp = regexp.MustCompile(`(?i)^GRANT (.+) ON (\S+) TO (.+)$`)
+
+// privDriftChecker 一次权限漂移检查的上下文
+type privDriftChecker struct {
+	para *DriftCheckPara
+	// 实例地址 -> 实例上应有的权限
+	expected map[string]expectedGrants
+	// 实例地址 -> 实例信息
+	targets map[string]driftTarget
+	// 计算应有权限时跳过的授权记录，比如已经过期回收的临时授权
+	excludeLogIds map[int64]struct{}
+	mu            sync.Mutex
+	clusters      map[string]Instance
+	accounts      map[string]*TbAccounts
+}
+
+func newPrivDriftChecker(para *DriftCheckPara) *privDriftChecker {
+	return &privDriftChecker{
+		para:          para,
+		expected:      make(map[string]expectedGrants),
+		targets:       make(map[string]driftTarget),
+		excludeLogIds: make(map[int64]struct{}),
+		clusters:      make(map[string]Instance),
+		accounts:      make(map[string]*TbAccounts),
+	}
+}
+
+// CheckPrivDrift 对比集群实例上的实际权限与账号规则、授权记录，输出缺失、多余、超出规则的权限
+// 只生成修复语句，不在实例上执行
+func (m *DriftCheckPara) CheckPrivDrift() ([]ClusterDriftReport, error) {
+	var reports DriftReports
+	if m.BkBizId == 0 {
+		return nil, errno.BkBizIdIsEmpty
+	}
+	if m.ClusterType == "" {
+		return nil, errno.ClusterTypeIsEmpty
+	}
+	if m.ClusterType != tendbha && m.ClusterType != tendbsingle && m.ClusterType != tendbcluster {
+		return nil, errno.NotSupportedClusterType
+	}
+	domains := m.ImmuteDomains
+	if len(domains) == 0 {
+		clusters, err := GetAllClustersInfo(BkBizIdPara{BkBizId: m.BkBizId})
+		if err != nil {
+			return nil, err
+		}
+		for _, cluster := range clusters {
+			if cluster.ClusterType == m.ClusterType {
+				domains = append(domains, cluster.ImmuteDomain)
+			}
+		}
+	}
+	if len(domains) == 0 {
+		return nil, nil
+	}
+
+	checker := newPrivDriftChecker(m)
+	// 过期的临时授权不再是应有的权限
+	if err := checker.excludeTemporaryPrivs(0); err != nil {
+		return nil, err
+	}
+	if err := checker.loadExpectedGrants(); err != nil {
+		return nil, err
+	}
+
+	wg := sync.WaitGroup{}
+	limit := rate.Every(time.Millisecond * 100) // QPS：10
+	burst := 10                                 // 桶容量 10
+	limiter := rate.NewLimiter(limit, burst)
+	for _, domain := range domains {
+		domain = strings.Trim(strings.TrimSpace(domain), ".")
+		if errLimiter := limiter.Wait(context.Background()); errLimiter != nil {
+			return nil, errLimiter
+		}
+		wg.Add(1)
+		go func(domain string) {
+			defer wg.Done()
+			report := checker.checkCluster(domain)
+			reports.mu.Lock()
+			reports.reports = append(reports.reports, report)
+			reports.mu.Unlock()
+		}(domain)
+	}
+	wg.Wait()
+
+	sort.Slice(reports.reports, func(i, j int) bool {
+		return reports.reports[i].ImmuteDomain < reports.reports[j].ImmuteDomain
+	})
+	for _, report := range reports.reports {
+		SavePrivDriftReport(m.BkBizId, m.Operator, report)
+	}
+	return reports.reports, nil
+}
+
+// GetPrivDriftReport 查询保存的权限漂移检查结果
+func (m *GetDriftReportPara) GetPrivDriftReport() ([]*TbPrivDriftReports, int, error) {
+	var reports []*TbPrivDriftReports
+	if m.BkBizId == 0 {
+		return nil, 0, errno.BkBizIdIsEmpty
+	}
+	query := DB.Self.Table("tb_priv_drift_reports").Where("bk_biz_id = ?", m.BkBizId)
+	if m.ClusterType != "" {
+		query = query.Where("cluster_type = ?", m.ClusterType)
+	}
+	if len(m.ImmuteDomains) > 0 {
+		query = query.Where("immute_domain in (?)", m.ImmuteDomains)
+	}
+	if m.OnlyDrift {
+		query = query.Where("drift_count > 0")
+	}
+	var count int
+	if err := query.Count(&count).Error; err != nil {
+		slog.Error("count tb_priv_drift_reports", "error", err)
+		return nil, 0, err
+	}
+	if count == 0 {
+		return nil, 0, nil
+	}
+	query = query.Order("id desc")
+	if m.Limit != nil {
+		query = query.Limit(*m.Limit)
+		if m.Offset != nil {
+			query = query.Offset(*m.Offset)
+		}
+	}
+	if err := query.Find(&reports).Error; err != nil {
+		slog.Error("query tb_priv_drift_reports", "error", err)
+		return nil, 0, err
+	}
+	return reports, count, nil
+}
+
+// SavePrivDriftReport 保存一个集群的检查结果，保存失败只记录日志
+func SavePrivDriftReport(bkBizId int64, operator string, report ClusterDriftReport) {
+	b, err := json.Marshal(report)
+	if err != nil {
+		slog.Error("marshal drift report", "immute_domain", report.ImmuteDomain, "error", err)
+		return
+	}
+	row := TbPrivDriftReports{BkBizId: bkBizId, ClusterType: report.ClusterType, ImmuteDomain: report.ImmuteDomain,
+		DriftCount: len(report.Items), Report: string(b), Operator: operator, CreateTime: time.Now()}
+	if err = DB.Self.Table("tb_priv_drift_reports").Create(&row).Error; err != nil {
+		slog.Error("save drift report", "immute_domain", report.ImmuteDomain, "error", err)
+	}
+}
+
+// loadExpectedGrants 根据 priv_logs 中的授权记录以及当前的账号规则，计算每个实例上应有的权限
+func (c *privDriftChecker) loadExpectedGrants() error {
+	var logs []PrivLog
+	err := DB.Self.Table("priv_logs").Where("bk_biz_id = ? and ticket in (?)", c.para.BkBizId,
+		driftPrivLogTickets).Order("id").Find(&logs).Error
+	if err != nil {
+		return err
+	}
+	rules := make(map[string]TbAccountRules)
+	for _, log := range logs {
+		if _, ok := c.excludeLogIds[log.Id]; ok {
+			continue
+		}
+		if log.Ticket == "add_priv_without_account_rule" {
+			var para AddPrivWithoutAccountRule
+			if err = json.Unmarshal([]byte(log.Para), &para); err != nil {
+				slog.Warn("unmarshal priv log", "id", log.Id, "error", err)
+				continue
+			}
+			if !c.userIncluded(para.User) {
+				continue
+			}
+			rule := TbAccountRules{Dbname: para.Dbname, DmlDdlPriv: para.DmlDdlPriv, GlobalPriv: para.GlobalPriv}
+			addRuleGrants(c.expectedOf(strings.TrimSpace(para.Address)), para.User, para.Hosts, rule, false, true)
+			continue
+		}
+
+		var para PrivTaskPara
+		if err = json.Unmarshal([]byte(log.Para), &para); err != nil {
+			slog.Warn("unmarshal priv log", "id", log.Id, "error", err)
+			continue
+		}
+		if para.ClusterType != c.para.ClusterType || !c.userIncluded(para.User) {
+			continue
+		}
+		for i, recorded := range para.AccoutRules {
+			// 以当前的账号规则为准，账号规则已经删除的，使用授权时记录的规则
+			key := fmt.Sprintf("%s|%s", para.User, recorded.Dbname)
+			rule, ok := rules[key]
+			if !ok {
+				var errRule error
+				_, rule, errRule = GetAccountRuleInfo(c.para.BkBizId, para.ClusterType, para.User, recorded.Dbname)
+				if errRule != nil {
+					rule = recorded
+				}
+				rules[key] = rule
+			}
+			para.AccoutRules[i] = rule
+		}
+		if err = c.addPrivTaskGrants(para); err != nil {
+			slog.Warn("get cluster", "id", log.Id, "error", err)
+		}
+	}
+	return nil
+}
+
+// addPrivTaskGrants 按授权记录中的账号规则计算各个实例上的权限，查询不到的集群跳过并返回错误
+func (c *privDriftChecker) addPrivTaskGrants(para PrivTaskPara) error {
+	var errMsg []string
+	for _, dns := range para.TargetInstances {
+		instance, err := c.getCluster(strings.Trim(strings.TrimSpace(dns), "."))
+		if err != nil {
+			errMsg = append(errMsg, fmt.Sprintf("%s: %s", dns, err.Error()))
+			continue
+		}
+		for _, rule := range para.AccoutRules {
+			c.addClusterGrants(para, rule, instance)
+		}
+	}
+	if len(errMsg) > 0 {
+		return errors.New(strings.Join(errMsg, "\n"))
+	}
+	return nil
+}
+
+// addClusterGrants 与 AddPriv 的授权方式保持一致，计算一条授权记录在集群各个实例上产生的权限
+func (c *privDriftChecker) addClusterGrants(para PrivTaskPara, rule TbAccountRules, instance Instance) {
+	repl := rule
+	var replFlag bool
+	for _, priv := range []string{"replication slave", "replication client"} {
+		if strings.Contains(rule.GlobalPriv, priv) {
+			replFlag = true
+			rule.GlobalPriv = strings.Replace(rule.GlobalPriv, priv, "", -1)
+		}
+	}
+	if replFlag {
+		rule.GlobalPriv = strings.Trim(regexp.MustCompile(`,+`).ReplaceAllString(rule.GlobalPriv, ","), ",")
+	}
+
+	if instance.ClusterType == tendbha || instance.ClusterType == tendbsingle {
+		masterDomain := instance.ClusterType == tendbha && instance.BindTo == machineTypeProxy
+		slaveDomain := instance.ClusterType == tendbha && !masterDomain
+		hosts := para.SourceIPs
+		if masterDomain && !instance.PaddingProxy {
+			hosts = nil
+			hasLocalhost := util.HasElem("localhost", para.SourceIPs)
+			if !hasLocalhost || len(para.SourceIPs) > 1 {
+				for _, proxy := range instance.Proxies {
+					hosts = append(hosts, proxy.IP)
+				}
+			}
+			if hasLocalhost {
+				hosts = append(hosts, "localhost")
+			}
+		}
+		for _, storage := range instance.Storages {
+			exp := c.targetOf(fmt.Sprintf("%s:%d", storage.IP, storage.Port), false, instance.BkCloudId)
+			addRuleGrants(exp, para.User, hosts, rule, slaveDomain, false)
+			if replFlag {
+				addRuleGrants(exp, para.User, para.SourceIPs, repl, slaveDomain, false)
+			}
+		}
+		if masterDomain && !instance.PaddingProxy {
+			for _, proxy := range instance.Proxies {
+				exp := c.targetOf(fmt.Sprintf("%s:%d", proxy.IP, proxy.AdminPort), true, instance.BkCloudId)
+				for _, ip := range para.SourceIPs {
+					if ip == "localhost" {
+						continue
+					}
+					exp[fmt.Sprintf("%s@%s", para.User, ip)] = make(map[string]map[string]struct{})
+				}
+			}
+		}
+	} else if instance.ClusterType == tendbcluster {
+		for _, spider := range entrySpiders(instance) {
+			addRuleGrants(c.targetOf(fmt.Sprintf("%s:%d", spider.IP, spider.Port), false, instance.BkCloudId),
+				para.User, para.SourceIPs, rule, false, false)
+		}
+	}
+}
+
+// checkCluster 检查一个集群的所有实例
+func (c *privDriftChecker) checkCluster(domain string) ClusterDriftReport {
+	report := ClusterDriftReport{ImmuteDomain: domain, ClusterType: c.para.ClusterType}
+	instance, err := c.getCluster(domain)
+	if err != nil {
+		report.Errors = append(report.Errors, err.Error())
+		return report
+	}
+	var targets []driftTarget
+	if instance.ClusterType == tendbha || instance.ClusterType == tendbsingle {
+		for _, storage := range instance.Storages {
+			targets = append(targets, driftTarget{Address: fmt.Sprintf("%s:%d", storage.IP, storage.Port),
+				BkCloudId: instance.BkCloudId})
+		}
+		if instance.ClusterType == tendbha && instance.BindTo == machineTypeProxy && !instance.PaddingProxy {
+			for _, proxy := range instance.Proxies {
+				targets = append(targets, driftTarget{Address: fmt.Sprintf("%s:%d", proxy.IP, proxy.AdminPort),
+					IsProxy: true, BkCloudId: instance.BkCloudId})
+			}
+		}
+	} else if instance.ClusterType == tendbcluster {
+		for _, spider := range append(instance.SpiderMaster, instance.SpiderSlave...) {
+			targets = append(targets, driftTarget{Address: fmt.Sprintf("%s:%d", spider.IP, spider.Port),
+				BkCloudId: instance.BkCloudId})
+		}
+	} else {
+		report.Errors = append(report.Errors, fmt.Sprintf("cluster type is %s, wrong type", instance.ClusterType))
+		return report
+	}
+
+	for _, target := range targets {
+		var sqls []string
+		if target.IsProxy {
+			sqls, err = c.checkProxy(target, &report)
+		} else {
+			sqls, err = c.checkMysql(target, &report)
+		}
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("%s: %s", target.Address, err.Error()))
+			continue
+		}
+		if c.para.GenerateSql && len(sqls) > 0 {
+			report.ReconcileSqls = append(report.ReconcileSqls, InstanceGrantSql{Ins: target.Address, Grants: sqls})
+		}
+	}
+	return report
+}
+
+// checkMysql 对比 mysql、spider 实例的 show grants 与应有的权限，返回修复语句
+func (c *privDriftChecker) checkMysql(target driftTarget, report *ClusterDriftReport) ([]string, error) {
+	userGrants, err := GetRemotePrivilege(target.Address, "", target.BkCloudId, machineTypeBackend,
+		strings.Join(c.para.Users, "','"), true)
+	if err != nil {
+		return nil, err
+	}
+	actual := make(expectedGrants)
+	for _, userGrant := range userGrants {
+		actual[userGrant.UserHost] = parseGrants(userGrant.Grants)
+	}
+	expected := c.expected[target.Address]
+
+	var version string
+	var sqls []string
+	for _, userHost := range unionKeys(expected, actual) {
+		exp, hasExp := expected[userHost]
+		act, hasAct := actual[userHost]
+		if !hasExp {
+			if c.ignoredUser(userHost) {
+				continue
+			}
+			report.Items = append(report.Items, DriftItem{Instance: target.Address, UserHost: userHost,
+				DriftType: driftExtra, Actual: describeScopes(act)})
+			sqls = append(sqls, fmt.Sprintf("DROP USER %s;", userHost))
+			continue
+		}
+		if !hasAct {
+			report.Items = append(report.Items, DriftItem{Instance: target.Address, UserHost: userHost,
+				DriftType: driftMissing, Expected: describeScopes(exp)})
+			if !c.para.GenerateSql {
+				continue
+			}
+			if version == "" {
+				if version, err = GetMySQLVersion(target.Address, target.BkCloudId); err != nil {
+					return nil, err
+				}
+			}
+			createSql, errCreate := c.createUserSql(userHost, version)
+			if errCreate != nil {
+				report.Errors = append(report.Errors, fmt.Sprintf("%s: %s", target.Address, errCreate.Error()))
+				continue
+			}
+			sqls = append(sqls, createSql)
+			for _, scope := range unionScopes(exp, nil) {
+				sqls = append(sqls, fmt.Sprintf("GRANT %s ON %s TO %s;", joinPrivs(exp[scope]), quoteScope(scope),
+					userHost))
+			}
+			continue
+		}
+
+		for _, scope := range unionScopes(exp, act) {
+			e, a := exp[scope], act[scope]
+			base := DriftItem{Instance: target.Address, UserHost: userHost, Scope: scope,
+				Expected: joinPrivs(e), Actual: joinPrivs(a)}
+			if len(a) == 0 {
+				base.DriftType = driftMissing
+				report.Items = append(report.Items, base)
+				sqls = append(sqls, fmt.Sprintf("GRANT %s ON %s TO %s;", joinPrivs(e), quoteScope(scope), userHost))
+				continue
+			}
+			if len(e) == 0 {
+				base.DriftType = driftExtra
+				report.Items = append(report.Items, base)
+				sqls = append(sqls, fmt.Sprintf("REVOKE %s ON %s FROM %s;", joinPrivs(a), quoteScope(scope), userHost))
+				continue
+			}
+			missing, over := diffPrivs(e, a)
+			if len(missing) > 0 {
+				item := base
+				item.DriftType = driftMissing
+				report.Items = append(report.Items, item)
+				sqls = append(sqls, fmt.Sprintf("GRANT %s ON %s TO %s;", strings.Join(missing, ", "),
+					quoteScope(scope), userHost))
+			}
+			if len(over) > 0 {
+				item := base
+				item.DriftType = driftOverPrivileged
+				report.Items = append(report.Items, item)
+				if util.HasElem(allPrivileges, over) {
+					// ALL PRIVILEGES 不能部分回收，全部回收后按规则重新授权
+					sqls = append(sqls, fmt.Sprintf("REVOKE ALL PRIVILEGES ON %s FROM %s;", quoteScope(scope), userHost),
+						fmt.Sprintf("GRANT %s ON %s TO %s;", joinPrivs(e), quoteScope(scope), userHost))
+				} else {
+					sqls = append(sqls, fmt.Sprintf("REVOKE %s ON %s FROM %s;", strings.Join(over, ", "),
+						quoteScope(scope), userHost))
+				}
+			}
+		}
+	}
+	if len(sqls) > 0 {
+		sqls = append(append([]string{setBinlogOff}, sqls...), setBinlogOn, flushPriv)
+	}
+	return sqls, nil
+}
+
+// checkProxy 对比 proxy 白名单与应有的白名单，返回修复语句
+func (c *privDriftChecker) checkProxy(target driftTarget, report *ClusterDriftReport) ([]string, error) {
+	sql := "select * from user;"
+	queryRequest := QueryRequest{[]string{target.Address}, []string{sql}, true, 30, target.BkCloudId}
+	output, err := OneAddressExecuteProxySql(queryRequest)
+	if err != nil {
+		return nil, err
+	}
+	actual := make(expectedGrants)
+	for _, row := range output.CmdResults[0].TableData {
+		userHost, ok := row["user@ip"].(string)
+		if !ok || !strings.Contains(userHost, "@") {
+			continue
+		}
+		if user := strings.Split(userHost, "@")[0]; user == "MONITOR" || !c.userIncluded(user) {
+			continue
+		}
+		actual[userHost] = nil
+	}
+	expected := c.expected[target.Address]
+
+	var sqls []string
+	for _, userHost := range unionKeys(expected, actual) {
+		_, hasExp := expected[userHost]
+		_, hasAct := actual[userHost]
+		if !hasExp && !c.ignoredUser(userHost) {
+			report.Items = append(report.Items, DriftItem{Instance: target.Address, UserHost: userHost,
+				DriftType: driftExtra})
+			sqls = append(sqls, fmt.Sprintf("refresh_users('%s','-');", userHost))
+		} else if !hasAct {
+			report.Items = append(report.Items, DriftItem{Instance: target.Address, UserHost: userHost,
+				DriftType: driftMissing})
+			sqls = append(sqls, fmt.Sprintf("refresh_users('%s','+');", userHost))
+		}
+	}
+	return sqls, nil
+}
+
+// createUserSql 生成创建账号的语句，密码取自账号表
+func (c *privDriftChecker) createUserSql(userHost string, version string) (string, error) {
+	user := userOfHost(userHost)
+	c.mu.Lock()
+	account, ok := c.accounts[user]
+	if !ok {
+		clusterType := c.para.ClusterType
+		if clusterType == tendbha || clusterType == tendbsingle {
+			clusterType = mysql
+		}
+		var tmp TbAccounts
+		err := DB.Self.Table("tb_accounts").Where(&TbAccounts{BkBizId: c.para.BkBizId, ClusterType: clusterType,
+			User: user}).Take(&tmp).Error
+		if err == nil {
+			account = &tmp
+		}
+		c.accounts[user] = account
+	}
+	c.mu.Unlock()
+	if account == nil {
+		return "", fmt.Errorf("账号%s不存在，无法生成%s的创建语句", user, userHost)
+	}
+	var multiPsw MultiPsw
+	if err := json.Unmarshal([]byte(account.Psw), &multiPsw); err != nil {
+		return "", err
+	}
+	if MySQLVersionParse(version, "") < MySQLVersionParse("5.7.6", "") {
+		return fmt.Sprintf("GRANT USAGE ON *.* TO %s IDENTIFIED BY PASSWORD '%s';", userHost, multiPsw.Psw), nil
+	}
+	return fmt.Sprintf("CREATE USER IF NOT EXISTS %s IDENTIFIED WITH mysql_native_password AS '%s';",
+		userHost, multiPsw.Psw), nil
+}
+
+// getCluster 查询并缓存域名对应的集群信息
+func (c *privDriftChecker) getCluster(domain string) (Instance, error) {
+	c.mu.Lock()
+	instance, ok := c.clusters[domain]
+	c.mu.Unlock()
+	if ok {
+		return instance, nil
+	}
+	instance, err := GetCluster(c.para.ClusterType, Domain{EntryName: domain})
+	if err != nil {
+		return instance, err
+	}
+	c.mu.Lock()
+	c.clusters[domain] = instance
+	c.mu.Unlock()
+	return instance, nil
+}
+
+// targetOf 记录实例信息，返回实例上应有的权限
+func (c *privDriftChecker) targetOf(address string, isProxy bool, bkCloudId int64) expectedGrants {
+	c.targets[address] = driftTarget{Address: address, IsProxy: isProxy, BkCloudId: bkCloudId}
+	return c.expectedOf(address)
+}
+
+func (c *privDriftChecker) expectedOf(address string) expectedGrants {
+	if _, ok := c.expected[address]; !ok {
+		c.expected[address] = make(expectedGrants)
+	}
+	return c.expected[address]
+}
+
+func (c *privDriftChecker) userIncluded(user string) bool {
+	return len(c.para.Users) == 0 || util.HasElem(user, c.para.Users)
+}
+
+// ignoredUser 内置账号不属于账号规则管理，实例上存在时不报告
+func (c *privDriftChecker) ignoredUser(userHost string) bool {
+	user := strings.ToLower(userOfHost(userHost))
+	return util.HasElem(user, mysqlInnerAccounts) || user == "mysql.infoschema" || user == "monitor_access"
+}
+
+// entrySpiders 域名对应的 spider 节点
+func entrySpiders(instance Instance) []Proxy {
+	if instance.EntryRole == masterEntry {
+		return instance.SpiderMaster
+	} else if instance.EntryRole == slaveEntry {
+		return instance.SpiderSlave
+	}
+	return nil
+}
+
+// addRuleGrants 与 GenerateBackendSQL 生成的授权语句保持一致
+func addRuleGrants(exp expectedGrants, user string, hosts []string, rule TbAccountRules, slaveDomain bool,
+	dedicated bool) {
+	dbScope := "*.*"
+	if rule.Dbname != "%" && rule.Dbname != "*" {
+		dbScope = fmt.Sprintf("%s.*", rule.Dbname)
+	}
+	connLogScope := fmt.Sprintf("%s.conn_log", connLogDB)
+	for _, host := range hosts {
+		userHost := fmt.Sprintf(`'%s'@'%s'`, user, host)
+		if _, ok := exp[userHost]; !ok {
+			exp[userHost] = make(map[string]map[string]struct{})
+		}
+		scopes := exp[userHost]
+		// 备库域名只授予查询类权限
+		if slaveDomain {
+			addPrivs(scopes, dbScope, "SELECT, SHOW VIEW")
+			if ContainConnLogDB(rule.Dbname) {
+				addPrivs(scopes, connLogScope, "INSERT")
+			}
+			for _, priv := range []string{"show databases", "replication slave", "replication client"} {
+				if strings.Contains(strings.ToLower(rule.GlobalPriv), priv) {
+					addPrivs(scopes, "*.*", priv)
+				}
+			}
+			continue
+		}
+		if rule.DmlDdlPriv != "" {
+			addPrivs(scopes, dbScope, rule.DmlDdlPriv)
+			if ContainConnLogDB(rule.Dbname) && !strings.Contains(strings.ToLower(rule.DmlDdlPriv), "insert") {
+				addPrivs(scopes, connLogScope, "INSERT")
+			}
+		}
+		if rule.GlobalPriv != "" {
+			addPrivs(scopes, "*.*", rule.GlobalPriv)
+			if dedicated && strings.Contains(strings.ToLower(rule.GlobalPriv), "all privileges") {
+				addPrivs(scopes, "*.*", grantOption)
+			}
+		}
+	}
+}
+
+// parseGrants 解析 show grants 的结果，返回 库表范围 -> 权限
+func parseGrants(grants []string) map[string]map[string]struct{} {
+	scopes := make(map[string]map[string]struct{})
+	for _, grant := range grants {
+		split := grantRegexp.FindStringSubmatch(strings.TrimSuffix(strings.TrimSpace(grant), ";"))
+		// show create user、角色授权
+		if len(split) != 4 || strings.EqualFold(split[1], "PROXY") {
+			continue
+		}
+		scope := strings.NewReplacer("`", "", "'", "", `"`, "").Replace(split[2])
+		addPrivs(scopes, scope, split[1])
+		if strings.Contains(strings.ToUpper(split[3]), "WITH GRANT OPTION") {
+			addPrivs(scopes, scope, grantOption)
+		}
+	}
+	return scopes
+}
+
+// addPrivs 添加逗号分隔的权限，列权限括号中的逗号不拆分
+func addPrivs(scopes map[string]map[string]struct{}, scope string, privs string) {
+	var items []string
+	var depth, start int
+	for i, ch := range privs {
+		switch ch {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				items = append(items, privs[start:i])
+				start = i + 1
+			}
+		}
+	}
+	items = append(items, privs[start:])
+	for _, item := range items {
+		priv := strings.ToUpper(strings.Join(strings.Fields(item), " "))
+		if priv == "" || priv == "USAGE" {
+			continue
+		}
+		if priv == "ALL" {
+			priv = allPrivileges
+		}
+		if _, ok := scopes[scope]; !ok {
+			scopes[scope] = make(map[string]struct{})
+		}
+		scopes[scope][priv] = struct{}{}
+	}
+}
+
+// diffPrivs 返回 expected 中缺失的权限，以及 actual 中超出的权限
+func diffPrivs(expected, actual map[string]struct{}) (missing []string, over []string) {
+	_, expectAll := expected[allPrivileges]
+	_, actualAll := actual[allPrivileges]
+	coverAll := actualAll
+	if !coverAll {
+		coverAll = true
+		for _, priv := range allDbPrivs {
+			if _, ok := actual[priv]; !ok {
+				coverAll = false
+				break
+			}
+		}
+	}
+	for _, priv := range sortedPrivs(expected) {
+		if priv == allPrivileges {
+			if !coverAll {
+				missing = append(missing, priv)
+			}
+			continue
+		}
+		if _, ok := actual[priv]; ok || (actualAll && priv != grantOption) {
+			continue
+		}
+		missing = append(missing, priv)
+	}
+	for _, priv := range sortedPrivs(actual) {
+		if _, ok := expected[priv]; ok || (expectAll && priv != grantOption) {
+			continue
+		}
+		over = append(over, priv)
+	}
+	return missing, over
+}
+
+// quoteScope db.* 转换为 `db`.*，用于生成授权语句
+func quoteScope(scope string) string {
+	if scope == "*.*" {
+		return scope
+	}
+	parts := strings.SplitN(scope, ".", 2)
+	if len(parts) != 2 {
+		return scope
+	}
+	if parts[1] == "*" {
+		return fmt.Sprintf("`%s`.*", parts[0])
+	}
+	return fmt.Sprintf("`%s`.`%s`", parts[0], parts[1])
+}
+
+// describeScopes 格式化为 scope:priv1,priv2; 展示在报告中
+func describeScopes(scopes map[string]map[string]struct{}) string {
+	var desc []string
+	for _, scope := range unionScopes(scopes, nil) {
+		desc = append(desc, fmt.Sprintf("%s:%s", scope, joinPrivs(scopes[scope])))
+	}
+	return strings.Join(desc, "; ")
+}
+
+func joinPrivs(privs map[string]struct{}) string {
+	return strings.Join(sortedPrivs(privs), ", ")
+}
+
+func sortedPrivs(privs map[string]struct{}) []string {
+	var l []string
+	for priv := range privs {
+		l = append(l, priv)
+	}
+	sort.Strings(l)
+	return l
+}
+
+func unionKeys(a, b expectedGrants) []string {
+	uniq := make(map[string]struct{})
+	for k := range a {
+		uniq[k] = struct{}{}
+	}
+	for k := range b {
+		uniq[k] = struct{}{}
+	}
+	return sortedPrivs(uniq)
+}
+
+func unionScopes(a, b map[string]map[string]struct{}) []string {
+	uniq := make(map[string]struct{})
+	for k := range a {
+		uniq[k] = struct{}{}
+	}
+	for k := range b {
+		uniq[k] = struct{}{}
+	}
+	return sortedPrivs(uniq)
+}
+
+// userOfHost 'user'@'host' 或者 user@host 中的 user
+func userOfHost(userHost string) string {
+	return strings.Trim(strings.Split(userHost, "@")[0], "'`")
+}
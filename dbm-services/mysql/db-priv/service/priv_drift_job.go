@@ -0,0 +1,67 @@
+package service
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// privDriftJobOperator 定时检查保存报告时记录的操作者
+const privDriftJobOperator = "priv_drift_job"
+
+// RunPrivDriftJob 定时检查所有业务 mysql、tendbcluster 集群的权限漂移，结果保存到 tb_priv_drift_reports
+// keepDays 大于 0 时清理更早的报告。多个 db-priv 实例只需要在一个实例上开启
+func RunPrivDriftJob(interval time.Duration, keepDays int) {
+	if interval <= 0 {
+		interval = 24 * time.Hour
+	}
+	slog.Info("priv drift job started", "interval", interval.String())
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		CheckAllPrivDrift()
+		if keepDays > 0 {
+			err := DB.Self.Exec("delete from tb_priv_drift_reports where create_time < ?",
+				time.Now().AddDate(0, 0, -keepDays)).Error
+			if err != nil {
+				slog.Error("clean priv drift reports", "error", err)
+			}
+		}
+	}
+}
+
+// CheckAllPrivDrift 检查有账号的业务下所有集群
+func CheckAllPrivDrift() {
+	type bizClusterType struct {
+		BkBizId     int64  `gorm:"column:bk_biz_id"`
+		ClusterType string `gorm:"column:cluster_type"`
+	}
+	var rows []bizClusterType
+	vsql := fmt.Sprintf("select distinct bk_biz_id, cluster_type from tb_accounts where cluster_type in ('%s','%s')",
+		mysql, tendbcluster)
+	if err := DB.Self.Raw(vsql).Scan(&rows).Error; err != nil {
+		slog.Error(vsql, "execute error", err)
+		return
+	}
+	for _, row := range rows {
+		clusterTypes := []string{row.ClusterType}
+		if row.ClusterType == mysql {
+			clusterTypes = []string{tendbha, tendbsingle}
+		}
+		for _, clusterType := range clusterTypes {
+			para := DriftCheckPara{BkBizId: row.BkBizId, ClusterType: clusterType, GenerateSql: true,
+				Operator: privDriftJobOperator}
+			reports, err := para.CheckPrivDrift()
+			if err != nil {
+				slog.Error("check priv drift", "bk_biz_id", row.BkBizId, "cluster_type", clusterType, "error", err)
+				continue
+			}
+			var drift int
+			for _, report := range reports {
+				drift += len(report.Items)
+			}
+			slog.Info("check priv drift", "bk_biz_id", row.BkBizId, "cluster_type", clusterType,
+				"clusters", len(reports), "drift", drift)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"sync"
+	"time"
+)
+
+// 权限漂移类型
+const (
+	// driftMissing 账号规则/授权记录中有，实例上没有
+	driftMissing = "missing"
+	// driftExtra 实例上有，账号规则/授权记录中没有
+	driftExtra = "extra"
+	// driftOverPrivileged 实例上的权限超出账号规则
+	driftOverPrivileged = "over_privileged"
+)
+
+// DriftCheckPara CheckPrivDrift 函数的入参
+type DriftCheckPara struct {
+	BkBizId     int64  `json:"bk_biz_id"`
+	ClusterType string `json:"cluster_type"`
+	// 为空时检查业务下 cluster_type 类型的所有集群
+	ImmuteDomains []string `json:"immute_domains"`
+	// 只检查指定账号，为空时检查所有账号
+	Users []string `json:"users"`
+	// 是否生成修复语句。权限服务只输出报告，不执行修复语句，
+	// 需要人工确认后通过 DBM 的授权、回收单据或者 sql 变更单据执行
+	GenerateSql bool   `json:"generate_sql"`
+	Operator    string `json:"operator"`
+}
+
+// DriftItem 一条权限漂移
+type DriftItem struct {
+	Instance  string `json:"instance"`
+	UserHost  string `json:"user_host"`
+	Scope     string `json:"scope"` // *.* 为全局权限，proxy 白名单为空
+	DriftType string `json:"drift_type"`
+	Expected  string `json:"expected"`
+	Actual    string `json:"actual"`
+}
+
+// ClusterDriftReport 一个集群的权限漂移报告
+type ClusterDriftReport struct {
+	ImmuteDomain string      `json:"immute_domain"`
+	ClusterType  string      `json:"cluster_type"`
+	Items        []DriftItem `json:"items"`
+	// ReconcileSqls 只用于展示，生成后实例权限可能又发生变化，执行前需要重新检查
+	ReconcileSqls []InstanceGrantSql `json:"reconcile_sqls"`
+	Errors        []string           `json:"errors"`
+}
+
+// DriftReports 并行时共同维护数组
+type DriftReports struct {
+	mu      sync.RWMutex
+	reports []ClusterDriftReport
+}
+
+// TbPrivDriftReports 权限漂移检查结果表
+type TbPrivDriftReports struct {
+	Id           int64     `gorm:"column:id;primary_key;auto_increment" json:"id"`
+	BkBizId      int64     `gorm:"column:bk_biz_id;not_null" json:"bk_biz_id"`
+	ClusterType  string    `gorm:"column:cluster_type;not_null" json:"cluster_type"`
+	ImmuteDomain string    `gorm:"column:immute_domain;not_null" json:"immute_domain"`
+	DriftCount   int       `gorm:"column:drift_count;not_null" json:"drift_count"`
+	Report       string    `gorm:"column:report;not_null" json:"report"`
+	Operator     string    `gorm:"column:operator" json:"operator"`
+	CreateTime   time.Time `gorm:"column:create_time" json:"create_time"`
+}
+
+// GetDriftReportPara GetPrivDriftReport 函数的入参
+type GetDriftReportPara struct {
+	BkBizId       int64    `json:"bk_biz_id"`
+	ClusterType   string   `json:"cluster_type"`
+	ImmuteDomains []string `json:"immute_domains"`
+	// 只返回有漂移的报告
+	OnlyDrift bool   `json:"only_drift"`
+	Limit     *int64 `json:"limit"`
+	Offset    *int64 `json:"offset"`
+}
+
+// expectedGrants 实例上应有的权限，user@host -> 库表范围 -> 权限
+type expectedGrants map[string]map[string]map[string]struct{}
+
+// driftTarget 一个需要检查的 mysql/spider/proxy 实例
+type driftTarget struct {
+	Address   string
+	IsProxy   bool
+	BkCloudId int64
+}
@@ -0,0 +1,413 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"dbm-services/common/go-pubpkg/errno"
+	"dbm-services/mysql/priv-service/util"
+)
+
+// ParseExpireTime 解析临时授权的过期时间，必须晚于当前时间
+func ParseExpireTime(expireTime string) (time.Time, error) {
+	expire, err := time.ParseInLocation(expireTimeLayout, strings.TrimSpace(expireTime), time.Local)
+	if err != nil {
+		expire, err = time.Parse(time.RFC3339, strings.TrimSpace(expireTime))
+	}
+	if err != nil {
+		return expire, errno.ExpireTimeInvalid.Add(fmt.Sprintf(" %s: %s", expireTime, err.Error()))
+	}
+	if !expire.After(time.Now()) {
+		return expire, errno.ExpireTimeInvalid.Add(" " + expireTime)
+	}
+	return expire, nil
+}
+
+// AddTemporaryPriv 在授权前记录临时授权，记录授权时的账号规则，到期后按此规则回收
+func (m *PrivTaskPara) AddTemporaryPriv(privLogId int64, ticket string) error {
+	expire, err := ParseExpireTime(m.ExpireTime)
+	if err != nil {
+		return err
+	}
+	if privLogId == 0 {
+		// 没有授权记录，到期回收时无法区分临时授权与其他授权
+		return errno.GrantPrivilegesFail.Add(" record priv log fail, temporary privilege not allowed")
+	}
+	para := *m
+	para.AccoutRules = nil
+	for _, rule := range m.AccoutRules {
+		_, accountRule, errRule := GetAccountRuleInfo(m.BkBizId, m.ClusterType, m.User, rule.Dbname)
+		if errRule != nil {
+			return errRule
+		}
+		para.AccoutRules = append(para.AccoutRules, accountRule)
+	}
+	b, err := json.Marshal(para)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	row := TbTemporaryPrivs{BkBizId: m.BkBizId, ClusterType: m.ClusterType, User: m.User, PrivLogId: privLogId,
+		Para: string(b), ExpireTime: expire, Status: tempPrivActive, Ticket: ticket, Creator: m.Operator,
+		CreateTime: now, Operator: m.Operator, UpdateTime: now}
+	return DB.Self.Table("tb_temporary_privs").Create(&row).Error
+}
+
+// GetTemporaryPriv 查询临时授权
+func (m *GetTemporaryPrivPara) GetTemporaryPriv() ([]*TbTemporaryPrivs, int, error) {
+	var privs []*TbTemporaryPrivs
+	if m.BkBizId == 0 {
+		return nil, 0, errno.BkBizIdIsEmpty
+	}
+	query := DB.Self.Table("tb_temporary_privs").Where("bk_biz_id = ?", m.BkBizId)
+	if m.ClusterType != nil {
+		query = query.Where("cluster_type = ?", *m.ClusterType)
+	}
+	if m.User != "" {
+		query = query.Where("user = ?", m.User)
+	}
+	if len(m.Status) > 0 {
+		query = query.Where("status in (?)", m.Status)
+	}
+	var count int
+	if err := query.Count(&count).Error; err != nil {
+		slog.Error("count tb_temporary_privs", "error", err)
+		return nil, 0, err
+	}
+	if count == 0 {
+		return nil, 0, nil
+	}
+	query = query.Order("id desc")
+	if m.Limit != nil {
+		query = query.Limit(*m.Limit)
+		if m.Offset != nil {
+			query = query.Offset(*m.Offset)
+		}
+	}
+	if err := query.Find(&privs).Error; err != nil {
+		slog.Error("query tb_temporary_privs", "error", err)
+		return nil, 0, err
+	}
+	return privs, count, nil
+}
+
+// ExtendTemporaryPriv 临时授权延期，只能延期未回收的临时授权
+func (m *ModifyTemporaryPrivPara) ExtendTemporaryPriv(jsonPara string, ticket string) error {
+	if m.BkBizId == 0 {
+		return errno.BkBizIdIsEmpty
+	}
+	if len(m.Ids) == 0 {
+		return errno.TemporaryPrivIdNull
+	}
+	expire, err := ParseExpireTime(m.ExpireTime)
+	if err != nil {
+		return err
+	}
+	result := DB.Self.Table("tb_temporary_privs").
+		Where("bk_biz_id = ? and id in (?) and status = ?", m.BkBizId, m.Ids, tempPrivActive).
+		Updates(map[string]interface{}{"expire_time": expire, "operator": m.Operator, "update_time": time.Now()})
+	if result.Error != nil {
+		return result.Error
+	}
+	if int(result.RowsAffected) != len(m.Ids) {
+		return errno.GrantPrivilegesParameterCheckFail.Add(fmt.Sprintf(
+			" %d of %d temporary privileges extended, others not existed or already revoked",
+			result.RowsAffected, len(m.Ids)))
+	}
+	AddPrivLog(PrivLog{BkBizId: m.BkBizId, Ticket: ticket, Operator: m.Operator, Para: jsonPara, Time: time.Now()})
+	return nil
+}
+
+// RevokeTemporaryPriv 提前回收临时授权
+func (m *ModifyTemporaryPrivPara) RevokeTemporaryPriv(jsonPara string, ticket string) error {
+	var errMsg []string
+	if m.BkBizId == 0 {
+		return errno.BkBizIdIsEmpty
+	}
+	if len(m.Ids) == 0 {
+		return errno.TemporaryPrivIdNull
+	}
+	AddPrivLog(PrivLog{BkBizId: m.BkBizId, Ticket: ticket, Operator: m.Operator, Para: jsonPara, Time: time.Now()})
+	for _, id := range m.Ids {
+		var row TbTemporaryPrivs
+		err := DB.Self.Table("tb_temporary_privs").Where("bk_biz_id = ? and id = ?", m.BkBizId, id).
+			Take(&row).Error
+		if err != nil {
+			errMsg = append(errMsg, fmt.Sprintf("id %d: %s", id, err.Error()))
+			continue
+		}
+		if row.Status == tempPrivRevoked {
+			continue
+		}
+		if row.Status == tempPrivRevoking {
+			errMsg = append(errMsg, fmt.Sprintf("id %d: is being revoked", id))
+			continue
+		}
+		claimed, err := claimTemporaryPriv(row)
+		if err != nil {
+			errMsg = append(errMsg, fmt.Sprintf("id %d: %s", id, err.Error()))
+			continue
+		}
+		if !claimed {
+			errMsg = append(errMsg, fmt.Sprintf("id %d: is being revoked", id))
+			continue
+		}
+		if err = finishTemporaryPriv(row, revokeTemporaryPriv(row), m.Operator); err != nil {
+			errMsg = append(errMsg, fmt.Sprintf("id %d: %s", id, err.Error()))
+		}
+	}
+	if len(errMsg) > 0 {
+		return errno.RevokePrivilegesFail.Add("\n" + strings.Join(errMsg, "\n"))
+	}
+	return nil
+}
+
+// RevokeExpiredTemporaryPriv 回收已经过期的临时授权，回收失败的在下一轮重试
+func RevokeExpiredTemporaryPriv() {
+	var rows []TbTemporaryPrivs
+	err := DB.Self.Table("tb_temporary_privs").
+		Where("(status in (?) or (status = ? and update_time < ?)) and expire_time <= ? and retries < ?",
+			[]string{tempPrivActive, tempPrivRevokeFailed}, tempPrivRevoking, time.Now().Add(-tempPrivRevokingTimeout),
+			time.Now(), tempPrivMaxRetries).
+		Order("expire_time").Find(&rows).Error
+	if err != nil {
+		slog.Error("query expired temporary privileges", "error", err)
+		return
+	}
+	for _, row := range rows {
+		claimed, err := claimTemporaryPriv(row)
+		if err != nil {
+			slog.Error("claim temporary privilege", "id", row.Id, "error", err)
+			continue
+		}
+		if !claimed {
+			// 其他 db-priv 实例正在回收
+			continue
+		}
+		slog.Info("revoke expired temporary privilege", "id", row.Id, "bk_biz_id", row.BkBizId,
+			"user", row.User, "expire_time", row.ExpireTime)
+		if err = finishTemporaryPriv(row, revokeTemporaryPriv(row), tempPrivJobOperator); err != nil {
+			slog.Error("revoke temporary privilege", "id", row.Id, "error", err)
+		}
+	}
+}
+
+// RunTemporaryPrivJob 定时回收过期的临时授权
+func RunTemporaryPrivJob(interval time.Duration) {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	slog.Info("temporary privilege job started", "interval", interval.String())
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		RevokeExpiredTemporaryPriv()
+	}
+}
+
+// excludeTemporaryPrivs 计算应有权限时跳过已经过期或者回收的临时授权，以及正在回收的临时授权 revokingId
+func (c *privDriftChecker) excludeTemporaryPrivs(revokingId int64) error {
+	var logIds []int64
+	db := DB.Self.Table("tb_temporary_privs").Where("bk_biz_id = ? and (status != ? or expire_time <= ? or id = ?)",
+		c.para.BkBizId, tempPrivActive, time.Now(), revokingId)
+	if len(c.para.Users) > 0 {
+		db = db.Where("user in (?)", c.para.Users)
+	}
+	if err := db.Pluck("priv_log_id", &logIds).Error; err != nil {
+		return err
+	}
+	for _, id := range logIds {
+		c.excludeLogIds[id] = struct{}{}
+	}
+	return nil
+}
+
+// claimTemporaryPriv 把状态改为 revoking，多个 db-priv 实例同时回收时只有一个成功
+func claimTemporaryPriv(row TbTemporaryPrivs) (bool, error) {
+	result := DB.Self.Table("tb_temporary_privs").
+		Where("id = ? and status = ? and update_time = ?", row.Id, row.Status, row.UpdateTime).
+		Updates(map[string]interface{}{"status": tempPrivRevoking, "update_time": time.Now()})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// finishTemporaryPriv 记录回收结果
+func finishTemporaryPriv(row TbTemporaryPrivs, revokeErr error, operator string) error {
+	now := time.Now()
+	updates := map[string]interface{}{"status": tempPrivRevoked, "err_msg": "", "operator": operator,
+		"update_time": now, "revoke_time": now}
+	if revokeErr != nil {
+		updates = map[string]interface{}{"status": tempPrivRevokeFailed, "err_msg": revokeErr.Error(),
+			"operator": operator, "update_time": now, "retries": row.Retries + 1}
+	}
+	if err := DB.Self.Table("tb_temporary_privs").Where("id = ?", row.Id).Updates(updates).Error; err != nil {
+		slog.Error("update temporary privilege", "id", row.Id, "error", err)
+	}
+	return revokeErr
+}
+
+// revokeTemporaryPriv 回收临时授权在各个实例上的权限
+// 其他授权记录（包括未过期的临时授权）仍然需要的权限不回收；账号没有其他授权并且回收后没有剩余权限时删除账号
+func revokeTemporaryPriv(row TbTemporaryPrivs) error {
+	var para PrivTaskPara
+	if err := json.Unmarshal([]byte(row.Para), &para); err != nil {
+		return err
+	}
+	checkPara := &DriftCheckPara{BkBizId: row.BkBizId, ClusterType: row.ClusterType, Users: []string{row.User}}
+	granted := newPrivDriftChecker(checkPara)
+	if err := granted.addPrivTaskGrants(para); err != nil {
+		return err
+	}
+
+	remaining := newPrivDriftChecker(checkPara)
+	remaining.clusters = granted.clusters
+	err := remaining.excludeTemporaryPrivs(row.Id)
+	if err != nil {
+		return err
+	}
+	if err = remaining.loadExpectedGrants(); err != nil {
+		return err
+	}
+
+	var errMsg []string
+	addresses := make([]string, 0, len(granted.expected))
+	for address := range granted.expected {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+	for _, address := range addresses {
+		target := granted.targets[address]
+		if target.IsProxy {
+			err = revokeOnProxy(target, granted.expected[address], remaining.expected[address])
+		} else {
+			err = revokeOnMysql(target, row.User, granted.expected[address], remaining.expected[address])
+		}
+		if err != nil {
+			errMsg = append(errMsg, fmt.Sprintf("%s: %s", address, err.Error()))
+		}
+	}
+	if len(errMsg) > 0 {
+		return errors.New(strings.Join(errMsg, "\n"))
+	}
+	return nil
+}
+
+// revokeOnMysql 在 mysql、spider 实例上回收权限，只回收实例上实际存在的权限
+func revokeOnMysql(target driftTarget, user string, granted, remaining expectedGrants) error {
+	userGrants, err := GetRemotePrivilege(target.Address, "", target.BkCloudId, machineTypeBackend, user, true)
+	if err != nil {
+		return err
+	}
+	actual := make(expectedGrants)
+	for _, userGrant := range userGrants {
+		actual[userGrant.UserHost] = parseGrants(userGrant.Grants)
+	}
+
+	var sqls []string
+	for _, userHost := range unionKeys(granted, nil) {
+		act, ok := actual[userHost]
+		if !ok {
+			continue
+		}
+		keep, keepUser := remaining[userHost]
+		var userSqls []string
+		var left bool
+		for _, scope := range unionScopes(act, nil) {
+			revoke, regrant := revokePrivs(granted[userHost][scope], keep[scope], act[scope])
+			if len(revoke) > 0 {
+				userSqls = append(userSqls, fmt.Sprintf("REVOKE %s ON %s FROM %s;", strings.Join(revoke, ", "),
+					quoteScope(scope), userHost))
+			}
+			if len(regrant) > 0 {
+				userSqls = append(userSqls, fmt.Sprintf("GRANT %s ON %s TO %s;", strings.Join(regrant, ", "),
+					quoteScope(scope), userHost))
+			}
+			if len(regrant) > 0 || (!util.HasElem(allPrivileges, revoke) && len(revoke) < len(act[scope])) {
+				left = true
+			}
+		}
+		// 账号只为临时授权创建，回收后没有任何权限，直接删除账号
+		if !keepUser && !left {
+			sqls = append(sqls, fmt.Sprintf("DROP USER %s;", userHost))
+			continue
+		}
+		sqls = append(sqls, userSqls...)
+	}
+	if len(sqls) == 0 {
+		return nil
+	}
+	sqls = append(append([]string{flushPriv, setBinlogOff}, sqls...), setBinlogOn, flushPriv)
+	queryRequest := QueryRequest{[]string{target.Address}, sqls, true, 60, target.BkCloudId}
+	_, err = OneAddressExecuteSql(queryRequest)
+	return err
+}
+
+// revokeOnProxy 删除 proxy 白名单，其他授权仍然需要的白名单不删除
+func revokeOnProxy(target driftTarget, granted, remaining expectedGrants) error {
+	sql := "select * from user;"
+	queryRequest := QueryRequest{[]string{target.Address}, []string{sql}, true, 30, target.BkCloudId}
+	output, err := OneAddressExecuteProxySql(queryRequest)
+	if err != nil {
+		return err
+	}
+	actual := make(map[string]struct{})
+	for _, row := range output.CmdResults[0].TableData {
+		if userHost, ok := row["user@ip"].(string); ok {
+			actual[userHost] = struct{}{}
+		}
+	}
+	var sqls []string
+	for _, userHost := range unionKeys(granted, nil) {
+		_, keep := remaining[userHost]
+		if _, ok := actual[userHost]; ok && !keep {
+			sqls = append(sqls, fmt.Sprintf("refresh_users('%s','-');", userHost))
+		}
+	}
+	if len(sqls) == 0 {
+		return nil
+	}
+	queryRequest = QueryRequest{[]string{target.Address}, sqls, true, 30, target.BkCloudId}
+	_, err = OneAddressExecuteProxySql(queryRequest)
+	return err
+}
+
+// revokePrivs 计算一个库表范围上需要回收的权限
+// granted 临时授权的权限，keep 其他授权仍然需要的权限，actual 实例上实际的权限
+// ALL PRIVILEGES 不能部分回收，需要保留部分权限时全部回收后再授予 regrant
+func revokePrivs(granted, keep, actual map[string]struct{}) (revoke []string, regrant []string) {
+	if len(granted) == 0 || len(actual) == 0 {
+		return nil, nil
+	}
+	if _, ok := keep[allPrivileges]; ok {
+		return nil, nil
+	}
+	_, grantedAll := granted[allPrivileges]
+	_, actualAll := actual[allPrivileges]
+	if actualAll {
+		if !grantedAll {
+			// ALL PRIVILEGES 不是临时授权授予的
+			return nil, nil
+		}
+		if len(keep) > 0 {
+			return []string{allPrivileges}, sortedPrivs(keep)
+		}
+		return []string{allPrivileges}, nil
+	}
+	for _, priv := range sortedPrivs(actual) {
+		if _, ok := keep[priv]; ok {
+			continue
+		}
+		// 8.0 show grants 展开了 ALL PRIVILEGES
+		if _, ok := granted[priv]; ok || (grantedAll && priv != grantOption) {
+			revoke = append(revoke, priv)
+		}
+	}
+	return revoke, nil
+}
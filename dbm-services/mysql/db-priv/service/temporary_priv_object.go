@@ -0,0 +1,63 @@
+package service
+
+import "time"
+
+// 临时授权的状态
+const (
+	tempPrivActive       = "active"
+	tempPrivRevoking     = "revoking"
+	tempPrivRevoked      = "revoked"
+	tempPrivRevokeFailed = "revoke_failed"
+)
+
+// tempPrivJobOperator 定时回收时记录的操作者
+const tempPrivJobOperator = "temporary_priv_job"
+
+// tempPrivRevokingTimeout 回收中的状态超过这个时间，认为回收进程已经退出，重新回收
+const tempPrivRevokingTimeout = 30 * time.Minute
+
+// tempPrivMaxRetries 定时回收失败的最大重试次数，超过后需要人工处理
+const tempPrivMaxRetries = 10
+
+// expireTimeLayout 临时授权过期时间的格式，也支持 RFC3339
+const expireTimeLayout = "2006-01-02 15:04:05"
+
+// TbTemporaryPrivs 临时授权表，记录授权时的参数以及账号规则，到期后按记录回收
+type TbTemporaryPrivs struct {
+	Id          int64      `gorm:"column:id;primary_key;auto_increment" json:"id"`
+	BkBizId     int64      `gorm:"column:bk_biz_id;not_null" json:"bk_biz_id"`
+	ClusterType string     `gorm:"column:cluster_type;not_null" json:"cluster_type"`
+	User        string     `gorm:"column:user;not_null" json:"user"`
+	PrivLogId   int64      `gorm:"column:priv_log_id;not_null" json:"priv_log_id"`
+	Para        string     `gorm:"column:para;not_null" json:"para"`
+	ExpireTime  time.Time  `gorm:"column:expire_time;not_null" json:"expire_time"`
+	Status      string     `gorm:"column:status;not_null" json:"status"`
+	Retries     int        `gorm:"column:retries;not_null" json:"retries"`
+	ErrMsg      string     `gorm:"column:err_msg" json:"err_msg"`
+	Ticket      string     `gorm:"column:ticket" json:"ticket"`
+	Creator     string     `gorm:"column:creator;not_null" json:"creator"`
+	CreateTime  time.Time  `gorm:"column:create_time" json:"create_time"`
+	Operator    string     `gorm:"column:operator" json:"operator"`
+	UpdateTime  time.Time  `gorm:"column:update_time" json:"update_time"`
+	RevokeTime  *time.Time `gorm:"column:revoke_time" json:"revoke_time"`
+}
+
+// GetTemporaryPrivPara GetTemporaryPriv 函数的入参
+type GetTemporaryPrivPara struct {
+	BkBizId     int64   `json:"bk_biz_id"`
+	ClusterType *string `json:"cluster_type"`
+	User        string  `json:"user"`
+	// 为空时查询所有状态
+	Status []string `json:"status"`
+	Limit  *int64   `json:"limit"`
+	Offset *int64   `json:"offset"`
+}
+
+// ModifyTemporaryPrivPara ExtendTemporaryPriv、RevokeTemporaryPriv 函数的入参
+type ModifyTemporaryPrivPara struct {
+	BkBizId int64   `json:"bk_biz_id"`
+	Ids     []int64 `json:"ids"`
+	// 延期后的过期时间，格式 2006-01-02 15:04:05，RevokeTemporaryPriv 不需要
+	ExpireTime string `json:"expire_time"`
+	Operator   string `json:"operator"`
+}
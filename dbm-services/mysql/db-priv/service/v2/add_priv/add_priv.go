@@ -59,7 +59,7 @@ func (c *PrivTaskPara) AddPriv(jsonPara, ticket string) (err error) {
 	slog.Info("add priv", slog.String("source ips", strings.Join(c.SourceIPs, ",")))
 
 	// 写审计日志
-	service.AddPrivLog(
+	privLogId := service.AddPrivLog(
 		service.PrivLog{
 			Id:       0,
 			BkBizId:  c.BkBizId,
@@ -69,6 +69,15 @@ func (c *PrivTaskPara) AddPriv(jsonPara, ticket string) (err error) {
 			Time:     time.Now(),
 		})
 
+	// 临时授权, 授权前记录, 到期后回收
+	if c.ExpireTime != "" {
+		err = c.AddTemporaryPriv(privLogId, ticket)
+		if err != nil {
+			slog.Error("add priv", slog.String("err", err.Error()))
+			return err
+		}
+	}
+
 	// 目标实例的 dbmeta 信息
 	targetMetaInfos, err := c.fetchTargetDBMetaInfo()
 	if err != nil {
@@ -18,6 +18,9 @@ func init() {
 	rootCmd.PersistentFlags().String("sqlserver_admin_password", "123", "sqlserver password")
 	rootCmd.PersistentFlags().String("sqlserver_admin_user", "root", "sqlserver user")
 
+	rootCmd.PersistentFlags().String("mongodb_admin_password", "", "mongodb password")
+	rootCmd.PersistentFlags().String("mongodb_admin_user", "", "mongodb user")
+
 	rootCmd.PersistentFlags().Int("port", 8888, "port")
 
 	rootCmd.PersistentFlags().Bool("log_json", true, "json format log")
@@ -48,6 +51,8 @@ func init() {
 
 	_ = viper.BindEnv("sqlserver_admin_user", "SQLSERVER_ADMIN_USER")
 	_ = viper.BindEnv("sqlserver_admin_password", "SQLSERVER_ADMIN_PASSWORD")
+	_ = viper.BindEnv("mongodb_admin_user", "MONGODB_ADMIN_USER")
+	_ = viper.BindEnv("mongodb_admin_password", "MONGODB_ADMIN_PASSWORD")
 	_ = viper.BindEnv("concurrent", "CONCURRENT")
 	_ = viper.BindEnv("port", "PORT")
 	_ = viper.BindEnv("tmysqlparser_bin", "TMYSQLPARSER_BIN")
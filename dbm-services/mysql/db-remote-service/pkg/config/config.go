@@ -25,6 +25,8 @@ type runtimeConfig struct {
 	Timezone               string
 	SqlserverAdminUser     string
 	SqlserverAdminPassword string
+	MongoDBAdminUser       string
+	MongoDBAdminPassword   string
 	Port                   int
 	ParserBin              string
 	CAFile                 string
@@ -54,6 +56,8 @@ func InitConfig() {
 		Timezone:               viper.GetString("time_zone"),
 		SqlserverAdminUser:     viper.GetString("sqlserver_admin_user"),
 		SqlserverAdminPassword: viper.GetString("sqlserver_admin_password"),
+		MongoDBAdminUser:       viper.GetString("mongodb_admin_user"),
+		MongoDBAdminPassword:   viper.GetString("mongodb_admin_password"),
 		Port:                   viper.GetInt("port"),
 		ParserBin:              viper.GetString("tmysqlparser_bin"),
 		TLS:                    viper.GetBool("tls"),
@@ -0,0 +1,99 @@
+package mongodb_rpc
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// maxDocuments 单个命令最多返回的文档数
+const maxDocuments = 1000
+
+type mongoConn struct {
+	client *mongo.Client
+}
+
+// Query 执行只读命令, find/aggregate 每个文档一行, 超过 maxDocuments 时截断; 其他命令返回一行
+func (c *mongoConn) Query(ctx context.Context, command string) (
+	tableData []map[string]interface{}, truncated bool, err error) {
+	database, doc, err := parseCommand(command)
+	if err != nil {
+		return nil, false, err
+	}
+
+	db := c.client.Database(database)
+	if !isCursorCommand(doc[0].Key) {
+		raw, err := db.RunCommand(ctx, doc).DecodeBytes()
+		if err != nil {
+			return nil, false, err
+		}
+		row, err := documentToMap(raw)
+		if err != nil {
+			return nil, false, err
+		}
+		return []map[string]interface{}{row}, false, nil
+	}
+
+	cursor, err := db.RunCommandCursor(ctx, doc)
+	if err != nil {
+		return nil, false, err
+	}
+	defer func() {
+		_ = cursor.Close(context.Background())
+	}()
+
+	tableData = make([]map[string]interface{}, 0)
+	for cursor.Next(ctx) {
+		if len(tableData) >= maxDocuments {
+			slog.Warn(
+				"too many documents, truncated",
+				slog.String("command", command),
+				slog.Int("max documents", maxDocuments),
+			)
+			truncated = true
+			break
+		}
+		row, err := documentToMap(cursor.Current)
+		if err != nil {
+			return nil, false, err
+		}
+		tableData = append(tableData, row)
+	}
+
+	if err = cursor.Err(); err != nil {
+		return nil, false, err
+	}
+	return tableData, truncated, nil
+}
+
+// Close 断开连接
+func (c *mongoConn) Close() error {
+	return c.client.Disconnect(context.Background())
+}
+
+func isCursorCommand(name string) bool {
+	for _, ele := range cursorCommands {
+		if name == ele {
+			return true
+		}
+	}
+	return false
+}
+
+// documentToMap 把文档转换为 relaxed extended json 格式的 map, ObjectId/Date 等类型保留类型信息
+func documentToMap(raw bson.Raw) (map[string]interface{}, error) {
+	b, err := bson.MarshalExtJSON(raw, false, false)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshal document to json")
+	}
+
+	row := make(map[string]interface{})
+	if err = json.Unmarshal(b, &row); err != nil {
+		return nil, errors.Wrap(err, "unmarshal document json")
+	}
+	return row, nil
+}
@@ -0,0 +1,177 @@
+// Package mongodb_rpc mongodb rpc
+package mongodb_rpc
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"dbm-services/mysql/db-remote-service/pkg/config"
+	"dbm-services/mysql/db-remote-service/pkg/parser"
+	"dbm-services/mysql/db-remote-service/pkg/rpc_core"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// 命令是 extended json 格式的 command document, 第一个 key 是命令名, $db 指定执行命令的库, 默认为 admin
+// 如 {"find": "users", "filter": {"age": {"$gt": 18}}, "limit": 10, "$db": "test"}
+const defaultDatabase = "admin"
+
+var mongodbQueryParseCommands = []string{
+	"find",
+	"aggregate",
+	"serverStatus",
+	"replSetGetStatus",
+	"currentOp",
+}
+
+// cursorCommands 返回游标的命令, 结果按文档逐行返回
+var cursorCommands = []string{
+	"find",
+	"aggregate",
+}
+
+// MongoRPCEmbed mongodb 实现
+type MongoRPCEmbed struct {
+}
+
+// ParseCommand mongodb 解析命令, 接口只读, 拒绝包含 $out/$merge 阶段的 aggregate
+func (c *MongoRPCEmbed) ParseCommand(command string) (*parser.ParseQueryBase, error) {
+	_, doc, err := parseCommand(command)
+	if err != nil {
+		return nil, err
+	}
+
+	name := doc[0].Key
+	if name == "aggregate" && isWriteAggregate(doc) {
+		return nil, errors.Errorf("aggregate with $out/$merge not support: %s", command)
+	}
+	return &parser.ParseQueryBase{
+		QueryId:   0,
+		Command:   name,
+		ErrorCode: 0,
+		ErrorMsg:  "",
+	}, nil
+}
+
+// MakeConnection mongodb 不支持 database/sql, 由 MakeNativeConnection 建立连接
+func (c *MongoRPCEmbed) MakeConnection(address string, user string, password string, timeout int, timezone string) (*sqlx.DB, error) {
+	return nil, errors.New("mongodb not support sql connection")
+}
+
+// MakeNativeConnection mongodb 建立连接, 直连指定的实例
+func (c *MongoRPCEmbed) MakeNativeConnection(address string, user string, password string, timeout int) (rpc_core.NativeConn, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*time.Duration(timeout))
+	defer cancel()
+
+	opts := options.Client().
+		ApplyURI(fmt.Sprintf("mongodb://%s", address)).
+		SetDirect(true).
+		SetConnectTimeout(time.Second * time.Duration(timeout)).
+		SetServerSelectionTimeout(time.Second * time.Duration(timeout))
+	if user != "" {
+		opts.SetAuth(options.Credential{Username: user, Password: password, AuthSource: defaultDatabase})
+	}
+
+	client, err := mongo.Connect(ctx, opts)
+	if err != nil {
+		slog.Error("connect to mongodb",
+			slog.String("error", err.Error()),
+			slog.String("address", address),
+		)
+		return nil, err
+	}
+
+	if err = client.Ping(ctx, nil); err != nil {
+		slog.Error("ping mongodb",
+			slog.String("error", err.Error()),
+			slog.String("address", address),
+		)
+		_ = client.Disconnect(context.Background())
+		return nil, err
+	}
+
+	return &mongoConn{client: client}, nil
+}
+
+// IsQueryCommand mongodb 解析命令
+func (c *MongoRPCEmbed) IsQueryCommand(pc *parser.ParseQueryBase) bool {
+	for _, ele := range mongodbQueryParseCommands {
+		if pc.Command == ele {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsExecuteCommand mongodb 只支持只读命令
+func (c *MongoRPCEmbed) IsExecuteCommand(pc *parser.ParseQueryBase) bool {
+	return false
+}
+
+// User mongodb 用户
+func (c *MongoRPCEmbed) User() string {
+	return config.RuntimeConfig.MongoDBAdminUser
+}
+
+// Password mongodb 密码
+func (c *MongoRPCEmbed) Password() string {
+	return config.RuntimeConfig.MongoDBAdminPassword
+}
+
+// parseCommand 解析命令, 返回执行命令的库和去掉 $db 后的 command document
+func parseCommand(command string) (database string, doc bson.D, err error) {
+	var raw bson.D
+	if err = bson.UnmarshalExtJSON([]byte(command), false, &raw); err != nil {
+		return "", nil, errors.Wrapf(err, "parse command %s", command)
+	}
+
+	database = defaultDatabase
+	for _, e := range raw {
+		if e.Key == "$db" {
+			db, ok := e.Value.(string)
+			if !ok || db == "" {
+				return "", nil, errors.Errorf("invalid $db in command %s", command)
+			}
+			database = db
+			continue
+		}
+		doc = append(doc, e)
+	}
+
+	if len(doc) == 0 {
+		return "", nil, errors.Errorf("empty command %s", command)
+	}
+	return database, doc, nil
+}
+
+// isWriteAggregate aggregate 是否包含 $out/$merge 阶段
+func isWriteAggregate(doc bson.D) bool {
+	for _, e := range doc {
+		if e.Key != "pipeline" {
+			continue
+		}
+		stages, ok := e.Value.(bson.A)
+		if !ok {
+			return false
+		}
+		for _, stage := range stages {
+			sd, ok := stage.(bson.D)
+			if !ok {
+				continue
+			}
+			for _, se := range sd {
+				if se.Key == "$out" || se.Key == "$merge" {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
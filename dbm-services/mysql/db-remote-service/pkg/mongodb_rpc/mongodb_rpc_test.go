@@ -0,0 +1,123 @@
+package mongodb_rpc
+
+import (
+	"testing"
+)
+
+func TestParseCommand(t *testing.T) {
+	cases := []struct {
+		name     string
+		command  string
+		database string
+		keys     []string
+		wantErr  bool
+	}{
+		{name: "default db", command: `{"find": "users", "limit": 10}`, database: "admin",
+			keys: []string{"find", "limit"}},
+		{name: "db removed", command: `{"find": "users", "$db": "test", "filter": {"age": {"$gt": 18}}}`,
+			database: "test", keys: []string{"find", "filter"}},
+		{name: "db first", command: `{"$db": "test", "serverStatus": 1}`, database: "test",
+			keys: []string{"serverStatus"}},
+		{name: "db not string", command: `{"find": "users", "$db": 1}`, wantErr: true},
+		{name: "db empty", command: `{"find": "users", "$db": ""}`, wantErr: true},
+		{name: "only db", command: `{"$db": "test"}`, wantErr: true},
+		{name: "empty", command: `{}`, wantErr: true},
+		{name: "invalid json", command: `{"find": `, wantErr: true},
+		{name: "not document", command: `["find"]`, wantErr: true},
+	}
+	for _, c := range cases {
+		database, doc, err := parseCommand(c.command)
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("%s: parseCommand(%s) want error", c.name, c.command)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: parseCommand(%s) %s", c.name, c.command, err.Error())
+			continue
+		}
+		var keys []string
+		for _, e := range doc {
+			keys = append(keys, e.Key)
+		}
+		if database != c.database || len(keys) != len(c.keys) {
+			t.Errorf("%s: parseCommand(%s) = %s %v, want %s %v", c.name, c.command, database, keys,
+				c.database, c.keys)
+			continue
+		}
+		for i := range keys {
+			if keys[i] != c.keys[i] {
+				t.Errorf("%s: parseCommand(%s) keys %v, want %v", c.name, c.command, keys, c.keys)
+				break
+			}
+		}
+	}
+}
+
+func TestIsWriteAggregate(t *testing.T) {
+	cases := []struct {
+		command string
+		want    bool
+	}{
+		{`{"aggregate": "c1", "pipeline": [{"$match": {"a": 1}}], "cursor": {}}`, false},
+		{`{"aggregate": "c1", "pipeline": [], "cursor": {}}`, false},
+		{`{"aggregate": "c1", "pipeline": [{"$match": {"a": 1}}, {"$out": "c2"}], "cursor": {}}`, true},
+		{`{"aggregate": "c1", "pipeline": [{"$out": "c2"}, {"$match": {"a": 1}}], "cursor": {}}`, true},
+		{`{"aggregate": "c1", "pipeline": [{"$group": {"_id": "$a"}}, {"$merge": {"into": "c2"}}]}`, true},
+		{`{"aggregate": "c1", "pipeline": [{"$merge": "c2"}]}`, true},
+		// 只识别顶层的阶段名, 字段名里的 $out 不算
+		{`{"aggregate": "c1", "pipeline": [{"$project": {"$out": 1}}]}`, false},
+		// pipeline 不是数组时交给 mongodb 报错
+		{`{"aggregate": "c1", "pipeline": {"$out": "c2"}}`, false},
+		{`{"aggregate": "c1", "pipeline": ["$out"]}`, false},
+		{`{"aggregate": "c1"}`, false},
+	}
+	for _, c := range cases {
+		_, doc, err := parseCommand(c.command)
+		if err != nil {
+			t.Fatalf("parseCommand(%s) %s", c.command, err.Error())
+		}
+		if got := isWriteAggregate(doc); got != c.want {
+			t.Errorf("isWriteAggregate(%s) = %v, want %v", c.command, got, c.want)
+		}
+	}
+}
+
+func TestMongoParseCommand(t *testing.T) {
+	c := &MongoRPCEmbed{}
+	cases := []struct {
+		command string
+		name    string
+		query   bool
+		wantErr bool
+	}{
+		{command: `{"find": "users", "$db": "test"}`, name: "find", query: true},
+		{command: `{"$db": "test", "aggregate": "c1", "pipeline": [{"$match": {}}]}`, name: "aggregate", query: true},
+		{command: `{"replSetGetStatus": 1}`, name: "replSetGetStatus", query: true},
+		{command: `{"aggregate": "c1", "pipeline": [{"$match": {}}, {"$out": "c2"}], "$db": "test"}`,
+			wantErr: true},
+		// 不在允许列表中的命令可以解析, 但不是查询命令, 也不能执行
+		{command: `{"insert": "c1", "documents": [{"a": 1}]}`, name: "insert"},
+		{command: `{"dropDatabase": 1, "$db": "test"}`, name: "dropDatabase"},
+		{command: `{"count": "c1"}`, name: "count"},
+		{command: `{"$db": "test"}`, wantErr: true},
+	}
+	for _, cs := range cases {
+		pc, err := c.ParseCommand(cs.command)
+		if cs.wantErr {
+			if err == nil {
+				t.Errorf("ParseCommand(%s) want error", cs.command)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseCommand(%s) %s", cs.command, err.Error())
+			continue
+		}
+		if pc.Command != cs.name || c.IsQueryCommand(pc) != cs.query || c.IsExecuteCommand(pc) {
+			t.Errorf("ParseCommand(%s) = %s, query %v, want %s, query %v", cs.command, pc.Command,
+				c.IsQueryCommand(pc), cs.name, cs.query)
+		}
+	}
+}
@@ -9,6 +9,10 @@ import (
 )
 
 func (c *RPCWrapper) executeOneAddr(address string) (res []cmdResult, err error) {
+	if ne, ok := c.RPCEmbedInterface.(NativeRPCEmbedInterface); ok {
+		return c.executeOneAddrNative(ne, address)
+	}
+
 	db, err := c.MakeConnection(address, c.user, c.password, c.connectTimeout, c.timezone)
 
 	if err != nil {
@@ -32,6 +36,47 @@ func (c *RPCWrapper) executeOneAddr(address string) (res []cmdResult, err error)
 		_ = conn.Close()
 	}()
 
+	return c.executeCommands(
+		address,
+		func(command string) (tableDataType, bool, error) {
+			tableData, err := queryCmd(conn, command, time.Second*time.Duration(c.queryTimeout))
+			return tableData, false, err
+		},
+		func(command string) (int64, error) {
+			return executeCmd(conn, command, time.Second*time.Duration(c.queryTimeout))
+		},
+	)
+}
+
+func (c *RPCWrapper) executeOneAddrNative(ne NativeRPCEmbedInterface, address string) (res []cmdResult, err error) {
+	conn, err := ne.MakeNativeConnection(address, c.user, c.password, c.connectTimeout)
+	if err != nil {
+		slog.Error("make native connection", slog.String("error", err.Error()))
+		return nil, err
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	return c.executeCommands(
+		address,
+		func(command string) (tableDataType, bool, error) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second*time.Duration(c.queryTimeout))
+			defer cancel()
+			return conn.Query(ctx, command)
+		},
+		func(command string) (int64, error) {
+			return 0, errors.Errorf("%s not support on native connection", command)
+		},
+	)
+}
+
+// executeCommands 按顺序执行命令, 非 force 模式下遇到错误立即返回
+func (c *RPCWrapper) executeCommands(
+	address string,
+	query func(string) (tableDataType, bool, error),
+	execute func(string) (int64, error),
+) (res []cmdResult, err error) {
 	for idx, command := range c.commands {
 		pc, err := c.ParseCommand(command)
 		if err != nil {
@@ -40,7 +85,7 @@ func (c *RPCWrapper) executeOneAddr(address string) (res []cmdResult, err error)
 		}
 
 		if c.IsQueryCommand(pc) {
-			tableData, err := queryCmd(conn, command, time.Second*time.Duration(c.queryTimeout))
+			tableData, truncated, err := query(command)
 			if err != nil {
 				slog.Error(
 					"query command",
@@ -66,10 +111,11 @@ func (c *RPCWrapper) executeOneAddr(address string) (res []cmdResult, err error)
 					TableData:    tableData,
 					RowsAffected: 0,
 					ErrorMsg:     "",
+					Truncated:    truncated,
 				},
 			)
 		} else if c.IsExecuteCommand(pc) {
-			rowsAffected, err := executeCmd(conn, command, time.Second*time.Duration(c.queryTimeout))
+			rowsAffected, err := execute(command)
 			if err != nil {
 				slog.Error(
 					"execute command",
@@ -7,6 +7,8 @@ type cmdResult struct {
 	TableData    tableDataType `json:"table_data"`
 	RowsAffected int64         `json:"rows_affected"`
 	ErrorMsg     string        `json:"error_msg"`
+	// Truncated 结果超过最大行数被截断, 目前只有 mongodb 会截断
+	Truncated bool `json:"truncated,omitempty"`
 }
 
 type oneAddressResult struct {
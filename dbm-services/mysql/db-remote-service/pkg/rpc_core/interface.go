@@ -1,6 +1,8 @@
 package rpc_core
 
 import (
+	"context"
+
 	"dbm-services/mysql/db-remote-service/pkg/parser"
 
 	"github.com/jmoiron/sqlx"
@@ -21,3 +23,21 @@ type RPCEmbedInterface interface {
 	User() string
 	Password() string
 }
+
+// NativeRPCEmbedInterface 不通过 database/sql 访问的存储额外实现该接口, 如 mongodb
+// RPCWrapper 使用 MakeNativeConnection 建立连接, 不再调用 MakeConnection
+type NativeRPCEmbedInterface interface {
+	MakeNativeConnection(
+		address string,
+		user string,
+		password string,
+		timeout int,
+	) (NativeConn, error)
+}
+
+// NativeConn 存储原生驱动的连接, 只支持只读命令
+// Query 返回的 truncated 表示结果超过最大行数被截断
+type NativeConn interface {
+	Query(ctx context.Context, command string) (tableData []map[string]interface{}, truncated bool, err error)
+	Close() error
+}
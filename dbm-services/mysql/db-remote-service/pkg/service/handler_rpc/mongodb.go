@@ -0,0 +1,6 @@
+package handler_rpc
+
+import "dbm-services/mysql/db-remote-service/pkg/mongodb_rpc"
+
+// MongoDBRPCHandler mongodb 请求响应
+var MongoDBRPCHandler = generalHandler(&mongodb_rpc.MongoRPCEmbed{})
@@ -25,4 +25,7 @@ func RegisterRouter(engine *gin.Engine) {
 
 	webConsoleGroup := engine.Group("/webconsole")
 	webConsoleGroup.POST("/rpc", handler_rpc.WebConsoleRPCHandler)
+
+	mongodbGroup := engine.Group("/mongodb")
+	mongodbGroup.POST("/rpc", handler_rpc.MongoDBRPCHandler)
 }
@@ -38,6 +38,7 @@ type AppConfig struct {
 	SimulationNodeLables  []LabelItem       `yaml:"simulationNodeLables"`
 	SimulationtaintLables []LabelItem       `yaml:"simulationtaintLables"`
 	Redis                 RedisDb           `yaml:"redis"`
+	Drs                   DrsConfig         `yaml:"drs"`
 }
 
 // BkRepoConfig bkrepo config
@@ -49,6 +50,13 @@ type BkRepoConfig struct {
 	EndPointUrl  string `yaml:"endpointUrl"`
 }
 
+// DrsConfig db-remote-service config
+type DrsConfig struct {
+	Addr      string `yaml:"addr"`
+	AppCode   string `yaml:"appCode"`
+	AppSecret string `yaml:"appSecret"`
+}
+
 // LabelItem kubernert lable item
 type LabelItem struct {
 	Key   string `json:"key" yaml:"key"`
@@ -141,6 +149,11 @@ func init() {
 		Port: viper.GetInt("DB_PORT"),
 		Name: viper.GetString("DBSIMULATION_DB"),
 	}
+	GAppConfig.Drs = DrsConfig{
+		Addr:      viper.GetString("DRS_ADDR"),
+		AppCode:   viper.GetString("BK_APP_CODE"),
+		AppSecret: viper.GetString("BK_APP_SECRET"),
+	}
 
 	if err := loadConfig(); err != nil {
 		logger.Error("load config file failed:%s", err.Error())
@@ -0,0 +1,121 @@
+/*
+ * TencentBlueKing is pleased to support the open source community by making 蓝鲸智云-DB管理系统(BlueKing-BK-DBM) available.
+ * Copyright (C) 2017-2023 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at https://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+ * an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+// Package drs 通过 db-remote-service 在目标实例上执行 sql
+package drs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"dbm-services/common/go-pubpkg/logger"
+	"dbm-services/mysql/db-simulation/app/config"
+)
+
+var client = &http.Client{Timeout: 60 * time.Second}
+
+// CmdResult 单条 sql 的执行结果
+type CmdResult struct {
+	Cmd          string                   `json:"cmd"`
+	TableData    []map[string]interface{} `json:"table_data"`
+	RowsAffected int64                    `json:"rows_affected"`
+	ErrorMsg     string                   `json:"error_msg"`
+}
+
+// OneAddressResult 单个实例的执行结果
+type OneAddressResult struct {
+	Address    string      `json:"address"`
+	CmdResults []CmdResult `json:"cmd_results"`
+	ErrorMsg   string      `json:"error_msg"`
+}
+
+type rpcRequest struct {
+	Addresses    []string `json:"addresses"`
+	Cmds         []string `json:"cmds"`
+	Force        bool     `json:"force"`
+	QueryTimeout int64    `json:"query_timeout"`
+	BkCloudId    int64    `json:"bk_cloud_id"`
+}
+
+type apiResponse struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// Enabled 是否配置了 db-remote-service
+func Enabled() bool {
+	return config.GAppConfig.Drs.Addr != ""
+}
+
+// RPCMySQL 在 mysql 实例上执行 sql，任意一条 sql 执行失败都返回错误
+func RPCMySQL(bkCloudId int64, address string, cmds []string, timeout int64) (*OneAddressResult, error) {
+	endPoint, err := url.JoinPath(config.GAppConfig.Drs.Addr, "/mysql/rpc/")
+	if err != nil {
+		return nil, err
+	}
+	body, err := json.Marshal(rpcRequest{
+		Addresses:    []string{address},
+		Cmds:         cmds,
+		QueryTimeout: timeout,
+		BkCloudId:    bkCloudId,
+	})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, endPoint, bytes.NewBuffer(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-bkapi-authorization", fmt.Sprintf(`{"bk_app_code": "%s", "bk_app_secret": "%s"}`,
+		config.GAppConfig.Drs.AppCode, config.GAppConfig.Drs.AppSecret))
+	resp, err := client.Do(req)
+	if err != nil {
+		logger.Error("request db-remote-service failed %s", err.Error())
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("request db-remote-service failed, http status: %s", resp.Status)
+	}
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	var apiResp apiResponse
+	if err = json.Unmarshal(b, &apiResp); err != nil {
+		return nil, err
+	}
+	if apiResp.Code != 0 {
+		return nil, fmt.Errorf("db-remote-service: %s", apiResp.Message)
+	}
+	var res []OneAddressResult
+	if err = json.Unmarshal(apiResp.Data, &res); err != nil {
+		return nil, err
+	}
+	if len(res) == 0 {
+		return nil, fmt.Errorf("db-remote-service returns no result of %s", address)
+	}
+	if res[0].ErrorMsg != "" {
+		return nil, fmt.Errorf("%s: %s", address, res[0].ErrorMsg)
+	}
+	for _, r := range res[0].CmdResults {
+		if r.ErrorMsg != "" {
+			return nil, fmt.Errorf("%s execute %s failed: %s", address, r.Cmd, r.ErrorMsg)
+		}
+	}
+	return &res[0], nil
+}
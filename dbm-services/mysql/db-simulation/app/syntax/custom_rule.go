@@ -0,0 +1,151 @@
+/*
+ * TencentBlueKing is pleased to support the open source community by making 蓝鲸智云-DB管理系统(BlueKing-BK-DBM) available.
+ * Copyright (C) 2017-2023 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at https://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+ * an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package syntax
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/antonmedv/expr"
+	"github.com/antonmedv/expr/vm"
+	"github.com/samber/lo"
+
+	"dbm-services/common/go-pubpkg/logger"
+	"dbm-services/mysql/db-simulation/model"
+)
+
+// CustomRule 业务或集群自定义的规则，expr 表达式为 true 时命中规则
+//
+//	如: Command == "create_table" && Stmt.table_name startsWith "tmp_"
+type CustomRule struct {
+	Name string `json:"name"`
+	// 规则作用的语句类型，如 create_table、alter_table，为空时作用于所有语句
+	Commands   []string `json:"commands"`
+	Expr       string   `json:"expr"`
+	Desc       string   `json:"desc"`
+	Ban        bool     `json:"ban"`
+	Suggestion string   `json:"suggestion"`
+	// 规则所属的规则集名称
+	ruleSet     string
+	ruleProgram *vm.Program
+}
+
+// CustomRuleEnv 自定义规则 expr 的运行环境
+type CustomRuleEnv struct {
+	// 语句类型，如 create_table
+	Command     string
+	DbName      string
+	QueryString string
+	// tmysqlparse 对该语句的完整解析结果
+	Stmt map[string]interface{}
+}
+
+// ParseCustomRules 解析并编译规则集中的规则
+func ParseCustomRules(ruleSet string, rules json.RawMessage) (crs []*CustomRule, err error) {
+	if err = json.Unmarshal(rules, &crs); err != nil {
+		logger.Error("unmarshal custom rules failed %s", err.Error())
+		return nil, err
+	}
+	names := make(map[string]struct{})
+	for _, r := range crs {
+		if r.Name == "" || r.Expr == "" {
+			return nil, fmt.Errorf("rule name and expr can't be empty")
+		}
+		if _, ok := names[r.Name]; ok {
+			return nil, fmt.Errorf("duplicate rule name %s", r.Name)
+		}
+		names[r.Name] = struct{}{}
+		r.ruleSet = ruleSet
+		if err = r.compile(); err != nil {
+			return nil, fmt.Errorf("rule %s compile failed: %w", r.Name, err)
+		}
+	}
+	return crs, nil
+}
+
+// LoadCustomRules 加载对集群生效的业务级别和集群级别的规则
+func LoadCustomRules(bkBizId int64, clusterDomain, dbType string) (crs []*CustomRule, err error) {
+	sets, err := model.GetEffectiveRuleSets(bkBizId, clusterDomain, dbType)
+	if err != nil {
+		logger.Error("get rule sets of %d %s failed %s", bkBizId, clusterDomain, err.Error())
+		return nil, err
+	}
+	for _, s := range sets {
+		rules, errx := ParseCustomRules(s.Name, s.Rules)
+		if errx != nil {
+			logger.Error("parse rule set %s failed %s", s.Name, errx.Error())
+			return nil, errx
+		}
+		crs = append(crs, rules...)
+	}
+	return crs, nil
+}
+
+func (r *CustomRule) compile() (err error) {
+	p, err := expr.Compile(r.Expr, expr.Env(CustomRuleEnv{}), expr.AsBool())
+	if err != nil {
+		logger.Error("%s:expr.Compile error %s\n", r.Name, err.Error())
+		return err
+	}
+	r.ruleProgram = p
+	return
+}
+
+// Match 运行规则检查
+func (r *CustomRule) Match(env CustomRuleEnv) (matched bool, err error) {
+	if len(r.Commands) > 0 && !lo.Contains(r.Commands, env.Command) {
+		return false, nil
+	}
+	p, err := expr.Run(r.ruleProgram, env)
+	if err != nil {
+		return false, err
+	}
+	matched, _ = p.(bool)
+	return matched, nil
+}
+
+// parseCustomRules 自定义规则检查
+func (c *CheckInfo) parseCustomRules(rules []*CustomRule, res ParseLineQueryBase, bs []byte, ver string) {
+	if len(rules) == 0 {
+		return
+	}
+	env := CustomRuleEnv{
+		Command:     res.Command,
+		DbName:      res.DbName,
+		QueryString: res.QueryString,
+	}
+	if err := json.Unmarshal(bs, &env.Stmt); err != nil {
+		logger.Error("json unmasrshal line failed %s", err.Error())
+		return
+	}
+	for _, r := range rules {
+		matched, err := r.Match(env)
+		if err != nil {
+			// 解析结果中缺少表达式用到的字段时会运行失败，不影响其他规则
+			logger.Warn("run rule %s.%s failed %s", r.ruleSet, r.Name, err.Error())
+			continue
+		}
+		if !matched {
+			continue
+		}
+		info := RiskInfo{
+			Line:        int64(res.QueryId),
+			Sqltext:     res.QueryString,
+			CommandType: res.Command,
+			WarnInfo:    fmt.Sprintf("[%s]: [%s.%s] %s\n%s", ver, r.ruleSet, r.Name, r.Desc, r.Suggestion),
+		}
+		if r.Ban {
+			c.BanWarnings = append(c.BanWarnings, info)
+		} else {
+			c.RiskWarnings = append(c.RiskWarnings, info)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+/*
+ * TencentBlueKing is pleased to support the open source community by making 蓝鲸智云-DB管理系统(BlueKing-BK-DBM) available.
+ * Copyright (C) 2017-2023 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at https://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+ * an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package syntax
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestParseCustomRules(t *testing.T) {
+	cases := []struct {
+		name    string
+		rules   string
+		wantErr string
+	}{
+		{name: "ok", rules: `[{"name":"r1","expr":"Command == \"drop_table\""},` +
+			`{"name":"r2","expr":"Stmt.table_name startsWith \"tmp_\"","commands":["create_table"]}]`},
+		{name: "empty", rules: `[]`},
+		{name: "invalid json", rules: `{"name":"r1"}`, wantErr: "cannot unmarshal"},
+		{name: "empty name", rules: `[{"expr":"true"}]`, wantErr: "can't be empty"},
+		{name: "empty expr", rules: `[{"name":"r1"}]`, wantErr: "can't be empty"},
+		{name: "duplicate name", rules: `[{"name":"r1","expr":"true"},{"name":"r1","expr":"false"}]`,
+			wantErr: "duplicate rule name r1"},
+		{name: "syntax error", rules: `[{"name":"r1","expr":"Command =="}]`, wantErr: "rule r1 compile failed"},
+		{name: "unknown field", rules: `[{"name":"r1","expr":"Table == \"t1\""}]`, wantErr: "rule r1 compile failed"},
+		{name: "not bool", rules: `[{"name":"r1","expr":"Command"}]`, wantErr: "rule r1 compile failed"},
+	}
+	for _, c := range cases {
+		crs, err := ParseCustomRules("set1", json.RawMessage(c.rules))
+		if c.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), c.wantErr) {
+				t.Errorf("%s: got err %v, want %s", c.name, err, c.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %s", c.name, err.Error())
+			continue
+		}
+		for _, r := range crs {
+			if r.ruleSet != "set1" || r.ruleProgram == nil {
+				t.Errorf("%s: rule %s not compiled", c.name, r.Name)
+			}
+		}
+	}
+}
+
+func TestCustomRuleMatch(t *testing.T) {
+	crs, err := ParseCustomRules("set1", json.RawMessage(`[
+		{"name":"tmp_table","commands":["create_table"],"expr":"Stmt.table_name startsWith \"tmp_\""},
+		{"name":"drop","expr":"Command in [\"drop_table\", \"drop_db\"]"},
+		{"name":"engine","expr":"Stmt.table_options.engine == \"myisam\""}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpTable, drop, engine := crs[0], crs[1], crs[2]
+	createTmp := CustomRuleEnv{Command: "create_table", Stmt: map[string]interface{}{"table_name": "tmp_t1"}}
+	cases := []struct {
+		name    string
+		rule    *CustomRule
+		env     CustomRuleEnv
+		want    bool
+		wantErr bool
+	}{
+		{name: "command and expr match", rule: tmpTable, env: createTmp, want: true},
+		{name: "expr not match", rule: tmpTable,
+			env: CustomRuleEnv{Command: "create_table", Stmt: map[string]interface{}{"table_name": "t1"}}},
+		{name: "command filtered", rule: tmpTable,
+			env: CustomRuleEnv{Command: "alter_table", Stmt: map[string]interface{}{"table_name": "tmp_t1"}}},
+		{name: "all commands", rule: drop, env: CustomRuleEnv{Command: "drop_db"}, want: true},
+		{name: "all commands not match", rule: drop, env: createTmp},
+		{name: "missing nested field", rule: engine, env: createTmp, wantErr: true},
+		{name: "nested field", rule: engine, want: true, env: CustomRuleEnv{Command: "create_table",
+			Stmt: map[string]interface{}{"table_options": map[string]interface{}{"engine": "myisam"}}}},
+	}
+	for _, c := range cases {
+		matched, err := c.rule.Match(c.env)
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("%s: want error", c.name)
+			}
+			continue
+		}
+		if err != nil || matched != c.want {
+			t.Errorf("%s: got %v, %v, want %v", c.name, matched, err, c.want)
+		}
+	}
+}
+
+func TestCheckInfoParseCustomRules(t *testing.T) {
+	crs, err := ParseCustomRules("set1", json.RawMessage(`[
+		{"name":"engine","expr":"Stmt.table_options.engine == \"myisam\"","desc":"engine"},
+		{"name":"tmp_table","commands":["create_table"],"expr":"Stmt.table_name startsWith \"tmp_\"",
+			"ban":true,"desc":"tmp table","suggestion":"rename"},
+		{"name":"create","expr":"Command == \"create_table\" && DbName == \"db1\"","desc":"create"},
+		{"name":"drop","expr":"Command == \"drop_table\"","ban":true}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := ParseLineQueryBase{QueryId: 3, Command: "create_table", DbName: "db1",
+		QueryString: "create table tmp_t1(id int)"}
+	bs := []byte(`{"query_id":3,"command":"create_table","db_name":"db1","table_name":"tmp_t1"}`)
+
+	var c CheckInfo
+	// engine 规则读取不存在的字段运行失败, 不影响其他规则
+	c.parseCustomRules(crs, res, bs, "mysql-5.7")
+	if len(c.BanWarnings) != 1 || len(c.RiskWarnings) != 1 {
+		t.Fatalf("got ban %v, risk %v", c.BanWarnings, c.RiskWarnings)
+	}
+	ban := c.BanWarnings[0]
+	if ban.Line != 3 || ban.CommandType != "create_table" || ban.Sqltext != res.QueryString ||
+		ban.WarnInfo != "[mysql-5.7]: [set1.tmp_table] tmp table\nrename" {
+		t.Errorf("unexpected ban warning %+v", ban)
+	}
+	if !strings.Contains(c.RiskWarnings[0].WarnInfo, "[set1.create]") {
+		t.Errorf("unexpected risk warning %+v", c.RiskWarnings[0])
+	}
+
+	// 没有规则或者解析结果不是 json 时不检查
+	c = CheckInfo{}
+	c.parseCustomRules(nil, res, bs, "mysql-5.7")
+	c.parseCustomRules(crs, res, []byte("not json"), "mysql-5.7")
+	if len(c.BanWarnings) != 0 || len(c.RiskWarnings) != 0 {
+		t.Errorf("got ban %v, risk %v", c.BanWarnings, c.RiskWarnings)
+	}
+}
@@ -0,0 +1,343 @@
+/*
+ * TencentBlueKing is pleased to support the open source community by making 蓝鲸智云-DB管理系统(BlueKing-BK-DBM) available.
+ * Copyright (C) 2017-2023 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at https://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+ * an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package syntax
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"dbm-services/common/go-pubpkg/cmutil"
+	"dbm-services/common/go-pubpkg/logger"
+	"dbm-services/mysql/db-simulation/app"
+	"dbm-services/mysql/db-simulation/app/drs"
+)
+
+// online ddl 的执行方式，按代价从小到大排列
+const (
+	// AlgorithmInstant 只修改数据字典
+	AlgorithmInstant = "INSTANT"
+	// AlgorithmInplace 不重建表，可能需要扫描全表构建索引
+	AlgorithmInplace = "INPLACE"
+	// AlgorithmInplaceRebuild 在引擎层重建表，变更期间允许并发DML
+	AlgorithmInplaceRebuild = "INPLACE_REBUILD"
+	// AlgorithmCopy 拷贝表，变更期间不允许DML
+	AlgorithmCopy = "COPY"
+)
+
+var algorithmCost = map[string]int{
+	AlgorithmInstant:        0,
+	AlgorithmInplace:        1,
+	AlgorithmInplaceRebuild: 2,
+	AlgorithmCopy:           3,
+}
+
+// 估算耗时使用的处理速度(字节/秒)，只用于给出数量级
+const (
+	buildIndexBytesPerSecond = 128 * 1024 * 1024
+	rebuildBytesPerSecond    = 64 * 1024 * 1024
+	copyBytesPerSecond       = 32 * 1024 * 1024
+)
+
+// 未指定版本时按照 5.7 判断
+const defaultOnlineDDLVersion = 5007000
+
+// spiderOnlineDDLVersion spider 基于 MariaDB 10.3，只支持在最后加列的 instant add column，
+// 其余规则与 5.7 相同，和 8.0.12 的规则一致
+const spiderOnlineDDLVersion = "8.0.12"
+
+// OnlineDDLInfo alter table 的执行方式以及耗时、额外磁盘空间的估算
+type OnlineDDLInfo struct {
+	Line         int64    `json:"line"`
+	Sqltext      string   `json:"sqltext"`
+	MysqlVersion string   `json:"mysql_version"`
+	DbName       string   `json:"db_name"`
+	TableName    string   `json:"table_name"`
+	Algorithm    string   `json:"algorithm"`
+	Reasons      []string `json:"reasons"`
+	// 从目标实例获取到表信息后才会估算
+	Estimated   bool   `json:"estimated"`
+	TableRows   int64  `json:"table_rows"`
+	TableSize   int64  `json:"table_size"`
+	EstimateSec int64  `json:"estimate_sec"`
+	ExtraDisk   int64  `json:"extra_disk"`
+	EstimateMsg string `json:"estimate_msg,omitempty"`
+}
+
+// TableStatSource 获取变更表行数、大小的实例，通常是集群的主库
+type TableStatSource struct {
+	Address   string
+	BkCloudId int64
+}
+
+// TableStat 表的行数以及大小
+type TableStat struct {
+	TableRows   int64
+	DataLength  int64
+	IndexLength int64
+}
+
+// alterOp 单个 alter 子句的执行方式
+type alterOp struct {
+	algorithm string
+	// INPLACE 时是否需要扫描全表构建索引
+	buildIndex bool
+	reason     string
+}
+
+// OnlineDDL 判断 alter table 在指定版本下的执行方式
+func (c AlterTableResult) OnlineDDL(mysqlVersion string) (algorithm string, buildIndex bool, reasons []string) {
+	ver := cmutil.MySQLVersionParse(mysqlVersion)
+	if ver == 0 {
+		ver = defaultOnlineDDLVersion
+	}
+	algorithm = AlgorithmInstant
+	var specified string
+	var dropPk, addPk bool
+	for _, cmd := range c.AlterCommands {
+		switch cmd.Type {
+		case "algorithm":
+			specified = strings.ToUpper(cmd.Algorithm)
+			continue
+		case "lock":
+			continue
+		}
+		if cmd.DropPrimary || (cmd.Type == "drop_key" && strings.EqualFold(cmd.KeyDef.KeyName, "primary")) {
+			dropPk = true
+		}
+		if cmd.KeyDef.PrimaryKey || cmd.ColDef.PrimaryKey {
+			addPk = true
+		}
+		op := cmd.onlineDDL(ver)
+		buildIndex = buildIndex || op.buildIndex
+		if algorithmCost[op.algorithm] > algorithmCost[algorithm] {
+			algorithm = op.algorithm
+		}
+		reasons = append(reasons, fmt.Sprintf("%s: %s, %s", cmd.Type, op.algorithm, op.reason))
+	}
+	// 删除主键的同时增加主键可以 INPLACE 重建表，只删除主键需要拷贝表
+	if dropPk && addPk && algorithm == AlgorithmCopy {
+		algorithm = AlgorithmInplaceRebuild
+		reasons = append(reasons, "drop primary key and add primary key in the same statement can rebuild inplace")
+	}
+	if specified == AlgorithmCopy {
+		algorithm = AlgorithmCopy
+		reasons = append(reasons, "algorithm=copy specified")
+	} else if specified != "" && specified != "DEFAULT" {
+		if algorithmCost[algorithm] > algorithmCost[specified] {
+			reasons = append(reasons, fmt.Sprintf("algorithm=%s specified, but %s is required, statement will fail",
+				specified, algorithm))
+		}
+	}
+	return algorithm, buildIndex, reasons
+}
+
+// onlineDDL 判断单个 alter 子句的执行方式，无法确定时按代价大的方式判断
+func (a AlterCommand) onlineDDL(ver uint64) alterOp {
+	switch a.Type {
+	case "add_column":
+		if a.ColDef.AutoIncrement || a.ColDef.PrimaryKey {
+			return alterOp{AlgorithmInplaceRebuild, false, "add auto_increment or primary key column rebuilds table"}
+		}
+		if ver >= 8000029 {
+			return alterOp{AlgorithmInstant, false, "add column is instant since 8.0.29"}
+		}
+		if ver >= 8000012 && a.After == "" {
+			return alterOp{AlgorithmInstant, false, "add column as the last column is instant since 8.0.12"}
+		}
+		if ver >= 5006000 {
+			return alterOp{AlgorithmInplaceRebuild, false, "add column rebuilds table"}
+		}
+	case "drop_column":
+		if ver >= 8000029 {
+			return alterOp{AlgorithmInstant, false, "drop column is instant since 8.0.29"}
+		}
+		if ver >= 5006000 {
+			return alterOp{AlgorithmInplaceRebuild, false, "drop column rebuilds table"}
+		}
+	case "change_column", "modify_column":
+		// 只修改列名、默认值是 INPLACE，修改类型需要拷贝表，这里无法获取列原来的定义
+		return alterOp{AlgorithmCopy, false, "changing column data type copies table, " +
+			"only rename column or change default value is inplace"}
+	case "alter_column":
+		if ver >= 5006000 {
+			return alterOp{AlgorithmInstant, false, "set or drop column default only modifies metadata"}
+		}
+	case "add_key":
+		if a.KeyDef.ForeignKey {
+			return alterOp{AlgorithmCopy, false, "add foreign key is inplace only when foreign_key_checks is disabled"}
+		}
+		if a.KeyDef.PrimaryKey {
+			if ver >= 5006000 {
+				return alterOp{AlgorithmInplaceRebuild, false, "add primary key rebuilds table"}
+			}
+			break
+		}
+		if strings.EqualFold(a.KeyDef.Type, "fulltext") {
+			return alterOp{AlgorithmInplaceRebuild, true, "add the first fulltext index rebuilds table"}
+		}
+		// 5.5 的 fast index creation 同样不需要拷贝表
+		return alterOp{AlgorithmInplace, true, "add secondary index scans table to build index"}
+	case "drop_key":
+		if a.DropPrimary || strings.EqualFold(a.KeyDef.KeyName, "primary") {
+			return alterOp{AlgorithmCopy, false, "drop primary key without adding a new one copies table"}
+		}
+		if a.DropForeign {
+			if ver >= 5006000 {
+				return alterOp{AlgorithmInplace, false, "drop foreign key only modifies metadata"}
+			}
+			break
+		}
+		return alterOp{AlgorithmInplace, false, "drop secondary index only modifies metadata"}
+	case "rename_key":
+		if ver >= 5007000 {
+			return alterOp{AlgorithmInplace, false, "rename index only modifies metadata"}
+		}
+	case "rename_table":
+		if ver >= 5006000 {
+			return alterOp{AlgorithmInplace, false, "rename table only modifies metadata"}
+		}
+	case "table_option":
+		return a.tableOptionOnlineDDL(ver)
+	case "force":
+		if ver >= 5006000 {
+			return alterOp{AlgorithmInplaceRebuild, false, "force rebuilds table"}
+		}
+	case "add_partition", "drop_partition", "truncate_partition":
+		if ver >= 5007000 {
+			return alterOp{AlgorithmInplace, false, "partition management only touches affected partitions"}
+		}
+	}
+	return alterOp{AlgorithmCopy, false, "copies table"}
+}
+
+func (a AlterCommand) tableOptionOnlineDDL(ver uint64) alterOp {
+	op := alterOp{AlgorithmInplace, false, "table option only modifies metadata"}
+	for _, o := range a.TableOptions {
+		switch strings.ToLower(o.Key) {
+		case "comment", "auto_increment", "character_set", "collate", "stats_persistent", "stats_auto_recalc":
+		case "engine", "row_format", "key_block_size":
+			if ver < 5006000 {
+				return alterOp{AlgorithmCopy, false, fmt.Sprintf("change %s copies table", o.Key)}
+			}
+			op = alterOp{AlgorithmInplaceRebuild, false, fmt.Sprintf("change %s rebuilds table", o.Key)}
+		default:
+			return alterOp{AlgorithmCopy, false, fmt.Sprintf("change %s copies table", o.Key)}
+		}
+	}
+	if op.algorithm == AlgorithmInplace && ver < 5006000 {
+		return alterOp{AlgorithmCopy, false, "change table option copies table before 5.6"}
+	}
+	return op
+}
+
+// estimate 根据表大小估算耗时以及需要的额外磁盘空间
+func (o *OnlineDDLInfo) estimate(stat *TableStat, buildIndex bool) {
+	o.Estimated = true
+	o.TableRows = stat.TableRows
+	o.TableSize = stat.DataLength + stat.IndexLength
+	switch o.Algorithm {
+	case AlgorithmInplace:
+		if buildIndex {
+			// 构建索引时排序的临时文件最多与数据一样大
+			o.EstimateSec = o.TableSize / buildIndexBytesPerSecond
+			o.ExtraDisk = stat.DataLength
+		}
+	case AlgorithmInplaceRebuild:
+		o.EstimateSec = o.TableSize / rebuildBytesPerSecond
+		o.ExtraDisk = o.TableSize
+	case AlgorithmCopy:
+		o.EstimateSec = o.TableSize / copyBytesPerSecond
+		o.ExtraDisk = o.TableSize
+	}
+}
+
+// OnlineDDLVersion 判断 online ddl 使用的版本
+// tmysqlparse 的版本按大版本归一过(比如 8.0 都是 8.0.18)，这里换回目标实例的真实版本
+func (t *TmysqlParse) OnlineDDLVersion(mysqlVersion string, dbtype string) string {
+	if dbtype == app.Spider {
+		return spiderOnlineDDLVersion
+	}
+	if v, ok := t.TargetVersions[mysqlVersion]; ok {
+		return v
+	}
+	return mysqlVersion
+}
+
+// analyzeOnlineDDL 分析 alter table 的执行方式
+func (t *TmysqlParse) analyzeOnlineDDL(c *CheckInfo, res ParseLineQueryBase, bs []byte, mysqlVersion string) {
+	if res.Command != SQLTypeAlterTable {
+		return
+	}
+	var o AlterTableResult
+	if err := json.Unmarshal(bs, &o); err != nil {
+		logger.Error("json unmasrshal line failed %s", err.Error())
+		return
+	}
+	algorithm, buildIndex, reasons := o.OnlineDDL(mysqlVersion)
+	info := OnlineDDLInfo{
+		Line:         int64(res.QueryId),
+		Sqltext:      res.QueryString,
+		MysqlVersion: mysqlVersion,
+		DbName:       o.DbName,
+		TableName:    o.TableName,
+		Algorithm:    algorithm,
+		Reasons:      reasons,
+	}
+	if algorithm != AlgorithmInstant {
+		stat, err := t.getTableStat(o.DbName, o.TableName)
+		if err != nil {
+			info.EstimateMsg = err.Error()
+		} else {
+			info.estimate(stat, buildIndex)
+		}
+	}
+	c.OnlineDDLInfos = append(c.OnlineDDLInfos, info)
+}
+
+// getTableStat 从目标实例获取表的行数和大小，同一个表只获取一次
+func (t *TmysqlParse) getTableStat(dbName, tableName string) (*TableStat, error) {
+	if t.TableStatSource == nil || t.TableStatSource.Address == "" || !drs.Enabled() {
+		return nil, fmt.Errorf("no target instance, skip estimate")
+	}
+	if dbName == "" || tableName == "" {
+		return nil, fmt.Errorf("database or table name unknown, skip estimate")
+	}
+	key := dbName + "." + tableName
+	if v, ok := t.tableStats.Load(key); ok {
+		return v.(*TableStat), nil
+	}
+	sql := fmt.Sprintf("select ifnull(TABLE_ROWS,0) as TABLE_ROWS,ifnull(DATA_LENGTH,0) as DATA_LENGTH,"+
+		"ifnull(INDEX_LENGTH,0) as INDEX_LENGTH from information_schema.TABLES "+
+		"where TABLE_SCHEMA='%s' and TABLE_NAME='%s'", dbName, tableName)
+	res, err := drs.RPCMySQL(t.TableStatSource.BkCloudId, t.TableStatSource.Address, []string{sql}, 30)
+	if err != nil {
+		logger.Error("get table stat of %s failed %s", key, err.Error())
+		return nil, err
+	}
+	if len(res.CmdResults) == 0 || len(res.CmdResults[0].TableData) == 0 {
+		return nil, fmt.Errorf("table %s not found on %s, skip estimate", key, t.TableStatSource.Address)
+	}
+	row := res.CmdResults[0].TableData[0]
+	stat := &TableStat{
+		TableRows:   parseInt64(row["TABLE_ROWS"]),
+		DataLength:  parseInt64(row["DATA_LENGTH"]),
+		IndexLength: parseInt64(row["INDEX_LENGTH"]),
+	}
+	t.tableStats.Store(key, stat)
+	return stat, nil
+}
+
+func parseInt64(v interface{}) int64 {
+	n, _ := strconv.ParseInt(fmt.Sprintf("%v", v), 10, 64)
+	return n
+}
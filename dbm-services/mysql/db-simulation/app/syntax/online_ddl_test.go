@@ -0,0 +1,106 @@
+/*
+ * TencentBlueKing is pleased to support the open source community by making 蓝鲸智云-DB管理系统(BlueKing-BK-DBM) available.
+ * Copyright (C) 2017-2023 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at https://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+ * an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package syntax_test
+
+import (
+	"testing"
+
+	"dbm-services/mysql/db-simulation/app"
+	"dbm-services/mysql/db-simulation/app/syntax"
+)
+
+func TestAlterTableOnlineDDL(t *testing.T) {
+	addColumn := syntax.AlterCommand{Type: "add_column", ColDef: syntax.ColDef{ColName: "c1", DataType: "int"}}
+	addColumnAfter := syntax.AlterCommand{Type: "add_column", ColDef: syntax.ColDef{ColName: "c1", DataType: "int"},
+		After: "id"}
+	dropColumn := syntax.AlterCommand{Type: "drop_column", ColDef: syntax.ColDef{ColName: "c1"}}
+	addIndex := syntax.AlterCommand{Type: "add_key", KeyDef: syntax.KeyDef{KeyName: "idx_c1"}}
+	dropPk := syntax.AlterCommand{Type: "drop_key", DropPrimary: true}
+	addPk := syntax.AlterCommand{Type: "add_key", KeyDef: syntax.KeyDef{KeyName: "PRIMARY", PrimaryKey: true}}
+	algorithmInplace := syntax.AlterCommand{Type: "algorithm", Algorithm: "inplace"}
+	algorithmCopy := syntax.AlterCommand{Type: "algorithm", Algorithm: "copy"}
+
+	cases := []struct {
+		name           string
+		mysqlVersion   string
+		commands       []syntax.AlterCommand
+		wantAlgorithm  string
+		wantBuildIndex bool
+	}{
+		{"add column 5.7", "5.7.20", []syntax.AlterCommand{addColumn}, syntax.AlgorithmInplaceRebuild, false},
+		{"add column 8.0.12", "8.0.12", []syntax.AlterCommand{addColumn}, syntax.AlgorithmInstant, false},
+		{"add column after 8.0.12", "8.0.12", []syntax.AlterCommand{addColumnAfter},
+			syntax.AlgorithmInplaceRebuild, false},
+		{"add column after 8.0.29", "8.0.29", []syntax.AlterCommand{addColumnAfter}, syntax.AlgorithmInstant, false},
+		{"drop column 5.7", "5.7.20", []syntax.AlterCommand{dropColumn}, syntax.AlgorithmInplaceRebuild, false},
+		{"drop column 8.0.12", "8.0.12", []syntax.AlterCommand{dropColumn}, syntax.AlgorithmInplaceRebuild, false},
+		{"drop column 8.0.29", "8.0.29", []syntax.AlterCommand{dropColumn}, syntax.AlgorithmInstant, false},
+		{"add index 5.7", "5.7.20", []syntax.AlterCommand{addIndex}, syntax.AlgorithmInplace, true},
+		{"add index 8.0.12", "8.0.12", []syntax.AlterCommand{addIndex}, syntax.AlgorithmInplace, true},
+		{"add index 8.0.29", "8.0.29", []syntax.AlterCommand{addIndex}, syntax.AlgorithmInplace, true},
+		{"drop pk 5.7", "5.7.20", []syntax.AlterCommand{dropPk}, syntax.AlgorithmCopy, false},
+		{"drop pk 8.0.12", "8.0.12", []syntax.AlterCommand{dropPk}, syntax.AlgorithmCopy, false},
+		{"drop pk 8.0.29", "8.0.29", []syntax.AlterCommand{dropPk}, syntax.AlgorithmCopy, false},
+		{"drop and add pk 5.7", "5.7.20", []syntax.AlterCommand{dropPk, addPk}, syntax.AlgorithmInplaceRebuild, false},
+		{"drop and add pk 8.0.29", "8.0.29", []syntax.AlterCommand{dropPk, addPk},
+			syntax.AlgorithmInplaceRebuild, false},
+		{"add column and index 8.0.29", "8.0.29", []syntax.AlterCommand{addColumn, addIndex},
+			syntax.AlgorithmInplace, true},
+		{"algorithm copy 8.0.29", "8.0.29", []syntax.AlterCommand{addColumn, algorithmCopy}, syntax.AlgorithmCopy, false},
+		{"algorithm inplace 5.7", "5.7.20", []syntax.AlterCommand{dropPk, algorithmInplace}, syntax.AlgorithmCopy, false},
+		{"unknown version as 5.7", "", []syntax.AlterCommand{addColumn}, syntax.AlgorithmInplaceRebuild, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r := syntax.AlterTableResult{Command: "alter_table", DbName: "db1", TableName: "t1", AlterCommands: c.commands}
+			algorithm, buildIndex, reasons := r.OnlineDDL(c.mysqlVersion)
+			if algorithm != c.wantAlgorithm || buildIndex != c.wantBuildIndex {
+				t.Errorf("OnlineDDL(%q) = %s, %v, want %s, %v, reasons: %v",
+					c.mysqlVersion, algorithm, buildIndex, c.wantAlgorithm, c.wantBuildIndex, reasons)
+			}
+		})
+	}
+}
+
+func TestOnlineDDLVersion(t *testing.T) {
+	p := &syntax.TmysqlParse{TargetVersions: map[string]string{"8.0.18": "8.0.30-txsql-3.3.1"}}
+	cases := []struct {
+		mysqlVersion string
+		dbtype       string
+		want         string
+	}{
+		{"8.0.18", app.MySQL, "8.0.30-txsql-3.3.1"},
+		{"5.7.20", app.MySQL, "5.7.20"},
+		{"", app.MySQL, ""},
+		{"", app.Spider, "8.0.12"},
+	}
+	for _, c := range cases {
+		if got := p.OnlineDDLVersion(c.mysqlVersion, c.dbtype); got != c.want {
+			t.Errorf("OnlineDDLVersion(%q, %s) = %s, want %s", c.mysqlVersion, c.dbtype, got, c.want)
+		}
+	}
+
+	// 8.0.30 可以 instant 在中间加列，tmysqlparse 的 8.0.18 不行
+	r := syntax.AlterTableResult{Command: "alter_table", DbName: "db1", TableName: "t1",
+		AlterCommands: []syntax.AlterCommand{{Type: "add_column", ColDef: syntax.ColDef{ColName: "c1", DataType: "int"},
+			After: "id"}}}
+	if algorithm, _, _ := r.OnlineDDL(p.OnlineDDLVersion("8.0.18", app.MySQL)); algorithm != syntax.AlgorithmInstant {
+		t.Errorf("add column after on 8.0.30 got %s", algorithm)
+	}
+	// spider 按 MariaDB 10.3 的规则，只有在最后加列是 instant
+	if algorithm, _, _ := r.OnlineDDL(p.OnlineDDLVersion("", app.Spider)); algorithm != syntax.AlgorithmInplaceRebuild {
+		t.Errorf("add column after on spider got %s", algorithm)
+	}
+	r.AlterCommands[0].After = ""
+	if algorithm, _, _ := r.OnlineDDL(p.OnlineDDLVersion("", app.Spider)); algorithm != syntax.AlgorithmInstant {
+		t.Errorf("add column on spider got %s", algorithm)
+	}
+}
@@ -67,7 +67,16 @@ type TmysqlParse struct {
 	bkRepoClient       *bkrepo.BkRepoClient
 	TmysqlParseBinPath string
 	BaseWorkdir        string
-	mu                 sync.Mutex
+	// CustomRules 业务、集群自定义的规则
+	CustomRules []*CustomRule
+	// OnlyCustomRule 只检查自定义的规则，用于测试规则
+	OnlyCustomRule bool
+	// TableStatSource 获取变更表的大小，估算 online ddl 的耗时
+	TableStatSource *TableStatSource
+	// TargetVersions tmysqlparse 使用的版本到目标实例真实版本的映射，online ddl 按真实版本判断
+	TargetVersions map[string]string
+	tableStats     sync.Map
+	mu             sync.Mutex
 }
 
 // AddFileResult add file syntax check result
@@ -78,6 +87,7 @@ func (t *TmysqlParse) AddFileResult(fileName string, result *CheckInfo, failedIn
 		t.result[fileName].SyntaxFailInfos = failedInfos
 	} else {
 		t.result[fileName].SyntaxFailInfos = append(t.result[fileName].SyntaxFailInfos, failedInfos...)
+		t.result[fileName].OnlineDDLInfos = append(t.result[fileName].OnlineDDLInfos, result.OnlineDDLInfos...)
 	}
 	t.result[fileName].BanWarnings = result.BanWarnings
 	t.result[fileName].RiskWarnings = result.RiskWarnings
@@ -89,6 +99,8 @@ type CheckInfo struct {
 	SyntaxFailInfos []FailedInfo `json:"syntax_fails"`
 	RiskWarnings    []RiskInfo   `json:"highrisk_warnings"`
 	BanWarnings     []RiskInfo   `json:"bancommand_warnings"`
+	// OnlineDDLInfos alter table 的执行方式以及耗时估算
+	OnlineDDLInfos []OnlineDDLInfo `json:"online_ddl_infos"`
 }
 
 // FailedInfo 语法错误结果
@@ -572,11 +584,16 @@ func (t *TmysqlParse) AnalyzeOne(inputfileName, mysqlVersion, dbtype string) (er
 			t.mu.Unlock()
 			continue
 		}
+		checkResult.parseCustomRules(t.CustomRules, res, bs, mysqlVersion)
+		if t.OnlyCustomRule {
+			continue
+		}
 		// tmysqlparse检查结果全部正确，开始判断语句是否符合定义的规则（即虽然语法正确，但语句可能是高危语句或禁用的命令）
 		switch dbtype {
 		case app.MySQL:
 			checkResult.parseResult(R.CommandRule.HighRiskCommandRule, res, mysqlVersion)
 			checkResult.parseResult(R.CommandRule.BanCommandRule, res, mysqlVersion)
+			t.analyzeOnlineDDL(checkResult, res, bs, t.OnlineDDLVersion(mysqlVersion, dbtype))
 			err = checkResult.runcheck(res, bs, mysqlVersion)
 			if err != nil {
 				goto END
@@ -584,6 +601,7 @@ func (t *TmysqlParse) AnalyzeOne(inputfileName, mysqlVersion, dbtype string) (er
 		case app.Spider:
 			checkResult.parseResult(SR.CommandRule.HighRiskCommandRule, res, mysqlVersion)
 			checkResult.parseResult(SR.CommandRule.BanCommandRule, res, mysqlVersion)
+			t.analyzeOnlineDDL(checkResult, res, bs, t.OnlineDDLVersion(mysqlVersion, dbtype))
 			err = checkResult.runSpidercheck(ddlTbls, res, bs, mysqlVersion)
 			if err != nil {
 				goto END
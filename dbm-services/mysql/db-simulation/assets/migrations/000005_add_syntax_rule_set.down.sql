@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS `tb_syntax_rule_set_versions`;
+DROP TABLE IF EXISTS `tb_syntax_rule_sets`;
@@ -0,0 +1,26 @@
+CREATE TABLE IF NOT EXISTS `tb_syntax_rule_sets` (
+    `id` int(11) NOT NULL AUTO_INCREMENT,
+    `bk_biz_id` bigint(20) NOT NULL,
+    `cluster_domain` varchar(255) NOT NULL DEFAULT '',
+    `db_type` varchar(32) NOT NULL,
+    `name` varchar(64) NOT NULL,
+    `rules` json NOT NULL,
+    `version` int(11) NOT NULL DEFAULT 1,
+    `status` tinyint(1) NOT NULL DEFAULT 1,
+    `creator` varchar(64) NOT NULL DEFAULT '',
+    `updater` varchar(64) NOT NULL DEFAULT '',
+    `update_time` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `create_time` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uk_scope_name` (`bk_biz_id`, `cluster_domain`, `db_type`, `name`)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4;
+CREATE TABLE IF NOT EXISTS `tb_syntax_rule_set_versions` (
+    `id` int(11) NOT NULL AUTO_INCREMENT,
+    `rule_set_id` int(11) NOT NULL,
+    `version` int(11) NOT NULL,
+    `rules` json NOT NULL,
+    `operator` varchar(64) NOT NULL DEFAULT '',
+    `create_time` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uk_set_version` (`rule_set_id`, `version`)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4;
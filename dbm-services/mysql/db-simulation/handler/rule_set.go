@@ -0,0 +1,302 @@
+/*
+ * TencentBlueKing is pleased to support the open source community by making 蓝鲸智云-DB管理系统(BlueKing-BK-DBM) available.
+ * Copyright (C) 2017-2023 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at https://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+ * an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"dbm-services/common/go-pubpkg/logger"
+	"dbm-services/mysql/db-simulation/app"
+	"dbm-services/mysql/db-simulation/app/syntax"
+	"dbm-services/mysql/db-simulation/model"
+)
+
+// RuleSetHandler 业务、集群自定义规则集 handler
+type RuleSetHandler struct {
+	BaseHandler
+}
+
+// RegisterRouter 注册路由信息
+func (m *RuleSetHandler) RegisterRouter(engine *gin.Engine) {
+	r := engine.Group("/ruleset")
+	{
+		r.POST("/create", m.CreateRuleSet)
+		r.POST("/update", m.UpdateRuleSet)
+		r.POST("/delete", m.DeleteRuleSet)
+		r.POST("/list", m.ListRuleSets)
+		r.POST("/versions", m.ListRuleSetVersions)
+		r.POST("/rollback", m.RollbackRuleSet)
+		r.POST("/test", m.TestRuleSet)
+	}
+}
+
+// CreateRuleSetParam 创建规则集参数
+type CreateRuleSetParam struct {
+	BkBizId int64 `json:"bk_biz_id" binding:"required"`
+	// 为空时对业务下所有集群生效
+	ClusterDomain string          `json:"cluster_domain"`
+	DbType        string          `json:"db_type" binding:"required"`
+	Name          string          `json:"name" binding:"required"`
+	Rules         json.RawMessage `json:"rules" binding:"required"`
+	Status        bool            `json:"status"`
+	Operator      string          `json:"operator"`
+}
+
+// CreateRuleSet 创建规则集
+func (m *RuleSetHandler) CreateRuleSet(c *gin.Context) {
+	var param CreateRuleSetParam
+	if err := m.Prepare(c, &param); err != nil {
+		logger.Error("ShouldBind failed %s", err)
+		return
+	}
+	if param.DbType != app.MySQL && param.DbType != app.Spider {
+		err := fmt.Errorf("db_type must be %s or %s", app.MySQL, app.Spider)
+		m.SendResponse(c, err, nil)
+		return
+	}
+	if _, err := syntax.ParseCustomRules(param.Name, param.Rules); err != nil {
+		m.SendResponse(c, err, nil)
+		return
+	}
+	rs := model.TbSyntaxRuleSet{
+		BkBizId:       param.BkBizId,
+		ClusterDomain: param.ClusterDomain,
+		DbType:        param.DbType,
+		Name:          param.Name,
+		Rules:         param.Rules,
+		Status:        param.Status,
+		Creator:       param.Operator,
+		Updater:       param.Operator,
+	}
+	if err := model.CreateRuleSet(&rs); err != nil {
+		logger.Error("create rule set failed %s", err.Error())
+		m.SendResponse(c, err, nil)
+		return
+	}
+	m.SendResponse(c, nil, rs)
+}
+
+// UpdateRuleSetParam 修改规则集参数
+type UpdateRuleSetParam struct {
+	ID       int             `json:"id" binding:"required"`
+	Rules    json.RawMessage `json:"rules" binding:"required"`
+	Status   bool            `json:"status"`
+	Operator string          `json:"operator"`
+}
+
+// UpdateRuleSet 修改规则集，每次修改生成一个新的版本
+func (m *RuleSetHandler) UpdateRuleSet(c *gin.Context) {
+	var param UpdateRuleSetParam
+	if err := m.Prepare(c, &param); err != nil {
+		logger.Error("ShouldBind failed %s", err)
+		return
+	}
+	rs, err := model.GetRuleSet(param.ID)
+	if err != nil {
+		logger.Error("get rule set %d failed %s", param.ID, err.Error())
+		m.SendResponse(c, err, nil)
+		return
+	}
+	if _, err = syntax.ParseCustomRules(rs.Name, param.Rules); err != nil {
+		m.SendResponse(c, err, nil)
+		return
+	}
+	version, err := model.UpdateRuleSet(param.ID, param.Rules, param.Status, param.Operator)
+	if err != nil {
+		logger.Error("update rule set %d failed %s", param.ID, err.Error())
+		m.SendResponse(c, err, nil)
+		return
+	}
+	m.SendResponse(c, nil, gin.H{"id": param.ID, "version": version})
+}
+
+// RuleSetIDParam 规则集id参数
+type RuleSetIDParam struct {
+	ID int `json:"id" binding:"required"`
+}
+
+// DeleteRuleSet 删除规则集
+func (m *RuleSetHandler) DeleteRuleSet(c *gin.Context) {
+	var param RuleSetIDParam
+	if err := m.Prepare(c, &param); err != nil {
+		logger.Error("ShouldBind failed %s", err)
+		return
+	}
+	if err := model.DeleteRuleSet(param.ID); err != nil {
+		logger.Error("delete rule set %d failed %s", param.ID, err.Error())
+		m.SendResponse(c, err, nil)
+		return
+	}
+	m.SendResponse(c, nil, "ok")
+}
+
+// ListRuleSetsParam 查询规则集参数
+type ListRuleSetsParam struct {
+	BkBizId       int64  `json:"bk_biz_id"`
+	ClusterDomain string `json:"cluster_domain"`
+	DbType        string `json:"db_type"`
+}
+
+// ListRuleSets 查询规则集
+func (m *RuleSetHandler) ListRuleSets(c *gin.Context) {
+	var param ListRuleSetsParam
+	if err := m.Prepare(c, &param); err != nil {
+		logger.Error("ShouldBind failed %s", err)
+		return
+	}
+	rs, err := model.ListRuleSets(param.BkBizId, param.ClusterDomain, param.DbType)
+	if err != nil {
+		logger.Error("query rule sets failed %s", err.Error())
+		m.SendResponse(c, err, nil)
+		return
+	}
+	m.SendResponse(c, nil, rs)
+}
+
+// ListRuleSetVersions 查询规则集的历史版本
+func (m *RuleSetHandler) ListRuleSetVersions(c *gin.Context) {
+	var param RuleSetIDParam
+	if err := m.Prepare(c, &param); err != nil {
+		logger.Error("ShouldBind failed %s", err)
+		return
+	}
+	vs, err := model.GetRuleSetVersions(param.ID)
+	if err != nil {
+		logger.Error("query rule set %d versions failed %s", param.ID, err.Error())
+		m.SendResponse(c, err, nil)
+		return
+	}
+	m.SendResponse(c, nil, vs)
+}
+
+// RollbackRuleSetParam 回滚规则集参数
+type RollbackRuleSetParam struct {
+	ID       int    `json:"id" binding:"required"`
+	Version  int    `json:"version" binding:"required"`
+	Operator string `json:"operator"`
+}
+
+// RollbackRuleSet 把规则集回滚到某个历史版本，回滚同样生成一个新的版本
+func (m *RuleSetHandler) RollbackRuleSet(c *gin.Context) {
+	var param RollbackRuleSetParam
+	if err := m.Prepare(c, &param); err != nil {
+		logger.Error("ShouldBind failed %s", err)
+		return
+	}
+	rs, err := model.GetRuleSet(param.ID)
+	if err != nil {
+		logger.Error("get rule set %d failed %s", param.ID, err.Error())
+		m.SendResponse(c, err, nil)
+		return
+	}
+	v, err := model.GetRuleSetVersion(param.ID, param.Version)
+	if err != nil {
+		logger.Error("get rule set %d version %d failed %s", param.ID, param.Version, err.Error())
+		m.SendResponse(c, err, nil)
+		return
+	}
+	version, err := model.UpdateRuleSet(param.ID, v.Rules, rs.Status, param.Operator)
+	if err != nil {
+		logger.Error("rollback rule set %d failed %s", param.ID, err.Error())
+		m.SendResponse(c, err, nil)
+		return
+	}
+	m.SendResponse(c, nil, gin.H{"id": param.ID, "version": version})
+}
+
+// TestRuleSetParam 使用样例SQL测试规则参数
+type TestRuleSetParam struct {
+	ClusterType string   `json:"cluster_type" binding:"required"`
+	Versions    []string `json:"versions"`
+	Sqls        []string `json:"sqls" binding:"gt=0,dive,required"`
+	// 测试已保存的规则集
+	RuleSetID int `json:"rule_set_id"`
+	// 测试未保存的规则，与 rule_set_id 二选一
+	Rules json.RawMessage `json:"rules"`
+}
+
+// TestRuleSet 使用样例SQL测试规则，只返回自定义规则的检查结果
+func (m *RuleSetHandler) TestRuleSet(c *gin.Context) {
+	var param TestRuleSetParam
+	var data map[string]*syntax.CheckInfo
+	if err := m.Prepare(c, &param); err != nil {
+		logger.Error("ShouldBind failed %s", err)
+		return
+	}
+	name, rules := "test", param.Rules
+	if param.RuleSetID > 0 {
+		rs, err := model.GetRuleSet(param.RuleSetID)
+		if err != nil {
+			logger.Error("get rule set %d failed %s", param.RuleSetID, err.Error())
+			m.SendResponse(c, err, nil)
+			return
+		}
+		name, rules = rs.Name, rs.Rules
+	}
+	if len(rules) == 0 {
+		m.SendResponse(c, fmt.Errorf("rule_set_id or rules is required"), nil)
+		return
+	}
+	customRules, err := syntax.ParseCustomRules(name, rules)
+	if err != nil {
+		m.SendResponse(c, err, nil)
+		return
+	}
+	tpWorkdir, fileName, err := writeSQLFile(param.Sqls)
+	if err != nil {
+		m.SendResponse(c, err, err.Error())
+		return
+	}
+	check := &syntax.TmysqlParseFile{
+		TmysqlParse: syntax.TmysqlParse{
+			TmysqlParseBinPath: tmysqlParserBin,
+			BaseWorkdir:        tpWorkdir,
+			CustomRules:        customRules,
+			OnlyCustomRule:     true,
+		},
+		IsLocalFile: true,
+		Param: syntax.CheckSQLFileParam{
+			BkRepoBasePath: "",
+			FileNames:      []string{fileName},
+		},
+	}
+	if ruleDbType(param.ClusterType) == app.Spider {
+		data, err = check.Do(app.Spider, []string{""})
+	} else {
+		versions := []string{""}
+		if len(param.Versions) > 0 {
+			versions, _ = rebuildVersion(param.Versions)
+		}
+		data, err = check.Do(app.MySQL, versions)
+	}
+	m.SendResponse(c, err, data)
+}
+
+// ruleDbType 集群类型对应的规则集数据库类型
+func ruleDbType(clusterType string) string {
+	switch strings.ToLower(clusterType) {
+	case app.Spider, app.TendbCluster:
+		return app.Spider
+	}
+	return app.MySQL
+}
+
+// loadCustomRules 加载业务、集群自定义的规则，未指定业务时不加载
+func loadCustomRules(bkBizId int64, clusterDomain, clusterType string) ([]*syntax.CustomRule, error) {
+	if bkBizId <= 0 {
+		return nil, nil
+	}
+	return syntax.LoadCustomRules(bkBizId, clusterDomain, ruleDbType(clusterType))
+}
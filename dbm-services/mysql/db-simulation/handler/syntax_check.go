@@ -74,6 +74,12 @@ type CheckSQLStringParam struct {
 	ClusterType string   `json:"cluster_type" binding:"required"`
 	Versions    []string `json:"versions"`
 	Sqls        []string `json:"sqls" binding:"gt=0,dive,required"`
+	// 指定业务、集群时会加载业务、集群自定义的规则
+	BkBizId       int64  `json:"bk_biz_id"`
+	ClusterDomain string `json:"cluster_domain"`
+	// 指定实例时会获取变更表的大小，估算 alter table 的耗时
+	Address   string `json:"address"`
+	BkCloudId int64  `json:"bk_cloud_id"`
 }
 
 // SetDumpAll set dump all
@@ -87,6 +93,7 @@ func (s *SyntaxHandler) SyntaxCheckSQL(r *gin.Context) {
 	var param CheckSQLStringParam
 	var data map[string]*syntax.CheckInfo
 	var versions []string
+	var targetVersions map[string]string
 	// 将request中的数据按照json格式直接解析到结构体中
 	if err := s.Prepare(r, &param); err != nil {
 		logger.Error("Preare Error %s", err.Error())
@@ -97,20 +104,17 @@ func (s *SyntaxHandler) SyntaxCheckSQL(r *gin.Context) {
 	if len(param.Versions) == 0 {
 		versions = []string{""}
 	} else {
-		versions = rebuildVersion(param.Versions)
+		versions, targetVersions = rebuildVersion(param.Versions)
 	}
 
-	sqlContext := strings.Join(param.Sqls, "\n")
-	fileName := "ce_" + cmutil.RandStr(10) + ".sql"
-	tpWorkdir := path.Join(workdir, time.Now().Format("20060102150405"))
-	if err := os.MkdirAll(tpWorkdir, 0755); err != nil {
+	tpWorkdir, fileName, err := writeSQLFile(param.Sqls)
+	if err != nil {
 		s.SendResponse(r, err, err.Error())
 		return
 	}
-	f := path.Join(tpWorkdir, fileName)
-	err := os.WriteFile(f, []byte(sqlContext), 0600)
+	customRules, err := loadCustomRules(param.BkBizId, param.ClusterDomain, param.ClusterType)
 	if err != nil {
-		s.SendResponse(r, err, err.Error())
+		s.SendResponse(r, err, nil)
 		return
 	}
 
@@ -125,6 +129,9 @@ func (s *SyntaxHandler) SyntaxCheckSQL(r *gin.Context) {
 			FileNames:      []string{fileName},
 		},
 	}
+	check.CustomRules = customRules
+	check.TargetVersions = targetVersions
+	check.TableStatSource = &syntax.TableStatSource{Address: param.Address, BkCloudId: param.BkCloudId}
 
 	logger.Info("cluster type :%s,versions:%v", param.ClusterType, versions)
 
@@ -146,10 +153,14 @@ func (s *SyntaxHandler) SyntaxCheckSQL(r *gin.Context) {
 
 // CheckFileParam 语法检查请求参数
 type CheckFileParam struct {
-	ClusterType string   `json:"cluster_type"`
-	Path        string   `json:"path" binding:"required"`
-	Versions    []string `json:"versions"`
-	Files       []string `json:"files" binding:"gt=0,dive,required"`
+	ClusterType   string   `json:"cluster_type"`
+	Path          string   `json:"path" binding:"required"`
+	Versions      []string `json:"versions"`
+	Files         []string `json:"files" binding:"gt=0,dive,required"`
+	BkBizId       int64    `json:"bk_biz_id"`
+	ClusterDomain string   `json:"cluster_domain"`
+	Address       string   `json:"address"`
+	BkCloudId     int64    `json:"bk_cloud_id"`
 }
 
 // SyntaxCheckFile 运行语法检查
@@ -158,6 +169,7 @@ func (s *SyntaxHandler) SyntaxCheckFile(r *gin.Context) {
 	var data map[string]*syntax.CheckInfo
 	var err error
 	var versions []string
+	var targetVersions map[string]string
 	// 将request中的数据按照json格式直接解析到结构体中
 	if err = s.Prepare(r, &param); err != nil {
 		logger.Error("ShouldBind failed %s", err)
@@ -167,7 +179,7 @@ func (s *SyntaxHandler) SyntaxCheckFile(r *gin.Context) {
 	if len(param.Versions) == 0 {
 		versions = []string{""}
 	} else {
-		versions = rebuildVersion(param.Versions)
+		versions, targetVersions = rebuildVersion(param.Versions)
 	}
 
 	check := &syntax.TmysqlParseFile{
@@ -180,6 +192,12 @@ func (s *SyntaxHandler) SyntaxCheckFile(r *gin.Context) {
 			FileNames:      param.Files,
 		},
 	}
+	if check.CustomRules, err = loadCustomRules(param.BkBizId, param.ClusterDomain, param.ClusterType); err != nil {
+		s.SendResponse(r, err, nil)
+		return
+	}
+	check.TargetVersions = targetVersions
+	check.TableStatSource = &syntax.TableStatSource{Address: param.Address, BkCloudId: param.BkCloudId}
 
 	logger.Info("cluster type :%s", param.ClusterType)
 	switch strings.ToLower(param.ClusterType) {
@@ -273,15 +291,7 @@ func (s *SyntaxHandler) ParseSQLRelationDb(r *gin.Context) {
 		logger.Error("Preare Error %s", err.Error())
 		return
 	}
-	sqlContext := strings.Join(param.Sqls, "\n")
-	fileName := "ce_" + cmutil.RandStr(10) + ".sql"
-	tmpWorkdir := path.Join(workdir, time.Now().Format("20060102150405"))
-	if err := os.MkdirAll(tmpWorkdir, 0755); err != nil {
-		s.SendResponse(r, err, err.Error())
-		return
-	}
-	f := path.Join(tmpWorkdir, fileName)
-	err := os.WriteFile(f, []byte(sqlContext), 0600)
+	tmpWorkdir, fileName, err := writeSQLFile(param.Sqls)
 	if err != nil {
 		s.SendResponse(r, err, err.Error())
 		return
@@ -311,23 +321,43 @@ func (s *SyntaxHandler) ParseSQLRelationDb(r *gin.Context) {
 	})
 }
 
+// writeSQLFile 把入参的SQL写到临时目录下的文件中
+func writeSQLFile(sqls []string) (tmpWorkdir, fileName string, err error) {
+	fileName = "ce_" + cmutil.RandStr(10) + ".sql"
+	tmpWorkdir = path.Join(workdir, time.Now().Format("20060102150405"))
+	if err = os.MkdirAll(tmpWorkdir, 0755); err != nil {
+		return "", "", err
+	}
+	err = os.WriteFile(path.Join(tmpWorkdir, fileName), []byte(strings.Join(sqls, "\n")), 0600)
+	return tmpWorkdir, fileName, err
+}
+
 // rebuildVersion  tmysql 需要指定特殊的version
-func rebuildVersion(versions []string) (rebuildVers []string) {
+// targetVers 记录归一后的版本对应的真实版本，同一个大版本有多个时取最小的
+func rebuildVersion(versions []string) (rebuildVers []string, targetVers map[string]string) {
 	if len(versions) == 0 {
 		return
 	}
 	rebuildVers = make([]string, 0)
+	targetVers = make(map[string]string)
 	for _, bVer := range versions {
+		var rVer string
 		switch {
 		case strings.Contains(bVer, "5.5"):
-			rebuildVers = append(rebuildVers, "5.5.24")
+			rVer = "5.5.24"
 		case strings.Contains(bVer, "5.6"):
-			rebuildVers = append(rebuildVers, "5.6.24")
+			rVer = "5.6.24"
 		case strings.Contains(bVer, "5.7"):
-			rebuildVers = append(rebuildVers, "5.7.20")
+			rVer = "5.7.20"
 		case strings.Contains(bVer, "8.0"):
-			rebuildVers = append(rebuildVers, "8.0.18")
+			rVer = "8.0.18"
+		default:
+			continue
+		}
+		rebuildVers = append(rebuildVers, rVer)
+		if v, ok := targetVers[rVer]; !ok || cmutil.MySQLVersionParse(bVer) < cmutil.MySQLVersionParse(v) {
+			targetVers[rVer] = bVer
 		}
 	}
-	return rebuildVers
+	return rebuildVers, targetVers
 }
@@ -0,0 +1,144 @@
+/*
+ * TencentBlueKing is pleased to support the open source community by making 蓝鲸智云-DB管理系统(BlueKing-BK-DBM) available.
+ * Copyright (C) 2017-2023 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at https://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+ * an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package model
+
+import (
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TbSyntaxRuleSet 按业务或集群生效的自定义语法规则集
+type TbSyntaxRuleSet struct {
+	ID      int   `gorm:"primaryKey;column:id;type:int(11);not null" json:"id"`
+	BkBizId int64 `gorm:"uniqueIndex:uk_scope_name;column:bk_biz_id;type:bigint(20);not null" json:"bk_biz_id"`
+	// 为空时对业务下所有集群生效
+	ClusterDomain string `gorm:"uniqueIndex:uk_scope_name;column:cluster_domain;type:varchar(255);not null" json:"cluster_domain"`
+	// 数据库类型 mysql、spider
+	DbType string `gorm:"uniqueIndex:uk_scope_name;column:db_type;type:varchar(32);not null" json:"db_type"`
+	Name   string `gorm:"uniqueIndex:uk_scope_name;column:name;type:varchar(64);not null" json:"name"`
+	// 规则列表，每条规则是一个 expr 表达式
+	Rules json.RawMessage `gorm:"column:rules;type:json;not null" json:"rules"`
+	// 每次修改规则，版本号加1
+	Version int `gorm:"column:version;type:int(11);not null" json:"version"`
+	// 1：启用，0:禁用
+	Status     bool      `gorm:"column:status;type:tinyint(1);not null" json:"status"`
+	Creator    string    `gorm:"column:creator;type:varchar(64);not null" json:"creator"`
+	Updater    string    `gorm:"column:updater;type:varchar(64);not null" json:"updater"`
+	UpdateTime time.Time `gorm:"column:update_time;type:timestamp;default:CURRENT_TIMESTAMP()" json:"update_time"`
+	CreateTime time.Time `gorm:"column:create_time;type:timestamp;default:CURRENT_TIMESTAMP()" json:"create_time"`
+}
+
+// TbSyntaxRuleSetVersion 规则集的历史版本
+type TbSyntaxRuleSetVersion struct {
+	ID         int             `gorm:"primaryKey;column:id;type:int(11);not null" json:"-"`
+	RuleSetID  int             `gorm:"uniqueIndex:uk_set_version;column:rule_set_id;type:int(11);not null" json:"rule_set_id"`
+	Version    int             `gorm:"uniqueIndex:uk_set_version;column:version;type:int(11);not null" json:"version"`
+	Rules      json.RawMessage `gorm:"column:rules;type:json;not null" json:"rules"`
+	Operator   string          `gorm:"column:operator;type:varchar(64);not null" json:"operator"`
+	CreateTime time.Time       `gorm:"column:create_time;type:timestamp;default:CURRENT_TIMESTAMP()" json:"create_time"`
+}
+
+// CreateRuleSet 创建规则集，同时记录第一个版本
+func CreateRuleSet(m *TbSyntaxRuleSet) (err error) {
+	m.Version = 1
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err = tx.Create(m).Error; err != nil {
+			return err
+		}
+		return tx.Create(&TbSyntaxRuleSetVersion{
+			RuleSetID: m.ID,
+			Version:   m.Version,
+			Rules:     m.Rules,
+			Operator:  m.Creator,
+		}).Error
+	})
+}
+
+// UpdateRuleSet 修改规则集的规则，生成新的版本，返回新的版本号
+func UpdateRuleSet(id int, rules json.RawMessage, status bool, operator string) (version int, err error) {
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		var rs TbSyntaxRuleSet
+		if err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&rs, id).Error; err != nil {
+			return err
+		}
+		version = rs.Version + 1
+		if err = tx.Model(&TbSyntaxRuleSet{}).Where("id = ?", id).Updates(map[string]interface{}{
+			"rules":       rules,
+			"version":     version,
+			"status":      status,
+			"updater":     operator,
+			"update_time": time.Now(),
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&TbSyntaxRuleSetVersion{
+			RuleSetID: id,
+			Version:   version,
+			Rules:     rules,
+			Operator:  operator,
+		}).Error
+	})
+	return
+}
+
+// DeleteRuleSet 删除规则集以及所有历史版本
+func DeleteRuleSet(id int) (err error) {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err = tx.Where("rule_set_id = ?", id).Delete(&TbSyntaxRuleSetVersion{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&TbSyntaxRuleSet{}, id).Error
+	})
+}
+
+// GetRuleSet get rule set by id
+func GetRuleSet(id int) (rs TbSyntaxRuleSet, err error) {
+	err = DB.First(&rs, id).Error
+	return
+}
+
+// ListRuleSets 按业务、集群、数据库类型查询规则集，参数为空时不过滤
+func ListRuleSets(bkBizId int64, clusterDomain, dbType string) (rs []TbSyntaxRuleSet, err error) {
+	db := DB.Model(&TbSyntaxRuleSet{})
+	if bkBizId > 0 {
+		db = db.Where("bk_biz_id = ?", bkBizId)
+	}
+	if clusterDomain != "" {
+		db = db.Where("cluster_domain = ?", clusterDomain)
+	}
+	if dbType != "" {
+		db = db.Where("db_type = ?", dbType)
+	}
+	err = db.Order("id").Find(&rs).Error
+	return
+}
+
+// GetEffectiveRuleSets 获取对集群生效的规则集，包括业务级别和集群级别的规则集
+func GetEffectiveRuleSets(bkBizId int64, clusterDomain, dbType string) (rs []TbSyntaxRuleSet, err error) {
+	err = DB.Where("bk_biz_id = ? and db_type = ? and status = 1 and (cluster_domain = '' or cluster_domain = ?)",
+		bkBizId, dbType, clusterDomain).Order("id").Find(&rs).Error
+	return
+}
+
+// GetRuleSetVersions 获取规则集的所有历史版本
+func GetRuleSetVersions(ruleSetID int) (vs []TbSyntaxRuleSetVersion, err error) {
+	err = DB.Where("rule_set_id = ?", ruleSetID).Order("version desc").Find(&vs).Error
+	return
+}
+
+// GetRuleSetVersion 获取规则集的某个历史版本
+func GetRuleSetVersion(ruleSetID, version int) (v TbSyntaxRuleSetVersion, err error) {
+	err = DB.Where("rule_set_id = ? and version = ?", ruleSetID, version).First(&v).Error
+	return
+}
@@ -36,6 +36,9 @@ func RegisterRouter(engine *gin.Engine) {
 	// rule
 	manageRuleHandler := handler.ManageRuleHandler{}
 	manageRuleHandler.RegisterRouter(engine)
+	// 业务、集群自定义规则集
+	ruleSetHandler := handler.RuleSetHandler{}
+	ruleSetHandler.RegisterRouter(engine)
 
 }
 
@@ -90,6 +90,11 @@ Buildstamp:%s`, version, githash, strings.ToUpper(external), buildstamp,
 			if subcmd.PrintSubCommandHelper(cmd, subcmd.GBaseOptions) {
 				runHelp(cmd, args)
 			}
+			if err := subcmd.CheckPlanSupported(cmd, subcmd.GBaseOptions); err != nil {
+				fmt.Fprintln(os.Stderr, err.Error())
+				logger.Error("%s", err.Error())
+				os.Exit(1)
+			}
 			// 定时输出标准心跳输出
 			startHeartbeat(10 * time.Second)
 		},
@@ -182,6 +187,20 @@ Buildstamp:%s`, version, githash, strings.ToUpper(external), buildstamp,
 		subcmd.GBaseOptions.RollBack,
 		"rollback task",
 	)
+	cmds.PersistentFlags().BoolVarP(
+		&subcmd.GBaseOptions.Resume,
+		"resume",
+		"",
+		subcmd.GBaseOptions.Resume,
+		"skip steps already succeeded in last run of the same uid and node_id",
+	)
+	cmds.PersistentFlags().BoolVarP(
+		&subcmd.GBaseOptions.Plan,
+		"plan",
+		"",
+		subcmd.GBaseOptions.Plan,
+		"only output sqls, files and processes to change in <ctx>, do not execute",
+	)
 	cmds.PersistentFlags().BoolVarP(
 		&subcmd.GBaseOptions.Helper,
 		"helper",
@@ -83,6 +83,8 @@ func (d *BackupDemandAct) Run() (err error) {
 		{
 			FunName: "执行备份",
 			Func:    d.Payload.DoBackup,
+			// 备份文件已经落盘, resume 时直接返回报告
+			SkipOnResume: true,
 		},
 		{
 			FunName: "返回报告",
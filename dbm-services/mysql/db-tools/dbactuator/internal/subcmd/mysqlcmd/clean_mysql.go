@@ -43,7 +43,7 @@ func CleanMysqlCommand() *cobra.Command {
 			util.CheckErr(act.Run())
 		},
 	}
-	return cmd
+	return subcmd.SupportPlan(cmd)
 }
 
 // Init TODO
@@ -68,6 +68,9 @@ func (d *CleanMysqlAct) Validate() error {
 // Run TODO
 func (d *CleanMysqlAct) Run() (err error) {
 	defer util.LoggerErrorStack(logger.Error, err)
+	if d.Plan {
+		return subcmd.RunPlan(&d.Payload)
+	}
 	steps := subcmd.Steps{
 		{
 			FunName: "初始化",
@@ -78,9 +78,17 @@ func (d *GoFlashbackBinlogAct) Run() (err error) {
 			Func:    d.Payload.Params.PreCheck,
 		},
 		{
-			FunName: "开始 flashback binlog",
+			FunName: "解析 flashback binlog",
 			Func:    d.Payload.Params.Start,
 		},
+		{
+			FunName: "检查闪回行数",
+			Func:    d.Payload.Params.CheckMatchedRows,
+		},
+		{
+			FunName: "导入 flashback binlog",
+			Func:    d.Payload.Params.Import,
+		},
 	}
 	if err = steps.Run(); err != nil {
 		return err
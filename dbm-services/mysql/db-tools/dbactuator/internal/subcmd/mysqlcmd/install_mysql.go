@@ -94,10 +94,31 @@ func (d *DeployMySQLAct) Rollback() (err error) {
 
 // Run TODO
 func (d *DeployMySQLAct) Run() (err error) {
-	steps := subcmd.Steps{
+	steps := d.steps()
+	if err := steps.Run(); err != nil {
+		rollbackCtxb, rerr := json.Marshal(d.Service.RollBackContext)
+		if rerr != nil {
+			logger.Error("json Marshal %s", err.Error())
+			fmt.Printf("<ctx>Can't RollBack<ctx>\n")
+		}
+		fmt.Printf("<ctx>%s<ctx>\n", string(rollbackCtxb))
+		return err
+	}
+
+	logger.Info("install_mysql successfully")
+	return nil
+}
+
+// steps 部署步骤
+// --resume 时跳过已经落盘的 step: 预检查会因为目录和进程已存在而失败, 初始化系统库表、启动、初始化权限不能重复执行
+// 渲染配置、初始化目录会重建 InsSockets 等内存状态, 每次都执行
+// 跳过的 step 不会再写入 RollBackContext
+func (d *DeployMySQLAct) steps() subcmd.Steps {
+	return subcmd.Steps{
 		{
-			FunName: "预检查",
-			Func:    d.Service.PreCheck,
+			FunName:      "预检查",
+			Func:         d.Service.PreCheck,
+			SkipOnResume: true,
 		},
 		{
 			FunName: "渲染my.cnf配置",
@@ -108,20 +129,24 @@ func (d *DeployMySQLAct) Run() (err error) {
 			Func:    d.Service.InitInstanceDirs,
 		},
 		{
-			FunName: "下载并且解压安装包",
-			Func:    d.Service.DecompressMysqlPkg,
+			FunName:      "下载并且解压安装包",
+			Func:         d.Service.DecompressMysqlPkg,
+			SkipOnResume: true,
 		},
 		{
-			FunName: "初始化mysqld系统库表",
-			Func:    d.Service.Install,
+			FunName:      "初始化mysqld系统库表",
+			Func:         d.Service.Install,
+			SkipOnResume: true,
 		},
 		{
-			FunName: "启动mysqld",
-			Func:    d.Service.Startup,
+			FunName:      "启动mysqld",
+			Func:         d.Service.Startup,
+			SkipOnResume: true,
 		},
 		{
-			FunName: "执行初始化系统基础权限、库表SQL",
-			Func:    d.Service.InitDefaultPrivAndSchemaWithResetMaster,
+			FunName:      "执行初始化系统基础权限、库表SQL",
+			Func:         d.Service.InitDefaultPrivAndSchemaWithResetMaster,
+			SkipOnResume: true,
 		},
 		{
 			FunName: "建立socker软连接",
@@ -134,22 +159,15 @@ func (d *DeployMySQLAct) Run() (err error) {
 		{
 			FunName: "输出系统的时区设置",
 			Func: func() error {
+				// resume 跳过了预检查时重新获取时区
+				if d.Service.TimeZone == "" {
+					if err := d.Service.CheckTimeZoneSetting(); err != nil {
+						return err
+					}
+				}
 				d.OutputCtx(fmt.Sprintf("{\"time_zone\": \"%s\"}", d.Service.TimeZone))
 				return nil
 			},
 		},
 	}
-
-	if err := steps.Run(); err != nil {
-		rollbackCtxb, rerr := json.Marshal(d.Service.RollBackContext)
-		if rerr != nil {
-			logger.Error("json Marshal %s", err.Error())
-			fmt.Printf("<ctx>Can't RollBack<ctx>\n")
-		}
-		fmt.Printf("<ctx>%s<ctx>\n", string(rollbackCtxb))
-		return err
-	}
-
-	logger.Info("install_mysql successfully")
-	return nil
 }
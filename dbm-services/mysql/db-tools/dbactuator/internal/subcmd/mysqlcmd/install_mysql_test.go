@@ -0,0 +1,62 @@
+package mysqlcmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"dbm-services/mysql/db-tools/dbactuator/internal/subcmd"
+)
+
+// TestDeployMySQLStepsResume 用部署命令真实的 step 列表走一遍失败后 resume
+func TestDeployMySQLStepsResume(t *testing.T) {
+	opt := subcmd.GBaseOptions
+	subcmd.GBaseOptions = &subcmd.BaseOptions{Uid: "test", NodeId: t.Name()}
+	t.Cleanup(func() { subcmd.GBaseOptions = opt })
+	executable, _ := os.Executable()
+	t.Cleanup(func() {
+		_ = os.RemoveAll(filepath.Join(filepath.Dir(executable), subcmd.StepCheckpointDir))
+	})
+
+	var called []string
+	failAt := "执行初始化系统基础权限、库表SQL"
+	// 保留真实的 step 名字和标记, 替换掉会操作机器的 Func
+	newSteps := func() subcmd.Steps {
+		steps := (&DeployMySQLAct{}).steps()
+		for i := range steps {
+			name := steps[i].FunName
+			steps[i].Func = func() error {
+				called = append(called, name)
+				if name == failAt {
+					return errors.New("init priv failed")
+				}
+				return nil
+			}
+		}
+		return steps
+	}
+
+	if err := newSteps().Run(); err == nil {
+		t.Fatalf("expect step %s failed", failAt)
+	}
+
+	failAt = ""
+	called = nil
+	subcmd.GBaseOptions.Resume = true
+	if err := newSteps().Run(); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"渲染my.cnf配置",
+		"初始化mysqld相关目录",
+		"执行初始化系统基础权限、库表SQL",
+		"建立socker软连接",
+		"生成exporter配置文件",
+		"输出系统的时区设置",
+	}
+	if !slices.Equal(called, want) {
+		t.Fatalf("resume called %v, want %v", called, want)
+	}
+}
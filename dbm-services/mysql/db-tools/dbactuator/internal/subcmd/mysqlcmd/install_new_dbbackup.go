@@ -38,10 +38,6 @@ func NewInstallNewDbBackupCommand() *cobra.Command {
 		),
 		Run: func(cmd *cobra.Command, args []string) {
 			util.CheckErr(act.Validate())
-			if act.RollBack {
-				util.CheckErr(act.Rollback())
-				return
-			}
 			util.CheckErr(act.Init())
 			util.CheckErr(act.Run())
 		},
@@ -77,6 +73,9 @@ func (d *InstallNewDbBackupAct) Run() (err error) {
 		{
 			FunName: "备份原备份程序",
 			Func:    d.Service.StageLegacyBackup,
+			// 重复执行会用新程序覆盖已经备份的原程序
+			SkipOnResume: true,
+			FuncRollback: d.Service.RestoreLegacyBackup,
 		},
 		{
 			FunName: "解压备份程序压缩包",
@@ -93,6 +92,8 @@ func (d *InstallNewDbBackupAct) Run() (err error) {
 		{
 			FunName: "添加系统crontab",
 			Func:    d.Service.AddCrontab,
+			// CreateOrReplace 可以重复调用, crond 重启时重试
+			Retries: 3,
 		},
 	}
 
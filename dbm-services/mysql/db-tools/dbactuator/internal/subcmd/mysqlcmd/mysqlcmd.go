@@ -99,6 +99,7 @@ func NewMysqlCommand() *cobra.Command {
 				NewBuildMsRelatioCommand(),
 				RestoreDRCommand(),
 				RecoverBinlogCommand(),
+				PitrCommand(),
 			},
 		},
 	}
@@ -0,0 +1,109 @@
+package mysqlcmd
+
+import (
+	"fmt"
+
+	"dbm-services/common/go-pubpkg/logger"
+	"dbm-services/mysql/db-tools/dbactuator/internal/subcmd"
+	"dbm-services/mysql/db-tools/dbactuator/pkg/components/mysql/restore"
+	"dbm-services/mysql/db-tools/dbactuator/pkg/util"
+
+	"github.com/spf13/cobra"
+)
+
+// PitrAct 定点恢复
+type PitrAct struct {
+	*subcmd.BaseOptions
+	Payload restore.PitrComp
+}
+
+// PitrCommand godoc
+//
+// @Summary  定点恢复
+// @Description  选择覆盖目标的最近全备恢复，再应用 binlog 到指定时间、GTID 或位点
+// @Tags         mysql
+// @Accept       json
+// @Param        body body      restore.PitrComp  true  "short description"
+// @Success      200  {object}  restore.PitrResult
+// @Router       /mysql/pitr [post]
+func PitrCommand() *cobra.Command {
+	act := PitrAct{
+		BaseOptions: subcmd.GBaseOptions,
+	}
+	cmd := &cobra.Command{
+		Use:   "pitr",
+		Short: "定点恢复",
+		Example: fmt.Sprintf(
+			"dbactuator mysql pitr %s %s\n"+
+				"\nOutput examples:\n%s",
+			subcmd.CmdBaseExampleStr,
+			subcmd.ToPrettyJson(act.Payload.Example()),
+			subcmd.ToPrettyJson(act.Payload.ExampleOutput()),
+		),
+		Run: func(cmd *cobra.Command, args []string) {
+			util.CheckErr(act.Validate())
+			util.CheckErr(act.Init())
+			util.CheckErr(act.Run())
+		},
+	}
+	return cmd
+}
+
+// Init TODO
+func (d *PitrAct) Init() (err error) {
+	if err = d.BaseOptions.Validate(); err != nil {
+		return err
+	}
+	if err = d.Deserialize(&d.Payload.Params); err != nil {
+		logger.Error("Deserialize err %s", err.Error())
+		return err
+	}
+	d.Payload.GeneralParam = subcmd.GeneralRuntimeParam
+	return
+}
+
+// Validate TODO
+func (d *PitrAct) Validate() error {
+	return nil
+}
+
+// Run TODO
+func (d *PitrAct) Run() (err error) {
+	defer util.LoggerErrorStack(logger.Error, err)
+	// 选择备份不修改任何状态，不放在 steps 里，--resume 时也需要重新选择
+	if err = d.Payload.Init(); err != nil {
+		return err
+	}
+	if err = d.Payload.ChooseBackup(); err != nil {
+		return err
+	}
+	steps := subcmd.Steps{
+		{
+			FunName:      "恢复备份",
+			Func:         d.Payload.Restore,
+			SkipOnResume: true,
+		},
+		{
+			FunName: "查找binlog停止位置",
+			Func:    d.Payload.FindStopPoint,
+		},
+		{
+			FunName:      "应用binlog",
+			Func:         d.Payload.ApplyBinlog,
+			SkipOnResume: true,
+		},
+		{
+			FunName: "校验gtid_executed",
+			Func:    d.Payload.Verify,
+		},
+		{
+			FunName: "输出结果",
+			Func:    d.Payload.OutputCtx,
+		},
+	}
+	if err = steps.Run(); err != nil {
+		return err
+	}
+	logger.Info("pitr successfully")
+	return nil
+}
@@ -88,6 +88,8 @@ func (d *RestoreDRAct) Run() (err error) {
 		{
 			FunName: "恢复",
 			Func:    d.Payload.Start,
+			// 数据已经导入目标实例, resume 时不重复导入; 需要带上相同的 work_id
+			SkipOnResume: true,
 		},
 		{
 			FunName: "等待恢复完成",
@@ -37,7 +37,7 @@ func NewTruncateOnMySQLCommand() *cobra.Command {
 			util.CheckErr(act.Run())
 		},
 	}
-	return cmd
+	return subcmd.SupportPlan(cmd)
 }
 
 func (c *TruncateOnMySQLAct) Init() error {
@@ -52,6 +52,13 @@ func (c *TruncateOnMySQLAct) Init() error {
 }
 
 func (c *TruncateOnMySQLAct) Run() error {
+	if c.Plan {
+		if err := c.BaseService.Init(c.Uid); err != nil {
+			return err
+		}
+		return subcmd.RunPlan(&c.BaseService)
+	}
+
 	steps := subcmd.Steps{
 		{
 			FunName: "初始化",
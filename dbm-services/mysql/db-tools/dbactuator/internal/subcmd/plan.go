@@ -0,0 +1,53 @@
+/*
+ * TencentBlueKing is pleased to support the open source community by making 蓝鲸智云-DB管理系统(BlueKing-BK-DBM) available.
+ * Copyright (C) 2017-2023 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at https://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+ * an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package subcmd
+
+import (
+	"dbm-services/common/go-pubpkg/logger"
+	"dbm-services/mysql/db-tools/dbactuator/pkg/components"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// AnnotationPlan 标记子命令支持 --plan
+const AnnotationPlan = "dbactuator/plan"
+
+// SupportPlan 标记 cmd 支持 --plan, 命令的 Run 里需要在 Plan 为 true 时调用 RunPlan
+func SupportPlan(cmd *cobra.Command) *cobra.Command {
+	if cmd.Annotations == nil {
+		cmd.Annotations = make(map[string]string)
+	}
+	cmd.Annotations[AnnotationPlan] = "true"
+	return cmd
+}
+
+// CheckPlanSupported 指定了 --plan 但子命令没有实现时直接拒绝, 避免真正执行
+func CheckPlanSupported(cmd *cobra.Command, opt *BaseOptions) error {
+	if !opt.Plan {
+		return nil
+	}
+	if cmd.Annotations[AnnotationPlan] != "true" {
+		return errors.Errorf("%s does not support --plan", cmd.CommandPath())
+	}
+	return nil
+}
+
+// RunPlan 收集组件的执行计划, 以 <ctx> 输出
+func RunPlan(planner components.Planner) error {
+	p := components.NewPlan()
+	if err := planner.Plan(p); err != nil {
+		logger.Error("generate plan failed: %s", err.Error())
+		return err
+	}
+	logger.Info("plan: %d sqls, %d files, %d processes", len(p.SQLs), len(p.Files), len(p.Processes))
+	return components.PrintOutputCtx(p)
+}
@@ -0,0 +1,373 @@
+/*
+ * TencentBlueKing is pleased to support the open source community by making 蓝鲸智云-DB管理系统(BlueKing-BK-DBM) available.
+ * Copyright (C) 2017-2023 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at https://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+ * an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package subcmd
+
+import (
+	"crypto/md5"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"dbm-services/common/go-pubpkg/cmutil"
+	"dbm-services/common/go-pubpkg/logger"
+
+	"github.com/pkg/errors"
+)
+
+// StepCheckpointDir checkpoint 文件目录, 相对 dbactuator 所在目录
+const StepCheckpointDir = "checkpoint"
+
+// maxStepRetryBackoff 重试间隔 1s, 2s, 4s ... 最大 60s
+const maxStepRetryBackoff = 60 * time.Second
+
+// StepCheckpoint 单个 step 的执行状态
+type StepCheckpoint struct {
+	FunName   string    `json:"fun_name"`
+	State     string    `json:"state"`
+	Retries   int       `json:"retries"`
+	Error     string    `json:"error,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// StepsCheckpoint 一个节点的 checkpoint 文件内容
+// 一条命令里可能有多组 Steps, 用 step 名字列表的摘要区分
+type StepsCheckpoint struct {
+	Uid    string                      `json:"uid"`
+	NodeId string                      `json:"node_id"`
+	Groups map[string][]StepCheckpoint `json:"groups"`
+}
+
+// stepsRunner 一次 Steps.Run 的执行状态
+type stepsRunner struct {
+	steps      Steps
+	opt        *BaseOptions
+	file       string
+	group      string
+	checkpoint *StepsCheckpoint
+	mu         sync.Mutex
+	// 当前正在执行的 step, 收到信号时用来调用 FuncStop
+	current int
+	// 收到的 SIGINT/SIGTERM, 不为空时不再执行后续 step
+	stopSig os.Signal
+}
+
+// Run 顺序执行 step
+// 1. 每个 step 的状态写入 checkpoint 文件, 指定 --resume 时跳过已经成功并且标记了 SkipOnResume 的 step
+// 2. step 失败后按 Retries 重试, 有 FuncRetry 时用 FuncRetry 重试
+// 3. 失败后如果指定了 --rollback, 倒序执行已执行 step 的 FuncRollback
+// 4. 执行中收到 SIGINT/SIGTERM, 调用当前 step 的 FuncStop, 状态记为 stopped, 返回错误, 可以 resume 继续
+func (s Steps) Run() (err error) {
+	if GBaseOptions.Plan {
+		// 支持 --plan 的命令不会走到这里, 兜底防止误执行
+		return errors.New("steps can not run in --plan mode")
+	}
+	r := newStepsRunner(s, GBaseOptions)
+	return r.run()
+}
+
+func newStepsRunner(s Steps, opt *BaseOptions) *stepsRunner {
+	r := &stepsRunner{
+		steps:   s,
+		opt:     opt,
+		current: -1,
+	}
+	var names []string
+	for _, step := range s {
+		names = append(names, step.FunName)
+	}
+	r.group = fmt.Sprintf("%x", md5.Sum([]byte(strings.Join(names, "\n"))))
+
+	if opt != nil && !cmutil.IsEmpty(opt.Uid) && !cmutil.IsEmpty(opt.NodeId) {
+		executable, _ := os.Executable()
+		r.file = filepath.Join(
+			filepath.Dir(executable),
+			StepCheckpointDir,
+			fmt.Sprintf("steps_%s_%s.json", opt.Uid, opt.NodeId),
+		)
+	}
+	return r
+}
+
+func (r *stepsRunner) run() (err error) {
+	if err = r.loadCheckpoint(); err != nil {
+		return err
+	}
+
+	stopCh := make(chan os.Signal, 1)
+	signal.Notify(stopCh, syscall.SIGINT, syscall.SIGTERM)
+	done := make(chan struct{})
+	defer func() {
+		signal.Stop(stopCh)
+		close(done)
+	}()
+	go r.handleStop(stopCh, done)
+
+	for idx := range r.steps {
+		step := &r.steps[idx]
+		if sig := r.signal(); sig != nil {
+			logger.Warn("receive signal %s, remain steps not run: %s", sig.String(), r.remainSteps(idx))
+			return fmt.Errorf("receive signal %s before step %s", sig.String(), step.FunName)
+		}
+		if step.State == StepStateSkip {
+			logger.Info("step <%d>, [%s] skipped by user", idx, step.FunName)
+			r.setState(idx, StepStateSkip, 0, nil)
+			continue
+		}
+		if r.resumable(idx) {
+			logger.Info("step <%d>, [%s] already succeeded, skip on resume", idx, step.FunName)
+			step.State = StepStateSucc
+			continue
+		}
+
+		logger.Info("step <%d>, ready start run [%s]", idx, step.FunName)
+		r.mu.Lock()
+		r.current = idx
+		r.mu.Unlock()
+		r.setState(idx, StepStateRunning, 0, nil)
+
+		retries, err := r.runStep(idx)
+
+		r.mu.Lock()
+		r.current = -1
+		stopped := step.State == StepStateStop
+		r.mu.Unlock()
+
+		if stopped {
+			r.setState(idx, StepStateStop, retries, err)
+			logger.Warn("step <%d>: %s stopped, remain steps: %s", idx, step.FunName, r.remainSteps(idx+1))
+			if err == nil {
+				err = fmt.Errorf("step %s stopped", step.FunName)
+			}
+			return err
+		}
+		if err != nil {
+			r.setState(idx, StepStateFail, retries, err)
+			logger.Error("step<%d>: %s失败 , 错误: %s", idx, step.FunName, err)
+			logger.Error("remain steps not run: %s", r.remainSteps(idx+1))
+			if r.opt != nil && r.opt.RollBack {
+				if rerr := r.rollback(idx); rerr != nil {
+					return errors.WithMessagef(err, "rollback failed: %s", rerr.Error())
+				}
+			}
+			return err
+		}
+		r.setState(idx, StepStateSucc, retries, nil)
+		logger.Info("step <%d>, start run [%s] successfully", idx, step.FunName)
+	}
+	return nil
+}
+
+// runStep 执行 step, 失败后按退避间隔重试, 返回实际重试次数
+func (r *stepsRunner) runStep(idx int) (retries int, err error) {
+	step := &r.steps[idx]
+	if err = step.Func(); err == nil {
+		return 0, nil
+	}
+
+	retryFunc := step.Func
+	if step.FuncRetry != nil {
+		retryFunc = step.FuncRetry
+	}
+	for i := 1; i <= step.Retries; i++ {
+		if r.stopped(idx) {
+			return retries, err
+		}
+
+		backoff := time.Duration(1<<(i-1)) * time.Second
+		if backoff > maxStepRetryBackoff {
+			backoff = maxStepRetryBackoff
+		}
+		logger.Warn("step <%d>: %s failed: %s, retry %d/%d after %s",
+			idx, step.FunName, err.Error(), i, step.Retries, backoff)
+		time.Sleep(backoff)
+
+		retries = i
+		r.setState(idx, StepStateRunning, retries, err)
+		if err = retryFunc(); err == nil {
+			return retries, nil
+		}
+	}
+	return retries, err
+}
+
+// rollback 从失败的 step 开始倒序回滚
+func (r *stepsRunner) rollback(failedIdx int) error {
+	var errs []string
+	for idx := failedIdx; idx >= 0; idx-- {
+		step := &r.steps[idx]
+		if step.FuncRollback == nil || step.State == StepStateSkip {
+			continue
+		}
+		logger.Info("step <%d>, rollback [%s]", idx, step.FunName)
+		if err := step.FuncRollback(); err != nil {
+			logger.Error("step <%d>, rollback [%s] failed: %s", idx, step.FunName, err.Error())
+			errs = append(errs, fmt.Sprintf("%s: %s", step.FunName, err.Error()))
+			continue
+		}
+		// 回滚后重新执行需要从头开始
+		r.setState(idx, StepStateDefault, 0, nil)
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// handleStop 收到信号后标记当前 step 为 stopped 并调用 FuncStop
+// 没有注册 FuncStop 时等当前 step 执行完, 由 run 返回错误, 不在这里退出进程
+func (r *stepsRunner) handleStop(stopCh chan os.Signal, done chan struct{}) {
+	select {
+	case sig := <-stopCh:
+		r.mu.Lock()
+		r.stopSig = sig
+		idx := r.current
+		if idx < 0 {
+			r.mu.Unlock()
+			return
+		}
+		step := &r.steps[idx]
+		step.State = StepStateStop
+		r.mu.Unlock()
+
+		logger.Warn("step <%d>: %s receive signal %s", idx, step.FunName, sig.String())
+		if step.FuncStop == nil {
+			logger.Warn("step <%d>: %s has no stop func, wait for it to finish", idx, step.FunName)
+			return
+		}
+		if err := step.FuncStop(); err != nil {
+			logger.Error("step <%d>: %s stop failed: %s", idx, step.FunName, err.Error())
+		}
+	case <-done:
+	}
+}
+
+func (r *stepsRunner) signal() os.Signal {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.stopSig
+}
+
+func (r *stepsRunner) stopped(idx int) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.steps[idx].State == StepStateStop
+}
+
+func (r *stepsRunner) remainSteps(from int) string {
+	var names []string
+	for i := from; i < len(r.steps); i++ {
+		names = append(names, r.steps[i].FunName)
+	}
+	return strings.Join(names, ",")
+}
+
+// resumable 只有 --resume, step 标记了 SkipOnResume, 并且 checkpoint 中同一位置同名 step 成功过才跳过
+func (r *stepsRunner) resumable(idx int) bool {
+	if r.opt == nil || !r.opt.Resume || r.checkpoint == nil || !r.steps[idx].SkipOnResume {
+		return false
+	}
+	states := r.checkpoint.Groups[r.group]
+	if idx >= len(states) {
+		return false
+	}
+	return states[idx].FunName == r.steps[idx].FunName && states[idx].State == StepStateSucc
+}
+
+func (r *stepsRunner) loadCheckpoint() error {
+	if r.file == "" {
+		return nil
+	}
+
+	r.checkpoint = &StepsCheckpoint{
+		Uid:    r.opt.Uid,
+		NodeId: r.opt.NodeId,
+		Groups: make(map[string][]StepCheckpoint),
+	}
+	b, err := os.ReadFile(r.file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return errors.Wrapf(err, "read checkpoint %s", r.file)
+	}
+	if err = json.Unmarshal(b, r.checkpoint); err != nil {
+		if r.opt.Resume {
+			return errors.Wrapf(err, "parse checkpoint %s", r.file)
+		}
+		// 不是 resume, 坏掉的 checkpoint 直接覆盖
+		logger.Warn("parse checkpoint %s failed, overwrite it: %s", r.file, err.Error())
+		r.checkpoint.Groups = make(map[string][]StepCheckpoint)
+	}
+	if r.checkpoint.Groups == nil {
+		r.checkpoint.Groups = make(map[string][]StepCheckpoint)
+	}
+
+	if !r.opt.Resume {
+		delete(r.checkpoint.Groups, r.group)
+	}
+	return nil
+}
+
+// setState 更新 step 状态并落盘, 写 checkpoint 失败不影响执行
+func (r *stepsRunner) setState(idx int, state string, retries int, stepErr error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.steps[idx].State != StepStateStop || state == StepStateStop {
+		r.steps[idx].State = state
+	}
+	if r.checkpoint == nil {
+		return
+	}
+
+	states := r.checkpoint.Groups[r.group]
+	if len(states) != len(r.steps) {
+		states = make([]StepCheckpoint, len(r.steps))
+		for i, step := range r.steps {
+			states[i] = StepCheckpoint{FunName: step.FunName, State: StepStateDefault}
+		}
+	}
+	states[idx] = StepCheckpoint{
+		FunName:   r.steps[idx].FunName,
+		State:     r.steps[idx].State,
+		Retries:   retries,
+		UpdatedAt: time.Now(),
+	}
+	if stepErr != nil {
+		states[idx].Error = stepErr.Error()
+	}
+	r.checkpoint.Groups[r.group] = states
+
+	if err := r.saveCheckpoint(); err != nil {
+		logger.Warn("save checkpoint %s failed: %s", r.file, err.Error())
+	}
+}
+
+func (r *stepsRunner) saveCheckpoint() error {
+	if err := os.MkdirAll(filepath.Dir(r.file), 0755); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(r.checkpoint, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmpFile := r.file + ".tmp"
+	if err = os.WriteFile(tmpFile, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile, r.file)
+}
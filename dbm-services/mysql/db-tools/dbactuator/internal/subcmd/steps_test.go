@@ -0,0 +1,123 @@
+package subcmd
+
+import (
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func newTestRunner(t *testing.T, s Steps, opt *BaseOptions) *stepsRunner {
+	r := newStepsRunner(s, opt)
+	r.file = filepath.Join(t.TempDir(), "steps.json")
+	return r
+}
+
+func TestStepsResume(t *testing.T) {
+	opt := &BaseOptions{Uid: "1", NodeId: "n1"}
+	var called []string
+	failB := true
+	newSteps := func() Steps {
+		return Steps{
+			{FunName: "a", Func: func() error { called = append(called, "a"); return nil }, SkipOnResume: true},
+			{FunName: "b", Func: func() error {
+				called = append(called, "b")
+				if failB {
+					return errors.New("b failed")
+				}
+				return nil
+			}},
+			{FunName: "c", Func: func() error { called = append(called, "c"); return nil }},
+		}
+	}
+
+	r := newTestRunner(t, newSteps(), opt)
+	if err := r.run(); err == nil {
+		t.Fatal("expect step b failed")
+	}
+
+	failB = false
+	called = nil
+	opt.Resume = true
+	r2 := newStepsRunner(newSteps(), opt)
+	r2.file = r.file
+	if err := r2.run(); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(called, []string{"b", "c"}) {
+		t.Fatalf("resume should skip succeeded step, called: %v", called)
+	}
+}
+
+func TestStepsResumeRebuildState(t *testing.T) {
+	opt := &BaseOptions{Uid: "1", NodeId: "n3"}
+	type component struct {
+		ins *string
+	}
+	failUse := true
+	newSteps := func(c *component) Steps {
+		return Steps{
+			{FunName: "init", Func: func() error { ins := "127.0.0.1:3306"; c.ins = &ins; return nil }},
+			{FunName: "use", Func: func() error {
+				if c.ins == nil {
+					return errors.New("ins not initialized")
+				}
+				if failUse {
+					return errors.New("use failed")
+				}
+				return nil
+			}},
+		}
+	}
+
+	r := newTestRunner(t, newSteps(&component{}), opt)
+	if err := r.run(); err == nil {
+		t.Fatal("expect step use failed")
+	}
+
+	failUse = false
+	opt.Resume = true
+	r2 := newStepsRunner(newSteps(&component{}), opt)
+	r2.file = r.file
+	if err := r2.run(); err != nil {
+		t.Fatalf("resume should rerun init step: %s", err.Error())
+	}
+}
+
+func TestStepsRetryAndRollback(t *testing.T) {
+	opt := &BaseOptions{Uid: "1", NodeId: "n2", RollBack: true}
+	var called []string
+	s := Steps{
+		{
+			FunName:      "a",
+			Func:         func() error { return nil },
+			FuncRollback: func() error { called = append(called, "rollback a"); return nil },
+		},
+		{
+			FunName:      "b",
+			Func:         func() error { return nil },
+			FuncRollback: func() error { called = append(called, "rollback b"); return nil },
+		},
+		{
+			FunName:      "c",
+			Func:         func() error { called = append(called, "c"); return errors.New("c failed") },
+			FuncRetry:    func() error { called = append(called, "retry c"); return errors.New("c failed") },
+			FuncRollback: func() error { called = append(called, "rollback c"); return nil },
+			Retries:      1,
+		},
+	}
+
+	r := newTestRunner(t, s, opt)
+	if err := r.run(); err == nil {
+		t.Fatal("expect step c failed")
+	}
+	expect := []string{"c", "retry c", "rollback c", "rollback b", "rollback a"}
+	if !slices.Equal(called, expect) {
+		t.Fatalf("expect %v, got %v", expect, called)
+	}
+	for _, st := range r.checkpoint.Groups[r.group] {
+		if st.State != StepStateDefault {
+			t.Fatalf("step %s should be reset after rollback, got %s", st.FunName, st.State)
+		}
+	}
+}
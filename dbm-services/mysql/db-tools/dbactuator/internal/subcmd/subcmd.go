@@ -62,7 +62,11 @@ type BaseOptions struct {
 	PayloadFormat       string
 	NotSensitivePayload string
 	RollBack            bool
-	Helper              bool
+	// Resume 跳过 checkpoint 中已经成功的 step
+	Resume bool
+	// Plan 只输出执行计划, 不实际执行
+	Plan   bool
+	Helper bool
 	// 是否为外部版本
 	// on ON
 	External string
@@ -97,27 +101,14 @@ type StepFunc struct {
 	FuncRollback func() error
 	FuncStop     func() error
 	Retries      int
+	// SkipOnResume step 的结果已经持久化(写入实例或磁盘), 成功后 --resume 可以跳过
+	// 默认 false, --resume 时重新执行, 如 Init 这类只在内存中构建状态的 step 不能跳过
+	SkipOnResume bool
 }
 
 // Steps TODO
 type Steps []StepFunc
 
-// Run TODO
-func (s Steps) Run() (err error) {
-	for idx, step := range s {
-		logMessage := fmt.Sprintf("step <%d>, ready start run [%s]", idx, step.FunName)
-		logger.Info(logMessage)
-		if err = step.Func(); err != nil {
-			logger.Error("step<%d>: %s失败 , 错误: %s", idx, step.FunName, err)
-			// @todo
-			// 顺便输出接下来还有哪些 step 未允许
-			return err
-		}
-		logger.Info("step <%d>, start run [%s] successfully", idx, step.FunName)
-	}
-	return nil
-}
-
 // DeserializeNonStandard TODO
 /*
 	反序列化payload,并校验参数
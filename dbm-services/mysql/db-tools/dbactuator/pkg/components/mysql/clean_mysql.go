@@ -120,6 +120,13 @@ func (c *CleanMysqlComp) PreCheck() error {
 	return nil
 }
 
+// cleanDatabasesSQL 查询计划删除的 databases 列表
+func cleanDatabasesSQL() string {
+	inStr, _ := mysqlcomm.UnsafeBuilderStringIn(native.DBSys, "'")
+	return fmt.Sprintf("select SCHEMA_NAME from information_schema.SCHEMATA where SCHEMA_NAME not in (%s)"+
+		" and SCHEMA_NAME != '__cdb_recycle_bin__'", inStr)
+}
+
 // Start TODO
 func (c *CleanMysqlComp) Start() error {
 	if c.Params.StopSlave {
@@ -134,9 +141,7 @@ func (c *CleanMysqlComp) Start() error {
 	}
 
 	// 计划删除的 databases 列表
-	inStr, _ := mysqlcomm.UnsafeBuilderStringIn(native.DBSys, "'")
-	dbsSql := fmt.Sprintf("select SCHEMA_NAME from information_schema.SCHEMATA where SCHEMA_NAME not in (%s)"+
-		" and SCHEMA_NAME != '__cdb_recycle_bin__'", inStr)
+	dbsSql := cleanDatabasesSQL()
 
 	if databases, err := c.dbworker.Query(dbsSql); err != nil {
 		if c.dbworker.IsNotRowFound(err) {
@@ -164,3 +169,40 @@ func (c *CleanMysqlComp) Start() error {
 	}
 	return nil
 }
+
+// Plan 记录 stop/reset slave、要删除的库和重启
+// drop_database=false 时 Start 只打印 drop 语句, 这里也不记录
+func (c *CleanMysqlComp) Plan(plan *components.Plan) error {
+	if err := c.Init(); err != nil {
+		return err
+	}
+	if err := c.PreCheck(); err != nil {
+		return err
+	}
+	instance := fmt.Sprintf("%s:%d", c.instObj.Host, c.instObj.Port)
+	if c.Params.StopSlave {
+		plan.AddSQL(instance, "", "stop slave;")
+	}
+	if c.Params.ResetSlave {
+		plan.AddSQL(instance, "", "reset slave /*!50516 all */;")
+	}
+	if !c.Params.DropDatabase {
+		return nil
+	}
+
+	dbsSql := cleanDatabasesSQL()
+	databases, err := c.dbworker.Query(dbsSql)
+	if err != nil {
+		if c.dbworker.IsNotRowFound(err) {
+			return nil
+		}
+		return err
+	}
+	for _, dbName := range databases {
+		plan.AddSQL(instance, "", fmt.Sprintf("DROP DATABASE `%s`;", dbName["SCHEMA_NAME"]))
+	}
+	if c.Params.Restart {
+		plan.AddProcess(fmt.Sprintf("mysqld:%d", c.instObj.Port), components.PlanProcessRestart)
+	}
+	return nil
+}
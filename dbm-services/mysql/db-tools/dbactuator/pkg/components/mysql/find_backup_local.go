@@ -243,3 +243,8 @@ func (f *FindLocalBackupParam) OutputCtx() error {
 	fmt.Println(ss)
 	return nil
 }
+
+// GetResp 返回 Start 查找到的备份
+func (f *FindLocalBackupParam) GetResp() FindLocalBackupResp {
+	return f.resp
+}
@@ -741,7 +741,7 @@ func (i *InstallMySQLComp) generateDefaultMysqlAccount(realVersion string) (init
                      Event_priv,Trigger_priv)
 VALUES ('%','test','','Y','Y','Y','Y','Y','Y','N','Y','Y','Y','Y','Y','Y','Y','Y','N','N','Y','Y');`
 		initAccountsql = append(initAccountsql, s)
-	} else if cmutil.MySQLVersionParse(i.Params.MysqlVersion) <= cmutil.MySQLVersionParse("5.6") {
+	} else if cmutil.MySQLVersionParse(realVersion) <= cmutil.MySQLVersionParse("5.6") {
 		s := `alter table mysql.general_log change thread_id thread_id bigint(21) unsigned NOT NULL;`
 		initAccountsql = append(initAccountsql, s)
 	}
@@ -3,6 +3,7 @@ package mysql
 import (
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
@@ -107,6 +108,11 @@ type ConfItemOp struct {
 
 // Init init
 func (c *MycnfChangeComp) Init() (err error) {
+	return c.init(nil)
+}
+
+// init 连接实例并加载 my.cnf, plan 不为空时只记录 my.cnf 备份, 不实际复制
+func (c *MycnfChangeComp) init(plan *components.Plan) (err error) {
 	c.ConnMap = make(map[Port]*native.DbWorker)
 	c.socketMap = make(map[Port]string)
 	c.CnfMap = make(map[int]*util.CnfFile)
@@ -125,9 +131,13 @@ func (c *MycnfChangeComp) Init() (err error) {
 		}
 		// 备份原配置文件
 		bakFile := util.GetMyCnfFileName(port) + time.Now().Format(".20060102150405")
-		stderr, errx := osutil.StandardShellCommand(false, fmt.Sprintf("cp %s %s", util.GetMyCnfFileName(port), bakFile))
-		if errx != nil {
-			logger.Warn("backup origin my.cnf failed %s,stderr:%s", errx, stderr)
+		if plan != nil {
+			plan.AddFile(util.GetMyCnfFileName(port), components.PlanFileCopy, bakFile)
+		} else {
+			stderr, errx := osutil.StandardShellCommand(false, fmt.Sprintf("cp %s %s", util.GetMyCnfFileName(port), bakFile))
+			if errx != nil {
+				logger.Warn("backup origin my.cnf failed %s,stderr:%s", errx, stderr)
+			}
 		}
 		c.ConnMap[port] = dbConn
 		cnf := &util.CnfFile{FileName: util.GetMyCnfFileName(port)}
@@ -266,3 +276,42 @@ func (c *MycnfChangeComp) Start() error {
 	}
 	return nil
 }
+
+// Plan 记录 set global、my.cnf 修改和重启, 判断逻辑与 DoInstance 一致
+func (c *MycnfChangeComp) Plan(plan *components.Plan) error {
+	if err := c.init(plan); err != nil {
+		return err
+	}
+	if err := c.PreCheck(); err != nil {
+		return err
+	}
+	var keys []string
+	for k := range c.Params.Items {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, port := range c.Params.Ports {
+		instance := fmt.Sprintf("%s:%d", c.Params.Host, port)
+		var modifies []string
+		for _, k := range keys {
+			v := c.Params.Items[k]
+			sk := util.GetSectionFromKey(k, true)
+			switch {
+			case v.OPType == OPTypeUpsert && c.Params.Persistent < 1:
+				if sk.Section == util.MysqldSec {
+					plan.AddSQL(instance, "", fmt.Sprintf("set global %s = %s", sk.Key, v.ConfValue))
+				}
+			case v.OPType == OPTypeUpsert || v.OPType == OPTypeRemove:
+				modifies = append(modifies, fmt.Sprintf("%s [%s]%s=%s",
+					v.OPType, sk.Section, common.MapNameVarToConf(sk.Key), v.ConfValue))
+			}
+		}
+		if c.Params.Persistent >= 1 && len(modifies) > 0 {
+			plan.AddFile(util.GetMyCnfFileName(port), components.PlanFileModify, strings.Join(modifies, "; "))
+		}
+		if c.needRestart || c.Params.Restart >= 1 {
+			plan.AddProcess(fmt.Sprintf("mysqld:%d", port), components.PlanProcessRestart)
+		}
+	}
+	return nil
+}
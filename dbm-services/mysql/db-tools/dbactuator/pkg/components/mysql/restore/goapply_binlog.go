@@ -82,6 +82,10 @@ type GoApplyBinlog struct {
 	// BinlogStartFile 只能由外部传入，不要内部修改
 	BinlogStartPos  uint   `json:"binlog_start_pos"`
 	BinlogStartFile string `json:"binlog_start_file"`
+	// 指定最后一个要应用的 binlog 和停止位置，从 binlog_stop_pos 开始的 event 不再应用
+	// binlog_stop_file 之后的 binlog 会从列表中移除
+	BinlogStopPos  uint   `json:"binlog_stop_pos"`
+	BinlogStopFile string `json:"binlog_stop_file"`
 	// 格式 "2006-01-02 15:04:05" 原样传递给 mysqlbinlog
 	// 格式"2006-01-02T15:04:05Z07:00"(示例"2023-12-11T05:03:05+08:00")按照机器本地时间，解析成 "2006-01-02 15:04:05" 再传递给 mysqlbinlog
 	// 在 Init 时会统一把时间字符串转换成 time.RFC3399
@@ -133,12 +137,7 @@ func (r *GoApplyBinlog) ParseBinlogFiles() error {
 			tokenBulkChan <- struct{}{}
 			go func(binlogFilePath string) {
 				logger.Info("parse %s", binlogFilePath)
-				if binlogFilePath == r.BinlogStartFile {
-					r.BinlogOpt.StartPos = r.BinlogStartPos
-				} else {
-					r.BinlogOpt.StartPos = 0
-				}
-				_, err := r.BinlogOpt.Parse(r.BinlogDir, binlogFilePath, r.QuickMode)
+				_, err := r.binlogOptOfFile(binlogFilePath).Parse(r.BinlogDir, binlogFilePath, r.QuickMode)
 
 				<-tokenBulkChan
 
@@ -161,6 +160,23 @@ func (r *GoApplyBinlog) ParseBinlogFiles() error {
 	return nil
 }
 
+// binlogOptOfFile 复制一份解析选项，设置这个 binlog 文件的 start_pos, stop_pos
+// 并发解析时不能直接修改 r.BinlogOpt
+func (r *GoApplyBinlog) binlogOptOfFile(fileName string) *GoMySQLBinlogUtil {
+	opt := *r.BinlogOpt
+	opt.StartPos = 0
+	if fileName == r.BinlogStartFile {
+		opt.StartPos = r.BinlogStartPos
+	}
+	if r.BinlogStopFile != "" {
+		opt.StopPos = 0
+		if fileName == r.BinlogStopFile {
+			opt.StopPos = r.BinlogStopPos
+		}
+	}
+	return &opt
+}
+
 // buildScript 创建 parse_binlog.sh, import_binlog.sh 脚本，需要调用执行
 func (r *GoApplyBinlog) buildScript() error {
 	// 创建解析 binlog 的脚本，只是为了查看或者后面手动跑
@@ -169,17 +185,14 @@ func (r *GoApplyBinlog) buildScript() error {
 	for _, fileName := range r.BinlogFiles {
 		if fileName == "" {
 			continue
-		} else if fileName == r.BinlogStartFile {
-			r.BinlogOpt.StartPos = r.BinlogStartPos
-		} else {
-			r.BinlogOpt.StartPos = 0
 		}
-		_, _ = r.BinlogOpt.BuildArgs(r.QuickMode)
+		opt := r.binlogOptOfFile(fileName)
+		_, _ = opt.BuildArgs(r.QuickMode)
 		parsedFile := fmt.Sprintf(`%s/%s.sql`, dirBinlogParsed, fileName)
 		logFile := fmt.Sprintf("logs/parse_%s.err", fileName)
 		binlogFile := filepath.Join(r.BinlogDir, fileName)
 		cmd := fmt.Sprintf("%s %s --file %s  -r %s 2>%s",
-			r.BinlogOpt.cmdPath, strings.Join(r.BinlogOpt.cmdArgs, " "), binlogFile, parsedFile, logFile)
+			opt.cmdPath, strings.Join(opt.cmdArgs, " "), binlogFile, parsedFile, logFile)
 		parseCmds = append(parseCmds, cmd)
 	}
 	r.parseScript = fmt.Sprintf(filepath.Join(r.taskDir, parseScript))
@@ -286,6 +299,9 @@ func (r *GoApplyBinlog) Init() error {
 	if r.BinlogStartPos > 0 && r.BinlogStartFile == "" {
 		return errors.Errorf("binlog_start_pos must has binlog_start_file")
 	}
+	if r.BinlogStopPos > 0 && r.BinlogStopFile == "" {
+		return errors.Errorf("binlog_stop_pos must has binlog_stop_file")
+	}
 	if r.BinlogStartPos == 0 && r.StartTime == "" {
 		return errors.Errorf("start_time and start_pos cannot be empty both")
 	}
@@ -445,6 +461,16 @@ func (r *GoApplyBinlog) checkBinlogFiles() error {
 		}
 		r.BinlogFiles = cmutil.StringsRemoveEmpty(r.BinlogFiles)
 	}
+	if r.BinlogStopFile != "" {
+		idx := slices.Index(r.BinlogFiles, r.BinlogStopFile)
+		if idx < 0 {
+			return errors.WithMessagef(ErrorBinlogMissing, "binlog_stop_file %s not found", r.BinlogStopFile)
+		}
+		for _, f := range r.BinlogFiles[idx+1:] {
+			logger.Info("remove binlog file %s from list", f)
+		}
+		r.BinlogFiles = r.BinlogFiles[:idx+1]
+	}
 
 	if err := r.checkTimeRange(); err != nil {
 		return err
@@ -746,3 +772,8 @@ func (r *GoApplyBinlog) GetDBWorker() *native.DbWorker {
 func (r *GoApplyBinlog) GetTaskDir() string {
 	return r.taskDir
 }
+
+// GetBinlogParsedDir 解析出来的 sql 文件目录, 文件名是 binlog 文件名加 .sql
+func (r *GoApplyBinlog) GetBinlogParsedDir() string {
+	return r.binlogParsedDir
+}
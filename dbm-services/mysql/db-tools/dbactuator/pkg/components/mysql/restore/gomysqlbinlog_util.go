@@ -87,6 +87,10 @@ func (b *GoMySQLBinlogUtil) BuildArgs(filterMode bool) ([]string, error) {
 	if b.RowsEventType != "" {
 		b.cmdArgs = append(b.cmdArgs, "--rows-event-type", b.RowsEventType)
 	}
+	for i := 0; i < b.Verbose; i++ {
+		// 以 ### 注释输出 row image
+		b.cmdArgs = append(b.cmdArgs, "-v")
+	}
 	if filterMode {
 		if b.RowsFilter != "" {
 			//b.cmdArgs = append(b.cmdArgs, "--rows-filter", b.RowsFilter)
@@ -0,0 +1,517 @@
+package restore
+
+import (
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"dbm-services/common/go-pubpkg/cmutil"
+	"dbm-services/common/go-pubpkg/logger"
+	"dbm-services/mysql/db-tools/dbactuator/pkg/components"
+	"dbm-services/mysql/db-tools/dbactuator/pkg/components/mysql"
+	"dbm-services/mysql/db-tools/dbactuator/pkg/components/mysql/common"
+	"dbm-services/mysql/db-tools/dbactuator/pkg/components/mysql/dbbackup"
+	"dbm-services/mysql/db-tools/dbactuator/pkg/native"
+	"dbm-services/mysql/db-tools/dbactuator/pkg/util"
+	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/src/dbareport"
+	binlogParser "dbm-services/mysql/db-tools/mysql-rotatebinlog/pkg/binlog-parser"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cast"
+)
+
+// PitrComp 定点恢复，有 resp 返回
+// 选择覆盖恢复目标的最近一次全备，恢复后从备份位点开始应用 binlog 到目标时间/GTID/位点，最后校验 gtid_executed
+type PitrComp struct {
+	GeneralParam *components.GeneralParam `json:"general"`
+	Params       PitrParam                `json:"extend"`
+
+	targetTime time.Time
+	targetFile string
+	targetPos  int64
+	// 选中的备份，以及备份在 src_instance binlog 上的位点
+	backup      *mysql.LocalBackupObj
+	startStatus *dbareport.StatusInfo
+	binlogFiles []string
+	stopPoint   *binlogParser.StopPoint
+	result      PitrResult
+}
+
+// PitrParam 定点恢复参数
+type PitrParam struct {
+	// 本地备份所在目录，从这些目录里选择备份
+	BackupDirs []string `json:"backup_dirs" validate:"required"`
+	// 备份是哪个实例上做的，不指定时为 src_instance。从 slave 备份恢复时指定 slave
+	BackupInstance *native.Instance `json:"backup_instance"`
+	// 指定只使用哪个 cluster_id 的备份
+	ClusterId int `json:"cluster_id"`
+	// 备份恢复、binlog 解析的工作目录
+	WorkDir string `json:"work_dir" validate:"required"`
+	WorkID  string `json:"work_id"`
+	// binlog 所属实例，备份位点以该实例的 binlog 为准
+	SrcInstance native.Instance `json:"src_instance" validate:"required"`
+	// 恢复本地的目标实例
+	TgtInstance native.InsObject `json:"tgt_instance" validate:"required"`
+	// src_instance 的 binlog 所在目录，一般是下载目录
+	BinlogDir string `json:"binlog_dir" validate:"required"`
+
+	// 恢复目标，target_time / target_gtid / target_pos 只能指定一个
+	// 格式 "2006-01-02 15:04:05" 或 "2006-01-02T15:04:05+08:00"
+	TargetTime string `json:"target_time"`
+	// 恢复完成后 gtid_executed 要达到的 GTID 集合，需要包含备份时已经执行过的部分
+	TargetGtid string `json:"target_gtid"`
+	// binlog_file:pos，从这个位置开始的 event 不再应用，需要是事务的边界
+	TargetPos string `json:"target_pos"`
+
+	// 恢复选项，recover_binlog 会强制为 true
+	RestoreOpt       *RestoreOpt     `json:"restore_opts"`
+	ParseConcurrency int             `json:"parse_concurrency"`
+	MySQLClientOpt   *MySQLClientOpt `json:"mysql_client_opt"`
+}
+
+// PitrResult 定点恢复结果
+type PitrResult struct {
+	BackupId             string    `json:"backup_id"`
+	BackupIndexFile      string    `json:"backup_index_file"`
+	BackupConsistentTime time.Time `json:"backup_consistent_time"`
+	StartBinlogFile      string    `json:"start_binlog_file"`
+	StartBinlogPos       int64     `json:"start_binlog_pos"`
+	StopBinlogFile       string    `json:"stop_binlog_file"`
+	// StopBinlogPos 0 表示应用了整个 stop_binlog_file
+	StopBinlogPos int64     `json:"stop_binlog_pos"`
+	StopTime      time.Time `json:"stop_time"`
+	// AppliedGtidSet 应用的 binlog 里的事务
+	AppliedGtidSet string `json:"applied_gtid_set"`
+	// ExecutedGtidSet 恢复完成后 tgt_instance 的 gtid_executed
+	ExecutedGtidSet string `json:"executed_gtid_set"`
+}
+
+// Example TODO
+func (p *PitrComp) Example() interface{} {
+	return PitrComp{
+		Params: PitrParam{
+			BackupDirs:  []string{"/data/dbbak", "/data1/dbbak"},
+			ClusterId:   1234,
+			WorkDir:     "/data1/dbbak/",
+			SrcInstance: common.InstanceExample,
+			TgtInstance: common.InstanceObjExample,
+			BinlogDir:   "/data/dbbak/123456/binlog",
+			TargetGtid:  "3d36ccfa-e4e6-11ee-9b4a-525400b1dfac:1-12345",
+			RestoreOpt: &RestoreOpt{
+				EnableBinlog: false,
+			},
+			ParseConcurrency: 4,
+		},
+		GeneralParam: &components.GeneralParam{
+			RuntimeAccountParam: components.RuntimeAccountParam{
+				MySQLAccountParam: common.AccountAdminExample,
+			},
+		},
+	}
+}
+
+// ExampleOutput TODO
+func (p *PitrComp) ExampleOutput() interface{} {
+	return &PitrResult{
+		BackupId:        "a5a1e5d0-8b7b-11ef-a6d4-525400b1dfac",
+		BackupIndexFile: "/data/dbbak/10_1234_x.x.x.x_3306_20241030030300_physical.index",
+		StartBinlogFile: "binlog20000.000011",
+		StartBinlogPos:  1234,
+		StopBinlogFile:  "binlog20000.000013",
+		StopBinlogPos:   5678,
+		AppliedGtidSet:  "3d36ccfa-e4e6-11ee-9b4a-525400b1dfac:10001-12345",
+		ExecutedGtidSet: "3d36ccfa-e4e6-11ee-9b4a-525400b1dfac:1-12345",
+	}
+}
+
+// Init 检查恢复目标
+func (p *PitrComp) Init() error {
+	var targets []string
+	for _, t := range []string{p.Params.TargetTime, p.Params.TargetGtid, p.Params.TargetPos} {
+		if t != "" {
+			targets = append(targets, t)
+		}
+	}
+	if len(targets) != 1 {
+		return errors.Errorf("one and only one of target_time, target_gtid, target_pos is needed, got %v", targets)
+	}
+
+	var err error
+	if p.Params.TargetTime != "" {
+		if p.targetTime, err = cmutil.ParseLocalTimeString(p.Params.TargetTime); err != nil {
+			return errors.WithMessagef(err, "target_time %s", p.Params.TargetTime)
+		}
+		if p.targetTime.After(time.Now()) {
+			return errors.Errorf("target_time %s cannot be greater than current time", p.Params.TargetTime)
+		}
+	}
+	if p.Params.TargetPos != "" {
+		idx := strings.LastIndex(p.Params.TargetPos, ":")
+		if idx <= 0 {
+			return errors.Errorf("target_pos %s should be binlog_file:pos", p.Params.TargetPos)
+		}
+		p.targetFile = p.Params.TargetPos[:idx]
+		if _, err = binlogFileSeq(p.targetFile); err != nil {
+			return errors.WithMessagef(err, "target_pos %s", p.Params.TargetPos)
+		}
+		if p.targetPos, err = cast.ToInt64E(p.Params.TargetPos[idx+1:]); err != nil || p.targetPos <= 0 {
+			return errors.Errorf("target_pos %s should be binlog_file:pos", p.Params.TargetPos)
+		}
+	}
+	p.Params.TargetGtid = normalizeGtidSet(p.Params.TargetGtid)
+
+	if p.Params.BackupInstance == nil {
+		p.Params.BackupInstance = &native.Instance{Host: p.Params.SrcInstance.Host, Port: p.Params.SrcInstance.Port}
+	}
+	if p.Params.WorkID == "" {
+		p.Params.WorkID = newTimestampString()
+	}
+	if p.Params.RestoreOpt == nil {
+		p.Params.RestoreOpt = &RestoreOpt{}
+	}
+	// 后面要应用 binlog，所有库表结构都需要恢复
+	p.Params.RestoreOpt.WillRecoverBinlog = true
+	if p.Params.MySQLClientOpt == nil {
+		p.Params.MySQLClientOpt = &MySQLClientOpt{MaxAllowedPacket: 1073741824, BinaryMode: true}
+	}
+	return nil
+}
+
+// ChooseBackup 从本地备份里选择覆盖恢复目标的最近一次全备
+// 不支持增量备份链，增量备份会被跳过，binlog 从全备的位点开始应用
+func (p *PitrComp) ChooseBackup() error {
+	finder := mysql.FindLocalBackupParam{
+		BackupDirs:  p.Params.BackupDirs,
+		TgtInstance: p.Params.BackupInstance,
+		ClusterId:   p.Params.ClusterId,
+	}
+	if err := finder.Init(); err != nil {
+		return err
+	}
+	if err := finder.PreCheck(); err != nil {
+		return err
+	}
+	if err := finder.Start(); err != nil {
+		return err
+	}
+	var backups []*mysql.LocalBackupObj
+	for _, b := range finder.GetResp().Backups {
+		backups = append(backups, b)
+	}
+	// 从最近的备份开始找
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].BackupConsistentTime.After(backups[j].BackupConsistentTime)
+	})
+	for _, b := range backups {
+		index := &dbbackup.BackupIndexFile{}
+		if err := dbbackup.ParseBackupIndexFile(b.IndexFile, index); err != nil {
+			logger.Warn("parse backup index %s failed: %s", b.IndexFile, err.Error())
+			continue
+		}
+		if !index.IsFullBackup {
+			logger.Info("backup %s is not full backup, skip", b.IndexFile)
+			continue
+		}
+		status, err := backupBinlogStatus(index, p.Params.SrcInstance)
+		if err != nil {
+			logger.Warn("backup %s: %s, skip", b.IndexFile, err.Error())
+			continue
+		}
+		if _, err = binlogFileSeq(status.BinlogFile); err != nil {
+			logger.Warn("backup %s: %s, skip", b.IndexFile, err.Error())
+			continue
+		}
+		covered, err := p.backupCovers(index, status)
+		if err != nil {
+			return err
+		}
+		if !covered {
+			logger.Info("backup %s at %s does not cover target, skip", b.IndexFile, status.String())
+			continue
+		}
+		p.backup, p.startStatus = b, status
+		p.result.BackupId = b.BackupId
+		p.result.BackupIndexFile = b.IndexFile
+		p.result.BackupConsistentTime = index.BackupConsistentTime
+		p.result.StartBinlogFile = status.BinlogFile
+		p.result.StartBinlogPos = cast.ToInt64(status.BinlogPos)
+		logger.Info("choose backup %s, binlog start at %s:%s", b.IndexFile, status.BinlogFile, status.BinlogPos)
+		return nil
+	}
+	return errors.Errorf("no full backup found in %v before target %s%s%s",
+		p.Params.BackupDirs, p.Params.TargetTime, p.Params.TargetGtid, p.Params.TargetPos)
+}
+
+// backupCovers 备份位点是否在恢复目标之前
+func (p *PitrComp) backupCovers(index *dbbackup.BackupIndexFile, status *dbareport.StatusInfo) (bool, error) {
+	switch {
+	case !p.targetTime.IsZero():
+		return !index.BackupConsistentTime.After(p.targetTime), nil
+	case p.targetFile != "":
+		cmp, err := compareBinlogPos(status.BinlogFile, cast.ToInt64(status.BinlogPos), p.targetFile, p.targetPos)
+		if err != nil {
+			return false, err
+		}
+		return cmp <= 0, nil
+	default:
+		gtid := normalizeGtidSet(status.Gtid)
+		if gtid == "" {
+			return false, nil
+		}
+		return binlogParser.GtidSetContain(p.Params.TargetGtid, gtid)
+	}
+}
+
+// Restore 恢复选中的备份
+func (p *PitrComp) Restore() error {
+	comp := &RestoreDRComp{
+		GeneralParam: p.GeneralParam,
+		Params: RestoreParam{
+			BackupInfo: BackupInfo{
+				WorkDir:   p.Params.WorkDir,
+				BackupDir: p.backup.BackupDir,
+				BackupFiles: map[string][]string{
+					"index": {filepath.Base(p.backup.IndexFile)},
+				},
+			},
+			TgtInstance: p.Params.TgtInstance,
+			SrcInstance: p.Params.SrcInstance,
+			WorkID:      p.Params.WorkID,
+			RestoreOpt:  p.Params.RestoreOpt,
+		},
+	}
+	if err := comp.ChooseType(); err != nil {
+		return err
+	}
+	for _, f := range []func() error{comp.Init, comp.PreCheck, comp.Start, comp.WaitDone, comp.PostCheck} {
+		if err := f(); err != nil {
+			return errors.WithMessagef(err, "restore backup %s", p.backup.IndexFile)
+		}
+	}
+	return nil
+}
+
+// FindStopPoint 从备份位点开始找到 binlog 的停止位置
+// 结果只保存在内存里, --resume 时需要重新查找
+func (p *PitrComp) FindStopPoint() error {
+	p.binlogFiles, p.stopPoint = nil, nil
+	startFile := p.startStatus.BinlogFile
+	startPos := cast.ToInt64(p.startStatus.BinlogPos)
+	nameParts := strings.Split(startFile, ".")
+	files, err := (&GoApplyBinlog{}).GetBinlogFilesFromDir(p.Params.BinlogDir, nameParts[0]+".")
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+	for _, f := range files {
+		if f >= startFile {
+			p.binlogFiles = append(p.binlogFiles, f)
+		}
+	}
+	if len(p.binlogFiles) == 0 || p.binlogFiles[0] != startFile {
+		return errors.WithMessagef(ErrorBinlogMissing, "backup binlog %s not found in %s", startFile, p.Params.BinlogDir)
+	}
+	fileSeqList := util.GetSuffixWithLenAndSep(p.binlogFiles, ".", 0)
+	if leakInts, err := util.IsConsecutiveStrings(fileSeqList, true); err != nil {
+		return errors.WithMessagef(ErrorBinlogMissing, "binlog leak number: %v", leakInts)
+	}
+
+	cond := binlogParser.StopCondition{
+		StopTime:        p.targetTime,
+		StopFile:        p.targetFile,
+		StopPos:         p.targetPos,
+		GtidSet:         p.Params.TargetGtid,
+		ExecutedGtidSet: normalizeGtidSet(p.startStatus.Gtid),
+	}
+	logger.Info("find stop point from %s:%d in %v", startFile, startPos, p.binlogFiles)
+	if p.stopPoint, err = binlogParser.FindStopPoint(p.Params.BinlogDir, p.binlogFiles, startPos, cond); err != nil {
+		return err
+	}
+	logger.Info("stop point: %+v", p.stopPoint)
+	p.binlogFiles = p.binlogFiles[:slices.Index(p.binlogFiles, p.stopPoint.BinlogFile)+1]
+	p.result.StopBinlogFile = p.stopPoint.BinlogFile
+	p.result.StopBinlogPos = p.stopPoint.StopPos
+	p.result.StopTime = p.stopPoint.StopTime
+	p.result.AppliedGtidSet = p.stopPoint.AppliedGtidSet
+	return nil
+}
+
+// ApplyBinlog 用 GoApplyBinlog 解析并导入 binlog
+func (p *PitrComp) ApplyBinlog() error {
+	if err := p.ensureStopPoint(); err != nil {
+		return err
+	}
+	startPos := cast.ToInt64(p.startStatus.BinlogPos)
+	if p.stopPoint.BinlogFile == p.startStatus.BinlogFile && p.stopPoint.StopPos == startPos {
+		logger.Info("backup is already at stop point, no binlog to apply")
+		return nil
+	}
+	// 有 stop_pos 时 stop_time 只用来满足 gomysqlbinlog 参数，以位点为准
+	stopTime := p.targetTime
+	if stopTime.IsZero() {
+		stopTime = time.Now()
+	}
+	applier := &GoApplyBinlog{
+		TgtInstance: p.Params.TgtInstance,
+		BinlogOpt: &GoMySQLBinlogUtil{
+			DisableLogBin: !p.Params.RestoreOpt.EnableBinlog,
+		},
+		BinlogDir:          p.Params.BinlogDir,
+		BinlogFiles:        p.binlogFiles,
+		BinlogStartFile:    p.startStatus.BinlogFile,
+		BinlogStartPos:     uint(startPos),
+		BinlogStopFile:     p.stopPoint.BinlogFile,
+		BinlogStopPos:      uint(p.stopPoint.StopPos),
+		StopTime:           stopTime.Format(time.RFC3339),
+		WorkDir:            p.Params.WorkDir,
+		WorkID:             p.Params.WorkID,
+		ParseOnly:          true,
+		ParseConcurrency:   p.Params.ParseConcurrency,
+		SourceBinlogFormat: p.Params.RestoreOpt.SourceBinlogFormat,
+		MySQLClientOpt:     p.Params.MySQLClientOpt,
+	}
+	if err := applier.Init(); err != nil {
+		return err
+	}
+	// binlog 文件和时间范围在 FindStopPoint 已经检查过，不走 PreCheck
+	if err := applier.buildMysqlCliOptions(); err != nil {
+		return err
+	}
+	if err := applier.buildBinlogOptions(); err != nil {
+		return err
+	}
+	if err := applier.Start(); err != nil {
+		return err
+	}
+	return applier.Import()
+}
+
+// Verify 检查恢复后的 gtid_executed
+func (p *PitrComp) Verify() error {
+	if err := p.ensureStopPoint(); err != nil {
+		return err
+	}
+	dbw, err := p.Params.TgtInstance.Conn()
+	if err != nil {
+		return errors.Wrap(err, "目标实例连接失败")
+	}
+	defer dbw.Stop()
+
+	gtidMode, err := dbw.GetSingleGlobalVar("gtid_mode")
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(gtidMode, "ON") {
+		if p.Params.TargetGtid != "" {
+			return errors.Errorf("gtid_mode=%s on %s:%d, cannot verify target_gtid",
+				gtidMode, p.Params.TgtInstance.Host, p.Params.TgtInstance.Port)
+		}
+		logger.Warn("gtid_mode=%s, skip checking gtid_executed", gtidMode)
+		return nil
+	}
+	executed, err := dbw.GetSingleGlobalVar("gtid_executed")
+	if err != nil {
+		return err
+	}
+	executed = normalizeGtidSet(executed)
+	p.result.ExecutedGtidSet = executed
+
+	if ok, err := binlogParser.GtidSetContain(executed, p.stopPoint.AppliedGtidSet); err != nil {
+		return err
+	} else if !ok {
+		return errors.Errorf("applied gtid %s is not in gtid_executed %s", p.stopPoint.AppliedGtidSet, executed)
+	}
+	if p.Params.TargetGtid != "" {
+		if ok, err := binlogParser.GtidSetContain(executed, p.Params.TargetGtid); err != nil {
+			return err
+		} else if !ok {
+			return errors.Errorf("gtid_executed %s does not reach target_gtid %s", executed, p.Params.TargetGtid)
+		}
+	}
+	// 恢复完成后不应该有备份和 binlog 之外的事务
+	expected := joinGtidSet(normalizeGtidSet(p.startStatus.Gtid), p.stopPoint.AppliedGtidSet)
+	if ok, err := binlogParser.GtidSetContain(expected, executed); err != nil {
+		return err
+	} else if !ok {
+		logger.Warn("gtid_executed %s has transactions beyond backup and applied binlog %s", executed, expected)
+	}
+	logger.Info("gtid_executed after pitr: %s", executed)
+	return nil
+}
+
+// ensureStopPoint 查找停止位置的 step 被跳过时重新查找
+func (p *PitrComp) ensureStopPoint() error {
+	if p.stopPoint != nil {
+		return nil
+	}
+	logger.Info("stop point not found yet, find it again")
+	return p.FindStopPoint()
+}
+
+// OutputCtx TODO
+func (p *PitrComp) OutputCtx() error {
+	return components.PrintOutputCtx(p.result)
+}
+
+// backupBinlogStatus 备份在 srcInstance binlog 上的位点
+// 备份就是在 srcInstance 上做的用 show master status，在 srcInstance 的 slave 上做的用 show slave status
+func backupBinlogStatus(index *dbbackup.BackupIndexFile, srcInstance native.Instance) (*dbareport.StatusInfo, error) {
+	masterInfo := index.BinlogInfo.ShowMasterStatus
+	slaveInfo := index.BinlogInfo.ShowSlaveStatus
+	if masterInfo != nil && masterInfo.BinlogFile != "" &&
+		masterInfo.MasterHost == srcInstance.Host && masterInfo.MasterPort == srcInstance.Port {
+		return masterInfo, nil
+	}
+	if slaveInfo != nil && slaveInfo.BinlogFile != "" &&
+		slaveInfo.MasterHost == srcInstance.Host && slaveInfo.MasterPort == srcInstance.Port {
+		return slaveInfo, nil
+	}
+	return nil, errors.Errorf("no binlog pos of %s:%d found in backup", srcInstance.Host, srcInstance.Port)
+}
+
+// compareBinlogPos 比较两个 binlog 位点，按 binlog 文件序号比较
+func compareBinlogPos(fileA string, posA int64, fileB string, posB int64) (int, error) {
+	seqA, err := binlogFileSeq(fileA)
+	if err != nil {
+		return 0, err
+	}
+	seqB, err := binlogFileSeq(fileB)
+	if err != nil {
+		return 0, err
+	}
+	if seqA != seqB {
+		return seqA - seqB, nil
+	}
+	if posA < posB {
+		return -1, nil
+	} else if posA > posB {
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// binlogFileSeq binlog 文件序号, 文件名格式为 prefix.000001
+func binlogFileSeq(file string) (int, error) {
+	idx := strings.LastIndex(file, ".")
+	if idx <= 0 || idx == len(file)-1 {
+		return 0, errors.Errorf("invalid binlog file name %s", file)
+	}
+	// 序号有前导 0, 不能用 cast 按八进制解析
+	seq, err := strconv.Atoi(file[idx+1:])
+	if err != nil || seq < 0 {
+		return 0, errors.Errorf("invalid binlog file name %s", file)
+	}
+	return seq, nil
+}
+
+// normalizeGtidSet 去掉 gtid_executed 里的换行和空格
+func normalizeGtidSet(gtid string) string {
+	return strings.Join(strings.Fields(gtid), "")
+}
+
+func joinGtidSet(sets ...string) string {
+	return strings.Join(cmutil.StringsRemoveEmpty(sets), ",")
+}
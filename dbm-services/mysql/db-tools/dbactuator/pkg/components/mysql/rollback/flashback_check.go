@@ -58,11 +58,15 @@ type FlashbackOpt struct {
 	// row event 解析指定 忽略 tables
 	TablesIgnore []string `json:"tables_ignore,omitempty"`
 
-	// RowsFilter max length 60000
-	RowsFilter string `json:"rows_filter"`
-	//RowsFilter            string `json:"rows_filter"`
+	// RowsFilter 行过滤, 解析 binlog 时按 row image 过滤, max length 60000. 支持 3 种格式:
+	//  表达式: @id == 1 && @name == "a", @列名 会替换成 col[列位置]
+	//  csv: 第一行是列名, 后面每一行是要闪回的列值
+	//  主键列表: 第一行是 PRIMARY, 后面每一行是一个主键值, 联合主键按主键列顺序用逗号分隔. 单列主键一行也可以写多个值
+	RowsFilter            string `json:"rows_filter"`
 	RowsEventType         string `json:"rows_event_type"`
 	ConvRowsUpdateToWrite bool   `json:"conv_rows_update_to_write"`
+	// MaxMatchedRows 解析出来的闪回行数超过该值时终止, 不导入. 0 表示不限制
+	MaxMatchedRows int `json:"max_matched_rows"`
 }
 
 // getBinlogFiles 从本地实例查找并过滤 binlog
@@ -1,12 +1,18 @@
 package rollback
 
 import (
+	"bufio"
 	errs "errors"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
 	"regexp"
+	"strings"
 	"time"
 
 	"dbm-services/common/go-pubpkg/logger"
+	"dbm-services/mysql/db-tools/dbactuator/pkg/components"
 	"dbm-services/mysql/db-tools/dbactuator/pkg/native"
 	"dbm-services/mysql/db-tools/dbactuator/pkg/util"
 	"dbm-services/mysql/db-tools/dbactuator/pkg/util/db_table_filter"
@@ -201,3 +207,74 @@ func (f *GoFlashback) checkDBRole() error {
 func (f *GoFlashback) checkDiskSpace() error {
 	return nil
 }
+
+// FlashbackRowsStat rows_filter 命中的行数
+type FlashbackRowsStat struct {
+	MatchedRows int `json:"matched_rows"`
+	// db.table: {INSERT: n, UPDATE: n, DELETE: n}, 是闪回之后的操作类型
+	Tables map[string]map[string]int `json:"tables"`
+}
+
+// verboseRowReg gomysqlbinlog -v 输出的 row image 注释, 每一行数据一条
+var verboseRowReg = regexp.MustCompile("^### (INSERT INTO|UPDATE|DELETE FROM) (`[^`]+`\\.`[^`]+`)")
+
+// CheckMatchedRows 导入前统计解析出来的行数, 输出到 <ctx>
+// 超过 max_matched_rows 时终止, 避免 rows_filter 写错导致大范围闪回
+func (f *GoFlashback) CheckMatchedRows() error {
+	if f.flashback.BinlogOpt.Verbose == 0 {
+		logger.Info("no rows_filter or max_matched_rows given, skip counting matched rows")
+		return nil
+	}
+	stat := &FlashbackRowsStat{Tables: make(map[string]map[string]int)}
+	for _, fileName := range f.flashback.BinlogFiles {
+		parsedFile := filepath.Join(f.flashback.GetBinlogParsedDir(), fileName+".sql")
+		if err := countVerboseRows(parsedFile, stat); err != nil {
+			return err
+		}
+	}
+	logger.Info("rows_filter matched %d rows: %+v", stat.MatchedRows, stat.Tables)
+	if stat.MatchedRows == 0 {
+		logger.Warn("rows_filter matched no rows, please check rows_filter and time range")
+	}
+	if err := components.PrintOutputCtx(stat); err != nil {
+		return err
+	}
+	if maxRows := f.FlashbackOpt.MaxMatchedRows; maxRows > 0 && stat.MatchedRows > maxRows {
+		return errors.Errorf("matched rows %d exceeds max_matched_rows %d, abort import",
+			stat.MatchedRows, maxRows)
+	}
+	return nil
+}
+
+func countVerboseRows(parsedFile string, stat *FlashbackRowsStat) error {
+	fh, err := os.Open(parsedFile)
+	if err != nil {
+		return errors.Wrap(err, "open parsed binlog")
+	}
+	defer fh.Close()
+
+	// BINLOG 语句可能很长, 只看每行的开头
+	reader := bufio.NewReaderSize(fh, 64*1024)
+	for {
+		line, isPrefix, err := reader.ReadLine()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return errors.Wrapf(err, "read %s", parsedFile)
+		}
+		if m := verboseRowReg.FindSubmatch(line); m != nil {
+			op := strings.Fields(string(m[1]))[0]
+			table := strings.ReplaceAll(string(m[2]), "`", "")
+			if _, ok := stat.Tables[table]; !ok {
+				stat.Tables[table] = make(map[string]int)
+			}
+			stat.Tables[table][op]++
+			stat.MatchedRows++
+		}
+		for isPrefix {
+			if _, isPrefix, err = reader.ReadLine(); err != nil {
+				return errors.Wrapf(err, "read %s", parsedFile)
+			}
+		}
+	}
+}
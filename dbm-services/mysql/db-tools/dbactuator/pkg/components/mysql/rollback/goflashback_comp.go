@@ -21,6 +21,9 @@ import (
 	"github.com/pkg/errors"
 )
 
+// rowsFilterPrimaryHeader rows_filter 主键列表格式的第一行
+const rowsFilterPrimaryHeader = "PRIMARY"
+
 // GoFlashbackComp TODO
 type GoFlashbackComp struct {
 	GeneralParam *components.GeneralParam `json:"general"`
@@ -67,15 +70,15 @@ func (f *GoFlashback) Init() error {
 		SourceBinlogFormat: "ROW", // 这里只代表 flashback 要求 ROW 模式，源实例 binlog_format 在 PreCheck 里会判断
 		ParseOnly:          true,
 		BinlogOpt: &restore.GoMySQLBinlogUtil{
-			Flashback:        true, // --flashback 模式
-			DisableLogBin:    false,
-			Idempotent:       true,
-			Autocommit:       true,
-			Databases:        f.FlashbackOpt.Databases,
-			Tables:           f.FlashbackOpt.Tables,
-			ExcludeDatabases: f.FlashbackOpt.DatabasesIgnore,
-			ExcludeTables:    f.FlashbackOpt.TablesIgnore,
-			//RowsFilter:            f.FlashbackOpt.RowsFilter,
+			Flashback:             true, // --flashback 模式
+			DisableLogBin:         false,
+			Idempotent:            true,
+			Autocommit:            true,
+			Databases:             f.FlashbackOpt.Databases,
+			Tables:                f.FlashbackOpt.Tables,
+			ExcludeDatabases:      f.FlashbackOpt.DatabasesIgnore,
+			ExcludeTables:         f.FlashbackOpt.TablesIgnore,
+			RowsFilter:            f.FlashbackOpt.RowsFilter,
 			RowsEventType:         f.FlashbackOpt.RowsEventType,
 			ConvRowsUpdateToWrite: f.FlashbackOpt.ConvRowsUpdateToWrite,
 		},
@@ -84,6 +87,10 @@ func (f *GoFlashback) Init() error {
 			MaxAllowedPacket: 1073741824,
 		},
 	}
+	if f.FlashbackOpt.RowsFilter != "" || f.FlashbackOpt.MaxMatchedRows > 0 {
+		// 输出 row image 注释, 导入前统计命中的行数
+		f.flashback.BinlogOpt.Verbose = 1
+	}
 	f.flashback.StartTime = f.TargetTime
 	if f.StopTime == "" {
 		timeNow := time.Now()
@@ -173,6 +180,14 @@ func (f *GoFlashback) PreCheck() error {
 		rowsFilterExpr := f.FlashbackOpt.RowsFilter
 		var columnNames, columnPositions []string
 		var columnInfo native.TableColumnInfo
+		if isPrimaryKeysRowsFilter(rowsFilterExpr) {
+			// 主键列表转换成 csv 格式
+			if rowsFilterExpr, err = f.primaryKeysToCsv(rowsFilterExpr); err != nil {
+				return err
+			}
+			logger.Info("rows_filter primary keys converted to csv, header: %s",
+				strings.SplitN(rowsFilterExpr, "\n", 2)[0])
+		}
 		if guessRowsFilterType(rowsFilterExpr) < 0 {
 			// 如果是 csv 格式
 			buf := bytes.NewBufferString(rowsFilterExpr)
@@ -253,6 +268,72 @@ func replaceColumnNamesWithPosition(rowsFilterExpr string, columnNames, columnPo
 	return rowsFilterExpr
 }
 
+// isPrimaryKeysRowsFilter 第一行是 PRIMARY 表示主键列表
+func isPrimaryKeysRowsFilter(rowsFilterExpr string) bool {
+	firstLine := strings.SplitN(rowsFilterExpr, "\n", 2)[0]
+	return strings.EqualFold(strings.TrimSpace(firstLine), rowsFilterPrimaryHeader)
+}
+
+// primaryKeysToCsv 把主键列表转换成以主键列名为表头的 csv
+// 所有要闪回的表主键列必须相同
+func (f *GoFlashback) primaryKeysToCsv(rowsFilterExpr string) (string, error) {
+	var primaryKey []string
+	for i, tableInfo := range f.tablesInfo {
+		dbTable := fmt.Sprintf("`%s`.`%s`", tableInfo.DbName, tableInfo.TableName)
+		uniqKeys, err := f.dbWorker.GetTableUniqueKeys(dbTable)
+		if err != nil {
+			return "", err
+		}
+		pk, ok := uniqKeys["PRIMARY"]
+		if !ok {
+			return "", errors.Errorf("table %s has no primary key", tableInfo.DbTableFullname)
+		}
+		f.tablesInfo[i].PrimaryKey = pk
+		if primaryKey == nil {
+			primaryKey = pk
+		} else if strings.Join(primaryKey, ",") != strings.Join(pk, ",") {
+			return "", errors.Errorf("primary key (%s) of table %s is not same with (%s)",
+				strings.Join(pk, ","), tableInfo.DbTableFullname, strings.Join(primaryKey, ","))
+		}
+	}
+
+	lines := strings.SplitN(rowsFilterExpr, "\n", 2)
+	if len(lines) < 2 {
+		return "", errors.New("rows_filter has no primary key values")
+	}
+	csvReader := csv.NewReader(strings.NewReader(lines[1]))
+	csvReader.FieldsPerRecord = -1
+	records, err := csvReader.ReadAll()
+	if err != nil {
+		return "", errors.WithMessage(err, "parse primary key values")
+	}
+
+	var buf bytes.Buffer
+	csvWriter := csv.NewWriter(&buf)
+	_ = csvWriter.Write(primaryKey)
+	for _, record := range records {
+		if len(primaryKey) == 1 {
+			// 单列主键, 一行可以写多个值
+			for _, v := range record {
+				if v = strings.TrimSpace(v); v != "" {
+					_ = csvWriter.Write([]string{v})
+				}
+			}
+			continue
+		}
+		if len(record) != len(primaryKey) {
+			return "", errors.Errorf("primary key value %v does not match columns (%s)",
+				record, strings.Join(primaryKey, ","))
+		}
+		_ = csvWriter.Write(record)
+	}
+	csvWriter.Flush()
+	if err = csvWriter.Error(); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
 // guessRowsFilterType 1:expr, -1:csv
 func guessRowsFilterType(rowsFilterExpr string) int {
 	if !strings.Contains(rowsFilterExpr, "\n") {
@@ -269,15 +350,14 @@ func guessRowsFilterType(rowsFilterExpr string) int {
 	return -1
 }
 
-// Start 检查版本、实例角色、 binlog 格式
+// Start 解析 binlog, 生成闪回 sql
 func (f *GoFlashback) Start() error {
-	if err := f.flashback.Start(); err != nil {
-		return err
-	}
-	if err := f.flashback.Import(); err != nil {
-		return err
-	}
-	return nil
+	return f.flashback.Start()
+}
+
+// Import 导入解析出来的闪回 sql
+func (f *GoFlashback) Import() error {
+	return f.flashback.Import()
 }
 
 // Example TODO
@@ -291,9 +371,10 @@ func (c *GoFlashbackComp) Example() interface{} {
 			BinlogDir:        "",
 			ParseConcurrency: 2,
 			FlashbackOpt: &FlashbackOpt{
-				Databases:  []string{"db1", "db2"},
-				Tables:     []string{"tb1", "tb2"},
-				RowsFilter: "col[0]==100",
+				Databases:      []string{"db1", "db2"},
+				Tables:         []string{"tb1", "tb2"},
+				RowsFilter:     "col[0]==100",
+				MaxMatchedRows: 10000,
 			},
 		},
 		GeneralParam: &components.GeneralParam{
@@ -88,6 +88,8 @@ type newDbBackupContext struct {
 	backupOpt   map[int]BackupOptions
 	ignoreDbs   map[int][]string
 	ignoreTbls  map[int][]string
+	// 本次执行是否把原备份程序移到了 -backup 目录, 回滚时用
+	legacyStaged bool
 }
 
 type BackupOptions struct {
@@ -15,6 +15,23 @@ func (c *NewDbBackupComp) StageLegacyBackup() (err error) {
 			err = fmt.Errorf("execute %s get an error:%s,%w", cmd, output, err)
 			return err
 		}
+		c.legacyStaged = true
 	}
 	return
 }
+
+// RestoreLegacyBackup 回滚 StageLegacyBackup, 用原备份程序替换部署了一半的新程序
+func (c *NewDbBackupComp) RestoreLegacyBackup() (err error) {
+	if !c.legacyStaged {
+		return nil
+	}
+	bakInstallPath := c.installPath + "-backup"
+	cmd := fmt.Sprintf("rm -rf %s; mv %s %s", c.installPath, bakInstallPath, c.installPath)
+	output, err := osutil.ExecShellCommand(false, cmd)
+	if err != nil {
+		err = fmt.Errorf("execute %s get an error:%s,%w", cmd, output, err)
+		return err
+	}
+	c.legacyStaged = false
+	return nil
+}
@@ -0,0 +1,88 @@
+/*
+ * TencentBlueKing is pleased to support the open source community by making 蓝鲸智云-DB管理系统(BlueKing-BK-DBM) available.
+ * Copyright (C) 2017-2023 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at https://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+ * an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package components
+
+const (
+	// PlanFileCreate 新建文件
+	PlanFileCreate = "create"
+	// PlanFileModify 修改文件
+	PlanFileModify = "modify"
+	// PlanFileCopy 复制文件, detail 是目标路径
+	PlanFileCopy = "copy"
+	// PlanFileDelete 删除文件
+	PlanFileDelete = "delete"
+
+	// PlanProcessRestart 重启进程
+	PlanProcessRestart = "restart"
+	// PlanProcessStart 启动进程
+	PlanProcessStart = "start"
+	// PlanProcessStop 停止进程
+	PlanProcessStop = "stop"
+)
+
+// Planner 支持 --plan 的组件实现
+// Plan 只允许读取当前状态, 把将要执行的 SQL、修改的文件、重启的进程记录到 p, 不能做任何修改
+type Planner interface {
+	Plan(p *Plan) error
+}
+
+// Plan --plan 模式下收集到的执行计划, 放在 <ctx> 里输出
+type Plan struct {
+	SQLs      []PlanSQL     `json:"sqls"`
+	Files     []PlanFile    `json:"files"`
+	Processes []PlanProcess `json:"processes"`
+}
+
+// PlanSQL 将要执行的 SQL
+type PlanSQL struct {
+	Instance string `json:"instance"`
+	Database string `json:"database,omitempty"`
+	SQL      string `json:"sql"`
+}
+
+// PlanFile 将要修改的文件
+type PlanFile struct {
+	Path   string `json:"path"`
+	Action string `json:"action"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// PlanProcess 将要启停的进程
+type PlanProcess struct {
+	Name   string `json:"name"`
+	Action string `json:"action"`
+}
+
+// NewPlan TODO
+func NewPlan() *Plan {
+	return &Plan{
+		SQLs:      []PlanSQL{},
+		Files:     []PlanFile{},
+		Processes: []PlanProcess{},
+	}
+}
+
+// AddSQL 记录在 instance 上执行的 SQL, database 可以为空
+func (p *Plan) AddSQL(instance, database string, sqls ...string) {
+	for _, s := range sqls {
+		p.SQLs = append(p.SQLs, PlanSQL{Instance: instance, Database: database, SQL: s})
+	}
+}
+
+// AddFile 记录文件改动
+func (p *Plan) AddFile(path, action, detail string) {
+	p.Files = append(p.Files, PlanFile{Path: path, Action: action, Detail: detail})
+}
+
+// AddProcess 记录进程启停
+func (p *Plan) AddProcess(name, action string) {
+	p.Processes = append(p.Processes, PlanProcess{Name: name, Action: action})
+}
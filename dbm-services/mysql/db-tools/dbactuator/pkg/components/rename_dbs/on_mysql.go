@@ -306,3 +306,41 @@ func (c *OnMySQLComponent) Example() interface{} {
 		},
 	}
 }
+
+// Plan 记录每个实例上的建目标库、导出导入非表对象、rename 表和删除源库
+func (c *OnMySQLComponent) Plan(plan *components.Plan) error {
+	for port := range c.Param.PortShardIdMap {
+		if err := c.instanceInit(port); err != nil {
+			return err
+		}
+		if err := c.instanceListTables(port); err != nil {
+			return err
+		}
+
+		instance := fmt.Sprintf("%s:%d", c.Param.Host, port)
+		for _, req := range c.Param.Requests {
+			fromDB := req.FromDatabase
+			toDB := req.ToDatabase
+			if *c.Param.HasShard {
+				fromDB = fmt.Sprintf("%s_%d", fromDB, c.Param.PortShardIdMap[port])
+				toDB = fmt.Sprintf("%s_%d", toDB, c.Param.PortShardIdMap[port])
+			}
+			plan.AddSQL(instance, "", fmt.Sprintf("CREATE DATABASE IF NOT EXISTS `%s`", toDB))
+
+			backupFilePath, err := pkg.DumpDBSchemaFilePath(c.Param.Host, port, fromDB, c.uid)
+			if err != nil {
+				return err
+			}
+			plan.AddFile(backupFilePath, components.PlanFileCreate, fmt.Sprintf("dump schema of %s", fromDB))
+			plan.AddSQL(instance, toDB, fmt.Sprintf("source %s", backupFilePath))
+
+			for _, table := range c.dbTablesMap[fromDB] {
+				plan.AddSQL(instance, "",
+					fmt.Sprintf("RENAME TABLE `%s`.`%s` TO `%s`.`%s`", fromDB, table, toDB, table))
+			}
+			plan.AddSQL(instance, "", fmt.Sprintf("DROP DATABASE IF EXISTS `%s`", fromDB))
+		}
+		_ = c.dbConn.Close()
+	}
+	return nil
+}
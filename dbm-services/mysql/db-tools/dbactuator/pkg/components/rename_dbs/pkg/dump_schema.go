@@ -19,13 +19,11 @@ func DumpDBSchema(ip string, port int, user, password, dbName string, uid string
 	}
 
 	backupCharset := "utf8" // mysql 表结构编码都是用 utf8，可能跟表数据编码不一样
-	_, backupDir, err := readBackupConfig(port)
+	outputFilePath, err := DumpDBSchemaFilePath(ip, port, dbName, uid)
 	if err != nil {
 		return "", err
 	}
-
-	outputFileName := fmt.Sprintf("%s_%d_%s_%s.sql", ip, port, dbName, uid)
-	outputFilePath := filepath.Join(backupDir, outputFileName)
+	backupDir, outputFileName := filepath.Dir(outputFilePath), filepath.Base(outputFilePath)
 
 	err = os.Remove(outputFilePath)
 	if err != nil && !os.IsNotExist(err) {
@@ -64,6 +62,15 @@ func DumpDBSchema(ip string, port int, user, password, dbName string, uid string
 	return outputFilePath, nil
 }
 
+// DumpDBSchemaFilePath DumpDBSchema 导出的文件路径
+func DumpDBSchemaFilePath(ip string, port int, dbName string, uid string) (string, error) {
+	_, backupDir, err := readBackupConfig(port)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(backupDir, fmt.Sprintf("%s_%d_%s_%s.sql", ip, port, dbName, uid)), nil
+}
+
 func readBackupConfig(port int) (string, string, error) {
 	backupConfigPath := filepath.Join(
 		cst.DbbackupGoInstallPath,
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"sort"
 	"strings"
 	"time"
 
@@ -431,3 +432,42 @@ func (c *OnMySQLComponent) Example() interface{} {
 		},
 	}
 }
+
+// Plan 记录每个实例上的建 stage 库、rename 表和重建/删除源库
+// 触发器会随 rename 删除, truncate_table 时在源表上重建
+func (c *OnMySQLComponent) Plan(plan *components.Plan) error {
+	for port := range c.Param.PortShardIdMap {
+		if err := c.instanceInit(port); err != nil {
+			return err
+		}
+		if err := c.instanceGetTarget(port); err != nil {
+			return err
+		}
+
+		instance := fmt.Sprintf("%s:%d", c.Param.Host, port)
+		var dbs []string
+		for db := range c.dbTablesMap {
+			dbs = append(dbs, db)
+		}
+		sort.Strings(dbs)
+		for _, db := range dbs {
+			stageDBName := generateStageDBName(c.Param.StageDBHeader, c.Param.FlowTimeStr, db)
+			plan.AddSQL(instance, "", fmt.Sprintf("CREATE DATABASE IF NOT EXISTS `%s`", stageDBName))
+			for _, table := range c.dbTablesMap[db] {
+				plan.AddSQL(instance, "",
+					fmt.Sprintf("RENAME TABLE `%s`.`%s` TO `%s`.`%s`", db, table, stageDBName, table))
+			}
+			switch c.Param.TruncateDataType {
+			case "truncate_table":
+				for _, table := range c.dbTablesMap[db] {
+					plan.AddSQL(instance, "", fmt.Sprintf(
+						"CREATE TABLE IF NOT EXISTS `%s`.`%s` LIKE `%s`.`%s`", db, table, stageDBName, table))
+				}
+			case "drop_database":
+				plan.AddSQL(instance, "", fmt.Sprintf("DROP DATABASE IF EXISTS `%s`", db))
+			}
+		}
+		_ = c.dbConn.Close()
+	}
+	return nil
+}
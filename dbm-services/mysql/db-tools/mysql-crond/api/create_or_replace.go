@@ -3,8 +3,11 @@ package api
 import (
 	"encoding/json"
 	"log/slog"
+	"time"
 
 	"github.com/pkg/errors"
+
+	"dbm-services/mysql/db-tools/mysql-crond/pkg/config"
 )
 
 // JobDefine TODO
@@ -17,6 +20,24 @@ type JobDefine struct {
 	Enable   bool     `json:"enable"`
 	WorkDir  string   `json:"work_dir"`
 	Overlap  bool     `json:"overlap"`
+	// Timeout 单次执行超时时间, 0 不限制
+	Timeout time.Duration `json:"timeout,omitempty"`
+	// KillSignal 超时后发给进程组的信号, 默认 SIGTERM
+	KillSignal string `json:"kill_signal,omitempty"`
+	// KillGracePeriod 发送 KillSignal 后等待多久 SIGKILL, 默认 10s
+	KillGracePeriod time.Duration `json:"kill_grace_period,omitempty"`
+	// Jitter 每次执行前随机延迟 [0, Jitter)
+	Jitter time.Duration `json:"jitter,omitempty"`
+	// Class 任务分类, 用于全机并发限制
+	Class string `json:"class,omitempty"`
+	// Resources cgroup v2 资源限制
+	Resources *config.ResourceLimits `json:"resources,omitempty"`
+	// DependsOn 这些任务成功后触发本任务
+	DependsOn []string `json:"depends_on,omitempty"`
+	// OnSuccess 本任务成功后触发这些任务
+	OnSuccess []string `json:"on_success,omitempty"`
+	// OnFailure 本任务失败或超时后触发这些任务
+	OnFailure []string `json:"on_failure,omitempty"`
 }
 
 // CreateOrReplace TODO
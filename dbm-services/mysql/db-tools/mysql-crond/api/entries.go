@@ -17,6 +17,8 @@ type SimpleEntry struct {
 	ID   int                `json:"ID"`
 	Job  config.ExternalJob `json:"Job"`
 	Next time.Time          `json:"NextTime"`
+	// Waiting 已调度但在 jitter 或等待 class 并发槽位的执行
+	Waiting []config.WaitingRun `json:"Waiting,omitempty"`
 }
 
 // Entries entries list
@@ -0,0 +1,37 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"dbm-services/mysql/db-tools/mysql-crond/pkg/config"
+)
+
+// History 查询任务执行记录, name 为空查询所有任务, since 为零值不限制开始时间
+func (m *Manager) History(name string, since time.Time) ([]*config.JobHistory, error) {
+	param := url.Values{}
+	if name != "" {
+		param.Add("name", name)
+	}
+	if !since.IsZero() {
+		param.Add("since", since.Format(time.RFC3339))
+	}
+
+	resp, err := m.do("/history", http.MethodGet, param)
+	if err != nil {
+		return nil, errors.Wrap(err, "manager call /history")
+	}
+
+	var res struct {
+		Histories []*config.JobHistory `json:"histories"`
+	}
+	err = json.Unmarshal(resp, &res)
+	if err != nil {
+		return nil, errors.Wrap(err, "manager unmarshal /history response")
+	}
+	return res.Histories, nil
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/spf13/cobra"
 
@@ -30,14 +31,30 @@ var addJobCmd = &cobra.Command{
 			jobWorkDir, _ := cmd.Flags().GetString("work_dir")
 			jobCreator, _ := cmd.Flags().GetString("creator")
 			jobEnable, _ := cmd.Flags().GetBool("enable")
+			jobTimeout, _ := cmd.Flags().GetDuration("timeout")
+			jobKillSignal, _ := cmd.Flags().GetString("kill_signal")
+			jobKillGracePeriod, _ := cmd.Flags().GetDuration("kill_grace_period")
+			jobJitter, _ := cmd.Flags().GetDuration("jitter")
+			jobClass, _ := cmd.Flags().GetString("class")
+			jobDependsOn, _ := cmd.Flags().GetStringSlice("depends_on")
+			jobOnSuccess, _ := cmd.Flags().GetStringSlice("on_success")
+			jobOnFailure, _ := cmd.Flags().GetStringSlice("on_failure")
 			jobEntry = api.JobDefine{
-				Name:     jobName,
-				Command:  jobCommand,
-				Args:     jobArgs,
-				Schedule: jobSchedule,
-				WorkDir:  jobWorkDir,
-				Creator:  jobCreator,
-				Enable:   jobEnable,
+				Name:            jobName,
+				Command:         jobCommand,
+				Args:            jobArgs,
+				Schedule:        jobSchedule,
+				WorkDir:         jobWorkDir,
+				Creator:         jobCreator,
+				Enable:          jobEnable,
+				Timeout:         jobTimeout,
+				KillSignal:      jobKillSignal,
+				KillGracePeriod: jobKillGracePeriod,
+				Jitter:          jobJitter,
+				Class:           jobClass,
+				DependsOn:       jobDependsOn,
+				OnSuccess:       jobOnSuccess,
+				OnFailure:       jobOnFailure,
 			}
 		}
 		return addEntry(cmd, jobEntry)
@@ -52,6 +69,14 @@ func init() {
 	addJobCmd.Flags().StringP("work_dir", "d", "", "work dir")
 	addJobCmd.Flags().StringP("creator", "r", "", "creator")
 	addJobCmd.Flags().BoolP("enable", "e", true, "enable")
+	addJobCmd.Flags().Duration("timeout", 0, "job execute timeout, 0 means no timeout")
+	addJobCmd.Flags().String("kill_signal", "SIGTERM", "signal send to job process group when timeout")
+	addJobCmd.Flags().Duration("kill_grace_period", 10*time.Second, "wait before SIGKILL after kill_signal sent")
+	addJobCmd.Flags().Duration("jitter", 0, "random delay in [0, jitter) before each run")
+	addJobCmd.Flags().String("class", "", "job class, host-wide concurrency limited by runtime config")
+	addJobCmd.Flags().StringSlice("depends_on", []string{}, "trigger this job after these jobs success, comma separate")
+	addJobCmd.Flags().StringSlice("on_success", []string{}, "trigger these jobs after this job success, comma separate")
+	addJobCmd.Flags().StringSlice("on_failure", []string{}, "trigger these jobs after this job failed, comma separate")
 	addJobCmd.Flags().String("body", "", "json body for api /create_or_replace")
 	addJobCmd.MarkFlagsMutuallyExclusive("command", "body")
 	addJobCmd.MarkFlagsMutuallyExclusive("name", "body")
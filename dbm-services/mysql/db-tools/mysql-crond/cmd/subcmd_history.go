@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cast"
+	"github.com/spf13/cobra"
+
+	"dbm-services/mysql/db-tools/mysql-crond/api"
+	"dbm-services/mysql/db-tools/mysql-crond/pkg/config"
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "list job execute history",
+	Long:  `list job execute history`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name, _ := cmd.Flags().GetString("name")
+		since, _ := cmd.Flags().GetDuration("since")
+		isDetail, _ := cmd.Flags().GetBool("detail")
+
+		configFile, _ := cmd.Flags().GetString("config")
+		apiUrl, err := config.GetApiUrlFromConfig(configFile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "read config error", err.Error())
+			os.Exit(1)
+		}
+		manager := api.NewManager(apiUrl)
+		histories, err := manager.History(name, time.Now().Add(-since))
+		if err != nil {
+			return err
+		}
+		printHistories(histories, isDetail)
+		return nil
+	},
+}
+
+func init() {
+	historyCmd.Flags().StringP("name", "n", "", "full job name, default all jobs")
+	historyCmd.Flags().DurationP("since", "s", 24*time.Hour, "history started in this duration")
+	historyCmd.Flags().Bool("detail", false, "show stdout and stderr")
+	rootCmd.AddCommand(historyCmd)
+}
+
+func printHistories(histories []*config.JobHistory, detail bool) {
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetAutoWrapText(false)
+	table.SetRowLine(true)
+	table.SetAutoFormatHeaders(false)
+
+	header := []string{"JobName", "Status", "StartTime", "EndTime", "ExitCode", "Error"}
+	if detail {
+		header = append(header, "Stdout", "Stderr")
+	}
+	table.SetHeader(header)
+
+	for _, h := range histories {
+		errMsg := h.Error
+		if h.Status == config.JobStatusSkipped {
+			errMsg = h.SkipReason
+		}
+		row := []string{
+			h.Name,
+			h.Status,
+			h.StartTime.Format(time.RFC3339),
+			h.EndTime.Format(time.RFC3339),
+			cast.ToString(h.ExitCode),
+			errMsg,
+		}
+		if detail {
+			row = append(row, h.Stdout, h.Stderr)
+		}
+		if h.Status == config.JobStatusSuccess {
+			table.Append(row)
+		} else {
+			colors := make([]tablewriter.Colors, len(row))
+			colors[1] = tablewriter.Colors{tablewriter.FgRedColor}
+			table.Rich(row, colors)
+		}
+	}
+	table.Render()
+}
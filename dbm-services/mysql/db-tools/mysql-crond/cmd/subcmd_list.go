@@ -26,6 +26,10 @@ var listEntriesCmd = &cobra.Command{
 		status, _ := cmd.Flags().GetString("status")
 		entries := listEntries(cmd, status)
 		isDetail, _ := cmd.Flags().GetBool("detail")
+		if isGraph, _ := cmd.Flags().GetBool("graph"); isGraph {
+			printDependencyGraph(entries)
+			return
+		}
 		printEntries(entries, isDetail)
 	},
 }
@@ -37,6 +41,9 @@ func init() {
 	listEntriesCmd.Flags().Bool("detail", false, "show more job column info")
 	_ = viper.BindPFlag("detail", listEntriesCmd.Flags().Lookup("detail"))
 
+	listEntriesCmd.Flags().Bool("graph", false, "show job dependency graph")
+	_ = viper.BindPFlag("graph", listEntriesCmd.Flags().Lookup("graph"))
+
 	listEntriesCmd.Flags().String("status", "disabled,enabled", "list jobs only this status, all,disabled,enabled")
 	_ = viper.BindPFlag("status", listEntriesCmd.Flags().Lookup("status"))
 
@@ -79,7 +86,9 @@ func printEntries(entries []*api.SimpleEntry, detail bool) {
 	table.SetAutoFormatHeaders(false)
 
 	if detail {
-		table.SetHeader([]string{"ID", "JobName", "Schedule", "Command", "Args", "WorkDir", "Enable", "NextTime"})
+		table.SetHeader([]string{
+			"ID", "JobName", "Schedule", "Command", "Args", "WorkDir", "Enable", "NextTime", "Class", "Waiting",
+		})
 		for _, e := range entries {
 			row := []string{
 				cast.ToString(e.ID),
@@ -90,12 +99,16 @@ func printEntries(entries []*api.SimpleEntry, detail bool) {
 				e.Job.WorkDir,
 				cast.ToString(e.Job.Enable),
 				e.Next.Format(time.RFC3339),
+				e.Job.Class,
+				cast.ToString(len(e.Waiting)),
 			}
 			if *(e.Job.Enable) {
 				table.Append(row)
 			} else {
 				table.Rich(row,
-					[]tablewriter.Colors{nil, nil, nil, nil, nil, nil, tablewriter.Colors{tablewriter.FgMagentaColor}, nil})
+					[]tablewriter.Colors{
+						nil, nil, nil, nil, nil, nil, tablewriter.Colors{tablewriter.FgMagentaColor}, nil, nil, nil,
+					})
 			}
 		}
 	} else {
@@ -119,3 +132,27 @@ func printEntries(entries []*api.SimpleEntry, detail bool) {
 	}
 	table.Render()
 }
+
+// printDependencyGraph 按上游任务分组展示 depends_on/on_success/on_failure 触发关系
+func printDependencyGraph(entries []*api.SimpleEntry) {
+	var jobs []*config.ExternalJob
+	for _, e := range entries {
+		j := e.Job
+		jobs = append(jobs, &j)
+	}
+	edges := config.JobEdges(jobs)
+	sort.SliceStable(edges, func(i, j int) bool {
+		return edges[i].From < edges[j].From
+	})
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetAutoWrapText(false)
+	table.SetAutoFormatHeaders(false)
+	table.SetAutoMergeCells(true)
+	table.SetRowLine(true)
+	table.SetHeader([]string{"JobName", "Relation", "Trigger"})
+	for _, e := range edges {
+		table.Append([]string{e.From, e.Relation, e.To})
+	}
+	table.Render()
+}
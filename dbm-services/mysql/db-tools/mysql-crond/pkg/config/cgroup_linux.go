@@ -0,0 +1,247 @@
+//go:build linux
+// +build linux
+
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const cgroup2SuperMagic = 0x63677270
+
+var cgroupNameReplacer = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+
+// jobCgroup 单次执行的临时 cgroup
+type jobCgroup struct {
+	path string
+	fd   *os.File
+}
+
+// setupCgroup 为本次执行创建临时 cgroup 并让 cmd 启动时直接进入
+// 任何失败都只打印警告, 返回 nil 表示不做资源限制
+func (j *ExternalJob) setupCgroup(cmd *exec.Cmd) *jobCgroup {
+	if j.Resources == nil {
+		return nil
+	}
+	cg, err := newJobCgroup(j.Name, j.Resources)
+	if err != nil {
+		slog.Warn(
+			"setup cgroup failed, run without resource limits",
+			slog.String("name", j.Name),
+			slog.String("error", err.Error()),
+		)
+		return nil
+	}
+	cmd.SysProcAttr.UseCgroupFD = true
+	cmd.SysProcAttr.CgroupFD = int(cg.fd.Fd())
+	return cg
+}
+
+func newJobCgroup(name string, r *ResourceLimits) (*jobCgroup, error) {
+	root := cgroupRoot()
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(filepath.Dir(root), &st); err != nil {
+		return nil, errors.Wrapf(err, "statfs %s", filepath.Dir(root))
+	}
+	if st.Type != cgroup2SuperMagic {
+		return nil, errors.Errorf("%s is not cgroup v2", filepath.Dir(root))
+	}
+
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, errors.Wrapf(err, "create cgroup %s", root)
+	}
+	// 父节点和 root 都要开启子树控制器, 子 cgroup 才能设置限制
+	for _, p := range []string{filepath.Dir(root), root} {
+		if err := enableControllers(p, r); err != nil {
+			return nil, err
+		}
+	}
+
+	cgPath := filepath.Join(
+		root,
+		fmt.Sprintf("%s-%d", cgroupNameReplacer.ReplaceAllString(name, "_"), time.Now().UnixNano()),
+	)
+	if err := os.Mkdir(cgPath, 0755); err != nil {
+		return nil, errors.Wrapf(err, "create cgroup %s", cgPath)
+	}
+	cg := &jobCgroup{path: cgPath}
+
+	if err := cg.applyLimits(r); err != nil {
+		cg.cleanup()
+		return nil, err
+	}
+
+	fd, err := os.Open(cgPath)
+	if err != nil {
+		cg.cleanup()
+		return nil, errors.Wrapf(err, "open cgroup %s", cgPath)
+	}
+	cg.fd = fd
+	return cg, nil
+}
+
+func enableControllers(p string, r *ResourceLimits) error {
+	var controllers []string
+	if r.CpuMax != "" {
+		controllers = append(controllers, "+cpu")
+	}
+	if r.MemoryMax != "" {
+		controllers = append(controllers, "+memory")
+	}
+	if r.hasIOLimit() {
+		controllers = append(controllers, "+io")
+	}
+	if len(controllers) == 0 {
+		return nil
+	}
+	f := filepath.Join(p, "cgroup.subtree_control")
+	if err := os.WriteFile(f, []byte(strings.Join(controllers, " ")), 0644); err != nil {
+		return errors.Wrapf(err, "enable controllers %s in %s", controllers, f)
+	}
+	return nil
+}
+
+func (c *jobCgroup) applyLimits(r *ResourceLimits) error {
+	if r.CpuMax != "" {
+		if err := c.write("cpu.max", r.CpuMax); err != nil {
+			return err
+		}
+	}
+	if r.MemoryMax != "" {
+		if err := c.write("memory.max", r.MemoryMax); err != nil {
+			return err
+		}
+	}
+	if r.hasIOLimit() {
+		dev, err := blockDevice(r.IODevicePath)
+		if err != nil {
+			return err
+		}
+		limit := dev
+		for _, kv := range []struct {
+			key   string
+			value uint64
+		}{
+			{"rbps", r.IOReadBps}, {"wbps", r.IOWriteBps}, {"riops", r.IOReadIops}, {"wiops", r.IOWriteIops},
+		} {
+			if kv.value > 0 {
+				limit += fmt.Sprintf(" %s=%d", kv.key, kv.value)
+			}
+		}
+		if err := c.write("io.max", limit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *jobCgroup) write(file string, value string) error {
+	err := os.WriteFile(filepath.Join(c.path, file), []byte(value), 0644)
+	if err != nil {
+		return errors.Wrapf(err, "write %s to %s/%s", value, c.path, file)
+	}
+	return nil
+}
+
+// blockDevice 返回 path 所在块设备的 major:minor, 分区会转换成所属磁盘
+// io.max 只能设置在整块磁盘上
+func blockDevice(path string) (string, error) {
+	var st syscall.Stat_t
+	if err := syscall.Stat(path, &st); err != nil {
+		return "", errors.Wrapf(err, "stat %s", path)
+	}
+	dev := uint64(st.Dev)
+	major := (dev>>8)&0xfff | (dev>>32)&^uint64(0xfff)
+	minor := dev&0xff | (dev>>12)&^uint64(0xff)
+	devNum := fmt.Sprintf("%d:%d", major, minor)
+
+	sysPath := filepath.Join("/sys/dev/block", devNum)
+	if _, err := os.Stat(filepath.Join(sysPath, "partition")); err == nil {
+		// /sys/dev/block/8:1 是指向 /sys/devices/.../sda/sda1 的软链, 要先解析软链再找上级目录
+		realPath, err := filepath.EvalSymlinks(sysPath)
+		if err != nil {
+			return "", errors.Wrapf(err, "resolve %s", sysPath)
+		}
+		parent, err := os.ReadFile(filepath.Join(filepath.Dir(realPath), "dev"))
+		if err != nil {
+			return "", errors.Wrapf(err, "find parent device of %s", devNum)
+		}
+		devNum = strings.TrimSpace(string(parent))
+	}
+	return devNum, nil
+}
+
+// usage 读取资源使用峰值/累计值, 读不到的项为 0
+// memory.peak 需要 5.19 以上内核
+func (c *jobCgroup) usage() *ResourceUsage {
+	u := &ResourceUsage{}
+	if content, err := os.ReadFile(filepath.Join(c.path, "memory.peak")); err == nil {
+		u.MemoryPeak, _ = strconv.ParseInt(strings.TrimSpace(string(content)), 10, 64)
+	}
+	for k, v := range c.readKeyValues("cpu.stat") {
+		if k == "usage_usec" {
+			u.CpuUsageUsec = v
+		}
+	}
+	for k, v := range c.readKeyValues("io.stat") {
+		switch k {
+		case "rbytes":
+			u.IOReadBytes += v
+		case "wbytes":
+			u.IOWriteBytes += v
+		}
+	}
+	return u
+}
+
+// readKeyValues 解析 cpu.stat, io.stat 这种 key=value 或 key value 的文件, 同名 key 累加
+func (c *jobCgroup) readKeyValues(file string) map[string]int64 {
+	res := make(map[string]int64)
+	f, err := os.Open(filepath.Join(c.path, file))
+	if err != nil {
+		return res
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && !strings.Contains(fields[1], "=") {
+			v, _ := strconv.ParseInt(fields[1], 10, 64)
+			res[fields[0]] += v
+			continue
+		}
+		for _, field := range fields {
+			kv := strings.SplitN(field, "=", 2)
+			if len(kv) != 2 {
+				continue
+			}
+			v, _ := strconv.ParseInt(kv[1], 10, 64)
+			res[kv[0]] += v
+		}
+	}
+	return res
+}
+
+func (c *jobCgroup) cleanup() {
+	if c.fd != nil {
+		_ = c.fd.Close()
+	}
+	if err := os.Remove(c.path); err != nil {
+		slog.Warn("remove cgroup", slog.String("path", c.path), slog.String("error", err.Error()))
+	}
+}
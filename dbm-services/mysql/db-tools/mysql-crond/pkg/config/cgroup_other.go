@@ -0,0 +1,26 @@
+//go:build !linux
+// +build !linux
+
+package config
+
+import (
+	"log/slog"
+	"os/exec"
+)
+
+// 这里只是为了能在非 linux 编译成功, 资源限制不生效
+
+type jobCgroup struct{}
+
+func (j *ExternalJob) setupCgroup(cmd *exec.Cmd) *jobCgroup {
+	if j.Resources != nil {
+		slog.Warn("resource limits only supported on linux", slog.String("name", j.Name))
+	}
+	return nil
+}
+
+func (c *jobCgroup) usage() *ResourceUsage {
+	return &ResourceUsage{}
+}
+
+func (c *jobCgroup) cleanup() {}
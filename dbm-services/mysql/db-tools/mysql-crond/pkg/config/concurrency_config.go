@@ -0,0 +1,11 @@
+package config
+
+import "time"
+
+// ConcurrencyConfig 按任务 class 限制全机并发
+type ConcurrencyConfig struct {
+	// Limits class => 同时运行的最大任务数, 没有配置的 class 不限制
+	Limits map[string]int `yaml:"limits"`
+	// ThrottleEventThreshold 等待并发槽位超过这个时间发送告警事件, 默认 10m
+	ThrottleEventThreshold time.Duration `yaml:"throttle_event_threshold"`
+}
@@ -29,6 +29,9 @@ var JobsUserGid int
 
 var mysqlCrondEventName = "mysql-crond-event"
 
+// mysqlCrondJobTimeoutEventName job 执行超时被杀时发送的事件, 和普通失败区分开
+var mysqlCrondJobTimeoutEventName = "mysql-crond-job-timeout"
+
 // InitConfig TODO
 func InitConfig(configFilePath string) error {
 	err := initConfig(configFilePath)
@@ -51,6 +54,12 @@ func InitConfig(configFilePath string) error {
 		return err
 	}
 
+	err = initHistory()
+	if err != nil {
+		return err
+	}
+	initConcurrency()
+
 	err = InitJobsConfig()
 	if err != nil {
 		return err
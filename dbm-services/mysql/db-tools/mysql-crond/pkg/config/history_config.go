@@ -0,0 +1,33 @@
+package config
+
+// HistoryConfig 作业执行历史的本地存储配置
+type HistoryConfig struct {
+	// FileDir 历史文件目录, 默认 pid_path
+	FileDir string `yaml:"file_dir"`
+	// MaxSizeMB 单个历史文件大小, 超过后滚动
+	MaxSizeMB int `yaml:"max_size_mb" validate:"omitempty,gte=1"`
+	// MaxBackups 保留的滚动文件个数
+	MaxBackups int `yaml:"max_backups" validate:"omitempty,gte=1"`
+	// MaxAgeDays 滚动文件保留天数
+	MaxAgeDays int `yaml:"max_age_days" validate:"omitempty,gte=1"`
+	// MaxOutputBytes 记录的 stdout/stderr 最大字节数, 超过后只保留尾部
+	MaxOutputBytes int `yaml:"max_output_bytes" validate:"omitempty,gte=1"`
+}
+
+func (c *HistoryConfig) setDefault() {
+	if c.FileDir == "" {
+		c.FileDir = RuntimeConfig.PidPath
+	}
+	if c.MaxSizeMB == 0 {
+		c.MaxSizeMB = 20
+	}
+	if c.MaxBackups == 0 {
+		c.MaxBackups = 5
+	}
+	if c.MaxAgeDays == 0 {
+		c.MaxAgeDays = 30
+	}
+	if c.MaxOutputBytes == 0 {
+		c.MaxOutputBytes = 4096
+	}
+}
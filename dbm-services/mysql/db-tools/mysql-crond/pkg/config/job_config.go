@@ -8,6 +8,7 @@ import (
 	"os/exec"
 	"path"
 	"syscall"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/robfig/cron/v3"
@@ -32,32 +33,119 @@ type ExternalJob struct {
 	Creator  string   `yaml:"creator" json:"creator" binding:"required" validate:"required"`
 	WorkDir  string   `yaml:"work_dir" json:"work_dir"`
 	Overlap  bool     `yaml:"overlap" json:"overlap"` // 是否允许作业重叠执行, 默认 false
+	// Timeout 单次执行的超时时间, 0 表示不限制
+	Timeout time.Duration `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+	// KillSignal 超时后发给整个进程组的信号, 默认 SIGTERM
+	KillSignal string `yaml:"kill_signal,omitempty" json:"kill_signal,omitempty"`
+	// KillGracePeriod 发送 KillSignal 后等待进程退出的时间, 超过后 SIGKILL. 默认 10s
+	KillGracePeriod time.Duration `yaml:"kill_grace_period,omitempty" json:"kill_grace_period,omitempty"`
+	// Jitter 每次执行前在 [0, Jitter) 内随机延迟, 错开同机相同调度的任务
+	Jitter time.Duration `yaml:"jitter,omitempty" json:"jitter,omitempty"`
+	// Class 任务分类, 同一 class 的全机并发由 runtime config concurrency.limits 限制
+	Class string `yaml:"class,omitempty" json:"class,omitempty"`
+	// Resources cgroup v2 资源限制, cgroup 不可用时不限制
+	Resources *ResourceLimits `yaml:"resources,omitempty" json:"resources,omitempty"`
+	// DependsOn 这些任务成功结束后触发本任务执行一次
+	DependsOn []string `yaml:"depends_on,omitempty" json:"depends_on,omitempty"`
+	// OnSuccess 本任务成功结束后触发这些任务执行一次
+	OnSuccess []string `yaml:"on_success,omitempty" json:"on_success,omitempty"`
+	// OnFailure 本任务失败或超时后触发这些任务执行一次
+	OnFailure []string `yaml:"on_failure,omitempty" json:"on_failure,omitempty"`
 	// JobID 这个 id 主要用于追溯哪个 cron job (如果有) 调起本 external job
 	JobID cron.EntryID `yaml:"-" json:"-"`
 	ch    chan struct{}
 }
 
-func (j *ExternalJob) run() {
-	cmd := exec.Command(j.Command, j.Args...)
-	if j.WorkDir != "" {
-		cmd.Dir = j.WorkDir
+func (j *ExternalJob) run() (status string) {
+	h := &JobHistory{
+		Name:      j.Name,
+		JobID:     j.JobID,
+		StartTime: time.Now(),
+		ExitCode:  -1,
 	}
+	defer func() {
+		h.EndTime = time.Now()
+		writeHistory(h)
+		status = h.Status
+	}()
 
 	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+	cmd := j.buildCmd(&stdout, &stderr)
+	cg := j.setupCgroup(cmd)
 
-	if currentUser.Uid != jobsUser.Uid {
-		cmd.SysProcAttr = &syscall.SysProcAttr{
-			Credential: &syscall.Credential{
-				Uid: uint32(JobsUserUid),
-				Gid: uint32(JobsUserGid),
+	// validate 已经检查过, 这里不会出错
+	sig, _ := parseKillSignal(j.KillSignal)
+
+	err := cmd.Start()
+	if err != nil && cg != nil {
+		// 老内核不支持 clone3 进入 cgroup, 去掉资源限制再试一次
+		slog.Warn(
+			"start job in cgroup failed, retry without resource limits",
+			slog.String("name", j.Name),
+			slog.String("error", err.Error()),
+		)
+		cg.cleanup()
+		cg = nil
+		stdout.Reset()
+		stderr.Reset()
+		cmd = j.buildCmd(&stdout, &stderr)
+		err = cmd.Start()
+	}
+	if err != nil {
+		h.Status = JobStatusFailed
+		h.Error = err.Error()
+		slog.Error(
+			"external job",
+			slog.String("error", err.Error()),
+			slog.String("name", j.Name),
+		)
+		err = SendEvent(
+			mysqlCrondEventName,
+			fmt.Sprintf("execute job %s failed: %s", j.Name, err.Error()),
+			map[string]interface{}{
+				"job_name": j.Name,
 			},
+		)
+		if err != nil {
+			slog.Error("send event", slog.String("error", err.Error()))
 		}
+		return
 	}
 
-	err := cmd.Run()
-	if err != nil {
+	killed, err := waitWithTimeout(cmd, j.Timeout, sig, j.KillGracePeriod)
+	if cg != nil {
+		h.ResourceUsage = cg.usage()
+		cg.cleanup()
+		j.sendResourceUsage(h.ResourceUsage)
+	}
+	h.ExitCode = cmd.ProcessState.ExitCode()
+	h.Stdout = stdout.String()
+	h.Stderr = stderr.String()
+	if killed {
+		h.Status = JobStatusTimeout
+		h.Error = fmt.Sprintf("timeout after %s, killed by %s", j.Timeout, sig.String())
+		slog.Error(
+			"external job timeout",
+			slog.String("name", j.Name),
+			slog.Duration("timeout", j.Timeout),
+			slog.String("stderr", stderr.String()),
+		)
+		err = SendEvent(
+			mysqlCrondJobTimeoutEventName,
+			fmt.Sprintf(
+				"execute job %s timeout after %s, killed by %s [%s]",
+				j.Name, j.Timeout, sig.String(), stderr.String(),
+			),
+			map[string]interface{}{
+				"job_name": j.Name,
+			},
+		)
+		if err != nil {
+			slog.Error("send event", slog.String("error", err.Error()))
+		}
+	} else if err != nil {
+		h.Status = JobStatusFailed
+		h.Error = err.Error()
 		slog.Error(
 			"external job",
 			slog.String("error", err.Error()),
@@ -78,12 +166,55 @@ func (j *ExternalJob) run() {
 			slog.Error("send event", slog.String("error", err.Error()))
 		}
 	} else {
+		h.Status = JobStatusSuccess
 		slog.Info(
 			"external job",
 			slog.String("name", j.Name),
 			slog.String("stdout", stdout.String()),
 		)
 	}
+	return
+}
+
+func (j *ExternalJob) buildCmd(stdout, stderr *bytes.Buffer) *exec.Cmd {
+	cmd := exec.Command(j.Command, j.Args...)
+	if j.WorkDir != "" {
+		cmd.Dir = j.WorkDir
+	}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	// 独立进程组, 超时时能把 job 拉起的子进程一起杀掉
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if currentUser.Uid != jobsUser.Uid {
+		cmd.SysProcAttr.Credential = &syscall.Credential{
+			Uid: uint32(JobsUserUid),
+			Gid: uint32(JobsUserGid),
+		}
+	}
+	return cmd
+}
+
+// sendResourceUsage 上报资源使用峰值
+func (j *ExternalJob) sendResourceUsage(u *ResourceUsage) {
+	for k, v := range map[string]int64{
+		"mysql_crond_job_memory_peak":    u.MemoryPeak,
+		"mysql_crond_job_cpu_usage_usec": u.CpuUsageUsec,
+		"mysql_crond_job_io_read_bytes":  u.IOReadBytes,
+		"mysql_crond_job_io_write_bytes": u.IOWriteBytes,
+	} {
+		err := SendMetrics(k, v, map[string]interface{}{"job_name": j.Name})
+		if err != nil {
+			slog.Error("send resource usage metrics", slog.String("error", err.Error()), slog.String("name", j.Name))
+		}
+	}
+}
+
+// execute 等待 class 并发槽位后执行
+func (j *ExternalJob) execute() string {
+	release := j.waitClassSlot()
+	defer release()
+	return j.run()
 }
 
 // Run TODO
@@ -94,15 +225,29 @@ func (j *ExternalJob) Run() {
 		slog.Bool("overlap", j.Overlap),
 	)
 
+	// jitter 放在 overlap 判断之前, 延迟期间不占用 overlap 槽位
+	j.waitJitter()
+
+	status := JobStatusSkipped
 	if j.Overlap {
-		j.run()
+		status = j.execute()
 	} else {
 		select {
 		case v := <-j.ch:
-			j.run()
+			status = j.execute()
 			j.ch <- v
 		default:
 			slog.Warn("skip job", slog.String("name", j.Name))
+			now := time.Now()
+			writeHistory(&JobHistory{
+				Name:       j.Name,
+				JobID:      j.JobID,
+				Status:     JobStatusSkipped,
+				StartTime:  now,
+				EndTime:    now,
+				ExitCode:   -1,
+				SkipReason: "last round still running",
+			})
 			err := SendEvent(
 				mysqlCrondEventName,
 				fmt.Sprintf("%s skipt for last round use too much time", j.Name),
@@ -116,7 +261,11 @@ func (j *ExternalJob) Run() {
 		}
 	}
 
-	slog.Info("finish job", slog.String("name", j.Name))
+	slog.Info("finish job", slog.String("name", j.Name), slog.String("status", status))
+
+	if status != JobStatusSkipped && JobFinishHook != nil {
+		JobFinishHook(j, status)
+	}
 }
 
 // SetupChannel TODO
@@ -127,7 +276,27 @@ func (j *ExternalJob) SetupChannel( /*ip string*/ ) {
 
 func (j *ExternalJob) validate() error {
 	validate := validator.New()
-	return validate.Struct(j)
+	err := validate.Struct(j)
+	if err != nil {
+		return err
+	}
+	return j.ValidateOptions()
+}
+
+// ValidateOptions 检查超时, jitter 等执行参数
+func (j *ExternalJob) ValidateOptions() error {
+	if j.Timeout < 0 || j.KillGracePeriod < 0 || j.Jitter < 0 {
+		return fmt.Errorf("job %s timeout, kill_grace_period and jitter must not be negative", j.Name)
+	}
+	if _, err := parseKillSignal(j.KillSignal); err != nil {
+		return fmt.Errorf("job %s: %s", j.Name, err.Error())
+	}
+	if j.Resources != nil {
+		if err := j.Resources.validate(); err != nil {
+			return fmt.Errorf("job %s: %s", j.Name, err.Error())
+		}
+	}
+	return nil
 }
 
 // InitJobsConfig TODO
@@ -182,5 +351,11 @@ func InitJobsConfig() error {
 			j.SetupChannel()
 		}
 	}
+
+	err = CheckJobsDependency(JobsConfig.Jobs)
+	if err != nil {
+		slog.Error("init jobs config", slog.String("error", err.Error()))
+		return err
+	}
 	return nil
 }
@@ -0,0 +1,152 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+)
+
+const (
+	// RelationDependsOn 被依赖任务成功后触发本任务
+	RelationDependsOn = "depends_on"
+	// RelationOnSuccess 本任务成功后触发目标任务
+	RelationOnSuccess = "on_success"
+	// RelationOnFailure 本任务失败(包括超时)后触发目标任务
+	RelationOnFailure = "on_failure"
+)
+
+// JobFinishHook 任务执行结束的回调, 由 crond 注册, 用于触发链式任务
+// skipped 的任务不会回调
+var JobFinishHook func(j *ExternalJob, status string)
+
+// JobEdge 任务之间的触发关系, From 结束后触发 To
+type JobEdge struct {
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Relation string `json:"relation"`
+}
+
+// ChainJob 被其他任务链式触发的一次性任务
+// 单独定义类型是为了不被当作 ExternalJob 出现在 entries 列表里
+type ChainJob struct {
+	Job           *ExternalJob
+	TriggeredBy   string
+	TriggerStatus string
+}
+
+// Run 直接复用 ExternalJob 的 Run, 共享 overlap 控制
+func (c *ChainJob) Run() {
+	slog.Info(
+		"run chain job",
+		slog.String("name", c.Job.Name),
+		slog.String("triggered by", c.TriggeredBy),
+		slog.String("trigger status", c.TriggerStatus),
+	)
+	c.Job.Run()
+}
+
+// JobEdges 根据 depends_on/on_success/on_failure 生成所有触发关系
+// depends_on 等价于被依赖任务的 on_success
+func JobEdges(jobs []*ExternalJob) (edges []*JobEdge) {
+	for _, j := range jobs {
+		for _, name := range j.OnSuccess {
+			edges = append(edges, &JobEdge{From: j.Name, To: name, Relation: RelationOnSuccess})
+		}
+		for _, name := range j.OnFailure {
+			edges = append(edges, &JobEdge{From: j.Name, To: name, Relation: RelationOnFailure})
+		}
+		for _, name := range j.DependsOn {
+			edges = append(edges, &JobEdge{From: name, To: j.Name, Relation: RelationDependsOn})
+		}
+	}
+	return edges
+}
+
+// TriggeredJobNames 任务以 status 结束后需要触发的任务名
+func TriggeredJobNames(j *ExternalJob, status string, jobs []*ExternalJob) (names []string) {
+	for _, e := range JobEdges(jobs) {
+		if e.From != j.Name || slices.Contains(names, e.To) {
+			continue
+		}
+		switch e.Relation {
+		case RelationOnSuccess, RelationDependsOn:
+			if status == JobStatusSuccess {
+				names = append(names, e.To)
+			}
+		case RelationOnFailure:
+			if status == JobStatusFailed || status == JobStatusTimeout {
+				names = append(names, e.To)
+			}
+		}
+	}
+	return names
+}
+
+// CheckJobsDependency 检查任务触发关系是否有环
+// 引用了不存在的任务只打印警告, 任务可能稍后通过 api 注册
+func CheckJobsDependency(jobs []*ExternalJob) error {
+	graph := make(map[string][]string)
+	known := make(map[string]bool)
+	for _, j := range jobs {
+		known[j.Name] = true
+	}
+	for _, e := range JobEdges(jobs) {
+		if e.From == e.To {
+			return fmt.Errorf("job %s %s itself", e.From, e.Relation)
+		}
+		if !known[e.From] || !known[e.To] {
+			slog.Warn(
+				"check jobs dependency reference unknown job",
+				slog.String("from", e.From),
+				slog.String("to", e.To),
+				slog.String("relation", e.Relation),
+			)
+		}
+		graph[e.From] = append(graph[e.From], e.To)
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int)
+	var path []string
+
+	var dfs func(name string) error
+	dfs = func(name string) error {
+		state[name] = visiting
+		path = append(path, name)
+		for _, next := range graph[name] {
+			switch state[next] {
+			case visiting:
+				idx := slices.Index(path, next)
+				cycle := append(slices.Clone(path[idx:]), next)
+				return fmt.Errorf("jobs dependency cycle found: %s", strings.Join(cycle, " -> "))
+			case unvisited:
+				if err := dfs(next); err != nil {
+					return err
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = visited
+		return nil
+	}
+
+	// 固定顺序遍历, 保证报错信息稳定
+	var names []string
+	for name := range graph {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		if state[name] == unvisited {
+			if err := dfs(name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package config
+
+import (
+	"bufio"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/robfig/cron/v3"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+const (
+	// JobStatusSuccess 执行成功
+	JobStatusSuccess = "success"
+	// JobStatusFailed 执行失败
+	JobStatusFailed = "failed"
+	// JobStatusTimeout 执行超时被杀
+	JobStatusTimeout = "timeout"
+	// JobStatusSkipped 上一轮未结束, 本轮跳过
+	JobStatusSkipped = "skipped"
+)
+
+const historyFileName = "job-history.jsonl"
+
+// historyWriter 自带滚动, Write 有锁, 一次 Write 一行能保证并发安全
+var historyWriter *lumberjack.Logger
+
+// JobHistory 一次 ExternalJob.Run 的执行记录
+type JobHistory struct {
+	Name       string       `json:"name"`
+	JobID      cron.EntryID `json:"job_id"`
+	Status     string       `json:"status"`
+	StartTime  time.Time    `json:"start_time"`
+	EndTime    time.Time    `json:"end_time"`
+	ExitCode   int          `json:"exit_code"`
+	Stdout     string       `json:"stdout,omitempty"`
+	Stderr     string       `json:"stderr,omitempty"`
+	Error      string       `json:"error,omitempty"`
+	SkipReason string       `json:"skip_reason,omitempty"`
+	// ResourceUsage 配置了资源限制且 cgroup 可用时才有
+	ResourceUsage *ResourceUsage `json:"resource_usage,omitempty"`
+}
+
+func initHistory() error {
+	if RuntimeConfig.History == nil {
+		RuntimeConfig.History = &HistoryConfig{}
+	}
+	RuntimeConfig.History.setDefault()
+
+	err := os.MkdirAll(RuntimeConfig.History.FileDir, 0755)
+	if err != nil {
+		slog.Error("init history", slog.String("error", err.Error()))
+		return err
+	}
+
+	historyWriter = &lumberjack.Logger{
+		Filename:   filepath.Join(RuntimeConfig.History.FileDir, historyFileName),
+		MaxSize:    RuntimeConfig.History.MaxSizeMB,
+		MaxBackups: RuntimeConfig.History.MaxBackups,
+		MaxAge:     RuntimeConfig.History.MaxAgeDays,
+		LocalTime:  true,
+		// 查询时要直接读滚动出去的文件, 不压缩
+		Compress: false,
+	}
+	return nil
+}
+
+// truncateOutput 只保留尾部, 错误信息一般在最后
+func truncateOutput(s string) string {
+	limit := RuntimeConfig.History.MaxOutputBytes
+	if len(s) <= limit {
+		return s
+	}
+	return "...(truncated)" + s[len(s)-limit:]
+}
+
+func writeHistory(h *JobHistory) {
+	if historyWriter == nil {
+		return
+	}
+	h.Stdout = truncateOutput(h.Stdout)
+	h.Stderr = truncateOutput(h.Stderr)
+
+	line, err := json.Marshal(h)
+	if err != nil {
+		slog.Error("write history encode", slog.String("error", err.Error()), slog.String("name", h.Name))
+		return
+	}
+	_, err = historyWriter.Write(append(line, '\n'))
+	if err != nil {
+		slog.Error("write history", slog.String("error", err.Error()), slog.String("name", h.Name))
+	}
+}
+
+// QueryHistory 按任务名和开始时间查询执行记录, name 为空表示所有任务
+// 结果按开始时间升序
+func QueryHistory(name string, since time.Time) ([]*JobHistory, error) {
+	if RuntimeConfig.History == nil {
+		return nil, errors.New("history not initialized")
+	}
+
+	// 当前文件 job-history.jsonl, 滚动文件 job-history-<timestamp>.jsonl
+	ext := filepath.Ext(historyFileName)
+	prefix := historyFileName[:len(historyFileName)-len(ext)]
+	files, err := filepath.Glob(filepath.Join(RuntimeConfig.History.FileDir, prefix+"*"+ext))
+	if err != nil {
+		return nil, errors.Wrap(err, "list history files")
+	}
+
+	var res []*JobHistory
+	for _, f := range files {
+		hs, err := readHistoryFile(f, name, since)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, hs...)
+	}
+
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].StartTime.Before(res[j].StartTime)
+	})
+	return res, nil
+}
+
+func readHistoryFile(f string, name string, since time.Time) ([]*JobHistory, error) {
+	fh, err := os.Open(f)
+	if err != nil {
+		// 查询过程中可能刚好被滚动清理掉
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "open history file %s", f)
+	}
+	defer func() {
+		_ = fh.Close()
+	}()
+
+	var res []*JobHistory
+	scanner := bufio.NewScanner(fh)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var h JobHistory
+		if err := json.Unmarshal(scanner.Bytes(), &h); err != nil {
+			// 进程被杀时可能残留半行, 跳过
+			slog.Warn("read history skip bad line", slog.String("file", f), slog.String("error", err.Error()))
+			continue
+		}
+		if name != "" && h.Name != name {
+			continue
+		}
+		if h.StartTime.Before(since) {
+			continue
+		}
+		res = append(res, &h)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrapf(err, "scan history file %s", f)
+	}
+	return res, nil
+}
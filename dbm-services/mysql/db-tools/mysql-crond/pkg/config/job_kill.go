@@ -0,0 +1,99 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// defaultKillGracePeriod 超时发送 kill_signal 后, 等待进程组退出的默认时间
+const defaultKillGracePeriod = 10 * time.Second
+
+var killSignals = map[string]syscall.Signal{
+	"SIGTERM": syscall.SIGTERM,
+	"SIGKILL": syscall.SIGKILL,
+	"SIGINT":  syscall.SIGINT,
+	"SIGQUIT": syscall.SIGQUIT,
+	"SIGHUP":  syscall.SIGHUP,
+	"SIGUSR1": syscall.SIGUSR1,
+	"SIGUSR2": syscall.SIGUSR2,
+}
+
+// parseKillSignal 空值默认 SIGTERM, 允许省略 SIG 前缀
+func parseKillSignal(name string) (syscall.Signal, error) {
+	if name == "" {
+		return syscall.SIGTERM, nil
+	}
+	name = strings.ToUpper(name)
+	if !strings.HasPrefix(name, "SIG") {
+		name = "SIG" + name
+	}
+	if sig, ok := killSignals[name]; ok {
+		return sig, nil
+	}
+	return 0, fmt.Errorf("unsupported kill signal: %s", name)
+}
+
+// waitWithTimeout 等待已经 Start 的 cmd 结束
+// 超时后向整个进程组发送 sig, 等待 grace 后仍未退出则 SIGKILL
+// 返回值 killed 表示是否因为超时被杀
+func waitWithTimeout(
+	cmd *exec.Cmd, timeout time.Duration, sig syscall.Signal, grace time.Duration,
+) (killed bool, err error) {
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	if timeout <= 0 {
+		return false, <-done
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case err = <-done:
+		return false, err
+	case <-timer.C:
+	}
+
+	pgid := cmd.Process.Pid
+	slog.Warn(
+		"external job timeout, kill process group",
+		slog.Int("pgid", pgid),
+		slog.Duration("timeout", timeout),
+		slog.String("signal", sig.String()),
+	)
+	if err := syscall.Kill(-pgid, sig); err != nil {
+		slog.Error("kill process group", slog.String("error", err.Error()), slog.Int("pgid", pgid))
+	}
+
+	if sig != syscall.SIGKILL {
+		if grace <= 0 {
+			grace = defaultKillGracePeriod
+		}
+		graceTimer := time.NewTimer(grace)
+		defer graceTimer.Stop()
+
+		select {
+		case err = <-done:
+			return true, err
+		case <-graceTimer.C:
+		}
+
+		slog.Warn(
+			"external job still alive after grace period, force kill process group",
+			slog.Int("pgid", pgid),
+			slog.Duration("grace", grace),
+		)
+		if err := syscall.Kill(-pgid, syscall.SIGKILL); err != nil {
+			slog.Error("kill process group", slog.String("error", err.Error()), slog.Int("pgid", pgid))
+		}
+	}
+
+	return true, <-done
+}
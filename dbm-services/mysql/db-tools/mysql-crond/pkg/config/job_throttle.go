@@ -0,0 +1,154 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+const (
+	// WaitPhaseJitter 正在随机延迟
+	WaitPhaseJitter = "jitter"
+	// WaitPhaseThrottle 正在等待 class 并发槽位
+	WaitPhaseThrottle = "throttle"
+)
+
+const defaultThrottleEventThreshold = 10 * time.Minute
+
+// mysqlCrondThrottleEventName 等待并发槽位太久时发送的事件
+var mysqlCrondThrottleEventName = "mysql-crond-job-throttle"
+
+// classSemaphores class => 信号量, 只在启动时根据 runtime config 初始化
+var classSemaphores map[string]chan struct{}
+
+var waitingRuns = struct {
+	sync.Mutex
+	seq  int64
+	runs map[int64]*WaitingRun
+}{runs: make(map[int64]*WaitingRun)}
+
+// WaitingRun 已经被调度但还没开始执行的任务
+type WaitingRun struct {
+	Name  string    `json:"name"`
+	Class string    `json:"class,omitempty"`
+	Phase string    `json:"phase"`
+	Since time.Time `json:"since"`
+}
+
+func initConcurrency() {
+	classSemaphores = make(map[string]chan struct{})
+	if RuntimeConfig.Concurrency == nil {
+		return
+	}
+	for class, limit := range RuntimeConfig.Concurrency.Limits {
+		if limit <= 0 {
+			slog.Warn("init concurrency ignore non-positive limit", slog.String("class", class), slog.Int("limit", limit))
+			continue
+		}
+		classSemaphores[class] = make(chan struct{}, limit)
+		slog.Info("init concurrency", slog.String("class", class), slog.Int("limit", limit))
+	}
+}
+
+func throttleEventThreshold() time.Duration {
+	if RuntimeConfig.Concurrency != nil && RuntimeConfig.Concurrency.ThrottleEventThreshold > 0 {
+		return RuntimeConfig.Concurrency.ThrottleEventThreshold
+	}
+	return defaultThrottleEventThreshold
+}
+
+func addWaiting(w *WaitingRun) int64 {
+	waitingRuns.Lock()
+	defer waitingRuns.Unlock()
+	waitingRuns.seq++
+	waitingRuns.runs[waitingRuns.seq] = w
+	return waitingRuns.seq
+}
+
+func removeWaiting(id int64) {
+	waitingRuns.Lock()
+	defer waitingRuns.Unlock()
+	delete(waitingRuns.runs, id)
+}
+
+// ListWaiting 返回任务 name 正在等待的执行, name 为空返回所有
+func ListWaiting(name string) (res []WaitingRun) {
+	waitingRuns.Lock()
+	defer waitingRuns.Unlock()
+	for _, w := range waitingRuns.runs {
+		if name == "" || w.Name == name {
+			res = append(res, *w)
+		}
+	}
+	return res
+}
+
+// waitJitter 随机延迟 jitter
+// 在获取 overlap 和 class 槽位之前执行, 延迟期间不占用槽位
+func (j *ExternalJob) waitJitter() {
+	if j.Jitter <= 0 {
+		return
+	}
+	id := addWaiting(&WaitingRun{Name: j.Name, Class: j.Class, Phase: WaitPhaseJitter, Since: time.Now()})
+	defer removeWaiting(id)
+
+	delay := time.Duration(rand.Int63n(int64(j.Jitter)))
+	slog.Info("job jitter", slog.String("name", j.Name), slog.Duration("delay", delay))
+	time.Sleep(delay)
+}
+
+// waitClassSlot 获取 class 并发槽位
+// 返回的函数用于释放槽位
+func (j *ExternalJob) waitClassSlot() (release func()) {
+	release = func() {}
+
+	sem := classSemaphores[j.Class]
+	if sem == nil {
+		return release
+	}
+
+	id := addWaiting(&WaitingRun{Name: j.Name, Class: j.Class, Phase: WaitPhaseThrottle, Since: time.Now()})
+	defer removeWaiting(id)
+
+	start := time.Now()
+	threshold := throttleEventThreshold()
+	timer := time.NewTimer(threshold)
+	defer timer.Stop()
+
+	for {
+		select {
+		case sem <- struct{}{}:
+			slog.Info(
+				"job acquire class slot",
+				slog.String("name", j.Name),
+				slog.String("class", j.Class),
+				slog.Duration("wait", time.Since(start)),
+			)
+			return func() {
+				<-sem
+			}
+		case <-timer.C:
+			slog.Warn(
+				"job wait class slot too long",
+				slog.String("name", j.Name),
+				slog.String("class", j.Class),
+				slog.Duration("wait", time.Since(start)),
+			)
+			err := SendEvent(
+				mysqlCrondThrottleEventName,
+				fmt.Sprintf(
+					"job %s wait for class %s slot more than %s, limit %d",
+					j.Name, j.Class, threshold, cap(sem),
+				),
+				map[string]interface{}{
+					"job_name": j.Name,
+				},
+			)
+			if err != nil {
+				slog.Error("send event", slog.String("error", err.Error()))
+			}
+		}
+	}
+}
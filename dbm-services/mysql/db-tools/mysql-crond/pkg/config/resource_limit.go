@@ -0,0 +1,63 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+)
+
+const defaultCgroupRoot = "/sys/fs/cgroup/mysql-crond"
+
+// CgroupConfig 任务资源限制使用的 cgroup v2 目录
+type CgroupConfig struct {
+	// Root mysql-crond 管理的 cgroup 目录, 每次执行在下面创建临时子 cgroup. 默认 /sys/fs/cgroup/mysql-crond
+	Root string `yaml:"root"`
+}
+
+// ResourceLimits 任务的 cgroup v2 资源限制, 不配置的项不限制
+type ResourceLimits struct {
+	// CpuMax 写入 cpu.max, 格式 "$MAX $PERIOD", 如 "50000 100000" 表示最多 0.5 核
+	CpuMax string `yaml:"cpu_max,omitempty" json:"cpu_max,omitempty"`
+	// MemoryMax 写入 memory.max, 如 "2G", 超过会被 OOM kill
+	MemoryMax string `yaml:"memory_max,omitempty" json:"memory_max,omitempty"`
+	// IODevicePath 数据目录, 用它所在的块设备设置 io.max, 如 /data1
+	IODevicePath string `yaml:"io_device_path,omitempty" json:"io_device_path,omitempty"`
+	IOReadBps    uint64 `yaml:"io_read_bps,omitempty" json:"io_read_bps,omitempty"`
+	IOWriteBps   uint64 `yaml:"io_write_bps,omitempty" json:"io_write_bps,omitempty"`
+	IOReadIops   uint64 `yaml:"io_read_iops,omitempty" json:"io_read_iops,omitempty"`
+	IOWriteIops  uint64 `yaml:"io_write_iops,omitempty" json:"io_write_iops,omitempty"`
+}
+
+// ResourceUsage 任务执行结束后从 cgroup 读取的资源使用
+type ResourceUsage struct {
+	MemoryPeak   int64 `json:"memory_peak"`
+	CpuUsageUsec int64 `json:"cpu_usage_usec"`
+	IOReadBytes  int64 `json:"io_read_bytes"`
+	IOWriteBytes int64 `json:"io_write_bytes"`
+}
+
+var cpuMaxPattern = regexp.MustCompile(`^(max|\d+)( \d+)?$`)
+var memoryMaxPattern = regexp.MustCompile(`^(max|\d+[KMGT]?)$`)
+
+func (r *ResourceLimits) hasIOLimit() bool {
+	return r.IOReadBps > 0 || r.IOWriteBps > 0 || r.IOReadIops > 0 || r.IOWriteIops > 0
+}
+
+func (r *ResourceLimits) validate() error {
+	if r.CpuMax != "" && !cpuMaxPattern.MatchString(r.CpuMax) {
+		return fmt.Errorf("invalid cpu_max %s", r.CpuMax)
+	}
+	if r.MemoryMax != "" && !memoryMaxPattern.MatchString(r.MemoryMax) {
+		return fmt.Errorf("invalid memory_max %s", r.MemoryMax)
+	}
+	if r.hasIOLimit() && r.IODevicePath == "" {
+		return fmt.Errorf("io_device_path required for io limit")
+	}
+	return nil
+}
+
+func cgroupRoot() string {
+	if RuntimeConfig.Cgroup != nil && RuntimeConfig.Cgroup.Root != "" {
+		return RuntimeConfig.Cgroup.Root
+	}
+	return defaultCgroupRoot
+}
@@ -33,6 +33,7 @@ func init() {
 			),
 		),
 	)
+	config.JobFinishHook = onJobFinish
 }
 
 // Stop TODO
@@ -33,7 +33,9 @@ func FindEntryByName(name string) *api.SimpleEntry {
 			continue
 		} else {
 			if j.Name == name {
-				return &api.SimpleEntry{ID: int(entry.ID), Job: *j, Next: entry.Next}
+				return &api.SimpleEntry{
+					ID: int(entry.ID), Job: *j, Next: entry.Next, Waiting: config.ListWaiting(j.Name),
+				}
 			}
 		}
 	}
@@ -77,10 +79,10 @@ func FindEntryByNameLike(nameMatch string, status string) ([]*api.SimpleEntry, e
 					continue
 				}
 			} else {
-				if nameMatch == "" {
-					entries = append(entries, &api.SimpleEntry{ID: int(entry.ID), Job: *j, Next: entry.Next})
-				} else if regMatch.MatchString(j.Name) {
-					entries = append(entries, &api.SimpleEntry{ID: int(entry.ID), Job: *j, Next: entry.Next})
+				if nameMatch == "" || regMatch.MatchString(j.Name) {
+					entries = append(entries, &api.SimpleEntry{
+						ID: int(entry.ID), Job: *j, Next: entry.Next, Waiting: config.ListWaiting(j.Name),
+					})
 				}
 			}
 		}
@@ -0,0 +1,64 @@
+package crond
+
+import (
+	"log/slog"
+	"time"
+
+	"dbm-services/mysql/db-tools/mysql-crond/pkg/config"
+	"dbm-services/mysql/db-tools/mysql-crond/pkg/schedule"
+)
+
+// ListAllJobs 活跃和停止的任务
+func ListAllJobs() (res []*config.ExternalJob) {
+	for _, entry := range ListEntry() {
+		if j, ok := entry.Job.(*config.ExternalJob); ok {
+			res = append(res, j)
+		}
+	}
+	res = append(res, ListDisabledJob()...)
+	return res
+}
+
+// onJobFinish 任务结束后按 depends_on/on_success/on_failure 触发下游任务执行一次
+// 下游任务处于停止状态时不触发
+func onJobFinish(j *config.ExternalJob, status string) {
+	for _, name := range config.TriggeredJobNames(j, status, ListAllJobs()) {
+		entry := findEntry(name)
+		if entry == nil {
+			slog.Warn(
+				"trigger chain job not found in active jobs",
+				slog.String("name", name),
+				slog.String("triggered by", j.Name),
+			)
+			continue
+		}
+		target, _ := entry.Job.(*config.ExternalJob)
+		entryID := cronJob.Schedule(
+			// OnceSchedule 要求 next 在未来
+			schedule.NewOnceSchedule(time.Now().Add(time.Second)),
+			&config.ChainJob{
+				Job:           target,
+				TriggeredBy:   j.Name,
+				TriggerStatus: status,
+			},
+		)
+		slog.Info(
+			"trigger chain job",
+			slog.String("name", name),
+			slog.String("triggered by", j.Name),
+			slog.String("status", status),
+			slog.Int("entry id", int(entryID)),
+		)
+	}
+}
+
+// checkDependencyWith 用 j 替换(或新增)同名任务后检查是否有环
+func checkDependencyWith(j *config.ExternalJob) error {
+	jobs := []*config.ExternalJob{j}
+	for _, e := range ListAllJobs() {
+		if e.Name != j.Name {
+			jobs = append(jobs, e)
+		}
+	}
+	return config.CheckJobsDependency(jobs)
+}
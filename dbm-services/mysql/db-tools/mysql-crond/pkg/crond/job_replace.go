@@ -10,7 +10,16 @@ import (
 
 // CreateOrReplace TODO
 func CreateOrReplace(j *config.ExternalJob, permanent bool) (int, error) {
-	_, err := Delete(j.Name, permanent)
+	err := checkDependencyWith(j)
+	if err != nil {
+		slog.Error("create or replace job",
+			slog.String("error", err.Error()),
+			slog.Any("job", j),
+		)
+		return 0, err
+	}
+
+	_, err = Delete(j.Name, permanent)
 
 	if err != nil {
 		var notFoundError NotFoundError
@@ -84,6 +84,31 @@ func Start(version string, buildStamp string, gitHash string, quit chan struct{}
 			}
 		},
 	)
+	r.GET(
+		"/history", func(ctx *gin.Context) {
+			name := ctx.Query("name")
+			var since time.Time
+			if s := ctx.Query("since"); s != "" {
+				var err error
+				since, err = time.Parse(time.RFC3339, s)
+				if err != nil {
+					ctx.AbortWithStatusJSON(http.StatusBadRequest,
+						api.NewErrorResp(http.StatusBadRequest, errors.Wrapf(err, "invalid since %s", s)))
+					return
+				}
+			}
+			histories, err := config.QueryHistory(name, since)
+			if err != nil {
+				ctx.AbortWithStatusJSON(http.StatusInternalServerError, api.NewErrorResp(500, err))
+				return
+			}
+			ctx.JSON(
+				http.StatusOK, gin.H{
+					"histories": histories,
+				},
+			)
+		},
+	)
 	r.POST(
 		"/disable", func(ctx *gin.Context) {
 			body := struct {
@@ -196,6 +221,11 @@ func Start(version string, buildStamp string, gitHash string, quit chan struct{}
 					api.NewErrorResp(http.StatusBadRequest, errors.Wrap(err, "request param")))
 				return
 			}
+			if err := body.Job.ValidateOptions(); err != nil {
+				ctx.AbortWithStatusJSON(http.StatusBadRequest,
+					api.NewErrorResp(http.StatusBadRequest, errors.Wrap(err, "request param")))
+				return
+			}
 			m.Lock()
 			defer func() {
 				m.Unlock()
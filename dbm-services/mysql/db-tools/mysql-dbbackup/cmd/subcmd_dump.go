@@ -167,6 +167,10 @@ func backupData(cnf *config.BackupConfig) (err error) {
 	if err = validate.GoValidateStruct(cnf.Public, false, false); err != nil {
 		return err
 	}
+	// 存储后端配置错误要在备份开始前发现，否则要等备份完成打包时才失败
+	if err = cnf.Storage.Validate(); err != nil {
+		return err
+	}
 	if cnf.Public.EncryptOpt == nil {
 		cnf.Public.EncryptOpt = &cmutil.EncryptOpt{EncryptEnable: false}
 	}
@@ -260,6 +264,11 @@ func backupData(cnf *config.BackupConfig) (err error) {
 	}
 	logger.Log.Info("report backup info: end")
 
+	// 记录增量链位置失败不影响本次备份，下一次增量会基于更早的备份
+	if err = backupexe.SavePhysicalChainState(cnf, metaInfo); err != nil {
+		logger.Log.Warn("failed to save physical chain state, err: ", err)
+	}
+
 	err = logReport.ReportBackupStatus("Success")
 	if err != nil {
 		logger.Log.Error("report success failed: ", err)
@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"dbm-services/common/go-pubpkg/cmutil"
+	"dbm-services/common/go-pubpkg/validate"
+	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/config"
+	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/src/backupexe"
+	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/src/dbareport"
+	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/src/logger"
+	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/src/verify"
+)
+
+func init() {
+	verifyCmd.Flags().StringP("config", "c", "", "config file, Verify section is required")
+	verifyCmd.Flags().StringP("load-index-file", "i", "", "backup index file to verify")
+	verifyCmd.Flags().String("load-dir", "", "untarred backup dir, overwrite LogicalLoad/PhysicalLoad.MysqlLoadDir")
+	verifyCmd.Flags().String("scratch-dir", "", "scratch mysqld work dir, overwrite Verify.ScratchDir")
+	verifyCmd.Flags().Int("scratch-port", 0, "scratch mysqld port, overwrite Verify.ScratchPort")
+	verifyCmd.Flags().String("mysqld", "", "mysqld binary for scratch instance, overwrite Verify.MysqldBin")
+	_ = viper.BindPFlag("Verify.ScratchDir", verifyCmd.Flags().Lookup("scratch-dir"))
+	_ = viper.BindPFlag("Verify.ScratchPort", verifyCmd.Flags().Lookup("scratch-port"))
+	_ = viper.BindPFlag("Verify.MysqldBin", verifyCmd.Flags().Lookup("mysqld"))
+	rootCmd.AddCommand(verifyCmd)
+}
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Restore a backup into a scratch mysqld and verify it",
+	Long: `Restore a backup into a scratch mysqld and verify it
+the backup is restored by the same way as loadbackup, then checked by: tables in backup files exist,
+Verify.SanityQueries return non-zero, Verify.ChecksumTables rows and checksum match those recorded in index when backup.
+result is written to <ReportPath>/verify/verify_result.log and <index_file without .index>.verify`,
+	Example:      `./dbbackup verify -c dbbackup.3306.ini -i /data/dbbak/xxx.index --load-dir /data/dbbak/xxx`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		cnf, indexFile, err := initVerifyCmd(cmd)
+		if err != nil {
+			return err
+		}
+		metaInfo, err := backupexe.ParseJsonFile(indexFile)
+		if err != nil {
+			return errors.WithMessagef(err, "parse index file %s", indexFile)
+		}
+		reportPath := cnf.Verify.ReportPath
+		if reportPath == "" {
+			reportPath = cnf.Public.ReportPath
+		}
+		if err = dbareport.InitReporter(reportPath); err != nil {
+			return err
+		}
+
+		report, err := verify.Run(cnf, indexFile, metaInfo)
+		if err != nil {
+			logger.Log.Error("Verify Dbbackup: Failed. ", err.Error())
+			return errors.WithMessage(err, "verify backup")
+		}
+		reportFile, err := report.Finish()
+		if err != nil {
+			return err
+		}
+		logger.Log.Infof("verify report written to %s", reportFile)
+		if !report.Passed() {
+			return errors.Errorf("verify backup %s failed: %s", metaInfo.BackupId, report.FailedChecks())
+		}
+		logger.Log.Info("Verify Success")
+		return nil
+	},
+}
+
+func initVerifyCmd(cmd *cobra.Command) (cnf *config.BackupConfig, indexFile string, err error) {
+	configFile, _ := cmd.Flags().GetString("config")
+	if configFile == "" {
+		return nil, "", errors.New("config file is required")
+	}
+	if err = cmutil.FileExistsErr(configFile); err != nil {
+		return nil, "", err
+	}
+	if err = initConfig(configFile, &cnf); err != nil {
+		return nil, "", errors.WithMessagef(err, "fail to parse %s", configFile)
+	}
+	if indexFile, _ = cmd.Flags().GetString("load-index-file"); indexFile == "" {
+		return nil, "", errors.New("--load-index-file is required")
+	}
+	if err = cmutil.FileExistsErr(indexFile); err != nil {
+		return nil, "", err
+	}
+	// loadbackup 已经把 --load-dir 绑定到 MysqlLoadDir，这里不能再绑定同一个 key
+	if loadDir, _ := cmd.Flags().GetString("load-dir"); loadDir != "" {
+		cnf.LogicalLoad.MysqlLoadDir = loadDir
+		cnf.PhysicalLoad.MysqlLoadDir = loadDir
+	}
+	if err = validate.GoValidateStruct(cnf.Verify, false, false); err != nil {
+		return nil, "", errors.WithMessage(err, "Verify config")
+	}
+	verifyLogFile := fmt.Sprintf("dbverify_%d.log", cnf.Public.MysqlPort)
+	if err = logger.InitLog(verifyLogFile); err != nil {
+		return nil, "", err
+	}
+	return cnf, indexFile, nil
+}
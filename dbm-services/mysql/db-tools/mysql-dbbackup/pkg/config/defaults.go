@@ -33,6 +33,10 @@ func SetDefaults() {
 
 	viper.SetDefault("PhysicalBackup.MaxMyisamTables", 10)
 	viper.SetDefault("PhysicalBackup.DisableSlaveMultiThread", false)
+	viper.SetDefault("PhysicalBackup.IncrementalMaxChain", 6)
+	viper.SetDefault("Verify.StartTimeout", 600)
+	viper.SetDefault("Storage.PartSizeMB", 64)
+	viper.SetDefault("Storage.PartRetry", 3)
 	viper.SetDefault("LogicalBackup.Threads", 4)
 	viper.SetDefault("LogicalBackup.InsertMode", "insert")
 	viper.SetDefault("LogicalBackup.UseMysqldump", cst.LogicalMysqldumpAuto)
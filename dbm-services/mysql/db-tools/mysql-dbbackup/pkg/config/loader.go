@@ -57,4 +57,7 @@ type PhysicalLoad struct {
 	Threads      int    `ini:"Threads"`
 	CopyBack     bool   `ini:"CopyBack"` // use copy-back or move-back
 	ExtraOpt     string `ini:"ExtraOpt"` // other xtrabackup recover options string to be appended
+	// ChainDir 恢复增量备份时，增量链上其它备份的 .index 文件和解压目录所在目录
+	// 解压目录名需要和 .index 文件名(去掉 .index)一致，默认是 IndexFilePath 所在目录
+	ChainDir string `ini:"ChainDir"`
 }
@@ -24,4 +24,9 @@ type PhysicalBackup struct {
 	// MaxMyisamTables 最大允许的 myisam tables 数量，默认 10，设置 大于 99999 表示不检查。不包含系统库
 	// 只有在 master 上进行物理备份数据时，才执行检查
 	MaxMyisamTables int `int:"MaxMyisamTables"`
+	// Incremental 开启增量物理备份，只对 innodb 有效。默认 false
+	// 开启后每个全备之后最多做 IncrementalMaxChain 个增量，增量基于上一次备份的 to_lsn
+	Incremental bool `ini:"Incremental"`
+	// IncrementalMaxChain 一个全备之后最多做几个增量，默认 6
+	IncrementalMaxChain int `ini:"IncrementalMaxChain"`
 }
@@ -0,0 +1,91 @@
+// TencentBlueKing is pleased to support the open source community by making 蓝鲸智云-DB管理系统(BlueKing-BK-DBM) available.
+// Copyright (C) 2017-2023 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package config
+
+import (
+	"github.com/pkg/errors"
+
+	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/cst"
+)
+
+// Storage the config of backup storage backend
+// Type 不为空时，打包的 tar 流直接分片上传到存储，不在 BackupDir 落地 tar 文件，也不再调用 backup_client
+type Storage struct {
+	// Type local / s3 / sftp，为空表示使用 BackupDir + backup_client
+	Type string `ini:"Type"`
+	// Prefix 对象 key 的前缀目录，比如 dbbackup/{bk_biz_id}
+	Prefix string `ini:"Prefix"`
+	// PartSizeMB 分片大小，s3 要求最小 5MB。默认 64
+	PartSizeMB int `ini:"PartSizeMB"`
+	// PartRetry 单个分片上传失败的重试次数。默认 3
+	PartRetry int `ini:"PartRetry"`
+
+	// LocalDir Type=local 时的目标目录，可以是 nfs 等挂载盘
+	LocalDir string `ini:"LocalDir"`
+
+	// S3Endpoint Type=s3 时 s3 兼容服务地址，比如 https://cos.ap-guangzhou.myqcloud.com
+	S3Endpoint  string `ini:"S3Endpoint"`
+	S3Region    string `ini:"S3Region"`
+	S3Bucket    string `ini:"S3Bucket"`
+	S3AccessKey string `ini:"S3AccessKey"`
+	S3SecretKey string `ini:"S3SecretKey"`
+	// S3PathStyle 使用 endpoint/bucket/key 的访问方式，默认 virtual-hosted bucket.endpoint/key
+	S3PathStyle bool `ini:"S3PathStyle"`
+
+	// SftpAddress Type=sftp 时的 host:port
+	SftpAddress  string `ini:"SftpAddress"`
+	SftpUser     string `ini:"SftpUser"`
+	SftpPassword string `ini:"SftpPassword"`
+	// SftpKeyFile 私钥文件，和 SftpPassword 二选一
+	SftpKeyFile string `ini:"SftpKeyFile"`
+	// SftpDir 远端目录
+	SftpDir string `ini:"SftpDir"`
+}
+
+// Enabled 是否使用存储后端
+func (s *Storage) Enabled() bool {
+	return s.Type != ""
+}
+
+// IsRemote 备份文件是否不在本机
+func (s *Storage) IsRemote() bool {
+	return s.Type == cst.StorageS3 || s.Type == cst.StorageSftp
+}
+
+// Validate check storage options
+func (s *Storage) Validate() error {
+	switch s.Type {
+	case "":
+		return nil
+	case cst.StorageLocal:
+		if s.LocalDir == "" {
+			return errors.New("Storage.LocalDir is required for local storage")
+		}
+	case cst.StorageS3:
+		if s.S3Endpoint == "" || s.S3Bucket == "" || s.S3AccessKey == "" || s.S3SecretKey == "" {
+			return errors.New("Storage.S3Endpoint, S3Bucket, S3AccessKey, S3SecretKey are required for s3 storage")
+		}
+		if s.PartSizeMB < 5 {
+			return errors.Errorf("Storage.PartSizeMB %d is less than 5 which s3 requires", s.PartSizeMB)
+		}
+	case cst.StorageSftp:
+		if s.SftpAddress == "" || s.SftpUser == "" || s.SftpDir == "" {
+			return errors.New("Storage.SftpAddress, SftpUser, SftpDir are required for sftp storage")
+		}
+		if s.SftpPassword == "" && s.SftpKeyFile == "" {
+			return errors.New("one of Storage.SftpPassword, SftpKeyFile is required for sftp storage")
+		}
+	default:
+		return errors.Errorf("unknown Storage.Type %s, should be local, s3 or sftp", s.Type)
+	}
+	if s.PartSizeMB <= 0 {
+		return errors.Errorf("Storage.PartSizeMB %d is invalid", s.PartSizeMB)
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+// TencentBlueKing is pleased to support the open source community by making 蓝鲸智云-DB管理系统(BlueKing-BK-DBM) available.
+// Copyright (C) 2017-2023 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package config
+
+// Verify the config of restore verification
+// 把备份恢复到一个临时启动的 scratch mysqld，再做检查
+type Verify struct {
+	// MysqldBin scratch 实例使用的 mysqld，版本要和备份匹配
+	MysqldBin string `ini:"MysqldBin" validate:"required,file"`
+	// ScratchDir scratch 实例的工作目录，datadir, socket, my.cnf 都放在这里，必须为空或者不存在
+	ScratchDir string `ini:"ScratchDir" validate:"required"`
+	// ScratchPort scratch 实例端口，只监听 127.0.0.1
+	ScratchPort int `ini:"ScratchPort" validate:"required"`
+	// StartTimeout scratch 实例启动超时，秒。默认 600
+	StartTimeout int `ini:"StartTimeout"`
+	// SanityQueries 分号分隔的检查 sql，返回的第一行第一列不能是 0, 空字符串或者 NULL
+	SanityQueries string `ini:"SanityQueries"`
+	// ChecksumTables 逗号分隔的 db.table，备份时把 count(*) 和 CHECKSUM TABLE 结果记录到 index，
+	// 恢复后重新计算并比对，不一致时验证失败。只配置备份期间没有写入的静态表，
+	// 备份前后各记录一次，两次不一致的表验证失败
+	ChecksumTables string `ini:"ChecksumTables"`
+	// KeepScratch 验证完不删除 scratch 目录，排查问题用
+	KeepScratch bool `ini:"KeepScratch"`
+	// ReportPath 验证报告 verify_result.log 的目录，为空则使用 Public.ReportPath
+	ReportPath string `ini:"ReportPath"`
+}
@@ -41,6 +41,22 @@ const (
 	BackupTypeAutoDataSizeGB = 400
 )
 
+const (
+	// ChainFull 物理全备，可以作为增量的起点
+	ChainFull = "full"
+	// ChainIncremental 物理增量备份，基于上一个备份的 to_lsn
+	ChainIncremental = "incremental"
+)
+
+const (
+	// StorageLocal 本地目录或者挂载盘
+	StorageLocal = "local"
+	// StorageS3 s3 兼容的对象存储
+	StorageS3 = "s3"
+	// StorageSftp sftp 服务器
+	StorageSftp = "sftp"
+)
+
 const (
 	// LogicalMysqldumpYes backup_type=logical时，使用 mysqldump
 	LogicalMysqldumpYes = "yes"
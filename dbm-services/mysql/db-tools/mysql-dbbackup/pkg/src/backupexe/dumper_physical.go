@@ -32,6 +32,8 @@ type PhysicalDumper struct {
 	backupStartTime             time.Time
 	backupEndTime               time.Time
 	tmpDisableSlaveMultiThreads bool
+	// chain 本次是全备还是增量
+	chain *dbareport.PhysicalChainInfo
 }
 
 func (p *PhysicalDumper) initConfig(mysqlVerStr string) error {
@@ -76,6 +78,7 @@ func (p *PhysicalDumper) initConfig(mysqlVerStr string) error {
 		return err
 	}
 	BackupTool = cst.ToolXtrabackup
+	p.chain = decidePhysicalChain(p.cnf)
 	return nil
 }
 
@@ -102,6 +105,12 @@ func (p *PhysicalDumper) buildArgs() []string {
 	} else {
 		args = append(args, fmt.Sprintf("--target-dir=%s", targetPath), "--backup")
 	}
+	if p.chain.IsIncremental() {
+		if strings.Compare(p.mysqlVersion, "005007000") < 0 {
+			args = append(args, "--incremental")
+		}
+		args = append(args, fmt.Sprintf("--incremental-lsn=%d", p.chain.FromLsn))
+	}
 	if strings.Compare(p.mysqlVersion, "005007000") > 0 {
 		if strings.Compare(p.mysqlVersion, "008000000") < 0 { // ver >=5.7 and ver < 8.0
 			args = append(args, "--binlog-info=ON")
@@ -286,6 +295,7 @@ func (p *PhysicalDumper) PrepareBackupMetaInfo(cnf *config.BackupConfig) (*dbare
 	var metaInfo = dbareport.IndexContent{
 		BinlogInfo: dbareport.BinlogStatusInfo{},
 	}
+	metaInfo.PhysicalChain = p.chain
 	// parse xtrabackup_info
 	if err = parseXtraInfo(qpressPath, xtrabackupInfoFileName, tmpFileName, &metaInfo); err != nil {
 		logger.Log.Warnf("xtrabackup_info file not found, use current time as BackupEndTime, err: %s", err.Error())
@@ -324,6 +334,10 @@ func (p *PhysicalDumper) PrepareBackupMetaInfo(cnf *config.BackupConfig) (*dbare
 		}
 	}
 	metaInfo.JudgeIsFullBackup(&cnf.Public)
+	if metaInfo.PhysicalChain.IsIncremental() {
+		// 增量备份需要和全备一起才能恢复
+		metaInfo.IsFullBackup = false
+	}
 	if err = os.Remove(tmpFileName); err != nil {
 		return &metaInfo, err
 	}
@@ -9,9 +9,12 @@
 package backupexe
 
 import (
+	"database/sql"
+	"slices"
 	"strings"
 
 	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/src/dbareport"
+	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/src/logger"
 	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/src/mysqlconn"
 	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/util"
 
@@ -50,6 +53,9 @@ func ExecuteBackup(cnf *config.BackupConfig) (*dbareport.IndexContent, error) {
 		return nil, envErr
 	}
 
+	// 备份的一致性时间点在备份过程中，外部拿不到这个时间点的校验值。在备份前后各记录一次，
+	// 两次一致说明备份期间表没有写入，和一致性时间点的数据相同
+	before := recordTableChecksums(cnf.Verify.ChecksumTables, db)
 	// needn't set timeout for slave
 	if strings.ToLower(cnf.Public.MysqlRole) == cst.RoleSlave || cnf.Public.BackupTimeOut == "" {
 		if err = dumper.Execute(false); err != nil {
@@ -60,10 +66,50 @@ func ExecuteBackup(cnf *config.BackupConfig) (*dbareport.IndexContent, error) {
 			return nil, err
 		}
 	}
+	checksums := staticTableChecksums(before, recordTableChecksums(cnf.Verify.ChecksumTables, db))
 	metaInfo, err := dumper.PrepareBackupMetaInfo(cnf)
 	if err != nil {
 		return nil, err
 	}
 	metaInfo.BackupTool = BackupTool
+	metaInfo.TableChecksums = checksums
 	return metaInfo, nil
 }
+
+// recordTableChecksums 记录 Verify.ChecksumTables 中表的行数和校验值，失败不影响备份
+// 没有记录的表 verify 时会检查失败
+func recordTableChecksums(tables string, db *sql.DB) []dbareport.TableChecksum {
+	var checksums []dbareport.TableChecksum
+	for _, t := range strings.Split(tables, ",") {
+		t = strings.TrimSpace(t)
+		if t == "" {
+			continue
+		}
+		rows, checksum, err := mysqlconn.ChecksumTable(t, db)
+		if err != nil {
+			logger.Log.Warnf("record checksum of %s failed: %s", t, err.Error())
+			continue
+		}
+		checksums = append(checksums, dbareport.TableChecksum{Table: t, Rows: rows, Checksum: checksum})
+	}
+	return checksums
+}
+
+// staticTableChecksums 比较备份前后的校验值，不一致的表标记为 Changed，verify 时检查失败
+// 备份后没有记录到的表不写入 index
+func staticTableChecksums(before, after []dbareport.TableChecksum) []dbareport.TableChecksum {
+	var checksums []dbareport.TableChecksum
+	for _, b := range before {
+		idx := slices.IndexFunc(after, func(a dbareport.TableChecksum) bool { return a.Table == b.Table })
+		if idx < 0 {
+			continue
+		}
+		if after[idx].Rows != b.Rows || after[idx].Checksum != b.Checksum {
+			logger.Log.Warnf("%s changed during backup, rows %d -> %d, checksum %d -> %d, it is not a static table",
+				b.Table, b.Rows, after[idx].Rows, b.Checksum, after[idx].Checksum)
+			b.Changed = true
+		}
+		checksums = append(checksums, b)
+	}
+	return checksums
+}
@@ -25,6 +25,7 @@ type PhysicalLoader struct {
 	storageEngine string
 	innodbCmd     InnodbCommand
 	isOfficial    bool
+	indexContent  *dbareport.IndexContent
 }
 
 func (p *PhysicalLoader) initConfig(indexContent *dbareport.IndexContent) error {
@@ -37,6 +38,7 @@ func (p *PhysicalLoader) initConfig(indexContent *dbareport.IndexContent) error
 		p.dbbackupHome = filepath.Dir(cmdPath)
 	}
 
+	p.indexContent = indexContent
 	p.mysqlVersion, p.isOfficial = util.VersionParser(indexContent.MysqlVersion)
 	p.storageEngine = strings.ToLower(indexContent.StorageEngine)
 	if err := p.innodbCmd.ChooseXtrabackupTool(p.mysqlVersion, p.isOfficial); err != nil {
@@ -52,18 +54,21 @@ func (p *PhysicalLoader) Execute() error {
 		logger.Log.Error(err)
 		return err
 	}
+	if p.indexContent.PhysicalChain.IsIncremental() {
+		return p.executeChain()
+	}
 
-	err := p.decompress()
+	err := p.decompress(p.cnf.PhysicalLoad.MysqlLoadDir)
 	if err != nil {
 		return err
 	}
 
-	err = p.apply()
+	err = p.apply(p.cnf.PhysicalLoad.MysqlLoadDir, "", false)
 	if err != nil {
 		return err
 	}
 
-	err = p.load()
+	err = p.load(p.cnf.PhysicalLoad.MysqlLoadDir)
 	if err != nil {
 		return err
 	}
@@ -72,7 +77,7 @@ func (p *PhysicalLoader) Execute() error {
 }
 
 // decompress todo use qpress command instead
-func (p *PhysicalLoader) decompress() error {
+func (p *PhysicalLoader) decompress(loadDir string) error {
 	binPath := filepath.Join(p.dbbackupHome, p.innodbCmd.innobackupexBin)
 
 	args := []string{
@@ -81,11 +86,11 @@ func (p *PhysicalLoader) decompress() error {
 		fmt.Sprintf("--parallel=%d", p.cnf.PhysicalLoad.Threads),
 	}
 	if strings.Compare(p.mysqlVersion, "005007000") < 0 {
-		args = append(args, p.cnf.PhysicalLoad.MysqlLoadDir)
+		args = append(args, loadDir)
 	} else {
 		args = append(args, "--remove-original")
 		args = append(args, []string{
-			fmt.Sprintf("--target-dir=%s", p.cnf.PhysicalLoad.MysqlLoadDir),
+			fmt.Sprintf("--target-dir=%s", loadDir),
 		}...)
 	}
 	if strings.Compare(p.mysqlVersion, "008000000") >= 0 && p.isOfficial {
@@ -108,7 +113,9 @@ func (p *PhysicalLoader) decompress() error {
 	return nil
 }
 
-func (p *PhysicalLoader) apply() error {
+// apply prepare 备份目录
+// incrementalDir 不为空时把增量合并到 loadDir，applyLogOnly 只 redo 不 rollback，后续还要合并增量
+func (p *PhysicalLoader) apply(loadDir string, incrementalDir string, applyLogOnly bool) error {
 	binPath := filepath.Join(p.dbbackupHome, p.innodbCmd.innobackupexBin)
 
 	args := []string{
@@ -129,15 +136,24 @@ func (p *PhysicalLoader) apply() error {
 	}
 	if strings.Compare(p.mysqlVersion, "005007000") < 0 {
 		args = append(args, "--apply-log")
+		if applyLogOnly {
+			args = append(args, "--redo-only")
+		}
 	} else {
 		args = append(args, "--prepare")
+		if applyLogOnly {
+			args = append(args, "--apply-log-only")
+		}
+	}
+	if incrementalDir != "" {
+		args = append(args, fmt.Sprintf("--incremental-dir=%s", incrementalDir))
 	}
 
 	if strings.Compare(p.mysqlVersion, "005007000") < 0 {
-		args = append(args, p.cnf.PhysicalLoad.MysqlLoadDir)
+		args = append(args, loadDir)
 	} else {
 		args = append(args, []string{
-			fmt.Sprintf("--target-dir=%s", p.cnf.PhysicalLoad.MysqlLoadDir),
+			fmt.Sprintf("--target-dir=%s", loadDir),
 		}...)
 	}
 
@@ -167,7 +183,7 @@ func (p *PhysicalLoader) apply() error {
 }
 
 // load copy-back or move-back
-func (p *PhysicalLoader) load() error {
+func (p *PhysicalLoader) load(loadDir string) error {
 	binPath := filepath.Join(p.dbbackupHome, p.innodbCmd.innobackupexBin)
 
 	_, _, err := cmutil.ExecCommand(false, "", "sed", "-i", "/^innodb_undo_directory/d",
@@ -197,10 +213,10 @@ func (p *PhysicalLoader) load() error {
 		args = append(args, fmt.Sprintf("--ibbackup=%s", filepath.Join(p.dbbackupHome, p.innodbCmd.xtrabackupBin)))
 	}
 	if strings.Compare(p.mysqlVersion, "005007000") < 0 {
-		args = append(args, p.cnf.PhysicalLoad.MysqlLoadDir)
+		args = append(args, loadDir)
 	} else {
 		args = append(args, []string{
-			fmt.Sprintf("--target-dir=%s", p.cnf.PhysicalLoad.MysqlLoadDir),
+			fmt.Sprintf("--target-dir=%s", loadDir),
 		}...)
 	}
 
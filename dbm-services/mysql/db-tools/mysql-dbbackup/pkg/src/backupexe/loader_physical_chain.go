@@ -0,0 +1,127 @@
+// TencentBlueKing is pleased to support the open source community by making 蓝鲸智云-DB管理系统(BlueKing-BK-DBM) available.
+// Copyright (C) 2017-2023 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package backupexe
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/cst"
+	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/src/dbareport"
+	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/src/logger"
+)
+
+// physicalChainLink 增量链上的一个备份
+type physicalChainLink struct {
+	indexContent *dbareport.IndexContent
+	loadDir      string
+}
+
+// resolvePhysicalChain 从最后一个增量往前找到全备，返回按恢复顺序排列的增量链
+// 链上任何一个备份缺失，或者 lsn 不连续，都拒绝恢复
+func resolvePhysicalChain(last *dbareport.IndexContent, lastDir string, chainDir string) ([]*physicalChainLink, error) {
+	indexFiles, err := filepath.Glob(filepath.Join(chainDir, "*.index"))
+	if err != nil {
+		return nil, err
+	}
+	backups := make(map[string]*physicalChainLink)
+	for _, indexFile := range indexFiles {
+		indexContent, err := ParseJsonFile(indexFile)
+		if err != nil {
+			logger.Log.Warnf("skip index file %s: %s", indexFile, err.Error())
+			continue
+		}
+		if indexContent.BackupType != cst.BackupPhysical || indexContent.PhysicalChain == nil {
+			continue
+		}
+		backups[indexContent.BackupId] = &physicalChainLink{
+			indexContent: indexContent,
+			loadDir:      filepath.Join(chainDir, strings.TrimSuffix(filepath.Base(indexFile), ".index")),
+		}
+	}
+
+	chain := []*physicalChainLink{{indexContent: last, loadDir: lastDir}}
+	cur := last
+	for cur.PhysicalChain.IsIncremental() {
+		curChain := cur.PhysicalChain
+		if len(chain) > last.PhysicalChain.ChainIndex {
+			return nil, errors.Errorf("physical chain of %s is longer than its chain_index %d",
+				last.BackupId, last.PhysicalChain.ChainIndex)
+		}
+		base, ok := backups[curChain.BaseBackupId]
+		if !ok {
+			return nil, errors.Errorf("physical chain broken: base backup %s of %s not found in %s",
+				curChain.BaseBackupId, cur.BackupId, chainDir)
+		}
+		baseChain := base.indexContent.PhysicalChain
+		if baseChain.ToLsn != curChain.FromLsn {
+			return nil, errors.Errorf("physical chain broken: %s to_lsn %d != %s from_lsn %d",
+				base.indexContent.BackupId, baseChain.ToLsn, cur.BackupId, curChain.FromLsn)
+		}
+		if baseChain.FullBackupId != curChain.FullBackupId || baseChain.ChainIndex != curChain.ChainIndex-1 {
+			return nil, errors.Errorf("physical chain broken: %s(full %s, index %d) is not the base of %s(full %s, index %d)",
+				base.indexContent.BackupId, baseChain.FullBackupId, baseChain.ChainIndex,
+				cur.BackupId, curChain.FullBackupId, curChain.ChainIndex)
+		}
+		chain = append([]*physicalChainLink{base}, chain...)
+		cur = base.indexContent
+	}
+	if cur.BackupId != last.PhysicalChain.FullBackupId {
+		return nil, errors.Errorf("physical chain broken: chain of %s ends at %s, expect full backup %s",
+			last.BackupId, cur.BackupId, last.PhysicalChain.FullBackupId)
+	}
+
+	for _, link := range chain {
+		if _, err := os.Stat(link.loadDir); err != nil {
+			return nil, errors.Wrapf(err, "backup %s load dir", link.indexContent.BackupId)
+		}
+	}
+	return chain, nil
+}
+
+// executeChain 依次合并增量到全备目录，再从全备目录恢复
+func (p *PhysicalLoader) executeChain() error {
+	chainDir := p.cnf.PhysicalLoad.ChainDir
+	if chainDir == "" {
+		chainDir = filepath.Dir(p.cnf.PhysicalLoad.IndexFilePath)
+	}
+	chain, err := resolvePhysicalChain(p.indexContent, p.cnf.PhysicalLoad.MysqlLoadDir, chainDir)
+	if err != nil {
+		logger.Log.Error("resolve physical chain failed: ", err)
+		return err
+	}
+	for _, link := range chain {
+		logger.Log.Infof("physical chain: %s %s index=%d lsn=%d-%d dir=%s",
+			link.indexContent.BackupId, link.indexContent.PhysicalChain.ChainType,
+			link.indexContent.PhysicalChain.ChainIndex, link.indexContent.PhysicalChain.FromLsn,
+			link.indexContent.PhysicalChain.ToLsn, link.loadDir)
+	}
+
+	for _, link := range chain {
+		if err = p.decompress(link.loadDir); err != nil {
+			return err
+		}
+	}
+
+	fullDir := chain[0].loadDir
+	if err = p.apply(fullDir, "", true); err != nil {
+		return err
+	}
+	for i, link := range chain[1:] {
+		applyLogOnly := i < len(chain)-2
+		if err = p.apply(fullDir, link.loadDir, applyLogOnly); err != nil {
+			return errors.WithMessagef(err, "apply incremental %s", link.indexContent.BackupId)
+		}
+	}
+
+	return p.load(fullDir)
+}
@@ -0,0 +1,135 @@
+// TencentBlueKing is pleased to support the open source community by making 蓝鲸智云-DB管理系统(BlueKing-BK-DBM) available.
+// Copyright (C) 2017-2023 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package backupexe
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/config"
+	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/cst"
+	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/src/dbareport"
+	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/src/logger"
+)
+
+// physicalChainState 本机上一次物理备份的增量链位置，下一次增量从这里开始
+type physicalChainState struct {
+	BackupId     string    `json:"backup_id"`
+	FullBackupId string    `json:"full_backup_id"`
+	ChainIndex   int       `json:"chain_index"`
+	ToLsn        uint64    `json:"to_lsn"`
+	MysqlHost    string    `json:"mysql_host"`
+	MysqlPort    int       `json:"mysql_port"`
+	BackupTime   time.Time `json:"backup_time"`
+}
+
+func physicalChainStateFile(port int) (string, error) {
+	exePath, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(exePath), fmt.Sprintf("physical_chain_%d.json", port)), nil
+}
+
+func readPhysicalChainState(port int) (*physicalChainState, error) {
+	stateFile, err := physicalChainStateFile(port)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(stateFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var state physicalChainState
+	if err = json.Unmarshal(data, &state); err != nil {
+		return nil, errors.Wrapf(err, "parse %s", stateFile)
+	}
+	return &state, nil
+}
+
+// decidePhysicalChain 决定本次物理备份是全备还是增量
+// 没有上一次备份记录，或者增量链已经达到 IncrementalMaxChain 时做全备
+func decidePhysicalChain(cnf *config.BackupConfig) *dbareport.PhysicalChainInfo {
+	full := &dbareport.PhysicalChainInfo{ChainType: cst.ChainFull}
+	if !cnf.PhysicalBackup.Incremental {
+		return full
+	}
+
+	state, err := readPhysicalChainState(cnf.Public.MysqlPort)
+	if err != nil {
+		logger.Log.Warnf("read physical chain state failed, will do full backup: %s", err.Error())
+		return full
+	}
+	if state == nil || state.ToLsn == 0 || state.FullBackupId == "" {
+		logger.Log.Info("no physical chain state found, will do full backup")
+		return full
+	}
+	if state.MysqlHost != cnf.Public.MysqlHost || state.MysqlPort != cnf.Public.MysqlPort {
+		logger.Log.Warnf("physical chain state belongs to %s:%d, will do full backup",
+			state.MysqlHost, state.MysqlPort)
+		return full
+	}
+	if state.ChainIndex >= cnf.PhysicalBackup.IncrementalMaxChain {
+		logger.Log.Infof("physical chain length %d reach IncrementalMaxChain %d, will do full backup",
+			state.ChainIndex, cnf.PhysicalBackup.IncrementalMaxChain)
+		return full
+	}
+
+	logger.Log.Infof("will do incremental backup based on %s, from lsn %d", state.BackupId, state.ToLsn)
+	return &dbareport.PhysicalChainInfo{
+		ChainType:    cst.ChainIncremental,
+		FullBackupId: state.FullBackupId,
+		BaseBackupId: state.BackupId,
+		ChainIndex:   state.ChainIndex + 1,
+		FromLsn:      state.ToLsn,
+	}
+}
+
+// SavePhysicalChainState 备份成功后记录增量链位置
+// 只有开启了增量备份才记录，关闭增量后旧的记录会在下一次开启时从全备重新开始
+func SavePhysicalChainState(cnf *config.BackupConfig, metaInfo *dbareport.IndexContent) error {
+	chain := metaInfo.PhysicalChain
+	if chain == nil || !cnf.PhysicalBackup.Incremental {
+		return nil
+	}
+	if chain.ToLsn == 0 {
+		return errors.Errorf("backup %s has no to_lsn, cannot be used as incremental base", metaInfo.BackupId)
+	}
+
+	state := physicalChainState{
+		BackupId:     metaInfo.BackupId,
+		FullBackupId: chain.FullBackupId,
+		ChainIndex:   chain.ChainIndex,
+		ToLsn:        chain.ToLsn,
+		MysqlHost:    cnf.Public.MysqlHost,
+		MysqlPort:    cnf.Public.MysqlPort,
+		BackupTime:   metaInfo.BackupConsistentTime,
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	stateFile, err := physicalChainStateFile(cnf.Public.MysqlPort)
+	if err != nil {
+		return err
+	}
+	if err = os.WriteFile(stateFile, data, 0644); err != nil {
+		return errors.Wrapf(err, "write %s", stateFile)
+	}
+	logger.Log.Infof("save physical chain state %s: %+v", stateFile, state)
+	return nil
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -220,6 +221,22 @@ func parseXtraInfo(qpress string, fileName string, tmpFileName string, metaInfo
 				return errors.Wrapf(err, "parse BackupEndTime %s", endTimeStr)
 			}
 		}
+		if metaInfo.PhysicalChain != nil {
+			if strings.HasPrefix(line, "innodb_from_lsn = ") { // innodb_from_lsn = 0
+				lsnStr := strings.TrimPrefix(line, "innodb_from_lsn = ")
+				metaInfo.PhysicalChain.FromLsn, err = strconv.ParseUint(lsnStr, 10, 64)
+				if err != nil {
+					return errors.Wrapf(err, "parse innodb_from_lsn %s", line)
+				}
+			}
+			if strings.HasPrefix(line, "innodb_to_lsn = ") { // innodb_to_lsn = 980247078
+				lsnStr := strings.TrimPrefix(line, "innodb_to_lsn = ")
+				metaInfo.PhysicalChain.ToLsn, err = strconv.ParseUint(lsnStr, 10, 64)
+				if err != nil {
+					return errors.Wrapf(err, "parse innodb_to_lsn %s", line)
+				}
+			}
+		}
 		if strings.HasPrefix(line, "binlog_pos =") { // binlog_pos = filename 'binlog20000.000353', position '181942'
 			regBinlogPos := regexp.MustCompile(`.* filename '(.+\.\d+)', position '(\d+)'`)
 			if matches := regBinlogPos.FindStringSubmatch(line); len(matches) == 3 {
@@ -17,6 +17,7 @@ import (
 	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/cst"
 	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/src/dbareport"
 	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/src/logger"
+	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/src/storage"
 	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/util"
 
 	"github.com/pkg/errors"
@@ -33,6 +34,8 @@ type PackageFile struct {
 	cnf           *config.BackupConfig
 	indexFile     *dbareport.IndexContent
 	indexFilePath string
+	// backend 不为空时，tar 流直接上传到存储后端
+	backend storage.Backend
 }
 
 // LogicalTarParts Package multiple backup files
@@ -171,14 +174,103 @@ func (p *PackageFile) LogicalTarSplit() (string, error) {
 }
 
 // SaveIndexFile add priv file and save .index to disk
+// 使用存储后端时，.priv 和 .index 也会上传，本地保留一份用于上报
 func (p *PackageFile) SaveIndexFile() (string, error) {
 	p.indexFile.AddPrivFileItem(p.dstDir)
-	if indexFilePath, err := p.indexFile.SaveIndexContent(&p.cnf.Public); err != nil {
+	if p.backend != nil {
+		for _, f := range p.indexFile.FileList {
+			if f.FileType != cst.FilePriv {
+				continue
+			}
+			obj, err := storage.UploadFile(p.backend, filepath.Join(p.cnf.Public.BackupDir, f.FileName),
+				p.cnf.Storage.Prefix, p.cnf.Storage.PartSizeMB*1024*1024, p.cnf.Storage.PartRetry)
+			if err != nil {
+				return "", err
+			}
+			f.Location = obj.Location
+		}
+	}
+	indexFilePath, err := p.indexFile.SaveIndexContent(&p.cnf.Public)
+	if err != nil {
 		return "", err
-	} else {
-		p.indexFilePath = indexFilePath
-		return p.indexFilePath, nil
 	}
+	p.indexFilePath = indexFilePath
+	if p.backend != nil {
+		if _, err = storage.UploadFile(p.backend, indexFilePath, p.cnf.Storage.Prefix,
+			p.cnf.Storage.PartSizeMB*1024*1024, p.cnf.Storage.PartRetry); err != nil {
+			return "", err
+		}
+	}
+	return p.indexFilePath, nil
+}
+
+// TarToStorage tar(encrypt) 流直接分片上传到存储后端，不在本地生成 tar 文件
+// 按 TarSizeThreshold 切分成多个对象，命名和 PhysicalTarSplit 一致: {target}.tar.part_N
+// will save index meta info to file
+func (p *PackageFile) TarToStorage() (string, error) {
+	logger.Log.Infof("Tarball Package: src dir %s to storage %s, iolimit %d MB/s",
+		p.srcDir, p.backend.Type(), p.cnf.Public.IOLimitMBPerSec)
+
+	var tarUtil = util.TarWriter{IOLimitMB: p.cnf.Public.IOLimitMBPerSec}
+	var dstTarName = fmt.Sprintf(`%s.tar`, filepath.Base(p.dstDir))
+	if p.cnf.Public.EncryptOpt.EncryptEnable {
+		logger.Log.Infof("tar file encrypt enabled for port: %d", p.cnf.Public.MysqlPort)
+		tarUtil.Encrypt = true
+		tarUtil.EncryptTool = p.cnf.Public.EncryptOpt.GetEncryptTool()
+		dstTarName = fmt.Sprintf(`%s.tar.%s`, filepath.Base(p.dstDir), tarUtil.EncryptTool.DefaultSuffix())
+	}
+	splitWriter := storage.NewSplitWriter(p.backend, p.cnf.Storage.Prefix, dstTarName,
+		int64(p.cnf.Public.TarSizeThreshold*1024*1024), p.cnf.Storage.PartSizeMB*1024*1024, p.cnf.Storage.PartRetry)
+	if err := tarUtil.NewStream(splitWriter); err != nil {
+		return "", err
+	}
+
+	var backupTotalFileSize uint64
+	// The files are walked in lexical order
+	walkErr := filepath.Walk(p.srcDir, func(filename string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.Join(p.cnf.Public.TargetName(), strings.TrimPrefix(filename, p.srcDir))
+		isFile, written, err := tarUtil.WriteTar(header, filename)
+		if err != nil {
+			return err
+		} else if !isFile {
+			return nil
+		}
+		backupTotalFileSize += uint64(written)
+		// 源文件删除时数据可能还在分片 buffer 里没上传，所以任何分片上传失败都是整个备份失败
+		if err = os.Remove(filename); err != nil {
+			logger.Log.Error("failed to remove file while taring, err:", err)
+		}
+		return nil
+	})
+	if walkErr != nil {
+		logger.Log.Error("walk dir, err: ", walkErr)
+		_ = splitWriter.Abort()
+		_ = tarUtil.Close()
+		return "", walkErr
+	}
+	if err := tarUtil.Close(); err != nil {
+		_ = splitWriter.Abort()
+		return "", errors.WithMessage(err, "upload tar stream")
+	}
+	for _, obj := range splitWriter.Objects() {
+		p.indexFile.FileList = append(p.indexFile.FileList, &dbareport.TarFileItem{
+			FileName: obj.Name, FileType: cst.FilePart, FileSize: obj.Size, Location: obj.Location})
+	}
+	p.indexFile.TotalFilesize = backupTotalFileSize
+
+	logger.Log.Infof("old srcDir removing io is limited to: %d MB/s", p.cnf.Public.IOLimitMBPerSec)
+	if err := cmutil.TruncateDir(p.srcDir, p.cnf.Public.IOLimitMBPerSec); err != nil {
+		logger.Log.Error("failed to remove useless backup files")
+		return "", err
+	}
+	return "", nil
 }
 
 // PhysicalTarSplit Firstly, put all backup files into the tar file. Secondly, split the tar file to multiple parts
@@ -392,12 +484,25 @@ func PackageBackupFiles(cnf *config.BackupConfig, metaInfo *dbareport.IndexConte
 		indexFile:  metaInfo,
 	}
 	logger.Log.Infof("Index BackupMetaInfo:%+v", metaInfo)
+	if cnf.Storage.Enabled() {
+		if packageFile.backend, err = storage.NewBackend(&cnf.Storage); err != nil {
+			return "", err
+		}
+		defer func() {
+			_ = packageFile.backend.Close()
+		}()
+		metaInfo.StorageType = cnf.Storage.Type
+	}
 	if cnf.Public.IfBackupGrantOnly() {
 		return packageFile.SaveIndexFile()
 	}
 
 	// package files, and produce the index file at the same time
-	if strings.ToLower(cnf.Public.BackupType) == cst.BackupLogical {
+	if packageFile.backend != nil {
+		if indexFilePath, err = packageFile.TarToStorage(); err != nil {
+			return "", err
+		}
+	} else if strings.ToLower(cnf.Public.BackupType) == cst.BackupLogical {
 		if cnf.LogicalBackup.UseMysqldump == cst.LogicalMysqldumpYes {
 			if indexFilePath, err = packageFile.LogicalTarSplit(); err != nil {
 				return "", err
@@ -271,7 +271,8 @@ func (r *BackupLogReport) ReportToLocalBackup(indexFilePath string, metaInfo *In
 	fileList := make([]*TarFileItem, 0)
 	for _, tf := range metaInfo.FileList {
 		fileList = append(fileList, &TarFileItem{
-			FileName: tf.FileName, FileSize: tf.FileSize, FileType: tf.FileType, TaskId: tf.TaskId})
+			FileName: tf.FileName, FileSize: tf.FileSize, FileType: tf.FileType, TaskId: tf.TaskId,
+			Location: tf.Location})
 	}
 	fileListRaw, _ := json.Marshal(fileList)
 
@@ -355,7 +356,10 @@ func (r *BackupLogReport) ReportBackupResult(indexFilePath string, index, upload
 			filePath := filepath.Join(r.cfg.Public.BackupDir, f.FileName)
 			var taskId string
 			var err22 error
-			if taskId, err22 = r.ExecuteBackupClient(filePath); err22 != nil {
+			if r.cfg.Storage.Enabled() {
+				// 已经上传到存储后端，不需要 backup_client
+				taskId = "-1"
+			} else if taskId, err22 = r.ExecuteBackupClient(filePath); err22 != nil {
 				err2 = errs.Join(err2, err22)
 				taskId = ""
 			}
@@ -378,7 +382,8 @@ func (r *BackupLogReport) ReportBackupResult(indexFilePath string, index, upload
 	fileListSimple := make([]*TarFileItem, 0)
 	for _, tf := range metaInfo.FileList {
 		fileListSimple = append(fileListSimple, &TarFileItem{
-			FileName: tf.FileName, FileSize: tf.FileSize, FileType: tf.FileType, TaskId: tf.TaskId})
+			FileName: tf.FileName, FileSize: tf.FileSize, FileType: tf.FileType, TaskId: tf.TaskId,
+			Location: tf.Location})
 	}
 	metaInfo.FileList = fileListSimple
 	Report().Result.Println(metaInfo)
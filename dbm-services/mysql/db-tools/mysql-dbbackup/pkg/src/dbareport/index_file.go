@@ -73,6 +73,9 @@ type IndexContent struct {
 
 	FileList []*TarFileItem `json:"file_list" db:"file_list"`
 
+	// TableChecksums Verify.ChecksumTables 中的表在备份时的行数和校验值，verify 恢复后用来比对
+	TableChecksums []TableChecksum `json:"table_checksums,omitempty" db:"table_checksums"`
+
 	reData       *regexp.Regexp
 	reSchema     *regexp.Regexp
 	reSchemaDb   *regexp.Regexp
@@ -82,6 +85,16 @@ type IndexContent struct {
 	reTar        *regexp.Regexp
 }
 
+// TableChecksum 表的行数和 CHECKSUM TABLE 结果
+type TableChecksum struct {
+	// Table db.table
+	Table    string `json:"table"`
+	Rows     int64  `json:"rows"`
+	Checksum int64  `json:"checksum"`
+	// Changed 备份前后校验值不一致，表在备份期间有写入
+	Changed bool `json:"changed,omitempty"`
+}
+
 // TarFileItem 备份打包文件信息
 type TarFileItem struct {
 	FileName      string   `json:"file_name"`
@@ -91,6 +104,8 @@ type TarFileItem struct {
 	ContainTables []string `json:"contain_tables"`
 	// TaskId backup task_id
 	TaskId string `json:"task_id"`
+	// Location 使用存储后端时，文件在远端的位置，比如 s3://bucket/key
+	Location string `json:"location,omitempty"`
 }
 
 func (f *TarFileItem) GetDBTables() {
@@ -126,6 +141,30 @@ type ExtraFields struct {
 	BinlogRowImage string `json:"binlog_row_image" db:"binlog_row_image"`
 	// BackupTool command name xtrabackup / mydumper / mysqldump
 	BackupTool string `json:"backup_tool" db:"backup_tool"`
+	// StorageType 备份文件上传到的存储后端 local / s3 / sftp，为空表示在 BackupDir
+	StorageType string `json:"storage_type,omitempty" db:"storage_type"`
+	// PhysicalChain 物理备份(innodb)的增量链信息
+	PhysicalChain *PhysicalChainInfo `json:"physical_chain,omitempty" db:"physical_chain"`
+}
+
+// PhysicalChainInfo 物理备份的增量链信息
+// 全备也会记录 to_lsn，作为下一个增量的起点
+type PhysicalChainInfo struct {
+	// ChainType full / incremental
+	ChainType string `json:"chain_type"`
+	// FullBackupId 增量链起点全备的 backup_id，全备是自己
+	FullBackupId string `json:"full_backup_id"`
+	// BaseBackupId 增量基于的上一个备份 backup_id，全备为空
+	BaseBackupId string `json:"base_backup_id"`
+	// ChainIndex 在增量链中的序号，全备为 0
+	ChainIndex int    `json:"chain_index"`
+	FromLsn    uint64 `json:"from_lsn"`
+	ToLsn      uint64 `json:"to_lsn"`
+}
+
+// IsIncremental 是否增量备份
+func (c *PhysicalChainInfo) IsIncremental() bool {
+	return c != nil && c.ChainType == cst.ChainIncremental
 }
 
 // JudgeIsFullBackup 是否是带所有数据的全备
@@ -189,6 +228,9 @@ func (r *BackupLogReport) BuildMetaInfo(cnf *config.Public, metaInfo *IndexConte
 	// BeginTime, EndTime, ConsistentTime, BinlogInfo,storageEngineStr build in PrepareBackupMetaInfo
 
 	metaInfo.BackupId = r.BackupId
+	if metaInfo.PhysicalChain != nil && metaInfo.PhysicalChain.FullBackupId == "" {
+		metaInfo.PhysicalChain.FullBackupId = r.BackupId
+	}
 	if r.EncryptedKey != "" {
 		metaInfo.EncryptEnable = true
 	}
@@ -24,6 +24,7 @@ type ReportLogger struct {
 	Result reportlog.Reporter
 	Files  reportlog.Reporter
 	Status reportlog.Reporter
+	Verify reportlog.Reporter
 }
 
 // reportLogger 全局可调用的 log reporter
@@ -35,6 +36,7 @@ func InitReporter(reportDir string) (err error) {
 		logger.Log.Warnf("do not report backup result to reportDir=%s", reportDir)
 		reportLogger.Files = reportlog.Reporter{Disable: true}
 		reportLogger.Result = reportlog.Reporter{Disable: true}
+		reportLogger.Verify = reportlog.Reporter{Disable: true}
 		return nil
 	}
 	reportLogger, err = NewLogReporter(reportDir)
@@ -70,9 +72,15 @@ func NewLogReporter(reportDir string) (*ReportLogger, error) {
 		//statusReport.Disable = true
 		return nil, errors.WithMessage(err, "fail to init statusReporter")
 	}
+	verifyReport, err := reportlog.NewReporter(filepath.Join(reportDir, "verify"), "verify_result.log", &logOpt)
+	if err != nil {
+		logger.Log.Warn("fail to init verifyReporter:", err.Error())
+		return nil, errors.WithMessage(err, "fail to init verifyReporter")
+	}
 	return &ReportLogger{
 		Result: *resultReport,
 		Files:  *filesReport,
 		Status: *statusReport,
+		Verify: *verifyReport,
 	}, nil
 }
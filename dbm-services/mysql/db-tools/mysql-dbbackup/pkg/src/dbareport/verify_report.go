@@ -0,0 +1,125 @@
+/*
+ * TencentBlueKing is pleased to support the open source community by making 蓝鲸智云-DB管理系统(BlueKing-BK-DBM) available.
+ * Copyright (C) 2017-2023 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at https://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+ * an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package dbareport
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"time"
+
+	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/src/logger"
+)
+
+const (
+	// VerifyStatusPass 恢复验证通过
+	VerifyStatusPass = "pass"
+	// VerifyStatusFail 恢复验证失败
+	VerifyStatusFail = "fail"
+)
+
+// VerifyCheck 一项验证结果
+type VerifyCheck struct {
+	Name   string `json:"name"`
+	Passed bool   `json:"passed"`
+	Detail string `json:"detail"`
+}
+
+// VerifyReport 备份恢复验证报告
+type VerifyReport struct {
+	BackupId       string    `json:"backup_id"`
+	BackupType     string    `json:"backup_type"`
+	BackupHost     string    `json:"backup_host"`
+	BackupPort     int       `json:"backup_port"`
+	ClusterId      int       `json:"cluster_id"`
+	ClusterAddress string    `json:"cluster_address"`
+	BkBizId        int       `json:"bk_biz_id"`
+	IndexFile      string    `json:"index_file"`
+	VerifyHost     string    `json:"verify_host"`
+	VerifyBegin    time.Time `json:"verify_begin_time"`
+	VerifyEnd      time.Time `json:"verify_end_time"`
+	// VerifyStatus pass / fail
+	VerifyStatus string         `json:"verify_status"`
+	Checks       []*VerifyCheck `json:"checks"`
+}
+
+// NewVerifyReport 从备份的 index 信息生成报告
+func NewVerifyReport(indexFile string, metaInfo *IndexContent) *VerifyReport {
+	hostname, _ := os.Hostname()
+	return &VerifyReport{
+		BackupId:       metaInfo.BackupId,
+		BackupType:     metaInfo.BackupType,
+		BackupHost:     metaInfo.BackupHost,
+		BackupPort:     metaInfo.BackupPort,
+		ClusterId:      metaInfo.ClusterId,
+		ClusterAddress: metaInfo.ClusterAddress,
+		BkBizId:        metaInfo.BkBizId,
+		IndexFile:      indexFile,
+		VerifyHost:     hostname,
+		VerifyBegin:    time.Now(),
+	}
+}
+
+// AddCheck 记录一项验证结果
+func (r *VerifyReport) AddCheck(name string, passed bool, detail string) {
+	if passed {
+		logger.Log.Infof("verify check %s passed: %s", name, detail)
+	} else {
+		logger.Log.Errorf("verify check %s failed: %s", name, detail)
+	}
+	r.Checks = append(r.Checks, &VerifyCheck{Name: name, Passed: passed, Detail: detail})
+}
+
+// Passed 所有检查都通过
+func (r *VerifyReport) Passed() bool {
+	if len(r.Checks) == 0 {
+		return false
+	}
+	for _, c := range r.Checks {
+		if !c.Passed {
+			return false
+		}
+	}
+	return true
+}
+
+// FailedChecks 没通过的检查名
+func (r *VerifyReport) FailedChecks() string {
+	var names []string
+	for _, c := range r.Checks {
+		if !c.Passed {
+			names = append(names, c.Name)
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// Finish 结束验证，写 verify_result.log 和 index 同目录的 .verify 文件
+func (r *VerifyReport) Finish() (string, error) {
+	r.VerifyEnd = time.Now()
+	if r.Passed() {
+		r.VerifyStatus = VerifyStatusPass
+	} else {
+		r.VerifyStatus = VerifyStatusFail
+	}
+	Report().Verify.Println(r)
+
+	content, err := json.Marshal(r)
+	if err != nil {
+		return "", err
+	}
+	reportFile := strings.TrimSuffix(r.IndexFile, ".index") + ".verify"
+	if err = os.WriteFile(reportFile, content, 0644); err != nil {
+		logger.Log.Error("failed to write verify report ", reportFile, err)
+		return "", err
+	}
+	return reportFile, nil
+}
@@ -106,6 +106,27 @@ func MysqlSingleColumnQuery(queryStr string, dbh *sql.DB) ([]string, error) {
 	return resArray, nil
 }
 
+// ChecksumTable 返回表的行数和 CHECKSUM TABLE 结果，table 格式为 db.table
+func ChecksumTable(table string, dbh *sql.DB) (rows int64, checksum int64, err error) {
+	parts := strings.SplitN(table, ".", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return 0, 0, errors.Errorf("%s: table should be db.table", table)
+	}
+	quoted := fmt.Sprintf("`%s`.`%s`", parts[0], parts[1])
+	if err = dbh.QueryRow("SELECT COUNT(*) FROM " + quoted).Scan(&rows); err != nil {
+		return 0, 0, errors.WithMessagef(err, "count %s", table)
+	}
+	var tbName string
+	var sum sql.NullInt64
+	if err = dbh.QueryRow("CHECKSUM TABLE "+quoted).Scan(&tbName, &sum); err != nil {
+		return 0, 0, errors.WithMessagef(err, "checksum %s", table)
+	}
+	if !sum.Valid {
+		return 0, 0, errors.Errorf("checksum %s: got NULL", table)
+	}
+	return rows, sum.Int64, nil
+}
+
 // GetMysqlVersion Get the server version of mysql
 func GetMysqlVersion(dbh *sql.DB) (string, error) {
 	version, err := MysqlSingleColumnQuery("select version()", dbh)
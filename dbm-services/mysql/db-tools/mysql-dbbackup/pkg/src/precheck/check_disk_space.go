@@ -78,7 +78,20 @@ func DeleteOldBackup(cnf *config.Public, expireDays int) error {
 }
 
 // CheckAndCleanDiskSpace 如果空间不足，则会强制删除所有备份文件
-func CheckAndCleanDiskSpace(cnf *config.Public, dbh *sql.DB) error {
+// streaming 表示 tar 包直接上传到存储后端，本地只需要放下备份文件本身
+func CheckAndCleanDiskSpace(cnf *config.Public, dbh *sql.DB, streaming bool) error {
+	// tar 包不落地，先用上一次备份的大小评估，不满足再走下面的流程
+	if streaming {
+		if lastBackupSize, err := GetLastBackupSize(cnf, dbh); err != nil {
+			logger.Log.Warn("failed to GetLastBackupSize for streaming backup, err:", err)
+		} else if lastBackupSize > 0 {
+			if sizeLeft, err := util.CheckDiskSpace(cnf.BackupDir, cnf.MysqlPort, lastBackupSize); err == nil {
+				logger.Log.Infof("disk space meets ok for streaming backup, sizeLeft=%d, lastBackupSize=%d",
+					sizeLeft, lastBackupSize)
+				return nil
+			}
+		}
+	}
 	dataDirSize, err := util.CalServerDataSize(cnf.MysqlPort)
 	if err != nil {
 		return err
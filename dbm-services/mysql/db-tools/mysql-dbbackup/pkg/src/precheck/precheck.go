@@ -64,7 +64,7 @@ func BeforeDump(cnf *config.BackupConfig) error {
 	}
 
 	if cnf.Public.IfBackupData() {
-		if err := CheckAndCleanDiskSpace(cnfPublic, dbh); err != nil {
+		if err := CheckAndCleanDiskSpace(cnfPublic, dbh, cnf.Storage.Enabled()); err != nil {
 			logger.Log.Errorf("disk space is not enough for %d, err:%s", cnfPublic.MysqlPort, err.Error())
 			return err
 		}
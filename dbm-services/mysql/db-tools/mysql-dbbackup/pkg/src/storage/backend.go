@@ -0,0 +1,62 @@
+// TencentBlueKing is pleased to support the open source community by making 蓝鲸智云-DB管理系统(BlueKing-BK-DBM) available.
+// Copyright (C) 2017-2023 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+// Package storage 备份文件存储后端，打包的 tar 流直接分片上传，不在本地落地
+package storage
+
+import (
+	"github.com/pkg/errors"
+
+	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/config"
+	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/cst"
+)
+
+// Part 一个已上传的分片
+type Part struct {
+	// Number 从 1 开始
+	Number int    `json:"number"`
+	Offset int64  `json:"offset"`
+	Size   int    `json:"size"`
+	ETag   string `json:"etag"`
+}
+
+// Backend 存储后端，所有对象都通过分片上传写入
+// local / sftp 没有原生的分片上传，按 offset 写临时文件，完成时 rename
+type Backend interface {
+	// Type local / s3 / sftp
+	Type() string
+	// Location 对象的完整位置，记录到 index 文件
+	Location(key string) string
+	// InitUpload 开始一个对象的上传，返回 upload id
+	InitUpload(key string) (uploadId string, err error)
+	// UploadPart 上传一个分片，成功后填充 part.ETag。同一个分片可以重复上传
+	UploadPart(key, uploadId string, part *Part, data []byte) error
+	// CompleteUpload 所有分片上传完成，对象可见
+	CompleteUpload(key, uploadId string, parts []*Part) error
+	// AbortUpload 放弃上传，清理已上传的分片
+	AbortUpload(key, uploadId string) error
+	// Close 释放连接
+	Close() error
+}
+
+// NewBackend 根据 Storage.Type 创建存储后端
+func NewBackend(cnf *config.Storage) (Backend, error) {
+	if err := cnf.Validate(); err != nil {
+		return nil, err
+	}
+	switch cnf.Type {
+	case cst.StorageLocal:
+		return NewLocalBackend(cnf.LocalDir)
+	case cst.StorageS3:
+		return NewS3Backend(cnf), nil
+	case cst.StorageSftp:
+		return NewSftpBackend(cnf)
+	default:
+		return nil, errors.Errorf("storage type is empty")
+	}
+}
@@ -0,0 +1,108 @@
+// TencentBlueKing is pleased to support the open source community by making 蓝鲸智云-DB管理系统(BlueKing-BK-DBM) available.
+// Copyright (C) 2017-2023 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package storage
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+
+	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/cst"
+)
+
+// uploadingSuffix local / sftp 上传中的临时文件后缀
+const uploadingSuffix = ".uploading"
+
+// LocalBackend 本地目录，可以是 nfs 等挂载盘
+type LocalBackend struct {
+	dir string
+}
+
+// NewLocalBackend local dir backend
+func NewLocalBackend(dir string) (*LocalBackend, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &LocalBackend{dir: dir}, nil
+}
+
+// Type implement Backend
+func (b *LocalBackend) Type() string {
+	return cst.StorageLocal
+}
+
+// Location implement Backend
+func (b *LocalBackend) Location(key string) string {
+	return filepath.Join(b.dir, key)
+}
+
+// InitUpload 上传到临时文件，upload id 就是临时文件名
+func (b *LocalBackend) InitUpload(key string) (string, error) {
+	dst := b.Location(key)
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return "", err
+	}
+	uploadId := dst + uploadingSuffix
+	f, err := os.OpenFile(uploadId, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return "", err
+	}
+	return uploadId, f.Close()
+}
+
+// UploadPart 按 offset 写入临时文件
+func (b *LocalBackend) UploadPart(key, uploadId string, part *Part, data []byte) error {
+	f, err := os.OpenFile(uploadId, os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err = f.WriteAt(data, part.Offset); err != nil {
+		_ = f.Close()
+		return err
+	}
+	// 分片写完就落盘，避免 rename 之后数据还在 page cache
+	if err = f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// CompleteUpload 临时文件 rename 为目标文件
+func (b *LocalBackend) CompleteUpload(key, uploadId string, parts []*Part) error {
+	size := partsSize(parts)
+	if st, err := os.Stat(uploadId); err != nil {
+		return err
+	} else if st.Size() != size {
+		return errors.Errorf("%s size %d not match parts size %d", uploadId, st.Size(), size)
+	}
+	return os.Rename(uploadId, b.Location(key))
+}
+
+// AbortUpload 删除临时文件
+func (b *LocalBackend) AbortUpload(key, uploadId string) error {
+	if err := os.Remove(uploadId); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Close implement Backend
+func (b *LocalBackend) Close() error {
+	return nil
+}
+
+func partsSize(parts []*Part) int64 {
+	var size int64
+	for _, p := range parts {
+		size += int64(p.Size)
+	}
+	return size
+}
@@ -0,0 +1,393 @@
+// TencentBlueKing is pleased to support the open source community by making 蓝鲸智云-DB管理系统(BlueKing-BK-DBM) available.
+// Copyright (C) 2017-2023 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/src/logger"
+)
+
+// uploadStateSuffix 上传进度文件后缀
+const uploadStateSuffix = ".upload_state"
+
+// ObjectWriter 把写入的数据按 partSize 切成分片上传到一个对象
+// 单个分片失败只重传这个分片，不需要从头再来
+// 指定 statePath 时每个分片成功后把上传进度落盘，进程重启后可以用 ResumeObjectWriter 续传
+type ObjectWriter struct {
+	backend   Backend
+	key       string
+	uploadId  string
+	partSize  int
+	retry     int
+	statePath string
+	source    string
+
+	buf     []byte
+	parts   []*Part
+	written int64
+	closed  bool
+}
+
+// uploadState 落盘的上传进度
+type uploadState struct {
+	Key      string `json:"key"`
+	UploadId string `json:"upload_id"`
+	PartSize int    `json:"part_size"`
+	// Source 数据来源的标识，比如本地文件的 path+size+mtime，变化后不能续传
+	Source string  `json:"source"`
+	Parts  []*Part `json:"parts"`
+}
+
+// NewObjectWriter 开始上传对象 key
+func NewObjectWriter(backend Backend, key string, partSize int, retry int) (*ObjectWriter, error) {
+	uploadId, err := backend.InitUpload(key)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "init upload %s", key)
+	}
+	logger.Log.Infof("storage %s init upload %s, upload_id=%s", backend.Type(), key, uploadId)
+	return &ObjectWriter{
+		backend:  backend,
+		key:      key,
+		uploadId: uploadId,
+		partSize: partSize,
+		retry:    retry,
+		buf:      make([]byte, 0, partSize),
+	}, nil
+}
+
+// ResumeObjectWriter 从 statePath 记录的进度继续上传对象 key，没有进度或者 source 变化时重新开始
+// 续传时调用方需要跳过 Size() 字节，从之后的数据开始写
+func ResumeObjectWriter(backend Backend, key, statePath, source string, partSize int, retry int) (*ObjectWriter,
+	error) {
+	state, err := loadUploadState(statePath)
+	if err != nil {
+		return nil, err
+	}
+	if state != nil {
+		if state.Key == key && state.PartSize == partSize && state.Source == source {
+			w := &ObjectWriter{
+				backend:   backend,
+				key:       key,
+				uploadId:  state.UploadId,
+				partSize:  partSize,
+				retry:     retry,
+				statePath: statePath,
+				source:    source,
+				buf:       make([]byte, 0, partSize),
+				parts:     state.Parts,
+				written:   partsSize(state.Parts),
+			}
+			logger.Log.Infof("storage %s resume upload %s, upload_id=%s parts=%d size=%d",
+				backend.Type(), key, w.uploadId, len(w.parts), w.written)
+			return w, nil
+		}
+		logger.Log.Warnf("upload state %s not match %s, abort upload_id=%s and start over",
+			statePath, key, state.UploadId)
+		if err = backend.AbortUpload(state.Key, state.UploadId); err != nil {
+			logger.Log.Warnf("abort stale upload %s: %s", state.UploadId, err)
+		}
+	}
+	w, err := NewObjectWriter(backend, key, partSize, retry)
+	if err != nil {
+		return nil, err
+	}
+	w.statePath = statePath
+	w.source = source
+	// 第一个分片之前就记录 upload id，失败后也能找到并清理
+	if err = w.saveState(); err != nil {
+		_ = w.Abort()
+		return nil, err
+	}
+	return w, nil
+}
+
+func loadUploadState(statePath string) (*uploadState, error) {
+	data, err := os.ReadFile(statePath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.WithMessagef(err, "read upload state %s", statePath)
+	}
+	state := &uploadState{}
+	if err = json.Unmarshal(data, state); err != nil {
+		return nil, errors.WithMessagef(err, "parse upload state %s", statePath)
+	}
+	return state, nil
+}
+
+// saveState 先写临时文件再 rename，避免进程中断留下不完整的进度
+func (w *ObjectWriter) saveState() error {
+	if w.statePath == "" {
+		return nil
+	}
+	data, err := json.Marshal(&uploadState{
+		Key:      w.key,
+		UploadId: w.uploadId,
+		PartSize: w.partSize,
+		Source:   w.source,
+		Parts:    w.parts,
+	})
+	if err != nil {
+		return err
+	}
+	tmpFile := w.statePath + ".tmp"
+	if err = os.WriteFile(tmpFile, data, 0644); err != nil {
+		return errors.WithMessagef(err, "save upload state %s", w.statePath)
+	}
+	return os.Rename(tmpFile, w.statePath)
+}
+
+func (w *ObjectWriter) removeState() {
+	if w.statePath == "" {
+		return
+	}
+	if err := os.Remove(w.statePath); err != nil && !os.IsNotExist(err) {
+		logger.Log.Warnf("remove upload state %s: %s", w.statePath, err)
+	}
+}
+
+// Write implement io.Writer
+func (w *ObjectWriter) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, errors.Errorf("write to closed object %s", w.key)
+	}
+	n := 0
+	for len(p) > 0 {
+		c := copy(w.buf[len(w.buf):w.partSize], p)
+		w.buf = w.buf[:len(w.buf)+c]
+		p = p[c:]
+		n += c
+		if len(w.buf) == w.partSize {
+			if err := w.flushPart(); err != nil {
+				return n, err
+			}
+		}
+	}
+	return n, nil
+}
+
+func (w *ObjectWriter) flushPart() error {
+	part := &Part{Number: len(w.parts) + 1, Offset: w.written, Size: len(w.buf)}
+	var err error
+	for i := 0; i <= w.retry; i++ {
+		if i > 0 {
+			logger.Log.Warnf("upload %s part %d failed, retry %d/%d: %s", w.key, part.Number, i, w.retry, err)
+			time.Sleep(time.Duration(i*2) * time.Second)
+		}
+		if err = w.backend.UploadPart(w.key, w.uploadId, part, w.buf); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return errors.WithMessagef(err, "upload %s part %d", w.key, part.Number)
+	}
+	w.parts = append(w.parts, part)
+	w.written += int64(part.Size)
+	w.buf = w.buf[:0]
+	return w.saveState()
+}
+
+// Close 上传最后一个分片并完成上传
+// 失败时上传仍然是未完成状态，可以再次 Close 重试，或者 Abort 清理
+func (w *ObjectWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	// 空对象也需要一个分片
+	if len(w.buf) > 0 || len(w.parts) == 0 {
+		if err := w.flushPart(); err != nil {
+			return err
+		}
+	}
+	if err := w.backend.CompleteUpload(w.key, w.uploadId, w.parts); err != nil {
+		return errors.WithMessagef(err, "complete upload %s", w.key)
+	}
+	w.closed = true
+	w.removeState()
+	logger.Log.Infof("storage %s uploaded %s, size=%d parts=%d", w.backend.Type(), w.key, w.written, len(w.parts))
+	return nil
+}
+
+// Abort 放弃上传
+func (w *ObjectWriter) Abort() error {
+	if w.closed {
+		return nil
+	}
+	if err := w.backend.AbortUpload(w.key, w.uploadId); err != nil {
+		return err
+	}
+	w.closed = true
+	w.removeState()
+	return nil
+}
+
+// Size 已经上传的字节数
+func (w *ObjectWriter) Size() int64 {
+	return w.written
+}
+
+// Object 上传完成的对象
+type Object struct {
+	// Name 文件名，和本地打包的文件名规则一致
+	Name     string
+	Key      string
+	Size     int64
+	Location string
+}
+
+// SplitWriter 按 splitSize 把一个流写成多个对象 {name}.part_0, {name}.part_1 ...
+// 命名和 util.SplitWriter 一致，下载后可以按原来的方式恢复
+type SplitWriter struct {
+	backend   Backend
+	prefix    string
+	name      string
+	splitSize int64
+	partSize  int
+	retry     int
+
+	cur        *ObjectWriter
+	curObject  *Object
+	curWritten int64
+	objects    []*Object
+	aborted    bool
+}
+
+// NewSplitWriter split writer to storage
+func NewSplitWriter(backend Backend, prefix, name string, splitSize int64, partSize int, retry int) *SplitWriter {
+	return &SplitWriter{
+		backend:   backend,
+		prefix:    prefix,
+		name:      name,
+		splitSize: splitSize,
+		partSize:  partSize,
+		retry:     retry,
+	}
+}
+
+// Write implement io.Writer
+func (s *SplitWriter) Write(p []byte) (int, error) {
+	if s.aborted {
+		return 0, errors.Errorf("write to aborted upload %s", s.name)
+	}
+	n := 0
+	for len(p) > 0 {
+		if s.cur == nil {
+			if err := s.next(); err != nil {
+				return n, err
+			}
+		}
+		c := len(p)
+		if left := s.splitSize - s.curWritten; int64(c) > left {
+			c = int(left)
+		}
+		written, err := s.cur.Write(p[:c])
+		n += written
+		s.curWritten += int64(written)
+		if err != nil {
+			return n, err
+		}
+		p = p[c:]
+		if s.curWritten >= s.splitSize {
+			if err = s.closeCurrent(); err != nil {
+				return n, err
+			}
+		}
+	}
+	return n, nil
+}
+
+func (s *SplitWriter) next() error {
+	name := s.name + ".part_" + strconv.Itoa(len(s.objects)) // need to be same with util.SplitWriter
+	key := path.Join(s.prefix, name)
+	w, err := NewObjectWriter(s.backend, key, s.partSize, s.retry)
+	if err != nil {
+		return err
+	}
+	s.cur = w
+	s.curWritten = 0
+	s.curObject = &Object{Name: name, Key: key, Location: s.backend.Location(key)}
+	return nil
+}
+
+func (s *SplitWriter) closeCurrent() error {
+	if err := s.cur.Close(); err != nil {
+		return err
+	}
+	s.curObject.Size = s.cur.Size()
+	s.objects = append(s.objects, s.curObject)
+	s.cur = nil
+	return nil
+}
+
+// Close 完成最后一个对象
+func (s *SplitWriter) Close() error {
+	if s.cur == nil {
+		return nil
+	}
+	return s.closeCurrent()
+}
+
+// Abort 放弃正在上传的对象，已经完成的对象不会删除。之后的写入都会失败
+func (s *SplitWriter) Abort() error {
+	s.aborted = true
+	if s.cur == nil {
+		return nil
+	}
+	err := s.cur.Abort()
+	s.cur = nil
+	return err
+}
+
+// Objects 上传完成的对象
+func (s *SplitWriter) Objects() []*Object {
+	return s.objects
+}
+
+// UploadFile 上传本地文件到 {prefix}/{文件名}
+// 上传进度记录在 {localFile}.upload_state，失败后不清理已上传的分片，重新执行时从断点续传
+func UploadFile(backend Backend, localFile, prefix string, partSize int, retry int) (*Object, error) {
+	f, err := os.Open(localFile)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	st, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	name := filepath.Base(localFile)
+	key := path.Join(prefix, name)
+	source := fmt.Sprintf("%s:%d:%d", localFile, st.Size(), st.ModTime().UnixNano())
+	w, err := ResumeObjectWriter(backend, key, localFile+uploadStateSuffix, source, partSize, retry)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = f.Seek(w.Size(), io.SeekStart); err != nil {
+		return nil, err
+	}
+	if _, err = io.Copy(w, f); err != nil {
+		return nil, errors.WithMessagef(err, "upload %s", localFile)
+	}
+	if err = w.Close(); err != nil {
+		return nil, err
+	}
+	return &Object{Name: name, Key: key, Size: w.Size(), Location: backend.Location(key)}, nil
+}
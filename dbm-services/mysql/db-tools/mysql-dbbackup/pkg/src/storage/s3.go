@@ -0,0 +1,283 @@
+// TencentBlueKing is pleased to support the open source community by making 蓝鲸智云-DB管理系统(BlueKing-BK-DBM) available.
+// Copyright (C) 2017-2023 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package storage
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/config"
+	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/cst"
+)
+
+const s3DefaultRegion = "us-east-1"
+
+// S3Backend s3 兼容的对象存储，使用原生 multipart upload，请求用 aws signature v4 签名
+type S3Backend struct {
+	cnf    *config.Storage
+	scheme string
+	host   string
+	region string
+	client *http.Client
+}
+
+// NewS3Backend s3 backend
+func NewS3Backend(cnf *config.Storage) *S3Backend {
+	scheme, host := "https", cnf.S3Endpoint
+	if u, err := url.Parse(cnf.S3Endpoint); err == nil && u.Host != "" {
+		scheme, host = u.Scheme, u.Host
+	}
+	region := cnf.S3Region
+	if region == "" {
+		region = s3DefaultRegion
+	}
+	return &S3Backend{
+		cnf:    cnf,
+		scheme: scheme,
+		host:   host,
+		region: region,
+		client: &http.Client{Timeout: 30 * time.Minute},
+	}
+}
+
+// Type implement Backend
+func (b *S3Backend) Type() string {
+	return cst.StorageS3
+}
+
+// Location implement Backend
+func (b *S3Backend) Location(key string) string {
+	return fmt.Sprintf("s3://%s/%s", b.cnf.S3Bucket, strings.TrimPrefix(key, "/"))
+}
+
+type s3InitiateResult struct {
+	UploadId string `xml:"UploadId"`
+}
+
+type s3CompletePart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+type s3CompleteUpload struct {
+	XMLName xml.Name          `xml:"CompleteMultipartUpload"`
+	Parts   []*s3CompletePart `xml:"Part"`
+}
+
+type s3Error struct {
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+// InitUpload CreateMultipartUpload
+func (b *S3Backend) InitUpload(key string) (string, error) {
+	resp, err := b.do(http.MethodPost, key, url.Values{"uploads": {""}}, nil)
+	if err != nil {
+		return "", err
+	}
+	var result s3InitiateResult
+	if err = xml.Unmarshal(resp, &result); err != nil {
+		return "", errors.WithMessage(err, "parse CreateMultipartUpload result")
+	}
+	if result.UploadId == "" {
+		return "", errors.Errorf("CreateMultipartUpload %s returns empty UploadId", key)
+	}
+	return result.UploadId, nil
+}
+
+// UploadPart UploadPart
+func (b *S3Backend) UploadPart(key, uploadId string, part *Part, data []byte) error {
+	query := url.Values{"partNumber": {strconv.Itoa(part.Number)}, "uploadId": {uploadId}}
+	req, err := b.newRequest(http.MethodPut, key, query, data)
+	if err != nil {
+		return err
+	}
+	sum := md5.Sum(data)
+	req.Header.Set("Content-MD5", base64.StdEncoding.EncodeToString(sum[:]))
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if _, err = readS3Response(resp); err != nil {
+		return err
+	}
+	part.ETag = resp.Header.Get("ETag")
+	return nil
+}
+
+// CompleteUpload CompleteMultipartUpload
+func (b *S3Backend) CompleteUpload(key, uploadId string, parts []*Part) error {
+	complete := s3CompleteUpload{}
+	for _, p := range parts {
+		complete.Parts = append(complete.Parts, &s3CompletePart{PartNumber: p.Number, ETag: p.ETag})
+	}
+	body, err := xml.Marshal(complete)
+	if err != nil {
+		return err
+	}
+	resp, err := b.do(http.MethodPost, key, url.Values{"uploadId": {uploadId}}, body)
+	if err != nil {
+		return err
+	}
+	// CompleteMultipartUpload 可能返回 200 但 body 里是错误
+	var e s3Error
+	if xml.Unmarshal(resp, &e) == nil && e.Code != "" {
+		return errors.Errorf("CompleteMultipartUpload %s: %s %s", key, e.Code, e.Message)
+	}
+	return nil
+}
+
+// AbortUpload AbortMultipartUpload
+func (b *S3Backend) AbortUpload(key, uploadId string) error {
+	_, err := b.do(http.MethodDelete, key, url.Values{"uploadId": {uploadId}}, nil)
+	return err
+}
+
+// Close implement Backend
+func (b *S3Backend) Close() error {
+	b.client.CloseIdleConnections()
+	return nil
+}
+
+func (b *S3Backend) do(method, key string, query url.Values, body []byte) ([]byte, error) {
+	req, err := b.newRequest(method, key, query, body)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	return readS3Response(resp)
+}
+
+func readS3Response(resp *http.Response) ([]byte, error) {
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		var e s3Error
+		if xml.Unmarshal(body, &e) == nil && e.Code != "" {
+			return nil, errors.Errorf("s3 %s %s: %s %s", resp.Request.Method, resp.Request.URL.Path, e.Code, e.Message)
+		}
+		return nil, errors.Errorf("s3 %s %s: http status %s", resp.Request.Method, resp.Request.URL.Path,
+			resp.Status)
+	}
+	return body, nil
+}
+
+// newRequest 构造签名后的请求
+func (b *S3Backend) newRequest(method, key string, query url.Values, body []byte) (*http.Request, error) {
+	host := b.host
+	uriPath := "/" + strings.TrimPrefix(key, "/")
+	if b.cnf.S3PathStyle {
+		uriPath = "/" + b.cnf.S3Bucket + uriPath
+	} else {
+		host = b.cnf.S3Bucket + "." + b.host
+	}
+	canonicalURI := s3EscapePath(uriPath)
+	canonicalQuery := s3CanonicalQuery(query)
+	req, err := http.NewRequest(method, fmt.Sprintf("%s://%s%s?%s", b.scheme, host, canonicalURI, canonicalQuery),
+		bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = int64(len(body))
+
+	now := time.Now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	shortDate := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+	req.Header.Set("Host", host)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalHeaders := fmt.Sprintf("host:%s\nx-amz-content-sha256:%s\nx-amz-date:%s\n", host, payloadHash, amzDate)
+	canonicalRequest := strings.Join([]string{method, canonicalURI, canonicalQuery, canonicalHeaders,
+		signedHeaders, payloadHash}, "\n")
+	scope := fmt.Sprintf("%s/%s/s3/aws4_request", shortDate, b.region)
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope,
+		sha256Hex([]byte(canonicalRequest))}, "\n")
+
+	signingKey := hmacSha256([]byte("AWS4"+b.cnf.S3SecretKey), shortDate)
+	signingKey = hmacSha256(signingKey, b.region)
+	signingKey = hmacSha256(signingKey, "s3")
+	signingKey = hmacSha256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSha256(signingKey, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		b.cnf.S3AccessKey, scope, signedHeaders, signature))
+	return req, nil
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSha256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// s3Escape uri encode, 只保留 A-Za-z0-9-_.~
+func s3Escape(s string, keepSlash bool) string {
+	var buf strings.Builder
+	for _, c := range []byte(s) {
+		if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' || (keepSlash && c == '/') {
+			buf.WriteByte(c)
+		} else {
+			fmt.Fprintf(&buf, "%%%02X", c)
+		}
+	}
+	return buf.String()
+}
+
+func s3EscapePath(p string) string {
+	return s3Escape(p, true)
+}
+
+func s3CanonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		for _, v := range query[k] {
+			parts = append(parts, s3Escape(k, false)+"="+s3Escape(v, false))
+		}
+	}
+	return strings.Join(parts, "&")
+}
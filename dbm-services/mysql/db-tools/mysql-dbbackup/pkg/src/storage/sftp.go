@@ -0,0 +1,171 @@
+// TencentBlueKing is pleased to support the open source community by making 蓝鲸智云-DB管理系统(BlueKing-BK-DBM) available.
+// Copyright (C) 2017-2023 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package storage
+
+import (
+	"os"
+	"path"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+
+	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/config"
+	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/cst"
+	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/src/logger"
+)
+
+// SftpBackend sftp 服务器上的目录
+// 每个分片单独打开文件按 offset 写，连接断开后重连，分片重试不需要从头上传
+type SftpBackend struct {
+	cnf        *config.Storage
+	sshConfig  *ssh.ClientConfig
+	sshClient  *ssh.Client
+	sftpClient *sftp.Client
+}
+
+// NewSftpBackend sftp backend
+func NewSftpBackend(cnf *config.Storage) (*SftpBackend, error) {
+	var auths []ssh.AuthMethod
+	if cnf.SftpKeyFile != "" {
+		key, err := os.ReadFile(cnf.SftpKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		signer, err := ssh.ParsePrivateKey(key)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "parse private key %s", cnf.SftpKeyFile)
+		}
+		auths = append(auths, ssh.PublicKeys(signer))
+	}
+	if cnf.SftpPassword != "" {
+		auths = append(auths, ssh.Password(cnf.SftpPassword))
+	}
+	b := &SftpBackend{
+		cnf: cnf,
+		sshConfig: &ssh.ClientConfig{
+			User:            cnf.SftpUser,
+			Auth:            auths,
+			HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+			Timeout:         30 * time.Second,
+		},
+	}
+	if err := b.connect(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func (b *SftpBackend) connect() error {
+	if b.sftpClient != nil {
+		if _, err := b.sftpClient.Getwd(); err == nil {
+			return nil
+		}
+		logger.Log.Warnf("sftp connection to %s is broken, reconnect", b.cnf.SftpAddress)
+		_ = b.Close()
+	}
+	sshClient, err := ssh.Dial("tcp", b.cnf.SftpAddress, b.sshConfig)
+	if err != nil {
+		return errors.WithMessagef(err, "ssh dial %s", b.cnf.SftpAddress)
+	}
+	sftpClient, err := sftp.NewClient(sshClient)
+	if err != nil {
+		_ = sshClient.Close()
+		return errors.WithMessagef(err, "sftp client %s", b.cnf.SftpAddress)
+	}
+	b.sshClient = sshClient
+	b.sftpClient = sftpClient
+	return nil
+}
+
+// Type implement Backend
+func (b *SftpBackend) Type() string {
+	return cst.StorageSftp
+}
+
+// Location implement Backend
+func (b *SftpBackend) Location(key string) string {
+	return "sftp://" + b.cnf.SftpUser + "@" + b.cnf.SftpAddress + path.Join("/", b.cnf.SftpDir, key)
+}
+
+func (b *SftpBackend) remotePath(key string) string {
+	return path.Join(b.cnf.SftpDir, key)
+}
+
+// InitUpload 上传到临时文件，upload id 就是临时文件名
+func (b *SftpBackend) InitUpload(key string) (string, error) {
+	if err := b.connect(); err != nil {
+		return "", err
+	}
+	dst := b.remotePath(key)
+	if err := b.sftpClient.MkdirAll(path.Dir(dst)); err != nil {
+		return "", err
+	}
+	uploadId := dst + uploadingSuffix
+	f, err := b.sftpClient.OpenFile(uploadId, os.O_CREATE|os.O_WRONLY|os.O_TRUNC)
+	if err != nil {
+		return "", err
+	}
+	return uploadId, f.Close()
+}
+
+// UploadPart 按 offset 写入临时文件
+func (b *SftpBackend) UploadPart(key, uploadId string, part *Part, data []byte) error {
+	if err := b.connect(); err != nil {
+		return err
+	}
+	f, err := b.sftpClient.OpenFile(uploadId, os.O_WRONLY)
+	if err != nil {
+		return err
+	}
+	if _, err = f.WriteAt(data, part.Offset); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// CompleteUpload 临时文件 rename 为目标文件
+func (b *SftpBackend) CompleteUpload(key, uploadId string, parts []*Part) error {
+	if err := b.connect(); err != nil {
+		return err
+	}
+	size := partsSize(parts)
+	if st, err := b.sftpClient.Stat(uploadId); err != nil {
+		return err
+	} else if st.Size() != size {
+		return errors.Errorf("%s size %d not match parts size %d", uploadId, st.Size(), size)
+	}
+	return b.sftpClient.PosixRename(uploadId, b.remotePath(key))
+}
+
+// AbortUpload 删除临时文件
+func (b *SftpBackend) AbortUpload(key, uploadId string) error {
+	if err := b.connect(); err != nil {
+		return err
+	}
+	if err := b.sftpClient.Remove(uploadId); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Close implement Backend
+func (b *SftpBackend) Close() error {
+	if b.sftpClient != nil {
+		_ = b.sftpClient.Close()
+		b.sftpClient = nil
+	}
+	if b.sshClient != nil {
+		_ = b.sshClient.Close()
+		b.sshClient = nil
+	}
+	return nil
+}
@@ -0,0 +1,231 @@
+// TencentBlueKing is pleased to support the open source community by making 蓝鲸智云-DB管理系统(BlueKing-BK-DBM) available.
+// Copyright (C) 2017-2023 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+// Package verify 把备份恢复到 scratch mysqld 并检查
+package verify
+
+import (
+	"bufio"
+	"database/sql"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"dbm-services/common/go-pubpkg/cmutil"
+	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/config"
+	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/src/logger"
+	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/src/mysqlconn"
+	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/util"
+)
+
+// scratchMysqld 临时 mysqld，只用于恢复验证
+type scratchMysqld struct {
+	cnf     *config.Verify
+	baseDir string
+	dataDir string
+	tmpDir  string
+	socket  string
+	myCnf   string
+	errLog  string
+	cmd     *exec.Cmd
+	exited  chan error
+}
+
+func newScratchMysqld(cnf *config.Verify) (*scratchMysqld, error) {
+	if entries, err := os.ReadDir(cnf.ScratchDir); err == nil && len(entries) > 0 {
+		return nil, errors.Errorf("scratch dir %s is not empty", cnf.ScratchDir)
+	}
+	s := &scratchMysqld{
+		cnf:     cnf,
+		baseDir: filepath.Dir(filepath.Dir(cnf.MysqldBin)),
+		dataDir: filepath.Join(cnf.ScratchDir, "data"),
+		tmpDir:  filepath.Join(cnf.ScratchDir, "tmp"),
+		socket:  filepath.Join(cnf.ScratchDir, "mysql.sock"),
+		myCnf:   filepath.Join(cnf.ScratchDir, "my.cnf"),
+		errLog:  filepath.Join(cnf.ScratchDir, "mysqld.err"),
+	}
+	for _, dir := range []string{s.dataDir, s.tmpDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// writeMyCnf 生成 scratch 实例的 my.cnf
+// 物理备份需要带上 backup-my.cnf 里的 innodb 参数，否则 innodb 可能起不来
+func (s *scratchMysqld) writeMyCnf(backupMyCnf string) error {
+	lines := []string{
+		"[mysqld]",
+		fmt.Sprintf("basedir=%s", s.baseDir),
+		fmt.Sprintf("datadir=%s", s.dataDir),
+		fmt.Sprintf("tmpdir=%s", s.tmpDir),
+		fmt.Sprintf("socket=%s", s.socket),
+		fmt.Sprintf("port=%d", s.cnf.ScratchPort),
+		fmt.Sprintf("log-error=%s", s.errLog),
+		fmt.Sprintf("pid-file=%s", filepath.Join(s.cnf.ScratchDir, "mysqld.pid")),
+		"bind-address=127.0.0.1",
+		"server-id=1",
+		"skip-slave-start",
+		"skip-name-resolve",
+		"innodb_buffer_pool_size=512M",
+	}
+	if backupMyCnf != "" {
+		extra, err := readMysqldSection(backupMyCnf)
+		if err != nil {
+			return err
+		}
+		lines = append(lines, extra...)
+	}
+	return os.WriteFile(s.myCnf, []byte(strings.Join(lines, "\n")+"\n"), 0644)
+}
+
+// readMysqldSection 读取 backup-my.cnf 里 [mysqld] 的 innodb 相关参数
+func readMysqldSection(cnfFile string) ([]string, error) {
+	f, err := os.Open(cnfFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			logger.Log.Warnf("%s not found, start scratch mysqld with default innodb options", cnfFile)
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	var lines []string
+	inMysqld := false
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			inMysqld = line == "[mysqld]"
+			continue
+		}
+		if inMysqld && strings.HasPrefix(line, "innodb_") {
+			lines = append(lines, line)
+		}
+	}
+	return lines, scanner.Err()
+}
+
+func (s *scratchMysqld) userArgs() []string {
+	if os.Getuid() == 0 {
+		return []string{"--user=mysql"}
+	}
+	return nil
+}
+
+func (s *scratchMysqld) chown() {
+	if os.Getuid() == 0 {
+		_, _, _ = cmutil.ExecCommand(false, "", "chown", "-R", "mysql.mysql", s.cnf.ScratchDir)
+	}
+}
+
+// initialize 初始化空的 datadir，逻辑备份恢复需要
+// 5.7.6 之前的版本没有 --initialize-insecure，使用 basedir 下的 scripts/mysql_install_db，root 同样是空密码
+func (s *scratchMysqld) initialize(mysqlVersion string) error {
+	s.chown()
+	bin := s.cnf.MysqldBin
+	args := []string{fmt.Sprintf("--defaults-file=%s", s.myCnf), "--initialize-insecure"}
+	if v, _ := util.VersionParser(mysqlVersion); v != "000000" && v < "005007006" {
+		bin = filepath.Join(s.baseDir, "scripts", "mysql_install_db")
+		args = []string{fmt.Sprintf("--defaults-file=%s", s.myCnf), fmt.Sprintf("--basedir=%s", s.baseDir),
+			fmt.Sprintf("--datadir=%s", s.dataDir)}
+	}
+	args = append(args, s.userArgs()...)
+	logger.Log.Info("scratch mysqld initialize: ", bin, " ", strings.Join(args, " "))
+	out, err := exec.Command(bin, args...).CombinedOutput()
+	if err != nil {
+		return errors.Wrapf(err, "initialize scratch mysqld: %s, see %s", string(out), s.errLog)
+	}
+	return nil
+}
+
+// start 启动并等待可以连接。skipGrant 为 true 时不校验权限，只能通过 socket 连接
+func (s *scratchMysqld) start(skipGrant bool) error {
+	s.chown()
+	args := []string{fmt.Sprintf("--defaults-file=%s", s.myCnf)}
+	args = append(args, s.userArgs()...)
+	if skipGrant {
+		args = append(args, "--skip-grant-tables", "--skip-networking")
+	}
+	s.cmd = exec.Command(s.cnf.MysqldBin, args...)
+	logger.Log.Info("scratch mysqld start: ", s.cmd.String())
+	if err := s.cmd.Start(); err != nil {
+		return errors.Wrap(err, "start scratch mysqld")
+	}
+	s.exited = make(chan error, 1)
+	go func() {
+		s.exited <- s.cmd.Wait()
+	}()
+
+	deadline := time.Now().Add(time.Duration(s.cnf.StartTimeout) * time.Second)
+	for time.Now().Before(deadline) {
+		select {
+		case err := <-s.exited:
+			s.cmd = nil
+			return errors.Errorf("scratch mysqld exited while starting: %v, see %s", err, s.errLog)
+		case <-time.After(2 * time.Second):
+		}
+		db, err := s.conn()
+		if err == nil {
+			err = db.Ping()
+			_ = db.Close()
+		}
+		if err == nil {
+			logger.Log.Info("scratch mysqld started")
+			return nil
+		}
+	}
+	_ = s.stop()
+	return errors.Errorf("scratch mysqld not ready in %ds, see %s", s.cnf.StartTimeout, s.errLog)
+}
+
+// conn 通过 socket 用 root 空密码连接，initialize-insecure 和 skip-grant-tables 都可以
+func (s *scratchMysqld) conn() (*sql.DB, error) {
+	return sql.Open("mysql", mysqlconn.DsnBySocket(s.socket, "root", ""))
+}
+
+// stop 关闭 scratch mysqld
+func (s *scratchMysqld) stop() error {
+	if s.cmd == nil || s.cmd.Process == nil {
+		return nil
+	}
+	defer func() {
+		s.cmd = nil
+	}()
+	_ = s.cmd.Process.Signal(os.Interrupt)
+	select {
+	case <-s.exited:
+		return nil
+	case <-time.After(5 * time.Minute):
+		logger.Log.Warn("scratch mysqld not exit in 5m, kill it")
+		return s.cmd.Process.Kill()
+	}
+}
+
+// cleanup 停止实例并删除 scratch 目录
+func (s *scratchMysqld) cleanup() {
+	if err := s.stop(); err != nil {
+		logger.Log.Warn("stop scratch mysqld: ", err)
+	}
+	if s.cnf.KeepScratch {
+		logger.Log.Infof("keep scratch dir %s", s.cnf.ScratchDir)
+		return
+	}
+	if err := os.RemoveAll(s.cnf.ScratchDir); err != nil {
+		logger.Log.Warn("remove scratch dir: ", err)
+	}
+}
@@ -0,0 +1,308 @@
+// TencentBlueKing is pleased to support the open source community by making 蓝鲸智云-DB管理系统(BlueKing-BK-DBM) available.
+// Copyright (C) 2017-2023 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package verify
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"dbm-services/common/go-pubpkg/cmutil"
+	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/config"
+	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/cst"
+	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/src/backupexe"
+	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/src/dbareport"
+	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/src/logger"
+	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/src/mysqlconn"
+)
+
+const verifyUser = "dbbackup_verify"
+
+// systemDbs 不参与表对比的系统库
+var systemDbs = []string{"mysql", "sys", "information_schema", "performance_schema", "db_infobase", "test"}
+
+// Run 把 indexFile 对应的备份恢复到 scratch mysqld 并检查，返回验证报告
+// 恢复失败也会返回报告，error 只表示验证过程本身出错
+func Run(cnf *config.BackupConfig, indexFile string, metaInfo *dbareport.IndexContent) (*dbareport.VerifyReport,
+	error) {
+	report := dbareport.NewVerifyReport(indexFile, metaInfo)
+	if cnf.Verify.StartTimeout <= 0 {
+		cnf.Verify.StartTimeout = 600
+	}
+	scratch, err := newScratchMysqld(&cnf.Verify)
+	if err != nil {
+		return nil, err
+	}
+	defer scratch.cleanup()
+
+	loadDir := cnf.PhysicalLoad.MysqlLoadDir
+	if metaInfo.BackupType == cst.BackupLogical {
+		loadDir = cnf.LogicalLoad.MysqlLoadDir
+	}
+	if loadDir == "" {
+		return nil, errors.New("load dir is required for verify")
+	}
+	// 恢复前先从备份文件里拿到表清单，物理备份 prepare 之后也不会变化
+	expectTables, err := backupTables(loadDir, metaInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = restore(cnf, scratch, loadDir, indexFile, metaInfo); err != nil {
+		report.AddCheck("restore", false, err.Error())
+		return report, nil
+	}
+	report.AddCheck("restore", true, fmt.Sprintf("%s backup restored from %s", metaInfo.BackupType, loadDir))
+
+	// 恢复完用 skip-grant-tables 重启，不依赖备份里的账号
+	_ = scratch.stop()
+	if err = scratch.start(true); err != nil {
+		report.AddCheck("start", false, err.Error())
+		return report, nil
+	}
+	report.AddCheck("start", true, "scratch mysqld started after restore")
+
+	db, err := scratch.conn()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+	db.SetMaxOpenConns(1)
+
+	if expectTables != nil {
+		checkTables(report, db, expectTables)
+	}
+	checkSanityQueries(report, db, cnf.Verify.SanityQueries)
+	checkChecksumTables(report, db, cnf.Verify.ChecksumTables, metaInfo.TableChecksums)
+	return report, nil
+}
+
+// restore 准备 scratch 实例并调用 loadbackup 的逻辑恢复
+func restore(cnf *config.BackupConfig, scratch *scratchMysqld, loadDir, indexFile string,
+	metaInfo *dbareport.IndexContent) error {
+	if metaInfo.BackupType == cst.BackupPhysical {
+		if err := scratch.writeMyCnf(filepath.Join(loadDir, "backup-my.cnf")); err != nil {
+			return err
+		}
+		cnf.PhysicalLoad.MysqlLoadDir = loadDir
+		cnf.PhysicalLoad.IndexFilePath = indexFile
+		cnf.PhysicalLoad.DefaultsFile = scratch.myCnf
+		cnf.PhysicalLoad.CopyBack = true
+		return backupexe.ExecuteLoad(cnf, metaInfo)
+	}
+
+	if err := scratch.writeMyCnf(""); err != nil {
+		return err
+	}
+	if err := scratch.initialize(metaInfo.MysqlVersion); err != nil {
+		return err
+	}
+	if err := scratch.start(false); err != nil {
+		return err
+	}
+	password := cmutil.RandomString(16)
+	if err := createVerifyUser(scratch, password); err != nil {
+		return err
+	}
+	cnf.LogicalLoad.MysqlLoadDir = loadDir
+	cnf.LogicalLoad.IndexFilePath = indexFile
+	cnf.LogicalLoad.MysqlHost = "127.0.0.1"
+	cnf.LogicalLoad.MysqlPort = cnf.Verify.ScratchPort
+	cnf.LogicalLoad.MysqlUser = verifyUser
+	cnf.LogicalLoad.MysqlPasswd = password
+	return backupexe.ExecuteLoad(cnf, metaInfo)
+}
+
+func createVerifyUser(scratch *scratchMysqld, password string) error {
+	db, err := scratch.conn()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+	sqls := []string{
+		fmt.Sprintf("CREATE USER '%s'@'127.0.0.1' IDENTIFIED BY '%s'", verifyUser, password),
+		fmt.Sprintf("GRANT ALL PRIVILEGES ON *.* TO '%s'@'127.0.0.1' WITH GRANT OPTION", verifyUser),
+	}
+	for _, s := range sqls {
+		if _, err = db.Exec(s); err != nil {
+			return errors.Wrapf(err, "create verify user %s", verifyUser)
+		}
+	}
+	return nil
+}
+
+// backupTables 从备份文件得到 db.table 清单
+// mysqldump 备份是单个 sql 文件，拿不到表清单，返回 nil 跳过表对比
+func backupTables(loadDir string, metaInfo *dbareport.IndexContent) (map[string]struct{}, error) {
+	tables := make(map[string]struct{})
+	if metaInfo.BackupType == cst.BackupLogical {
+		if metaInfo.BackupTool == cst.ToolMysqldump {
+			logger.Log.Warn("mysqldump backup has no table list, skip tables check")
+			return nil, nil
+		}
+		files, err := filepath.Glob(filepath.Join(loadDir, "*-schema.sql*"))
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range files {
+			name := filepath.Base(f)
+			name = name[:strings.Index(name, "-schema.sql")]
+			if strings.Count(name, ".") != 1 || strings.HasSuffix(name, "-schema-create") {
+				continue // db-schema-create.sql, view, trigger ...
+			}
+			dbName := name[:strings.Index(name, ".")]
+			if !cmutil.StringsHas(systemDbs, dbName) {
+				tables[name] = struct{}{}
+			}
+		}
+		return tables, nil
+	}
+
+	entries, err := os.ReadDir(loadDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		if !e.IsDir() || cmutil.StringsHas(systemDbs, e.Name()) || strings.Contains(e.Name(), "@") {
+			continue
+		}
+		files, err := os.ReadDir(filepath.Join(loadDir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range files {
+			name := strings.TrimSuffix(f.Name(), ".qp")
+			ext := filepath.Ext(name)
+			if ext != ".frm" && ext != ".ibd" {
+				continue
+			}
+			name = strings.TrimSuffix(name, ext)
+			if i := strings.Index(name, "#"); i > 0 {
+				name = name[:i] // 分区表 t#P#p0
+			}
+			if strings.Contains(name, "@") {
+				continue // 需要转码的表名，跳过
+			}
+			tables[e.Name()+"."+name] = struct{}{}
+		}
+	}
+	return tables, nil
+}
+
+// checkTables 备份里的表在恢复后都要存在
+func checkTables(report *dbareport.VerifyReport, db *sql.DB, expect map[string]struct{}) {
+	rows, err := db.Query("SELECT TABLE_SCHEMA, TABLE_NAME FROM information_schema.TABLES " +
+		"WHERE TABLE_TYPE='BASE TABLE'")
+	if err != nil {
+		report.AddCheck("tables", false, err.Error())
+		return
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+	restored := make(map[string]struct{})
+	for rows.Next() {
+		var dbName, tbName string
+		if err = rows.Scan(&dbName, &tbName); err != nil {
+			report.AddCheck("tables", false, err.Error())
+			return
+		}
+		restored[dbName+"."+tbName] = struct{}{}
+	}
+	var missing []string
+	for t := range expect {
+		if _, ok := restored[t]; !ok {
+			missing = append(missing, t)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		report.AddCheck("tables", false, fmt.Sprintf("%d of %d tables missing after restore: %s",
+			len(missing), len(expect), strings.Join(missing, ",")))
+		return
+	}
+	report.AddCheck("tables", true, fmt.Sprintf("%d tables restored", len(expect)))
+}
+
+// checkSanityQueries 每条 sql 返回的第一行第一列不能是 0, 空字符串或者 NULL
+func checkSanityQueries(report *dbareport.VerifyReport, db *sql.DB, queries string) {
+	for _, q := range strings.Split(queries, ";") {
+		q = strings.TrimSpace(q)
+		if q == "" {
+			continue
+		}
+		var val sql.NullString
+		rows, err := db.Query(q)
+		if err != nil {
+			report.AddCheck("sanity_query", false, fmt.Sprintf("%s: %s", q, err.Error()))
+			continue
+		}
+		cols, _ := rows.Columns()
+		if rows.Next() && len(cols) > 0 {
+			dest := make([]interface{}, len(cols))
+			dest[0] = &val
+			for i := 1; i < len(cols); i++ {
+				dest[i] = new(sql.RawBytes)
+			}
+			err = rows.Scan(dest...)
+		}
+		_ = rows.Close()
+		if err != nil {
+			report.AddCheck("sanity_query", false, fmt.Sprintf("%s: %s", q, err.Error()))
+		} else if !val.Valid || val.String == "" || val.String == "0" {
+			report.AddCheck("sanity_query", false, fmt.Sprintf("%s: got %q", q, val.String))
+		} else {
+			report.AddCheck("sanity_query", true, fmt.Sprintf("%s: got %q", q, val.String))
+		}
+	}
+}
+
+// checkChecksumTables 比对指定表恢复后的行数和 CHECKSUM TABLE 结果与备份时 index 中记录的是否一致
+func checkChecksumTables(report *dbareport.VerifyReport, db *sql.DB, tables string,
+	recorded []dbareport.TableChecksum) {
+	for _, t := range strings.Split(tables, ",") {
+		t = strings.TrimSpace(t)
+		if t == "" {
+			continue
+		}
+		idx := slices.IndexFunc(recorded, func(c dbareport.TableChecksum) bool { return c.Table == t })
+		if idx < 0 {
+			report.AddCheck("checksum", false, fmt.Sprintf("%s: not recorded in backup index", t))
+			continue
+		}
+		expect := recorded[idx]
+		if expect.Changed {
+			report.AddCheck("checksum", false, fmt.Sprintf(
+				"%s: changed during backup, only static tables can be checked", t))
+			continue
+		}
+		rows, checksum, err := mysqlconn.ChecksumTable(t, db)
+		if err != nil {
+			report.AddCheck("checksum", false, err.Error())
+			continue
+		}
+		if rows != expect.Rows || checksum != expect.Checksum {
+			report.AddCheck("checksum", false, fmt.Sprintf(
+				"%s: rows=%d checksum=%d, but backup has rows=%d checksum=%d",
+				t, rows, checksum, expect.Rows, expect.Checksum))
+			continue
+		}
+		report.AddCheck("checksum", true, fmt.Sprintf("%s: rows=%d checksum=%d", t, rows, checksum))
+	}
+}
@@ -43,6 +43,12 @@ type TarWriter struct {
 	mu                sync.Mutex
 
 	splitWriter *SplitWriter
+
+	streamWriter io.WriteCloser
+	streamDone   chan error
+	// streamErr 加密流写入 dst 失败，后续文件不再打包
+	streamErr error
+	streamMu  sync.Mutex
 }
 
 type TarSplitWriter struct {
@@ -84,6 +90,59 @@ func (t *TarWriter) GetSplitTars() map[string]int {
 	return t.splitWriter.ReturnFiles()
 }
 
+// NewStream tar 流直接写入 dst，比如存储后端的 writer
+// 加密时加密进程的 stdout 是一个 pipe，Close 会等 pipe 里的数据全部写入 dst，再关闭 dst
+func (t *TarWriter) NewStream(dst io.WriteCloser) (err error) {
+	t.streamWriter = dst
+	if !t.Encrypt {
+		t.tarWriter = tar.NewWriter(dst)
+		return nil
+	}
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	t.destEncryptWriter, err = iocrypt.FileEncryptWriter(t.EncryptTool, pw)
+	// 加密进程已经继承了 pw，这里关闭后，进程退出时 pr 会读到 EOF
+	_ = pw.Close()
+	if err != nil {
+		_ = pr.Close()
+		return err
+	}
+	t.streamDone = make(chan error, 1)
+	go func() {
+		_, copyErr := io.Copy(dst, pr)
+		if copyErr != nil {
+			t.streamMu.Lock()
+			t.streamErr = copyErr
+			t.streamMu.Unlock()
+			// 继续读完，避免加密进程阻塞在写 stdout
+			_, _ = io.Copy(io.Discard, pr)
+		}
+		_ = pr.Close()
+		t.streamDone <- copyErr
+	}()
+	t.tarWriter = tar.NewWriter(t.destEncryptWriter)
+	return nil
+}
+
+// closeStream 关闭 tar 流，返回数据是否完整写入 dst
+func (t *TarWriter) closeStream() error {
+	err := t.tarWriter.Close()
+	if t.Encrypt {
+		if encErr := t.destEncryptWriter.Close(); err == nil {
+			err = encErr
+		}
+		if copyErr := <-t.streamDone; err == nil {
+			err = copyErr
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return t.streamWriter.Close()
+}
+
 // New TODO
 // init tarWriter destFileWriter destEncryptWriter
 // will open destFile
@@ -112,6 +171,12 @@ func (t *TarWriter) WriteTar(header *tar.Header, srcFile string) (isFile bool, w
 	t.mu.Lock()
 	defer t.mu.Unlock()
 	isFile = true
+	t.streamMu.Lock()
+	err = t.streamErr
+	t.streamMu.Unlock()
+	if err != nil {
+		return isFile, 0, errors.WithMessage(err, "write tar stream")
+	}
 	if err = t.tarWriter.WriteHeader(header); err != nil {
 		return isFile, 0, err
 	}
@@ -137,6 +202,9 @@ func (t *TarWriter) WriteTar(header *tar.Header, srcFile string) (isFile bool, w
 // will close destFile
 // close won't reset IOLimitMB EncryptTool, could reuse it with new tarFilename
 func (t *TarWriter) Close() error {
+	if t.streamWriter != nil {
+		return t.closeStream()
+	}
 	defer func() {
 		if t.Encrypt {
 			t.destEncryptWriter.Close()
@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 
 	"dbm-services/mysql/db-tools/mysql-monitor/pkg/config"
+	"dbm-services/mysql/db-tools/mysql-monitor/pkg/itemscollect"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -43,6 +44,12 @@ var subCmdReschedule = &cobra.Command{
 			return err
 		}
 
+		err = itemscollect.RegisterCustomItems()
+		if err != nil {
+			slog.Error("reschedule register custom items", slog.String("error", err.Error()))
+			return err
+		}
+
 		config.InjectMonitorDbUpItem()
 		config.InjectMonitorHeartBeatItem()
 
@@ -4,6 +4,7 @@ import (
 	"log/slog"
 
 	"dbm-services/mysql/db-tools/mysql-monitor/pkg/config"
+	"dbm-services/mysql/db-tools/mysql-monitor/pkg/itemscollect"
 	"dbm-services/mysql/db-tools/mysql-monitor/pkg/mainloop"
 
 	"github.com/spf13/cobra"
@@ -27,6 +28,12 @@ var subCmdRun = &cobra.Command{
 			return err
 		}
 
+		err = itemscollect.RegisterCustomItems()
+		if err != nil {
+			slog.Error("run monitor register custom items", slog.String("error", err.Error()))
+			return err
+		}
+
 		err = mainloop.Run(false)
 		if err != nil {
 			slog.Error("run monitor items", slog.String("error", err.Error()))
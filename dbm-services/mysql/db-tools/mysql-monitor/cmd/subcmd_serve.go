@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"log/slog"
+	"net/http"
+
+	"dbm-services/mysql/db-tools/mysql-monitor/pkg/config"
+	"dbm-services/mysql/db-tools/mysql-monitor/pkg/exporter"
+	"dbm-services/mysql/db-tools/mysql-monitor/pkg/itemscollect"
+	"dbm-services/mysql/db-tools/mysql-monitor/pkg/mainloop"
+	"dbm-services/mysql/db-tools/mysql-monitor/pkg/utils"
+
+	"github.com/robfig/cron/v3"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var subCmdServe = &cobra.Command{
+	Use:   "serve",
+	Short: "long running with OpenMetrics endpoint",
+	Long:  "schedule monitor items internally and expose results as OpenMetrics",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		err := config.InitConfig(viper.GetString("serve-config"))
+		if err != nil {
+			return err
+		}
+		initLogger(config.MonitorConfig.Log)
+
+		err = config.LoadMonitorItemsConfig()
+		if err != nil {
+			slog.Error("serve load items", slog.String("error", err.Error()))
+			return err
+		}
+
+		err = itemscollect.RegisterCustomItems()
+		if err != nil {
+			slog.Error("serve register custom items", slog.String("error", err.Error()))
+			return err
+		}
+		config.InjectHardCodeItem()
+
+		exporter.Enable()
+		utils.PushToCrond = viper.GetBool("serve-push-crond")
+
+		c := cron.New(
+			cron.WithParser(
+				cron.NewParser(
+					cron.SecondOptional |
+						cron.Minute |
+						cron.Hour |
+						cron.Dom |
+						cron.Month |
+						cron.Dow |
+						cron.Descriptor,
+				),
+			),
+		)
+
+		itemGroups, hardCodeItems := config.GroupItemsBySchedule()
+		for schedule, items := range itemGroups {
+			var itemNames []string
+			for _, ele := range items {
+				itemNames = append(itemNames, ele.Name)
+			}
+			entryID, err := c.AddFunc(schedule, func() {
+				_ = mainloop.RunItems(itemNames, false)
+			})
+			if err != nil {
+				slog.Error("serve add items", slog.String("error", err.Error()), slog.String("schedule", schedule))
+				return err
+			}
+			slog.Info(
+				"serve add items",
+				slog.Int("entry id", int(entryID)),
+				slog.String("schedule", schedule),
+				slog.Any("items", itemNames),
+			)
+		}
+
+		for _, ele := range hardCodeItems {
+			itemNames := []string{ele.Name}
+			entryID, err := c.AddFunc(config.HardCodeSchedule, func() {
+				_ = mainloop.RunItems(itemNames, true)
+			})
+			if err != nil {
+				slog.Error("serve add hardcode item", slog.String("error", err.Error()), slog.String("name", ele.Name))
+				return err
+			}
+			slog.Info("serve add hardcode item", slog.Int("entry id", int(entryID)), slog.String("name", ele.Name))
+		}
+
+		c.Start()
+		defer c.Stop()
+
+		listen := viper.GetString("serve-listen")
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", exporter.Handler())
+		slog.Info("serve start", slog.String("listen", listen))
+		return http.ListenAndServe(listen, mux)
+	},
+}
+
+func init() {
+	subCmdServe.PersistentFlags().StringP("config", "c", "", "config file")
+	_ = subCmdServe.MarkPersistentFlagRequired("config")
+	_ = viper.BindPFlag("serve-config", subCmdServe.PersistentFlags().Lookup("config"))
+
+	subCmdServe.PersistentFlags().StringP("listen", "l", "", "OpenMetrics listen address, e.g. 127.0.0.1:9104")
+	_ = subCmdServe.MarkPersistentFlagRequired("listen")
+	_ = viper.BindPFlag("serve-listen", subCmdServe.PersistentFlags().Lookup("listen"))
+
+	subCmdServe.PersistentFlags().Bool("push-crond", true, "also push events and metrics through mysql-crond")
+	_ = viper.BindPFlag("serve-push-crond", subCmdServe.PersistentFlags().Lookup("push-crond"))
+
+	rootCmd.AddCommand(subCmdServe)
+}
@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"dbm-services/mysql/db-tools/mysql-monitor/pkg/alertstate"
+	"dbm-services/mysql/db-tools/mysql-monitor/pkg/config"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var subCmdSilence = &cobra.Command{
+	Use:   "silence",
+	Short: "silence monitor events for maintenance",
+	Long:  "silence monitor events for maintenance",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		err := config.InitConfig(viper.GetString("silence-config"))
+		if err != nil {
+			return err
+		}
+		initLogger(config.MonitorConfig.Log)
+
+		items := viper.GetStringSlice("silence-items")
+
+		if viper.GetBool("silence-list") {
+			silences, err := alertstate.ListSilences()
+			if err != nil {
+				slog.Error("silence list", slog.String("error", err.Error()))
+				return err
+			}
+			printSilences(silences)
+			return nil
+		}
+
+		if viper.GetBool("silence-clear") {
+			cleared, err := alertstate.ClearSilences(items)
+			if err != nil {
+				slog.Error("silence clear", slog.String("error", err.Error()))
+				return err
+			}
+			fmt.Printf("%d silence cleared\n", cleared)
+			return nil
+		}
+
+		duration := viper.GetDuration("silence-duration")
+		if duration <= 0 {
+			err = fmt.Errorf("duration required and must be positive")
+			slog.Error("silence add", slog.String("error", err.Error()))
+			return err
+		}
+
+		now := time.Now()
+		s := &alertstate.Silence{
+			Items:     items,
+			Until:     now.Add(duration),
+			Reason:    viper.GetString("silence-reason"),
+			Staff:     viper.GetString("silence-staff"),
+			CreatedAt: now,
+		}
+		err = alertstate.AddSilence(s)
+		if err != nil {
+			slog.Error("silence add", slog.String("error", err.Error()))
+			return err
+		}
+		slog.Info(
+			"silence add",
+			slog.Any("items", s.Items),
+			slog.Time("until", s.Until),
+			slog.String("staff", s.Staff),
+		)
+		fmt.Printf("silenced until %s\n", s.Until.Format(time.RFC3339))
+		return nil
+	},
+}
+
+func printSilences(silences []*alertstate.Silence) {
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Items", "Until", "Staff", "Reason"})
+	for _, s := range silences {
+		items := "*"
+		if len(s.Items) > 0 {
+			items = strings.Join(s.Items, ",")
+		}
+		table.Append([]string{items, s.Until.Format(time.RFC3339), s.Staff, s.Reason})
+	}
+	table.Render()
+}
+
+func init() {
+	subCmdSilence.PersistentFlags().StringP("config", "c", "", "config file")
+	_ = subCmdSilence.MarkPersistentFlagRequired("config")
+	_ = viper.BindPFlag("silence-config", subCmdSilence.PersistentFlags().Lookup("config"))
+
+	subCmdSilence.PersistentFlags().Duration("duration", 0, "silence duration, e.g. 2h")
+	_ = viper.BindPFlag("silence-duration", subCmdSilence.PersistentFlags().Lookup("duration"))
+
+	subCmdSilence.PersistentFlags().StringSlice("items", nil, "event names to silence, empty means all")
+	_ = viper.BindPFlag("silence-items", subCmdSilence.PersistentFlags().Lookup("items"))
+
+	subCmdSilence.PersistentFlags().String("reason", "", "silence reason")
+	_ = viper.BindPFlag("silence-reason", subCmdSilence.PersistentFlags().Lookup("reason"))
+
+	subCmdSilence.PersistentFlags().String("staff", "", "staff name")
+	_ = viper.BindPFlag("silence-staff", subCmdSilence.PersistentFlags().Lookup("staff"))
+
+	subCmdSilence.PersistentFlags().Bool("list", false, "list active silences")
+	_ = viper.BindPFlag("silence-list", subCmdSilence.PersistentFlags().Lookup("list"))
+
+	subCmdSilence.PersistentFlags().Bool("clear", false, "clear silences matching --items, all if --items empty")
+	_ = viper.BindPFlag("silence-clear", subCmdSilence.PersistentFlags().Lookup("clear"))
+
+	rootCmd.AddCommand(subCmdSilence)
+}
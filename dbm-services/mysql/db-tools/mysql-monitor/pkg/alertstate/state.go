@@ -0,0 +1,295 @@
+// TencentBlueKing is pleased to support the open source community by making 蓝鲸智云-DB管理系统(BlueKing-BK-DBM) available.
+// Copyright (C) 2017-2023 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+// Package alertstate 本地告警状态, 用于重复告警抑制, 恢复通知和维护静默
+package alertstate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"dbm-services/mysql/db-tools/dbactuator/pkg/core/cst"
+	"dbm-services/mysql/db-tools/mysql-monitor/pkg/config"
+
+	"github.com/juju/fslock"
+	"github.com/pkg/errors"
+)
+
+// itemState 监控项最近一次告警
+type itemState struct {
+	Fingerprint string    `json:"fingerprint"`
+	FirstSeen   time.Time `json:"first_seen"`
+	LastSeen    time.Time `json:"last_seen"`
+	LastSent    time.Time `json:"last_sent"`
+	Suppressed  int       `json:"suppressed"`
+}
+
+// Silence 维护静默, Items 为空表示静默所有事件
+type Silence struct {
+	Items     []string  `json:"items"`
+	Until     time.Time `json:"until"`
+	Reason    string    `json:"reason"`
+	Staff     string    `json:"staff"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// IsMatch 是否静默这个事件
+func (s *Silence) IsMatch(name string, now time.Time) bool {
+	if now.After(s.Until) {
+		return false
+	}
+	return len(s.Items) == 0 || slices.Index(s.Items, name) >= 0
+}
+
+type state struct {
+	Items    map[string]*itemState `json:"items"`
+	Silences []*Silence            `json:"silences"`
+}
+
+// 同一个进程内并发的监控项也要互斥, fslock 只管多个进程之间
+var mu sync.Mutex
+
+func stateFilePath() string {
+	return filepath.Join(
+		cst.MySQLMonitorInstallPath,
+		fmt.Sprintf("alert-state-%d.json", config.MonitorConfig.Port),
+	)
+}
+
+// lockState 进程内和进程间加锁, 返回的函数用于解锁
+func lockState(path string) (unlock func(), err error) {
+	mu.Lock()
+	lk := fslock.New(path + ".lock")
+	err = lk.LockWithTimeout(10 * time.Second)
+	if err != nil {
+		mu.Unlock()
+		return nil, errors.Wrapf(err, "lock alert state %s", path)
+	}
+	return func() {
+		_ = lk.Unlock()
+		mu.Unlock()
+	}, nil
+}
+
+func readState(path string) (*state, error) {
+	st := &state{}
+	content, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, errors.Wrapf(err, "read alert state %s", path)
+	}
+	if len(content) > 0 {
+		err = json.Unmarshal(content, st)
+		if err != nil {
+			// 状态文件损坏就重新开始, 最多多发一次告警
+			slog.Warn("unmarshal alert state", slog.String("error", err.Error()))
+			st = &state{}
+		}
+	}
+	if st.Items == nil {
+		st.Items = make(map[string]*itemState)
+	}
+	return st, nil
+}
+
+// view 加锁读出状态, 只读不写回
+func view(f func(st *state)) error {
+	path := stateFilePath()
+	unlock, err := lockState(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	st, err := readState(path)
+	if err != nil {
+		return err
+	}
+	f(st)
+	return nil
+}
+
+// update 加锁读出状态, f 修改后写回. 过期的静默在写回时清理
+func update(f func(st *state) error) error {
+	path := stateFilePath()
+	unlock, err := lockState(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	st, err := readState(path)
+	if err != nil {
+		return err
+	}
+
+	err = f(st)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	st.Silences = slices.DeleteFunc(st.Silences, func(s *Silence) bool {
+		return now.After(s.Until)
+	})
+
+	content, err := json.Marshal(st)
+	if err != nil {
+		return errors.Wrap(err, "marshal alert state")
+	}
+	tmpPath := path + ".tmp"
+	err = os.WriteFile(tmpPath, content, 0644)
+	if err != nil {
+		return errors.Wrapf(err, "write alert state %s", tmpPath)
+	}
+	return os.Rename(tmpPath, path)
+}
+
+func fingerprint(msg string) string {
+	sum := sha256.Sum256([]byte(msg))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Fire 监控项产生告警, 返回是否需要发送
+// 相同内容在抑制窗口内只发送一次, 内容变化立即发送
+// 状态读写失败时总是发送
+func Fire(name string, msg string) bool {
+	send := true
+	err := update(func(st *state) error {
+		now := time.Now()
+		for _, s := range st.Silences {
+			if s.IsMatch(name, now) {
+				slog.Info("alert silenced", slog.String("name", name), slog.String("reason", s.Reason))
+				send = false
+				return nil
+			}
+		}
+
+		fp := fingerprint(msg)
+		is, ok := st.Items[name]
+		if !ok || is.Fingerprint != fp {
+			st.Items[name] = &itemState{
+				Fingerprint: fp,
+				FirstSeen:   now,
+				LastSeen:    now,
+				LastSent:    now,
+			}
+			return nil
+		}
+
+		is.LastSeen = now
+		if now.Sub(is.LastSent) >= config.GetItemSuppressWindow(name) {
+			is.LastSent = now
+			is.Suppressed = 0
+			return nil
+		}
+
+		is.Suppressed += 1
+		send = false
+		slog.Info(
+			"alert suppressed",
+			slog.String("name", name),
+			slog.Time("last sent", is.LastSent),
+			slog.Int("suppressed", is.Suppressed),
+		)
+		return nil
+	})
+	if err != nil {
+		slog.Error("alert state fire", slog.String("error", err.Error()), slog.String("name", name))
+		return true
+	}
+	return send
+}
+
+// Pass 监控项通过, 如果之前发送过告警返回 true 和告警持续时间
+func Pass(name string) (recovered bool, duration time.Duration) {
+	err := update(func(st *state) error {
+		is, ok := st.Items[name]
+		if !ok {
+			return nil
+		}
+		delete(st.Items, name)
+
+		if !is.LastSent.IsZero() {
+			recovered = true
+			duration = time.Since(is.FirstSeen)
+		}
+		return nil
+	})
+	if err != nil {
+		slog.Error("alert state pass", slog.String("error", err.Error()), slog.String("name", name))
+		return false, 0
+	}
+	return recovered, duration
+}
+
+// IsSilenced 事件是否在静默中
+func IsSilenced(name string) bool {
+	silences, err := ListSilences()
+	if err != nil {
+		slog.Error("alert state list silences", slog.String("error", err.Error()))
+		return false
+	}
+
+	now := time.Now()
+	for _, s := range silences {
+		if s.IsMatch(name, now) {
+			return true
+		}
+	}
+	return false
+}
+
+// AddSilence 新增静默
+func AddSilence(s *Silence) error {
+	return update(func(st *state) error {
+		st.Silences = append(st.Silences, s)
+		return nil
+	})
+}
+
+// ListSilences 未过期的静默, 不修改状态文件
+func ListSilences() (silences []*Silence, err error) {
+	err = view(func(st *state) {
+		now := time.Now()
+		for _, s := range st.Silences {
+			if !now.After(s.Until) {
+				silences = append(silences, s)
+			}
+		}
+	})
+	return silences, err
+}
+
+// ClearSilences 清除包含 items 中任意一项的静默, items 为空清除所有
+func ClearSilences(items []string) (cleared int, err error) {
+	err = update(func(st *state) error {
+		before := len(st.Silences)
+		st.Silences = slices.DeleteFunc(st.Silences, func(s *Silence) bool {
+			if len(items) == 0 {
+				return true
+			}
+			for _, name := range items {
+				if slices.Index(s.Items, name) >= 0 {
+					return true
+				}
+			}
+			return false
+		})
+		cleared = before - len(st.Silences)
+		return nil
+	})
+	return cleared, err
+}
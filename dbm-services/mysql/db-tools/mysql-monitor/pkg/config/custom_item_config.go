@@ -0,0 +1,44 @@
+package config
+
+// 自定义 SQL 监控项可以使用的连接
+const (
+	CustomConnMySQL = "mysql"
+	CustomConnProxy = "proxy"
+	CustomConnCtl   = "ctl"
+)
+
+// CustomSQLItem 自定义 SQL 监控项
+// 查询结果的每一行都可以生成一个指标, 也可以和阈值比较生成事件
+type CustomSQLItem struct {
+	// Connection 执行 SQL 的连接, 默认 mysql
+	Connection string `json:"connection" yaml:"connection" validate:"omitempty,oneof=mysql proxy ctl"`
+	Query      string `json:"query" yaml:"query" validate:"required"`
+	// ValueColumn 取值的列, 指标和阈值比较都用这一列
+	ValueColumn string           `json:"value_column" yaml:"value_column"`
+	Metric      *CustomSQLMetric `json:"metric,omitempty" yaml:"metric,omitempty"`
+	Alarm       *CustomSQLAlarm  `json:"alarm,omitempty" yaml:"alarm,omitempty"`
+}
+
+// CustomSQLMetric 查询结果转指标
+type CustomSQLMetric struct {
+	Name string `json:"name" yaml:"name" validate:"required"`
+	// DimensionColumns 这些列的值作为指标维度
+	DimensionColumns []string `json:"dimension_columns" yaml:"dimension_columns"`
+}
+
+// CustomSQLAlarm 查询结果转事件
+// Operator 为空时只要查询有返回行就告警
+type CustomSQLAlarm struct {
+	Operator  string  `json:"operator" yaml:"operator" validate:"omitempty,oneof=gt ge lt le eq ne"`
+	Threshold float64 `json:"threshold" yaml:"threshold"`
+	// Message 事件内容模板, text/template 语法, 可以引用行内的列名
+	Message string `json:"message" yaml:"message"`
+}
+
+// GetConnection 连接类型
+func (c *CustomSQLItem) GetConnection() string {
+	if c.Connection == "" {
+		return CustomConnMySQL
+	}
+	return c.Connection
+}
@@ -5,6 +5,7 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 	"gopkg.in/yaml.v2"
@@ -15,6 +16,15 @@ var MonitorConfig *Config
 var ItemsConfig []*MonitorItem
 var HardCodeSchedule = "@every 10s"
 
+// DefaultItemTimeout 监控项默认执行超时
+var DefaultItemTimeout = 5 * time.Minute
+
+// DefaultAlertSuppressWindow 相同告警默认的重复发送间隔
+var DefaultAlertSuppressWindow = time.Hour
+
+// DefaultItemConcurrency 监控项默认并发数
+var DefaultItemConcurrency = 4
+
 // InitConfig 配置初始化
 func InitConfig(configPath string) error {
 	fmt.Printf("config flag: %s\n", configPath)
@@ -167,3 +177,26 @@ func WriteMonitorItemsBack() error {
 	}
 	return nil
 }
+
+// GroupItemsBySchedule 按 schedule 把启用且匹配的监控项分组, 硬编码项单独返回
+func GroupItemsBySchedule() (groups map[string][]*MonitorItem, hardCodeItems []*MonitorItem) {
+	groups = make(map[string][]*MonitorItem)
+	for _, ele := range ItemsConfig {
+		// 硬编码监控项先排除掉
+		if ele.Name == "db-up" || ele.Name == HeartBeatName {
+			if ele.IsEnable() {
+				hardCodeItems = append(hardCodeItems, ele)
+			}
+			continue
+		}
+
+		if ele.IsEnable() && ele.IsMatchMachineType() && ele.IsMatchRole() {
+			key := MonitorConfig.DefaultSchedule
+			if ele.Schedule != nil {
+				key = *ele.Schedule
+			}
+			groups[key] = append(groups[key], ele)
+		}
+	}
+	return groups, hardCodeItems
+}
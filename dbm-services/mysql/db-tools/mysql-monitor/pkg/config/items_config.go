@@ -1,6 +1,9 @@
 package config
 
-import "slices"
+import (
+	"slices"
+	"time"
+)
 
 // MonitorItem 监控项
 type MonitorItem struct {
@@ -9,6 +12,12 @@ type MonitorItem struct {
 	Schedule    *string  `json:"schedule" yaml:"schedule"`
 	MachineType []string `json:"machine_type" yaml:"machine_type"`
 	Role        []string `json:"role" yaml:"role"`
+	// Timeout 单个监控项执行超时, 不配置使用 runtime 配置的 item_timeout
+	Timeout time.Duration `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	// SuppressWindow 相同告警的重复发送间隔, 不配置使用 runtime 配置的 alert_suppress_window
+	SuppressWindow time.Duration `json:"suppress_window,omitempty" yaml:"suppress_window,omitempty"`
+	// Custom 自定义 SQL 监控项, 不需要发版
+	Custom *CustomSQLItem `json:"custom,omitempty" yaml:"custom,omitempty"`
 }
 
 // IsEnable 监控项启用
@@ -37,3 +46,29 @@ func (c *MonitorItem) IsMatchRole() bool {
 
 	return slices.Index(c.Role, *MonitorConfig.Role) >= 0
 }
+
+// GetItemTimeout 监控项超时, 监控项没有配置时使用全局默认
+func GetItemTimeout(name string) time.Duration {
+	for _, ele := range ItemsConfig {
+		if ele.Name == name && ele.Timeout > 0 {
+			return ele.Timeout
+		}
+	}
+	if MonitorConfig.ItemTimeout > 0 {
+		return MonitorConfig.ItemTimeout
+	}
+	return DefaultItemTimeout
+}
+
+// GetItemSuppressWindow 监控项告警抑制窗口, 监控项没有配置时使用全局默认
+func GetItemSuppressWindow(name string) time.Duration {
+	for _, ele := range ItemsConfig {
+		if ele.Name == name && ele.SuppressWindow > 0 {
+			return ele.SuppressWindow
+		}
+	}
+	if MonitorConfig.AlertSuppressWindow > 0 {
+		return MonitorConfig.AlertSuppressWindow
+	}
+	return DefaultAlertSuppressWindow
+}
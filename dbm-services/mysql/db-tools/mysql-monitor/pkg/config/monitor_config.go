@@ -33,4 +33,10 @@ type Config struct {
 	DBASysDbs       []string      `yaml:"dba_sys_dbs" validate:"required"`
 	InteractTimeout time.Duration `yaml:"interact_timeout" validate:"required"`
 	DefaultSchedule string        `yaml:"default_schedule" validate:"required"`
+	// ItemTimeout 监控项默认执行超时, 默认 DefaultItemTimeout
+	ItemTimeout time.Duration `yaml:"item_timeout" validate:"gte=0"`
+	// ItemConcurrency 同一轮并发执行的监控项数量, 默认 DefaultItemConcurrency
+	ItemConcurrency int `yaml:"item_concurrency" validate:"gte=0"`
+	// AlertSuppressWindow 相同告警的重复发送间隔, 默认 DefaultAlertSuppressWindow
+	AlertSuppressWindow time.Duration `yaml:"alert_suppress_window" validate:"gte=0"`
 }
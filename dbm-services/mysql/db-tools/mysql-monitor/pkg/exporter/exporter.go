@@ -0,0 +1,158 @@
+// TencentBlueKing is pleased to support the open source community by making 蓝鲸智云-DB管理系统(BlueKing-BK-DBM) available.
+// Copyright (C) 2017-2023 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+// Package exporter serve 模式下把监控项结果暴露为 OpenMetrics
+package exporter
+
+import (
+	"log/slog"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/spf13/cast"
+)
+
+const (
+	// EventTotalName 事件计数, 每次发送事件加 1
+	EventTotalName = "mysql_monitor_event_total"
+	// ItemResultName 监控项最近一次结果, 0 通过, 1 有告警, 2 执行失败或超时
+	ItemResultName = "mysql_monitor_item_result"
+)
+
+const (
+	ItemResultPass = iota
+	ItemResultAlarm
+	ItemResultError
+)
+
+var invalidNameChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+type sample struct {
+	name        string
+	labelNames  []string
+	labelValues []string
+	value       float64
+	valueType   prometheus.ValueType
+}
+
+type store struct {
+	mu      sync.Mutex
+	enabled bool
+	samples map[string]*sample
+}
+
+var defaultStore = &store{samples: make(map[string]*sample)}
+
+// Enable 开启后 SetGauge, IncEvent 才会记录
+func Enable() {
+	defaultStore.mu.Lock()
+	defer defaultStore.mu.Unlock()
+	defaultStore.enabled = true
+}
+
+// Enabled 是否 serve 模式
+func Enabled() bool {
+	defaultStore.mu.Lock()
+	defer defaultStore.mu.Unlock()
+	return defaultStore.enabled
+}
+
+// SetGauge 记录一个指标, dimension 转为 label
+func SetGauge(name string, value float64, dimension map[string]interface{}) {
+	defaultStore.update(name, dimension, prometheus.GaugeValue, func(s *sample) {
+		s.value = value
+	})
+}
+
+// IncEvent 事件计数加 1, 事件内容不作为 label 避免基数膨胀
+func IncEvent(name string, dimension map[string]interface{}) {
+	labels := make(map[string]interface{}, len(dimension)+1)
+	for k, v := range dimension {
+		labels[k] = v
+	}
+	labels["event_name"] = name
+	defaultStore.update(EventTotalName, labels, prometheus.CounterValue, func(s *sample) {
+		s.value++
+	})
+}
+
+func (s *store) update(
+	name string, dimension map[string]interface{}, valueType prometheus.ValueType, f func(*sample),
+) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.enabled {
+		return
+	}
+
+	name = sanitize(name)
+	var labelNames []string
+	for k := range dimension {
+		labelNames = append(labelNames, k)
+	}
+	sort.Strings(labelNames)
+
+	var labelValues []string
+	for i, k := range labelNames {
+		labelValues = append(labelValues, cast.ToString(dimension[k]))
+		labelNames[i] = sanitize(k)
+	}
+
+	key := name + "{" + strings.Join(labelNames, ",") + "}" + strings.Join(labelValues, "\x00")
+	smp, ok := s.samples[key]
+	if !ok {
+		smp = &sample{name: name, labelNames: labelNames, labelValues: labelValues, valueType: valueType}
+		s.samples[key] = smp
+	}
+	f(smp)
+}
+
+// Describe 不预先声明, 作为 unchecked collector
+func (s *store) Describe(chan<- *prometheus.Desc) {}
+
+// Collect 输出所有记录的指标
+func (s *store) Collect(ch chan<- prometheus.Metric) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, smp := range s.samples {
+		m, err := prometheus.NewConstMetric(
+			prometheus.NewDesc(smp.name, smp.name, smp.labelNames, nil),
+			smp.valueType,
+			smp.value,
+			smp.labelValues...,
+		)
+		if err != nil {
+			slog.Error("collect metric", slog.String("error", err.Error()), slog.String("name", smp.name))
+			continue
+		}
+		ch <- m
+	}
+}
+
+func sanitize(name string) string {
+	name = invalidNameChars.ReplaceAllString(name, "_")
+	if name != "" && name[0] >= '0' && name[0] <= '9' {
+		name = "_" + name
+	}
+	return name
+}
+
+// Handler OpenMetrics http handler
+func Handler() http.Handler {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(defaultStore)
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{
+		EnableOpenMetrics: true,
+		ErrorHandling:     promhttp.ContinueOnError,
+	})
+}
@@ -0,0 +1,284 @@
+// TencentBlueKing is pleased to support the open source community by making 蓝鲸智云-DB管理系统(BlueKing-BK-DBM) available.
+// Copyright (C) 2017-2023 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+// Package customsql 监控项配置中声明的自定义 SQL 监控项
+package customsql
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"dbm-services/mysql/db-tools/mysql-monitor/pkg/config"
+	"dbm-services/mysql/db-tools/mysql-monitor/pkg/monitoriteminterface"
+	"dbm-services/mysql/db-tools/mysql-monitor/pkg/utils"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/spf13/cast"
+)
+
+// maxMessageRows 一次最多拼接多少行的告警内容
+var maxMessageRows = 10
+
+// 自定义 SQL 只允许 SELECT / SHOW
+var readOnlyQueryReg = regexp.MustCompile(`(?i)^(select|show)\b`)
+
+// 查询语句里也能写文件或者加锁
+var writeClauseReg = regexp.MustCompile(`(?i)\binto\s+(outfile|dumpfile)\b|\bfor\s+(update|share)\b|\block\s+in\s+share\s+mode\b`)
+
+// checkReadOnlyQuery 监控连接开启了 multiStatements, 所以还要拒绝多条语句
+func checkReadOnlyQuery(query string) error {
+	q := strings.TrimRight(strings.TrimSpace(query), "; \t\r\n")
+	if strings.Contains(q, ";") {
+		return errors.Errorf("multiple statements not allowed")
+	}
+	if !readOnlyQueryReg.MatchString(q) {
+		return errors.Errorf("only SELECT and SHOW allowed")
+	}
+	if writeClauseReg.MatchString(q) {
+		return errors.Errorf("INTO OUTFILE/DUMPFILE and locking reads not allowed")
+	}
+	return nil
+}
+
+type checker struct {
+	name    string
+	cfg     *config.CustomSQLItem
+	db      *sqlx.DB
+	message *template.Template
+}
+
+// Run 运行
+func (c *checker) Run() (msg string, err error) {
+	return c.RunContext(context.Background())
+}
+
+// RunContext 运行, 超时后取消查询
+func (c *checker) RunContext(ctx context.Context) (msg string, err error) {
+	if c.db == nil {
+		return "", errors.Errorf("connection %s not available", c.cfg.GetConnection())
+	}
+
+	var rows *sqlx.Rows
+	if c.cfg.GetConnection() == config.CustomConnProxy {
+		// proxy 管理端口不支持事务, 只依赖配置时的语句检查
+		rows, err = c.db.QueryxContext(ctx, c.cfg.Query)
+	} else {
+		// 只读事务里执行, 即使查询调用了有写操作的函数也会失败
+		var tx *sqlx.Tx
+		tx, err = c.db.BeginTxx(ctx, &sql.TxOptions{ReadOnly: true})
+		if err != nil {
+			slog.Error("custom sql begin read only", slog.String("name", c.name), slog.String("error", err.Error()))
+			return "", err
+		}
+		defer func() {
+			_ = tx.Rollback()
+		}()
+		rows, err = tx.QueryxContext(ctx, c.cfg.Query)
+	}
+	if err != nil {
+		slog.Error("custom sql query", slog.String("name", c.name), slog.String("error", err.Error()))
+		return "", err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var msgs []string
+	var alarmRows int
+	for rows.Next() {
+		row := make(map[string]interface{})
+		err = rows.MapScan(row)
+		if err != nil {
+			slog.Error("custom sql scan", slog.String("name", c.name), slog.String("error", err.Error()))
+			return "", err
+		}
+		for k, v := range row {
+			if b, ok := v.([]byte); ok {
+				row[k] = string(b)
+			}
+		}
+
+		if c.cfg.Metric != nil {
+			err = c.sendMetric(row)
+			if err != nil {
+				return "", err
+			}
+		}
+
+		if c.cfg.Alarm != nil {
+			hit, err := c.isAlarm(row)
+			if err != nil {
+				return "", err
+			}
+			if !hit {
+				continue
+			}
+
+			alarmRows += 1
+			if alarmRows > maxMessageRows {
+				continue
+			}
+			m, err := c.renderMessage(row)
+			if err != nil {
+				return "", err
+			}
+			msgs = append(msgs, m)
+		}
+	}
+	err = rows.Err()
+	if err != nil {
+		slog.Error("custom sql rows", slog.String("name", c.name), slog.String("error", err.Error()))
+		return "", err
+	}
+
+	if alarmRows > maxMessageRows {
+		msgs = append(msgs, fmt.Sprintf("and %d more", alarmRows-maxMessageRows))
+	}
+	return strings.Join(msgs, "; "), nil
+}
+
+func (c *checker) value(row map[string]interface{}) (float64, error) {
+	v, ok := row[c.cfg.ValueColumn]
+	if !ok {
+		return 0, errors.Errorf("value column %s not found in result", c.cfg.ValueColumn)
+	}
+	if v == nil {
+		return 0, nil
+	}
+	f, err := cast.ToFloat64E(v)
+	if err != nil {
+		return 0, errors.Wrapf(err, "value column %s", c.cfg.ValueColumn)
+	}
+	return f, nil
+}
+
+func (c *checker) sendMetric(row map[string]interface{}) error {
+	v, err := c.value(row)
+	if err != nil {
+		slog.Error("custom sql metric", slog.String("name", c.name), slog.String("error", err.Error()))
+		return err
+	}
+
+	dimension := make(map[string]interface{})
+	for _, col := range c.cfg.Metric.DimensionColumns {
+		dimension[col] = cast.ToString(row[col])
+	}
+	utils.SendMonitorMetrics(c.cfg.Metric.Name, int64(v), dimension)
+	return nil
+}
+
+func (c *checker) isAlarm(row map[string]interface{}) (bool, error) {
+	if c.cfg.Alarm.Operator == "" {
+		return true, nil
+	}
+
+	v, err := c.value(row)
+	if err != nil {
+		slog.Error("custom sql alarm", slog.String("name", c.name), slog.String("error", err.Error()))
+		return false, err
+	}
+
+	threshold := c.cfg.Alarm.Threshold
+	switch c.cfg.Alarm.Operator {
+	case "gt":
+		return v > threshold, nil
+	case "ge":
+		return v >= threshold, nil
+	case "lt":
+		return v < threshold, nil
+	case "le":
+		return v <= threshold, nil
+	case "eq":
+		return v == threshold, nil
+	case "ne":
+		return v != threshold, nil
+	default:
+		return false, errors.Errorf("unknown operator %s", c.cfg.Alarm.Operator)
+	}
+}
+
+func (c *checker) renderMessage(row map[string]interface{}) (string, error) {
+	if c.message == nil {
+		var parts []string
+		if c.cfg.ValueColumn != "" {
+			parts = append(parts, fmt.Sprintf("%s=%v", c.cfg.ValueColumn, row[c.cfg.ValueColumn]))
+		}
+		if c.cfg.Alarm.Operator != "" {
+			parts = append(parts, fmt.Sprintf("%s %v", c.cfg.Alarm.Operator, c.cfg.Alarm.Threshold))
+		}
+		if len(parts) == 0 {
+			return fmt.Sprintf("%s returned rows", c.name), nil
+		}
+		return strings.Join(parts, " "), nil
+	}
+
+	var buf bytes.Buffer
+	err := c.message.Execute(&buf, row)
+	if err != nil {
+		slog.Error("custom sql render message", slog.String("name", c.name), slog.String("error", err.Error()))
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// Name 监控项名
+func (c *checker) Name() string {
+	return c.name
+}
+
+// NewConstructor 根据监控项配置生成构造函数, 配置有问题时返回错误
+func NewConstructor(item *config.MonitorItem) (monitoriteminterface.MonitorItemConstructorFuncType, error) {
+	cfg := item.Custom
+	if cfg == nil {
+		return nil, errors.Errorf("%s is not custom sql item", item.Name)
+	}
+
+	if err := checkReadOnlyQuery(cfg.Query); err != nil {
+		return nil, errors.Wrapf(err, "%s: query", item.Name)
+	}
+	if cfg.Metric == nil && cfg.Alarm == nil {
+		return nil, errors.Errorf("%s: at least one of metric and alarm required", item.Name)
+	}
+	if cfg.ValueColumn == "" && (cfg.Metric != nil || (cfg.Alarm != nil && cfg.Alarm.Operator != "")) {
+		return nil, errors.Errorf("%s: value_column required", item.Name)
+	}
+
+	var message *template.Template
+	if cfg.Alarm != nil && cfg.Alarm.Message != "" {
+		var err error
+		message, err = template.New(item.Name).Option("missingkey=zero").Parse(cfg.Alarm.Message)
+		if err != nil {
+			return nil, errors.Wrapf(err, "%s: parse message template", item.Name)
+		}
+	}
+
+	return func(cc *monitoriteminterface.ConnectionCollect) monitoriteminterface.MonitorItemInterface {
+		var db *sqlx.DB
+		switch cfg.GetConnection() {
+		case config.CustomConnMySQL:
+			db = cc.MySqlDB
+		case config.CustomConnProxy:
+			db = cc.ProxyDB
+		case config.CustomConnCtl:
+			db = cc.CtlDB
+		}
+		return &checker{
+			name:    item.Name,
+			cfg:     cfg,
+			db:      db,
+			message: message,
+		}
+	}, nil
+}
@@ -17,8 +17,11 @@ var nameTrigger = "trigger-definer"
 
 var mysqlUsers []string
 
+// snapMu 保护快照, snapDB 是上次做快照用的连接
+// 每轮采集都会新建连接, 连接变化时重新做快照, serve 模式下每轮都会刷新
+var snapMu sync.Mutex
+var snapDB *sqlx.DB
 var snapErr error
-var once sync.Once
 
 // Checker TODO
 type Checker struct {
@@ -29,11 +32,8 @@ type Checker struct {
 
 // Run TODO
 func (c *Checker) Run() (msg string, err error) {
-	once.Do(func() {
-		snapErr = snapshot(c.db)
-	})
-	if snapErr != nil {
-		return "", snapErr
+	if err = refreshSnapshot(c.db); err != nil {
+		return "", err
 	}
 
 	msgSlice, err := c.f(c.db)
@@ -44,6 +44,17 @@ func (c *Checker) Run() (msg string, err error) {
 	return strings.Join(msgSlice, ". "), nil
 }
 
+// refreshSnapshot 同一轮采集只做一次快照
+func refreshSnapshot(db *sqlx.DB) error {
+	snapMu.Lock()
+	defer snapMu.Unlock()
+	if snapDB != db {
+		snapErr = snapshot(db)
+		snapDB = db
+	}
+	return snapErr
+}
+
 // Name TODO
 func (c *Checker) Name() string {
 	return c.name
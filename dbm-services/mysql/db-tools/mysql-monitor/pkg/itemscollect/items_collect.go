@@ -14,7 +14,9 @@ import (
 	"fmt"
 	"log/slog"
 
+	"dbm-services/mysql/db-tools/mysql-monitor/pkg/config"
 	"dbm-services/mysql/db-tools/mysql-monitor/pkg/itemscollect/characterconsistency"
+	"dbm-services/mysql/db-tools/mysql-monitor/pkg/itemscollect/customsql"
 	"dbm-services/mysql/db-tools/mysql-monitor/pkg/itemscollect/dbhaheartbeat"
 	"dbm-services/mysql/db-tools/mysql-monitor/pkg/itemscollect/definer"
 	"dbm-services/mysql/db-tools/mysql-monitor/pkg/itemscollect/engine"
@@ -56,6 +58,28 @@ func RegisteredItemConstructor() map[string]func(*mi.ConnectionCollect) mi.Monit
 	return registeredItemConstructor
 }
 
+// RegisterCustomItems 注册监控项配置中的自定义 SQL 监控项
+// 需要在 LoadMonitorItemsConfig 之后调用, 不能和内置监控项重名
+func RegisterCustomItems() error {
+	for _, ele := range config.ItemsConfig {
+		if ele.Custom == nil {
+			continue
+		}
+
+		f, err := customsql.NewConstructor(ele)
+		if err != nil {
+			slog.Error("register custom item", slog.String("error", err.Error()))
+			return err
+		}
+
+		err = registerItemConstructor(ele.Name, f)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func init() {
 	registeredItemConstructor = make(map[string]func(*mi.ConnectionCollect) mi.MonitorItemInterface)
 	/*
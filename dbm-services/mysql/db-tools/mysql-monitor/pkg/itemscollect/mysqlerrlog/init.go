@@ -52,20 +52,14 @@ var mysqldRestartPattern *regexp2.Regexp
 var once sync.Once
 var snapShotErr error
 
+// snapMu 保护快照, snapDB 是上次做快照用的连接
+// 每轮采集都会新建连接, 连接变化时重新做快照, serve 模式下每轮都会刷新
+var snapMu sync.Mutex
+var snapDB *sqlx.DB
+
 func init() {
 	executable, _ = os.Executable()
 
-	now := time.Now()
-	rowStartPattern = regexp2.MustCompile(
-		fmt.Sprintf(
-			`^(?=(?:(%s|%s|%s)))`,
-			now.Format("060102"),
-			now.Format("20060102"),
-			now.Format("2006-01-02"),
-		),
-		regexp2.None,
-	)
-
 	baseErrTokenPattern = regexp2.MustCompile(
 		fmt.Sprintf(
 			`(?=(?:(%s)))`,
@@ -122,26 +116,47 @@ type Checker struct {
 
 // Run TODO
 func (c *Checker) Run() (msg string, err error) {
-	offsetRegFile = filepath.Join(
-		filepath.Dir(executable),
-		fmt.Sprintf("errlog_offset.%d.reg", config.MonitorConfig.Port),
-	)
-	errLogRegFile = filepath.Join(filepath.Dir(executable),
-		fmt.Sprintf("errlog.%d.reg", config.MonitorConfig.Port),
-	)
-
+	// 监控项会并发执行, 全局变量只在 once 里初始化
 	once.Do(func() {
-		snapShotErr = snapShot(c.db)
+		offsetRegFile = filepath.Join(
+			filepath.Dir(executable),
+			fmt.Sprintf("errlog_offset.%d.reg", config.MonitorConfig.Port),
+		)
+		errLogRegFile = filepath.Join(filepath.Dir(executable),
+			fmt.Sprintf("errlog.%d.reg", config.MonitorConfig.Port),
+		)
 	})
-	//err = snapShot(c.db)
-	if snapShotErr != nil {
-		slog.Error(c.name, slog.String("error", snapShotErr.Error()))
-		return "", snapShotErr
+	if err = refreshSnapShot(c.db); err != nil {
+		slog.Error(c.name, slog.String("error", err.Error()))
+		return "", err
 	}
 
 	return c.f()
 }
 
+// refreshSnapShot 同一轮采集只做一次快照, 跨天后行首的日期也要更新
+func refreshSnapShot(db *sqlx.DB) error {
+	snapMu.Lock()
+	defer snapMu.Unlock()
+	if snapDB == db {
+		return snapShotErr
+	}
+
+	now := time.Now()
+	rowStartPattern = regexp2.MustCompile(
+		fmt.Sprintf(
+			`^(?=(?:(%s|%s|%s)))`,
+			now.Format("060102"),
+			now.Format("20060102"),
+			now.Format("2006-01-02"),
+		),
+		regexp2.None,
+	)
+	snapShotErr = snapShot(db)
+	snapDB = db
+	return snapShotErr
+}
+
 // Name TODO
 func (c *Checker) Name() string {
 	return c.name
@@ -23,7 +23,10 @@ var executable string
 var nameMySQLLock = "mysql-lock"
 var nameMySQLInject = "mysql-inject"
 
-var once sync.Once
+// snapMu 保护快照, snapDB 是上次做快照用的连接
+// 每轮采集都会新建连接, 连接变化时重新做快照, serve 模式下每轮都会刷新
+var snapMu sync.Mutex
+var snapDB *sqlx.DB
 var snapShotErr error
 
 func init() {
@@ -39,16 +42,23 @@ type Checker struct {
 
 // Run TODO
 func (c *Checker) Run() (msg string, err error) {
-	once.Do(func() {
-		snapShotErr = snapShot(c.db)
-	})
-
-	if snapShotErr != nil {
-		return "", snapShotErr
+	if err = refreshSnapShot(c.db); err != nil {
+		return "", err
 	}
 	return c.f()
 }
 
+// refreshSnapShot 同一轮采集只做一次快照
+func refreshSnapShot(db *sqlx.DB) error {
+	snapMu.Lock()
+	defer snapMu.Unlock()
+	if snapDB != db {
+		snapShotErr = snapShot(db)
+		snapDB = db
+	}
+	return snapShotErr
+}
+
 // Name TODO
 func (c *Checker) Name() string {
 	return c.name
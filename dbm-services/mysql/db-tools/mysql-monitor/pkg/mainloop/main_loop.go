@@ -18,7 +18,6 @@ import (
 	"strings"
 
 	"dbm-services/mysql/db-tools/mysql-monitor/pkg/config"
-	"dbm-services/mysql/db-tools/mysql-monitor/pkg/itemscollect"
 	"dbm-services/mysql/db-tools/mysql-monitor/pkg/monitoriteminterface"
 	"dbm-services/mysql/db-tools/mysql-monitor/pkg/utils"
 
@@ -36,6 +35,11 @@ func Run(hardcode bool) error {
 	} else {
 		iNames = viper.GetStringSlice("run-items")
 	}
+	return RunItems(iNames, hardcode)
+}
+
+// RunItems 执行一轮监控项, serve 模式下由内部调度直接调用
+func RunItems(iNames []string, hardcode bool) error {
 	slog.Info("main loop", slog.String("items", strings.Join(iNames, ",")))
 	slog.Info("main loop", slog.Bool("hardcode", hardcode))
 
@@ -80,36 +84,6 @@ func Run(hardcode bool) error {
 		return nil
 	}
 
-	for _, iName := range iNames {
-
-		if constructor, ok := itemscollect.RegisteredItemConstructor()[iName]; ok {
-			msg, err := constructor(cc).Run()
-			if err != nil {
-				slog.Error("run monitor item", slog.String("error", err.Error()), slog.String("name", iName))
-				utils.SendMonitorEvent(
-					"monitor-internal-error",
-					fmt.Sprintf("run monitor item %s failed: %s", iName, err.Error()),
-				)
-				continue
-			}
-
-			if msg != "" {
-				slog.Info(
-					"run monitor items",
-					slog.String("name", iName),
-					slog.String("msg", msg),
-				)
-				utils.SendMonitorEvent(iName, msg)
-				continue
-			}
-
-			slog.Info("run monitor item pass", slog.String("name", iName))
-
-		} else {
-			err := errors.Errorf("%s not registered", iName)
-			slog.Error("run monitor item", slog.String("error", err.Error()))
-			continue
-		}
-	}
+	runItems(cc, iNames)
 	return nil
 }
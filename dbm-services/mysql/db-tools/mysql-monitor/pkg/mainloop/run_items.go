@@ -0,0 +1,162 @@
+// TencentBlueKing is pleased to support the open source community by making 蓝鲸智云-DB管理系统(BlueKing-BK-DBM) available.
+// Copyright (C) 2017-2023 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package mainloop
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"dbm-services/mysql/db-tools/mysql-monitor/pkg/alertstate"
+	"dbm-services/mysql/db-tools/mysql-monitor/pkg/config"
+	"dbm-services/mysql/db-tools/mysql-monitor/pkg/exporter"
+	"dbm-services/mysql/db-tools/mysql-monitor/pkg/itemscollect"
+	"dbm-services/mysql/db-tools/mysql-monitor/pkg/monitoriteminterface"
+	"dbm-services/mysql/db-tools/mysql-monitor/pkg/utils"
+
+	"github.com/pkg/errors"
+)
+
+const itemDurationMetricName = "mysql_monitor_item_duration_ms"
+
+const itemRecoveredEventName = "monitor-item-recovered"
+
+type itemResult struct {
+	msg string
+	err error
+}
+
+// runItems 用有限的并发执行监控项, 每个监控项有独立的超时
+func runItems(cc *monitoriteminterface.ConnectionCollect, iNames []string) {
+	concurrency := config.MonitorConfig.ItemConcurrency
+	if concurrency <= 0 {
+		concurrency = config.DefaultItemConcurrency
+	}
+
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for _, iName := range iNames {
+		constructor, ok := itemscollect.RegisteredItemConstructor()[iName]
+		if !ok {
+			err := errors.Errorf("%s not registered", iName)
+			slog.Error("run monitor item", slog.String("error", err.Error()))
+			continue
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(iName string, constructor monitoriteminterface.MonitorItemConstructorFuncType) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			runItem(cc, iName, constructor)
+		}(iName, constructor)
+	}
+	wg.Wait()
+}
+
+// runItem 超时后马上上报, 实现了 MonitorItemContextInterface 的监控项会被取消
+// 仍然要等监控项退出才返回, 避免调用方关闭连接、释放锁后监控项还在使用连接
+func runItem(
+	cc *monitoriteminterface.ConnectionCollect,
+	iName string,
+	constructor monitoriteminterface.MonitorItemConstructorFuncType,
+) {
+	timeout := config.GetItemTimeout(iName)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	start := time.Now()
+	ch := make(chan itemResult, 1)
+	go func() {
+		var res itemResult
+		item := constructor(cc)
+		if ci, ok := item.(monitoriteminterface.MonitorItemContextInterface); ok {
+			res.msg, res.err = ci.RunContext(ctx)
+		} else {
+			res.msg, res.err = item.Run()
+		}
+		ch <- res
+	}()
+
+	var res itemResult
+	select {
+	case res = <-ch:
+	case <-ctx.Done():
+		slog.Error(
+			"run monitor item timeout",
+			slog.String("name", iName),
+			slog.Duration("timeout", timeout),
+		)
+		utils.SendMonitorEvent(
+			"monitor-item-timeout",
+			fmt.Sprintf("run monitor item %s timeout after %s", iName, timeout),
+		)
+		setItemResult(iName, exporter.ItemResultError)
+		<-ch
+		slog.Info("timeout monitor item exited", slog.String("name", iName), slog.Duration("cost", time.Since(start)))
+		return
+	}
+
+	cost := time.Since(start)
+	utils.SendMonitorMetrics(
+		itemDurationMetricName,
+		cost.Milliseconds(),
+		map[string]interface{}{"item_name": iName},
+	)
+
+	if res.err != nil {
+		slog.Error("run monitor item", slog.String("error", res.err.Error()), slog.String("name", iName))
+		utils.SendMonitorEvent(
+			"monitor-internal-error",
+			fmt.Sprintf("run monitor item %s failed: %s", iName, res.err.Error()),
+		)
+		setItemResult(iName, exporter.ItemResultError)
+		return
+	}
+
+	if res.msg != "" {
+		slog.Info(
+			"run monitor items",
+			slog.String("name", iName),
+			slog.String("msg", res.msg),
+			slog.Duration("cost", cost),
+		)
+		if alertstate.Fire(iName, res.msg) {
+			utils.SendMonitorEvent(iName, res.msg)
+		}
+		setItemResult(iName, exporter.ItemResultAlarm)
+		return
+	}
+
+	slog.Info("run monitor item pass", slog.String("name", iName), slog.Duration("cost", cost))
+	if recovered, duration := alertstate.Pass(iName); recovered {
+		utils.SendMonitorEvent(
+			itemRecoveredEventName,
+			fmt.Sprintf("%s recovered after %s", iName, duration.Round(time.Second)),
+		)
+	}
+	setItemResult(iName, exporter.ItemResultPass)
+}
+
+// setItemResult 只在 serve 模式暴露, 不推送到 crond
+func setItemResult(iName string, result int) {
+	exporter.SetGauge(
+		exporter.ItemResultName,
+		float64(result),
+		map[string]interface{}{
+			"item_name":     iName,
+			"instance_host": config.MonitorConfig.Ip,
+			"instance_port": config.MonitorConfig.Port,
+		},
+	)
+}
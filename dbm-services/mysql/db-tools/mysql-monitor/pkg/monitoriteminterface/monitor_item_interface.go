@@ -1,11 +1,18 @@
 // Package monitoriteminterface 监控项接口
 package monitoriteminterface
 
+import "context"
+
 // MonitorItemInterface TODO
 type MonitorItemInterface interface {
 	Run() (msg string, err error)
 	Name() string
 }
 
+// MonitorItemContextInterface 支持取消的监控项, 超时后 ctx 会被取消
+type MonitorItemContextInterface interface {
+	RunContext(ctx context.Context) (msg string, err error)
+}
+
 // MonitorItemConstructorFuncType TODO
 type MonitorItemConstructorFuncType func(cc *ConnectionCollect) MonitorItemInterface
@@ -5,11 +5,21 @@ import (
 	"strconv"
 
 	ma "dbm-services/mysql/db-tools/mysql-crond/api"
+	"dbm-services/mysql/db-tools/mysql-monitor/pkg/alertstate"
 	"dbm-services/mysql/db-tools/mysql-monitor/pkg/config"
+	"dbm-services/mysql/db-tools/mysql-monitor/pkg/exporter"
 )
 
+// PushToCrond 是否通过 mysql-crond 发送到蓝鲸监控, serve 模式可以关闭
+var PushToCrond = true
+
 // SendMonitorEvent TODO
 func SendMonitorEvent(name string, msg string) {
+	if alertstate.IsSilenced(name) {
+		slog.Info("send event silenced", slog.String("name", name), slog.String("msg", msg))
+		return
+	}
+
 	crondManager := ma.NewManager(config.MonitorConfig.ApiUrl)
 
 	additionDimension := map[string]interface{}{
@@ -28,6 +38,11 @@ func SendMonitorEvent(name string, msg string) {
 		additionDimension["instance_role"] = *config.MonitorConfig.Role
 	}
 
+	exporter.IncEvent(name, additionDimension)
+	if !PushToCrond {
+		return
+	}
+
 	err := crondManager.SendEvent(
 		name,
 		msg,
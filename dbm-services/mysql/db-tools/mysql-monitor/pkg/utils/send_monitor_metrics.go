@@ -7,6 +7,7 @@ import (
 
 	ma "dbm-services/mysql/db-tools/mysql-crond/api"
 	"dbm-services/mysql/db-tools/mysql-monitor/pkg/config"
+	"dbm-services/mysql/db-tools/mysql-monitor/pkg/exporter"
 )
 
 // SendMonitorMetrics TODO
@@ -31,6 +32,11 @@ func SendMonitorMetrics(name string, value int64, customDimension map[string]int
 		additionDimension["instance_role"] = *config.MonitorConfig.Role
 	}
 
+	exporter.SetGauge(name, float64(value), additionDimension)
+	if !PushToCrond {
+		return
+	}
+
 	err := crondManager.SendMetrics(
 		name,
 		value,
@@ -0,0 +1,194 @@
+package binlog_parser
+
+import (
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/go-mysql-org/go-mysql/replication"
+	"github.com/pkg/errors"
+)
+
+// StopCondition 定点恢复应用 binlog 的停止条件, StopTime / StopFile+StopPos / GtidSet 三选一
+type StopCondition struct {
+	// StopTime 第一个 event 时间 >= StopTime 的事务开始不再应用, 同 mysqlbinlog --stop-datetime
+	StopTime time.Time
+	// StopFile StopPos 从这个位置开始的 event 不再应用, 同 mysqlbinlog --stop-position, 必须是事务边界
+	StopFile string
+	StopPos  int64
+	// GtidSet 应用完 binlog 后要达到的 GTID 集合, 包含起始位置已经执行过的部分
+	GtidSet string
+	// ExecutedGtidSet 起始位置已经执行过的 GTID 集合, 一般是备份里记录的 gtid
+	ExecutedGtidSet string
+}
+
+// StopPoint 根据 StopCondition 在 binlog 中找到的停止位置
+type StopPoint struct {
+	// BinlogFile 最后一个需要应用的 binlog
+	BinlogFile string `json:"binlog_file"`
+	// StopPos 在 BinlogFile 的这个位置停止, 0 表示整个文件都要应用
+	StopPos int64 `json:"stop_pos"`
+	// StopTime 停止位置 event 的时间, 应用到文件结尾时是最后一个 event 的时间
+	StopTime time.Time `json:"stop_time"`
+	// AppliedGtidSet 起始位置到停止位置之间的事务 GTID, binlog 没有开启 gtid 时为空
+	AppliedGtidSet string `json:"applied_gtid_set"`
+}
+
+// errStopPointReached 找到停止位置后用来中断 ParseFile
+var errStopPointReached = errors.New("stop point reached")
+
+// FindStopPoint 从 binlogFiles[0] 的 startPos 开始顺序解析 binlog, 返回满足 cond 的停止位置
+// binlogFiles 需要按顺序连续
+func FindStopPoint(binlogDir string, binlogFiles []string, startPos int64, cond StopCondition) (*StopPoint, error) {
+	if len(binlogFiles) == 0 {
+		return nil, errors.New("no binlog files to find stop point")
+	}
+	byTime := !cond.StopTime.IsZero()
+	byPos := cond.StopFile != ""
+	byGtid := cond.GtidSet != ""
+
+	covered, err := mysql.ParseMysqlGTIDSet(cond.ExecutedGtidSet)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parse executed gtid set %s", cond.ExecutedGtidSet)
+	}
+	applied, _ := mysql.ParseMysqlGTIDSet("")
+	var target mysql.GTIDSet
+	if byGtid {
+		if target, err = mysql.ParseMysqlGTIDSet(cond.GtidSet); err != nil {
+			return nil, errors.Wrapf(err, "parse target gtid set %s", cond.GtidSet)
+		}
+	}
+
+	parser := replication.NewBinlogParser()
+	parser.SetFlavor("mysql")
+	point := &StopPoint{}
+	for i, f := range binlogFiles {
+		offset := int64(len(replication.BinLogFileHeader))
+		if i == 0 && startPos > offset {
+			offset = startPos
+		}
+		point.BinlogFile = f
+		point.StopPos = 0
+		var lastEnd int64
+		reached := false
+		prevType := replication.UNKNOWN_EVENT
+		err = parser.ParseFile(filepath.Join(binlogDir, f), offset, func(e *replication.BinlogEvent) error {
+			evStart := int64(e.Header.LogPos) - int64(e.Header.EventSize)
+			if evStart < offset {
+				// offset > 4 时会先解析 FormatDescriptionEvent
+				return nil
+			}
+			lastEnd = int64(e.Header.LogPos)
+			evTime := time.Unix(int64(e.Header.Timestamp), 0)
+			boundary := isTrxBoundary(e, prevType)
+			prevType = e.Header.EventType
+
+			if byPos && f == cond.StopFile && evStart >= cond.StopPos {
+				if evStart != cond.StopPos || !boundary {
+					return errors.Errorf("stop pos %s:%d is not at a transaction boundary, next event %s starts at %d",
+						f, cond.StopPos, e.Header.EventType, evStart)
+				}
+				point.StopPos, point.StopTime, reached = evStart, evTime, true
+				return errStopPointReached
+			}
+			point.StopTime = evTime
+
+			switch ev := e.Event.(type) {
+			case *replication.GTIDEvent:
+				if byTime && !evTime.Before(cond.StopTime) {
+					point.StopPos, reached = evStart, true
+					return errStopPointReached
+				}
+				gtid := formatGtid(ev)
+				if byGtid {
+					if covered.Contain(target) {
+						point.StopPos, reached = evStart, true
+						return errStopPointReached
+					}
+					one, _ := mysql.ParseMysqlGTIDSet(gtid)
+					if !target.Contain(one) {
+						return errors.Errorf("transaction %s at %s:%d is not in target gtid set, "+
+							"binlog can not stop exactly at %s", gtid, f, evStart, cond.GtidSet)
+					}
+				}
+				if err := covered.Update(gtid); err != nil {
+					return err
+				}
+				if err := applied.Update(gtid); err != nil {
+					return err
+				}
+			default:
+				if byTime && boundary && !evTime.Before(cond.StopTime) {
+					point.StopPos, reached = evStart, true
+					return errStopPointReached
+				}
+			}
+			return nil
+		})
+		if reached {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "parse binlog %s", f)
+		}
+		if byPos && f == cond.StopFile {
+			if cond.StopPos != lastEnd {
+				return nil, errors.Errorf("stop pos %d is beyond the end of %s, last event end at %d",
+					cond.StopPos, f, lastEnd)
+			}
+			break
+		}
+	}
+
+	if byPos && point.BinlogFile != cond.StopFile {
+		return nil, errors.Errorf("stop file %s not found in binlog files %v", cond.StopFile, binlogFiles)
+	}
+	if byGtid && !covered.Contain(target) {
+		return nil, errors.Errorf("binlog files end at %s, executed gtid %s does not reach target %s",
+			point.BinlogFile, covered.String(), cond.GtidSet)
+	}
+	point.AppliedGtidSet = applied.String()
+	return point, nil
+}
+
+// GtidSetContain set 是否包含 sub
+func GtidSetContain(set, sub string) (bool, error) {
+	s, err := mysql.ParseMysqlGTIDSet(set)
+	if err != nil {
+		return false, errors.Wrapf(err, "parse gtid set %s", set)
+	}
+	o, err := mysql.ParseMysqlGTIDSet(sub)
+	if err != nil {
+		return false, errors.Wrapf(err, "parse gtid set %s", sub)
+	}
+	return s.Contain(o), nil
+}
+
+// isTrxBoundary event 是否是一个事务的开始, prevType 是上一个 event 的类型
+func isTrxBoundary(e *replication.BinlogEvent, prevType replication.EventType) bool {
+	switch e.Header.EventType {
+	case replication.GTID_EVENT, replication.ANONYMOUS_GTID_EVENT, replication.ROTATE_EVENT, replication.STOP_EVENT:
+		return true
+	case replication.QUERY_EVENT:
+		// 没有 gtid event 的老版本, 事务从 BEGIN 开始
+		if prevType == replication.GTID_EVENT || prevType == replication.ANONYMOUS_GTID_EVENT {
+			return false
+		}
+		if q, ok := e.Event.(*replication.QueryEvent); ok {
+			return strings.EqualFold(string(q.Query), "BEGIN")
+		}
+	}
+	return false
+}
+
+// formatGtid 转换成 uuid:gno 格式
+func formatGtid(e *replication.GTIDEvent) string {
+	sid := hex.EncodeToString(e.SID)
+	if len(sid) != 32 {
+		return fmt.Sprintf("%s:%d", sid, e.GNO)
+	}
+	return fmt.Sprintf("%s-%s-%s-%s-%s:%d", sid[0:8], sid[8:12], sid[12:16], sid[16:20], sid[20:32], e.GNO)
+}
@@ -0,0 +1,202 @@
+package binlog_parser
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-mysql-org/go-mysql/replication"
+	"github.com/stretchr/testify/assert"
+)
+
+const testServerUUID = "3e11fa47-71ca-11e1-9e33-c80aa9429562"
+
+var testSID = []byte{0x3e, 0x11, 0xfa, 0x47, 0x71, 0xca, 0x11, 0xe1, 0x9e, 0x33, 0xc8, 0x0a, 0xa9, 0x42, 0x95, 0x62}
+
+// testBinlog 生成测试用的 binlog 文件, 不带 checksum
+type testBinlog struct {
+	buf bytes.Buffer
+	// trxStart 每个事务第一个 event 的位置
+	trxStart []int64
+}
+
+func newTestBinlog() *testBinlog {
+	b := &testBinlog{}
+	b.buf.Write(replication.BinLogFileHeader)
+	body := make([]byte, 0, 100)
+	body = binary.LittleEndian.AppendUint16(body, 4)
+	version := make([]byte, 50)
+	copy(version, "5.7.20-log")
+	body = append(body, version...)
+	body = binary.LittleEndian.AppendUint32(body, 0)
+	body = append(body, byte(replication.EventHeaderSize))
+	body = append(body, make([]byte, 38)...)
+	// checksum 算法 OFF 以及 4 字节 checksum
+	body = append(body, replication.BINLOG_CHECKSUM_ALG_OFF, 0, 0, 0, 0)
+	b.event(replication.FORMAT_DESCRIPTION_EVENT, 0, body)
+	return b
+}
+
+func (b *testBinlog) event(t replication.EventType, ts uint32, body []byte) {
+	size := uint32(replication.EventHeaderSize + len(body))
+	header := make([]byte, 0, replication.EventHeaderSize)
+	header = binary.LittleEndian.AppendUint32(header, ts)
+	header = append(header, byte(t))
+	header = binary.LittleEndian.AppendUint32(header, 1)
+	header = binary.LittleEndian.AppendUint32(header, size)
+	header = binary.LittleEndian.AppendUint32(header, uint32(b.buf.Len())+size)
+	header = binary.LittleEndian.AppendUint16(header, 0)
+	b.buf.Write(header)
+	b.buf.Write(body)
+}
+
+func (b *testBinlog) query(ts uint32, q string) {
+	body := make([]byte, 13)
+	body = append(body, 0)
+	body = append(body, q...)
+	b.event(replication.QUERY_EVENT, ts, body)
+}
+
+// trx 写入一个 gtid 事务: GTID, BEGIN, XID
+func (b *testBinlog) trx(ts uint32, gno int64) {
+	b.trxStart = append(b.trxStart, int64(b.buf.Len()))
+	body := []byte{1}
+	body = append(body, testSID...)
+	body = binary.LittleEndian.AppendUint64(body, uint64(gno))
+	b.event(replication.GTID_EVENT, ts, body)
+	b.query(ts, "BEGIN")
+	b.event(replication.XID_EVENT, ts, make([]byte, 8))
+}
+
+func (b *testBinlog) write(t *testing.T, dir, name string) {
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, name), b.buf.Bytes(), 0644))
+}
+
+func TestFindStopPoint(t *testing.T) {
+	dir := t.TempDir()
+	// binlog.000001: 1@100 2@200 3@300, binlog.000002: 4@400
+	b1 := newTestBinlog()
+	b1.trx(100, 1)
+	b1.trx(200, 2)
+	b1.trx(300, 3)
+	b1.write(t, dir, "binlog.000001")
+	b2 := newTestBinlog()
+	b2.trx(400, 4)
+	b2.write(t, dir, "binlog.000002")
+	files := []string{"binlog.000001", "binlog.000002"}
+	gtid := func(s string) string { return testServerUUID + ":" + s }
+
+	cases := []struct {
+		name     string
+		files    []string
+		startPos int64
+		cond     StopCondition
+		wantFile string
+		wantPos  int64
+		wantGtid string
+		wantErr  bool
+	}{
+		{name: "no files", cond: StopCondition{StopTime: time.Unix(200, 0)}, wantErr: true},
+		{name: "time", files: files, cond: StopCondition{StopTime: time.Unix(200, 0)},
+			wantFile: "binlog.000001", wantPos: b1.trxStart[1], wantGtid: gtid("1")},
+		{name: "time between trx", files: files, cond: StopCondition{StopTime: time.Unix(350, 0)},
+			wantFile: "binlog.000002", wantPos: b2.trxStart[0], wantGtid: gtid("1-3")},
+		{name: "time after end", files: files, cond: StopCondition{StopTime: time.Unix(1000, 0)},
+			wantFile: "binlog.000002", wantPos: 0, wantGtid: gtid("1-4")},
+		{name: "time from start pos", files: files, startPos: b1.trxStart[1],
+			cond:     StopCondition{StopTime: time.Unix(300, 0)},
+			wantFile: "binlog.000001", wantPos: b1.trxStart[2], wantGtid: gtid("2")},
+		{name: "pos", files: files, cond: StopCondition{StopFile: "binlog.000001", StopPos: b1.trxStart[2]},
+			wantFile: "binlog.000001", wantPos: b1.trxStart[2], wantGtid: gtid("1-2")},
+		{name: "pos at file end", files: files,
+			cond:     StopCondition{StopFile: "binlog.000001", StopPos: int64(b1.buf.Len())},
+			wantFile: "binlog.000001", wantPos: 0, wantGtid: gtid("1-3")},
+		{name: "pos inside trx", files: files,
+			cond: StopCondition{StopFile: "binlog.000001", StopPos: b1.trxStart[1] + 10}, wantErr: true},
+		{name: "pos not trx boundary", files: files,
+			cond: StopCondition{StopFile: "binlog.000001", StopPos: b1.trxStart[2] - 27}, wantErr: true},
+		{name: "pos beyond file end", files: files,
+			cond: StopCondition{StopFile: "binlog.000001", StopPos: int64(b1.buf.Len()) + 100}, wantErr: true},
+		{name: "pos file not found", files: files,
+			cond: StopCondition{StopFile: "binlog.000003", StopPos: 4}, wantErr: true},
+		{name: "gtid", files: files, cond: StopCondition{GtidSet: gtid("1-2")},
+			wantFile: "binlog.000001", wantPos: b1.trxStart[2], wantGtid: gtid("1-2")},
+		{name: "gtid with executed", files: files, startPos: b1.trxStart[1],
+			cond:     StopCondition{GtidSet: gtid("1-3"), ExecutedGtidSet: gtid("1")},
+			wantFile: "binlog.000002", wantPos: b2.trxStart[0], wantGtid: gtid("2-3")},
+		{name: "gtid at end", files: files, cond: StopCondition{GtidSet: gtid("1-4")},
+			wantFile: "binlog.000002", wantPos: 0, wantGtid: gtid("1-4")},
+		{name: "gtid not reached", files: files, cond: StopCondition{GtidSet: gtid("1-9")}, wantErr: true},
+		{name: "gtid with gap", files: files, cond: StopCondition{GtidSet: gtid("1:3")}, wantErr: true},
+		{name: "invalid gtid", files: files, cond: StopCondition{GtidSet: "xx"}, wantErr: true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			point, err := FindStopPoint(dir, c.files, c.startPos, c.cond)
+			if c.wantErr {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, c.wantFile, point.BinlogFile)
+			assert.Equal(t, c.wantPos, point.StopPos)
+			assert.Equal(t, c.wantGtid, point.AppliedGtidSet)
+		})
+	}
+}
+
+func TestGtidSetContain(t *testing.T) {
+	cases := []struct {
+		set, sub string
+		want     bool
+		wantErr  bool
+	}{
+		{testServerUUID + ":1-10", testServerUUID + ":3-5", true, false},
+		{testServerUUID + ":1-10", testServerUUID + ":5-11", false, false},
+		{testServerUUID + ":1-10", "", true, false},
+		{"", testServerUUID + ":1", false, false},
+		{"xx", testServerUUID + ":1", false, true},
+		{testServerUUID + ":1", "xx", false, true},
+	}
+	for _, c := range cases {
+		got, err := GtidSetContain(c.set, c.sub)
+		if c.wantErr {
+			assert.NotNil(t, err, "%s contain %s", c.set, c.sub)
+			continue
+		}
+		assert.Nil(t, err)
+		assert.Equal(t, c.want, got, "%s contain %s", c.set, c.sub)
+	}
+}
+
+func TestIsTrxBoundary(t *testing.T) {
+	query := func(q string) *replication.BinlogEvent {
+		return &replication.BinlogEvent{
+			Header: &replication.EventHeader{EventType: replication.QUERY_EVENT},
+			Event:  &replication.QueryEvent{Query: []byte(q)},
+		}
+	}
+	typed := func(t replication.EventType) *replication.BinlogEvent {
+		return &replication.BinlogEvent{Header: &replication.EventHeader{EventType: t}}
+	}
+	cases := []struct {
+		name string
+		e    *replication.BinlogEvent
+		prev replication.EventType
+		want bool
+	}{
+		{"gtid", typed(replication.GTID_EVENT), replication.XID_EVENT, true},
+		{"anonymous gtid", typed(replication.ANONYMOUS_GTID_EVENT), replication.XID_EVENT, true},
+		{"rotate", typed(replication.ROTATE_EVENT), replication.XID_EVENT, true},
+		{"begin without gtid", query("BEGIN"), replication.XID_EVENT, true},
+		{"begin after gtid", query("BEGIN"), replication.GTID_EVENT, false},
+		{"ddl", query("create table t1(id int)"), replication.XID_EVENT, false},
+		{"xid", typed(replication.XID_EVENT), replication.QUERY_EVENT, false},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.want, isTrxBoundary(c.e, c.prev), c.name)
+	}
+}
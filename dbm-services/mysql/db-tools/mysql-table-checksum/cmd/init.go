@@ -56,6 +56,11 @@ func initLogger(cfg *config.LogConfig, mode config.CheckMode) {
 				*cfg.LogFileDir,
 				fmt.Sprintf("%s_%d.log", executableName, config.ChecksumConfig.Port),
 			)
+		} else if mode == config.RepairMode {
+			logFile = path.Join(
+				*cfg.LogFileDir,
+				fmt.Sprintf("%s_%d_repair.log", executableName, config.ChecksumConfig.Port),
+			)
 		} else {
 			logFile = path.Join(
 				*cfg.LogFileDir,
@@ -11,11 +11,14 @@ import (
 	"github.com/juju/fslock"
 )
 
-func generateRun(mode config.CheckMode, configPath string) error {
+func generateRun(mode config.CheckMode, configPath string, engine string) error {
 	err := config.InitConfig(configPath)
 	if err != nil {
 		return err
 	}
+	if engine != "" {
+		config.ChecksumConfig.Engine = config.EngineEnum(engine)
+	}
 
 	initLogger(config.ChecksumConfig.Log, mode)
 
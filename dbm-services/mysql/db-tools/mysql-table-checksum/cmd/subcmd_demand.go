@@ -12,7 +12,7 @@ var subCmdDemand = &cobra.Command{
 	Short: "demand checksum",
 	Long:  "demand checksum",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return generateRun(config.DemandMode, viper.GetString("demand-config"))
+		return generateRun(config.DemandMode, viper.GetString("demand-config"), viper.GetString("demand-engine"))
 	},
 }
 
@@ -21,6 +21,9 @@ func init() {
 	_ = subCmdDemand.MarkPersistentFlagRequired("config")
 	_ = viper.BindPFlag("demand-config", subCmdDemand.PersistentFlags().Lookup("config"))
 
+	subCmdDemand.PersistentFlags().StringP("engine", "", "", "checksum engine: pt|native, overwrite engine in config")
+	_ = viper.BindPFlag("demand-engine", subCmdDemand.PersistentFlags().Lookup("engine"))
+
 	subCmdDemand.PersistentFlags().StringP("uuid", "", "", "unique id for each demand")
 	_ = subCmdDemand.MarkPersistentFlagRequired("uuid")
 	_ = viper.BindPFlag("uuid", subCmdDemand.PersistentFlags().Lookup("uuid"))
@@ -12,7 +12,7 @@ var subCmdGeneral = &cobra.Command{
 	Short: "general checksum",
 	Long:  "general checksum",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return generateRun(config.GeneralMode, viper.GetString("general-config"))
+		return generateRun(config.GeneralMode, viper.GetString("general-config"), viper.GetString("general-engine"))
 	},
 }
 
@@ -21,5 +21,8 @@ func init() {
 	_ = subCmdGeneral.MarkPersistentFlagRequired("config")
 	_ = viper.BindPFlag("general-config", subCmdGeneral.PersistentFlags().Lookup("config"))
 
+	subCmdGeneral.PersistentFlags().StringP("engine", "", "", "checksum engine: pt|native, overwrite engine in config")
+	_ = viper.BindPFlag("general-engine", subCmdGeneral.PersistentFlags().Lookup("engine"))
+
 	rootCmd.AddCommand(subCmdGeneral)
 }
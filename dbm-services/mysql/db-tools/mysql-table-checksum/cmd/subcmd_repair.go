@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+
+	"dbm-services/mysql/db-tools/mysql-table-checksum/pkg/checker"
+	"dbm-services/mysql/db-tools/mysql-table-checksum/pkg/config"
+
+	"github.com/juju/fslock"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var subCmdRepair = &cobra.Command{
+	Use:   "repair",
+	Short: "repair inconsistent chunks",
+	Long:  "diff inconsistent chunks in checksum result row by row, generate idempotent repair sql and optionally execute it on master",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		err := config.InitConfig(viper.GetString("repair-config"))
+		if err != nil {
+			return err
+		}
+
+		initLogger(config.ChecksumConfig.Log, config.RepairMode)
+
+		rp, err := checker.NewRepairer(checker.RepairOptions{
+			Tables:      viper.GetStringSlice("repair-tables"),
+			OutputFile:  viper.GetString("repair-output"),
+			DryRun:      viper.GetBool("repair-dry-run"),
+			Execute:     viper.GetBool("repair-execute"),
+			WaitTimeout: viper.GetInt("repair-wait-timeout"),
+		})
+		if err != nil {
+			return err
+		}
+		defer rp.Close()
+
+		lockFilePath := fmt.Sprintf(".%s_%d_%s.lock", rp.Config.Ip, rp.Config.Port, config.RepairMode)
+		lock := fslock.New(lockFilePath)
+		defer func() {
+			_ = os.Remove(lockFilePath)
+		}()
+		err = lock.TryLock()
+		if err != nil {
+			slog.Error("another repair already running", slog.String("error", err.Error()))
+			return err
+		}
+
+		slog.Info("repair start")
+		report, err := rp.Repair()
+		if report != nil {
+			fmt.Println(report.String())
+		}
+		if err != nil {
+			slog.Error("repair", slog.String("error", err.Error()))
+			return err
+		}
+		if len(report.Errors) > 0 {
+			return fmt.Errorf("repair finish with %d errors", len(report.Errors))
+		}
+		slog.Info("repair finish")
+		return nil
+	},
+}
+
+func init() {
+	subCmdRepair.PersistentFlags().StringP("config", "c", "", "config file")
+	_ = subCmdRepair.MarkPersistentFlagRequired("config")
+	_ = viper.BindPFlag("repair-config", subCmdRepair.PersistentFlags().Lookup("config"))
+
+	subCmdRepair.PersistentFlags().StringSliceP("tables", "", nil, "only repair these tables, as db.tbl")
+	_ = viper.BindPFlag("repair-tables", subCmdRepair.PersistentFlags().Lookup("tables"))
+
+	subCmdRepair.PersistentFlags().StringP("output", "o", "", "repair sql file, default in report_path")
+	_ = viper.BindPFlag("repair-output", subCmdRepair.PersistentFlags().Lookup("output"))
+
+	subCmdRepair.PersistentFlags().BoolP("dry-run", "", false, "only report diff rows of each table")
+	_ = viper.BindPFlag("repair-dry-run", subCmdRepair.PersistentFlags().Lookup("dry-run"))
+
+	subCmdRepair.PersistentFlags().BoolP("execute", "", false, "execute repair sql on master")
+	_ = viper.BindPFlag("repair-execute", subCmdRepair.PersistentFlags().Lookup("execute"))
+
+	subCmdRepair.PersistentFlags().IntP("wait-timeout", "", 60, "seconds to wait slave catch up master")
+	_ = viper.BindPFlag("repair-wait-timeout", subCmdRepair.PersistentFlags().Lookup("wait-timeout"))
+
+	subCmdRepair.MarkFlagsMutuallyExclusive("dry-run", "execute")
+
+	rootCmd.AddCommand(subCmdRepair)
+}
@@ -37,6 +37,16 @@ func NewChecker(mode config.CheckMode) (*Checker, error) {
 
 	// checker 需要一个序列化器方便打日志
 
+	switch checker.Config.Engine {
+	case "":
+		checker.Config.Engine = config.EnginePt
+	case config.EnginePt, config.EngineNative:
+	default:
+		err := fmt.Errorf("unknown checksum engine: %s", checker.Config.Engine)
+		slog.Error("new checker", slog.String("error", err.Error()))
+		return nil, err
+	}
+
 	splitR := strings.Split(checker.Config.PtChecksum.Replicate, ".")
 	checker.resultDB = splitR[0]
 	checker.resultTbl = splitR[1]
@@ -47,8 +57,10 @@ func NewChecker(mode config.CheckMode) (*Checker, error) {
 		return nil, err
 	}
 
-	if err := checker.ptPrecheck(); err != nil {
-		return nil, err
+	if checker.Config.Engine == config.EnginePt {
+		if err := checker.ptPrecheck(); err != nil {
+			return nil, err
+		}
 	}
 
 	err := checker.prepareReplicateTable()
@@ -80,12 +92,19 @@ func NewChecker(mode config.CheckMode) (*Checker, error) {
 			return nil, err
 		}
 
-		if err := checker.prepareDsnsTable(); err != nil {
-			return nil, err
+		// 原生引擎直接连接 slave, 不需要 pt 的 dsns 表
+		if checker.Config.Engine == config.EnginePt {
+			if err := checker.prepareDsnsTable(); err != nil {
+				return nil, err
+			}
 		}
 	}
 
-	checker.buildCommandArgs()
+	if checker.Config.Engine == config.EngineNative {
+		checker.applyNativeDefaults()
+	} else {
+		checker.buildCommandArgs()
+	}
 
 	return checker, nil
 }
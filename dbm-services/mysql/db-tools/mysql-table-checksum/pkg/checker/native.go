@@ -0,0 +1,531 @@
+package checker
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"dbm-services/mysql/db-tools/mysql-table-checksum/pkg/config"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// 进度日志间隔
+const nativeProgressInterval = 30 * time.Second
+
+// 单据校验结束后等待 slave 回放校验结果的最长时间
+const nativeSlaveWaitTimeout = 10 * time.Minute
+
+// applyNativeDefaults 原生引擎的默认参数, 和 pt 的默认策略保持一致
+func (r *Checker) applyNativeDefaults() {
+	nc := &r.Config.NativeChecksum
+	if nc.ChunkSize <= 0 {
+		nc.ChunkSize = 1000
+	}
+	if nc.ChunkTime <= 0 {
+		nc.ChunkTime = 1
+	}
+	if nc.ChunkSizeLimit <= 0 {
+		nc.ChunkSizeLimit = 5
+	}
+	if nc.MaxThreadsRunning <= 0 {
+		nc.MaxThreadsRunning = 500
+	}
+	if nc.MaxLag <= 0 {
+		nc.MaxLag = 10
+	}
+	if nc.LockWaitTimeout <= 0 {
+		nc.LockWaitTimeout = 1
+	}
+	if nc.RunTime <= 0 {
+		if r.Mode == config.GeneralMode {
+			nc.RunTime = time.Hour * 2
+		} else {
+			nc.RunTime = time.Hour * 48
+		}
+	}
+}
+
+// nativeResume 例行校验的断点, 也就是上次最后完成的分块
+type nativeResume struct {
+	Db            string         `db:"db"`
+	Tbl           string         `db:"tbl"`
+	Chunk         int            `db:"chunk"`
+	UpperBoundary sql.NullString `db:"upper_boundary"`
+}
+
+// nativeSlave 单据校验时用来检查延迟和对比结果
+type nativeSlave struct {
+	config.Host
+	db *sqlx.DB
+}
+
+// nativeRun 一次原生校验的运行状态
+type nativeRun struct {
+	deadline time.Time
+	slaves   []*nativeSlave
+	errLines []string
+	flags    map[int]struct{}
+	// 最后一个完成的分块, 用于等待 slave 追上
+	lastTable *nativeTable
+	lastChunk int
+}
+
+func (n *nativeRun) addError(format string, a ...interface{}) {
+	line := fmt.Sprintf(format, a...)
+	slog.Error("native checksum", slog.String("error", line))
+	n.errLines = append(n.errLines, line)
+	n.flags[1] = struct{}{}
+}
+
+func (r *Checker) runNative() (output *Output, err error, pterr error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	defer cancel()
+
+	_, err = r.conn.ExecContext(
+		ctx, fmt.Sprintf(`SET SESSION innodb_lock_wait_timeout = %d`, r.Config.NativeChecksum.LockWaitTimeout))
+	if err != nil {
+		slog.Error("set innodb_lock_wait_timeout", slog.String("error", err.Error()))
+		return nil, err, nil
+	}
+
+	tables, err := r.nativeTables()
+	if err != nil {
+		return nil, err, nil
+	}
+
+	run := &nativeRun{
+		flags: make(map[int]struct{}),
+	}
+	if r.Mode == config.DemandMode {
+		run.slaves, err = r.connectNativeSlaves()
+		if err != nil {
+			return nil, err, nil
+		}
+		defer func() {
+			for _, s := range run.slaves {
+				_ = s.db.Close()
+			}
+		}()
+	}
+
+	var resume *nativeResume
+	if r.Mode == config.GeneralMode {
+		resume, err = r.nativeResumePoint()
+		if err != nil {
+			return nil, err, nil
+		}
+	}
+
+	r.startTS = time.Now()
+	run.deadline = r.startTS.Add(r.Config.NativeChecksum.RunTime)
+	slog.Info("sleep 2s")
+	time.Sleep(2 * time.Second) // 和 pt 引擎一样, 让时间往前走一下, mysql 时间戳精度不够
+
+	var summaries []ChecksumSummary
+	for _, t := range tables {
+		var tableResume *nativeResume
+		if resume != nil {
+			c := compareTableName(t.Schema, t.Name, resume.Db, resume.Tbl)
+			if c < 0 {
+				continue
+			}
+			if c == 0 {
+				tableResume = resume
+			}
+		}
+
+		if time.Now().After(run.deadline) {
+			slog.Info("native checksum reach run time", slog.Duration("run time", r.Config.NativeChecksum.RunTime))
+			break
+		}
+
+		cs, checked := r.checksumTable(ctx, run, t, tableResume)
+		if checked {
+			summaries = append(summaries, cs)
+		}
+	}
+
+	if r.Mode == config.DemandMode && len(summaries) > 0 {
+		r.compareNativeSlaves(ctx, run, summaries)
+	}
+
+	ptFlags := make([]PtExitFlag, 0)
+	for bit := range run.flags {
+		ptFlags = append(ptFlags, PtExitFlagMap[bit])
+	}
+
+	output = &Output{
+		PtStderr:    strings.Join(run.errLines, "\n"),
+		Summaries:   summaries,
+		PtExitFlags: ptFlags,
+	}
+	slog.Info("native checksum summary", slog.String("summary", output.String()))
+	return output, nil, nil
+}
+
+// checksumTable 校验一张表, checked 为 false 表示断点续跑时这张表已经完成
+func (r *Checker) checksumTable(ctx context.Context, run *nativeRun, t *nativeTable, resume *nativeResume) (cs ChecksumSummary, checked bool) {
+	start := time.Now()
+	cs.Table = t.String()
+
+	err := r.loadSchema(t)
+	if err != nil {
+		run.addError("%s load schema: %s", t, err.Error())
+		run.flags[64] = struct{}{}
+		cs.Errors += 1
+		cs.Ts = time.Now()
+		return cs, true
+	}
+
+	if t.Index == "" {
+		// 没有可用索引, 小表整表校验, 大表跳过
+		if float64(t.RowsEstimate) > float64(r.Config.NativeChecksum.ChunkSize)*r.Config.NativeChecksum.ChunkSizeLimit {
+			slog.Warn("skip table without good index and oversized",
+				slog.String("table", t.String()), slog.Int64("rows", t.RowsEstimate))
+			run.flags[64] = struct{}{}
+			cs.Skipped += 1
+			cs.Ts = time.Now()
+			return cs, true
+		}
+		if resume != nil {
+			return cs, false
+		}
+	}
+
+	chunk := &nativeChunk{Number: 1}
+	if t.Index != "" {
+		var resumeUpper []string
+		if resume != nil && resume.UpperBoundary.Valid {
+			resumeUpper, _ = splitBoundary(resume.UpperBoundary.String)
+			if len(resumeUpper) != len(t.IndexColumns) {
+				slog.Warn("native resume boundary not match index, restart table",
+					slog.String("table", t.String()), slog.String("boundary", resume.UpperBoundary.String))
+				resume, resumeUpper = nil, nil
+			}
+		} else if resume != nil {
+			// 上次最后一块没有上界, 说明这张表已经完成
+			return cs, false
+		}
+
+		if resumeUpper != nil {
+			cond, args := boundaryCondition(t.IndexColumns, resumeUpper, ">")
+			chunk.Lower, err = r.boundaryRow(ctx, t, cond, args, 0)
+			if err != nil {
+				run.addError("%s find resume boundary: %s", t, err.Error())
+				cs.Errors += 1
+				cs.Ts = time.Now()
+				return cs, true
+			}
+			if chunk.Lower == nil {
+				return cs, false
+			}
+			chunk.Number = resume.Chunk + 1
+			slog.Info("native checksum resume table", slog.String("table", t.String()), slog.Int("chunk", chunk.Number))
+		} else {
+			chunk.Lower, err = r.boundaryRow(ctx, t, "1=1", nil, 0)
+			if err != nil {
+				run.addError("%s find first boundary: %s", t, err.Error())
+				cs.Errors += 1
+				cs.Ts = time.Now()
+				return cs, true
+			}
+		}
+	}
+
+	if resume == nil {
+		// 从头开始校验的表先清理上次的结果, 这条语句同样复制到 slave
+		_, err = r.conn.ExecContext(
+			ctx,
+			fmt.Sprintf(
+				"DELETE FROM %s.%s WHERE master_ip = ? AND master_port = ? AND db = ? AND tbl = ?",
+				r.resultDB, r.resultTbl),
+			r.Config.Ip, r.Config.Port, t.Schema, t.Name,
+		)
+		if err != nil {
+			run.addError("%s clean old result: %s", t, err.Error())
+			cs.Errors += 1
+			cs.Ts = time.Now()
+			return cs, true
+		}
+	}
+
+	var rate float64
+	size := r.Config.NativeChecksum.ChunkSize
+	lastProgress := time.Now()
+	for {
+		if time.Now().After(run.deadline) {
+			slog.Info("native checksum reach run time in table", slog.String("table", t.String()))
+			break
+		}
+		if err := r.waitNativeThrottle(ctx, run); err != nil {
+			run.addError("%s wait throttle: %s", t, err.Error())
+			cs.Errors += 1
+			break
+		}
+
+		chunk.Upper = nil
+		if t.Index != "" && chunk.Lower != nil {
+			cond, args := boundaryCondition(t.IndexColumns, chunk.Lower, ">=")
+			chunk.Upper, err = r.boundaryRow(ctx, t, cond, args, size-1)
+			if err != nil {
+				run.addError("%s find chunk %d boundary: %s", t, chunk.Number, err.Error())
+				cs.Errors += 1
+				break
+			}
+		}
+
+		cnt, elapsed, skipped, err := r.checksumChunkWithRetry(ctx, t, chunk)
+		if err != nil {
+			run.addError("%s checksum chunk %d: %s", t, chunk.Number, err.Error())
+			cs.Errors += 1
+			break
+		}
+		if skipped {
+			slog.Warn("native checksum skip chunk", slog.String("table", t.String()), slog.Int("chunk", chunk.Number))
+			run.flags[32] = struct{}{}
+			cs.Skipped += 1
+		} else {
+			cs.Rows += cnt
+			run.lastTable, run.lastChunk = t, chunk.Number
+		}
+		cs.Chunks += 1
+		size = r.nextChunkSize(&rate, size, cnt, elapsed)
+
+		if time.Since(lastProgress) > nativeProgressInterval {
+			lastProgress = time.Now()
+			slog.Info(
+				"native checksum progress",
+				slog.String("table", t.String()),
+				slog.Int("rows", cs.Rows),
+				slog.Int64("estimate rows", t.RowsEstimate),
+				slog.Int("chunks", cs.Chunks),
+				slog.Int("chunk size", size),
+			)
+		}
+
+		if chunk.Upper == nil {
+			break
+		}
+
+		cond, args := boundaryCondition(t.IndexColumns, chunk.Upper, ">")
+		chunk.Lower, err = r.boundaryRow(ctx, t, cond, args, 0)
+		if err != nil {
+			run.addError("%s find chunk %d boundary: %s", t, chunk.Number+1, err.Error())
+			cs.Errors += 1
+			break
+		}
+		if chunk.Lower == nil {
+			break
+		}
+		chunk.Number += 1
+	}
+
+	cs.Ts = time.Now()
+	cs.Time = int(time.Since(start).Seconds())
+	slog.Info("native checksum table finish",
+		slog.String("table", t.String()), slog.Int("rows", cs.Rows), slog.Int("chunks", cs.Chunks))
+	return cs, cs.Chunks > 0 || cs.Errors > 0
+}
+
+// nativeResumePoint 和 pt --resume 一样, 找到上次最后完成的分块
+func (r *Checker) nativeResumePoint() (*nativeResume, error) {
+	var res nativeResume
+	err := r.db.Get(
+		&res,
+		fmt.Sprintf(
+			"SELECT db, tbl, chunk, upper_boundary FROM %s.%s "+
+				"WHERE master_ip = ? AND master_port = ? AND master_cnt IS NOT NULL "+
+				"ORDER BY ts DESC, db DESC, tbl DESC, chunk DESC LIMIT 1",
+			r.resultDB, r.resultTbl),
+		r.Config.Ip, r.Config.Port,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		slog.Error("native query resume point", slog.String("error", err.Error()))
+		return nil, err
+	}
+	slog.Info("native resume point", slog.String("db", res.Db), slog.String("tbl", res.Tbl), slog.Int("chunk", res.Chunk))
+	return &res, nil
+}
+
+func (r *Checker) connectNativeSlaves() (slaves []*nativeSlave, err error) {
+	for _, slave := range r.Config.Slaves {
+		db, err := sqlx.Connect(
+			"mysql",
+			fmt.Sprintf(
+				"%s:%s@tcp(%s:%d)/",
+				slave.User,
+				slave.Password,
+				slave.Ip,
+				slave.Port,
+			),
+		)
+		if err != nil {
+			slog.Error("native connect slave", slog.String("error", err.Error()))
+			for _, s := range slaves {
+				_ = s.db.Close()
+			}
+			return nil, err
+		}
+		slaves = append(slaves, &nativeSlave{Host: slave, db: db})
+	}
+	return slaves, nil
+}
+
+// waitNativeThrottle master 负载过高或者 slave 延迟过大时等待
+func (r *Checker) waitNativeThrottle(ctx context.Context, run *nativeRun) error {
+	for {
+		if time.Now().After(run.deadline) {
+			return nil
+		}
+
+		busy, err := r.masterTooBusy()
+		if err != nil {
+			return err
+		}
+		if !busy {
+			busy, err = r.slavesTooLag(run)
+			if err != nil {
+				return err
+			}
+		}
+		if !busy {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+func (r *Checker) masterTooBusy() (bool, error) {
+	var name string
+	var threadsRunning int
+	err := r.db.QueryRow(`SHOW GLOBAL STATUS LIKE 'Threads_running'`).Scan(&name, &threadsRunning)
+	if err != nil {
+		slog.Error("native query threads running", slog.String("error", err.Error()))
+		return false, err
+	}
+	if threadsRunning > r.Config.NativeChecksum.MaxThreadsRunning {
+		slog.Info("native checksum pause for threads running", slog.Int("threads running", threadsRunning))
+		return true, nil
+	}
+	return false, nil
+}
+
+func (r *Checker) slavesTooLag(run *nativeRun) (bool, error) {
+	for _, s := range run.slaves {
+		rows, err := s.db.Queryx(`SHOW SLAVE STATUS`)
+		if err != nil {
+			slog.Error("native query slave status", slog.String("error", err.Error()))
+			return false, err
+		}
+
+		slaveStatus := make(map[string]interface{})
+		for rows.Next() {
+			err = rows.MapScan(slaveStatus)
+			if err != nil {
+				_ = rows.Close()
+				slog.Error("native scan slave status", slog.String("error", err.Error()))
+				return false, err
+			}
+		}
+		_ = rows.Close()
+
+		if len(slaveStatus) == 0 {
+			return false, fmt.Errorf("%s:%d is not a slave", s.Ip, s.Port)
+		}
+
+		// 复制停止时 Seconds_Behind_Master 是 NULL, 同样需要等待
+		lag, ok := slaveStatus["Seconds_Behind_Master"].([]byte)
+		if !ok {
+			slog.Info("native checksum pause for slave replication stopped",
+				slog.String("slave", fmt.Sprintf("%s:%d", s.Ip, s.Port)))
+			return true, nil
+		}
+		var seconds int
+		_, _ = fmt.Sscanf(string(lag), "%d", &seconds)
+		if seconds > r.Config.NativeChecksum.MaxLag {
+			slog.Info("native checksum pause for slave lag",
+				slog.String("slave", fmt.Sprintf("%s:%d", s.Ip, s.Port)), slog.Int("lag", seconds))
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// compareNativeSlaves 等 slave 回放完最后一个分块, 再统计每张表的差异分块
+func (r *Checker) compareNativeSlaves(ctx context.Context, run *nativeRun, summaries []ChecksumSummary) {
+	if run.lastTable == nil {
+		return
+	}
+
+	waitDeadline := time.Now().Add(nativeSlaveWaitTimeout)
+	for _, s := range run.slaves {
+		slaveAddr := fmt.Sprintf("%s:%d", s.Ip, s.Port)
+		for {
+			var cnt int
+			err := s.db.QueryRowContext(
+				ctx,
+				fmt.Sprintf(
+					"SELECT COUNT(*) FROM %s.%s WHERE master_ip = ? AND master_port = ? "+
+						"AND db = ? AND tbl = ? AND chunk = ? AND master_cnt IS NOT NULL",
+					r.resultDB, r.resultTbl),
+				r.Config.Ip, r.Config.Port, run.lastTable.Schema, run.lastTable.Name, run.lastChunk,
+			).Scan(&cnt)
+			if err != nil {
+				run.addError("slave %s wait checksum result: %s", slaveAddr, err.Error())
+				break
+			}
+			if cnt > 0 {
+				break
+			}
+			if time.Now().After(waitDeadline) {
+				run.addError("slave %s wait checksum result timeout", slaveAddr)
+				break
+			}
+			time.Sleep(time.Second)
+		}
+
+		for i := range summaries {
+			schema, name, _ := strings.Cut(summaries[i].Table, ".")
+			var diffs, diffRows int
+			err := s.db.QueryRowContext(
+				ctx,
+				fmt.Sprintf(
+					"SELECT "+
+						"IFNULL(SUM(this_cnt <> master_cnt OR this_crc <> master_crc), 0), "+
+						"IFNULL(SUM(ABS(CAST(this_cnt AS SIGNED) - CAST(master_cnt AS SIGNED))), 0) "+
+						"FROM %s.%s WHERE master_ip = ? AND master_port = ? AND db = ? AND tbl = ?",
+					r.resultDB, r.resultTbl),
+				r.Config.Ip, r.Config.Port, schema, name,
+			).Scan(&diffs, &diffRows)
+			if err != nil {
+				run.addError("slave %s compare %s: %s", slaveAddr, summaries[i].Table, err.Error())
+				continue
+			}
+			if diffs > summaries[i].Diffs {
+				summaries[i].Diffs = diffs
+			}
+			if diffRows > summaries[i].DiffRows {
+				summaries[i].DiffRows = diffRows
+			}
+			if diffs > 0 {
+				slog.Info("native checksum found diff",
+					slog.String("slave", slaveAddr), slog.String("table", summaries[i].Table), slog.Int("chunks", diffs))
+				run.flags[16] = struct{}{}
+			}
+		}
+	}
+}
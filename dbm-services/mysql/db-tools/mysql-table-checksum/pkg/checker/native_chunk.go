@@ -0,0 +1,256 @@
+package checker
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// 和 pt-table-checksum 一样, 这些类型转成 utf8mb4 后再计算 crc
+var nativeCharTypes = []string{
+	"char", "varchar", "tinytext", "text", "mediumtext", "longtext", "enum", "set",
+}
+
+// 锁等待超时和死锁时重试分块
+const nativeChunkRetry = 3
+
+// crcExpression 行校验表达式和 pt-table-checksum 的 CRC32 + BIT_XOR 算法保持一致
+// 这样两种引擎的结果可以互相比较
+func (t *nativeTable) crcExpression() string {
+	var columns []string
+	var nullColumns []string
+	for _, c := range t.Columns {
+		if slices.Contains(nativeCharTypes, strings.ToLower(c.DataType)) {
+			columns = append(columns, fmt.Sprintf("CONVERT(%s USING utf8mb4)", quoteIdentifier(c.Name)))
+		} else {
+			columns = append(columns, quoteIdentifier(c.Name))
+		}
+		if c.Nullable == "YES" {
+			nullColumns = append(nullColumns, fmt.Sprintf("ISNULL(%s)", quoteIdentifier(c.Name)))
+		}
+	}
+	if len(nullColumns) > 0 {
+		columns = append(columns, fmt.Sprintf("CONCAT(%s)", strings.Join(nullColumns, ", ")))
+	}
+
+	return fmt.Sprintf(
+		"COALESCE(LOWER(CONV(BIT_XOR(CAST(CRC32(CONCAT_WS('#', %s)) AS UNSIGNED)), 10, 16)), 0)",
+		strings.Join(columns, ", "),
+	)
+}
+
+// boundaryCondition 生成多列索引的范围条件
+// 展开成 (a > ?) OR (a = ? AND b >= ?) 的形式, 老版本 mysql 对行构造器比较用不上索引
+func boundaryCondition(columns []string, values []string, op string) (string, []interface{}) {
+	strictOp := strings.TrimSuffix(op, "=")
+
+	var ors []string
+	var args []interface{}
+	for i := range columns {
+		var ands []string
+		for j := 0; j < i; j++ {
+			ands = append(ands, fmt.Sprintf("%s = ?", quoteIdentifier(columns[j])))
+			args = append(args, values[j])
+		}
+		thisOp := strictOp
+		if i == len(columns)-1 {
+			thisOp = op
+		}
+		ands = append(ands, fmt.Sprintf("%s %s ?", quoteIdentifier(columns[i]), thisOp))
+		args = append(args, values[i])
+		ors = append(ors, fmt.Sprintf("(%s)", strings.Join(ands, " AND ")))
+	}
+	return fmt.Sprintf("(%s)", strings.Join(ors, " OR ")), args
+}
+
+// nativeChunk 一个分块, 没有上下界表示整表或者最后一块
+type nativeChunk struct {
+	Number int
+	Lower  []string
+	Upper  []string
+}
+
+func (c *nativeChunk) where(t *nativeTable) (string, []interface{}) {
+	if t.Index == "" || c.Lower == nil {
+		return "1=1", nil
+	}
+	lowerCond, args := boundaryCondition(t.IndexColumns, c.Lower, ">=")
+	if c.Upper == nil {
+		return lowerCond, args
+	}
+	upperCond, upperArgs := boundaryCondition(t.IndexColumns, c.Upper, "<=")
+	return fmt.Sprintf("%s AND %s", lowerCond, upperCond), append(args, upperArgs...)
+}
+
+// joinBoundary 原生引擎的边界值存成 json 数组, 值里有逗号也能原样还原
+func joinBoundary(values []string) interface{} {
+	if values == nil {
+		return nil
+	}
+	b, _ := json.Marshal(values)
+	return string(b)
+}
+
+// splitBoundary 还原边界值, 原生引擎是 json 数组, pt-table-checksum 是逗号分隔
+// exact 为 false 表示按逗号拆分, 值里有逗号时拆出来的个数会比索引列多
+func splitBoundary(boundary string) (values []string, exact bool) {
+	if strings.HasPrefix(boundary, "[") {
+		if err := json.Unmarshal([]byte(boundary), &values); err == nil {
+			return values, true
+		}
+	}
+	return strings.Split(boundary, ","), false
+}
+
+// boundaryRow 按分块索引顺序取一行的索引列值, 没有数据返回 nil
+func (r *Checker) boundaryRow(ctx context.Context, t *nativeTable, cond string, args []interface{}, offset int) ([]string, error) {
+	var columns []string
+	for _, c := range t.IndexColumns {
+		columns = append(columns, quoteIdentifier(c))
+	}
+	q := fmt.Sprintf(
+		"SELECT %s FROM %s FORCE INDEX(%s) WHERE %s ORDER BY %s LIMIT %d, 1",
+		strings.Join(columns, ", "),
+		t.quotedName(),
+		quoteIdentifier(t.Index),
+		cond,
+		strings.Join(columns, ", "),
+		offset,
+	)
+
+	values := make([]sql.NullString, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	err := r.conn.QueryRowContext(ctx, q, args...).Scan(dest...)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	res := make([]string, len(values))
+	for i, v := range values {
+		res[i] = v.String
+	}
+	return res, nil
+}
+
+// checksumChunk 和 pt-table-checksum 一样, 以 statement 格式的 REPLACE ... SELECT 在 master 计算 this_crc
+// 这条语句复制到 slave 后会在同一个复制位点用 slave 自己的数据重新计算
+// 随后的 UPDATE 把 master 的结果写到 master_crc, slave 上两者对比即可发现差异
+func (r *Checker) checksumChunk(ctx context.Context, t *nativeTable, chunk *nativeChunk) (cnt int, elapsed time.Duration, err error) {
+	where, whereArgs := chunk.where(t)
+
+	forceIndex := ""
+	var chunkIndex interface{}
+	if t.Index != "" {
+		forceIndex = fmt.Sprintf(" FORCE INDEX(%s)", quoteIdentifier(t.Index))
+		chunkIndex = t.Index
+	}
+
+	q := fmt.Sprintf(
+		"REPLACE INTO %s.%s "+
+			"(master_ip, master_port, db, tbl, chunk, chunk_index, lower_boundary, upper_boundary, this_cnt, this_crc) "+
+			"SELECT ?, ?, ?, ?, ?, ?, ?, ?, COUNT(*), %s FROM %s%s WHERE %s",
+		r.resultDB, r.resultTbl,
+		t.crcExpression(), t.quotedName(), forceIndex, where,
+	)
+	args := append([]interface{}{
+		r.Config.Ip, r.Config.Port, t.Schema, t.Name, chunk.Number, chunkIndex,
+		joinBoundary(chunk.Lower), joinBoundary(chunk.Upper),
+	}, whereArgs...)
+
+	start := time.Now()
+	_, err = r.conn.ExecContext(ctx, q, args...)
+	if err != nil {
+		return 0, 0, err
+	}
+	elapsed = time.Since(start)
+
+	var crc string
+	err = r.conn.QueryRowContext(
+		ctx,
+		fmt.Sprintf(
+			"SELECT this_crc, this_cnt FROM %s.%s "+
+				"WHERE master_ip = ? AND master_port = ? AND db = ? AND tbl = ? AND chunk = ?",
+			r.resultDB, r.resultTbl),
+		r.Config.Ip, r.Config.Port, t.Schema, t.Name, chunk.Number,
+	).Scan(&crc, &cnt)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	_, err = r.conn.ExecContext(
+		ctx,
+		fmt.Sprintf(
+			"UPDATE %s.%s SET chunk_time = ?, master_crc = ?, master_cnt = ? "+
+				"WHERE master_ip = ? AND master_port = ? AND db = ? AND tbl = ? AND chunk = ?",
+			r.resultDB, r.resultTbl),
+		elapsed.Seconds(), crc, cnt,
+		r.Config.Ip, r.Config.Port, t.Schema, t.Name, chunk.Number,
+	)
+	if err != nil {
+		return 0, 0, err
+	}
+	return cnt, elapsed, nil
+}
+
+func isRetryableChunkError(err error) bool {
+	var myErr *mysql.MySQLError
+	if errors.As(err, &myErr) {
+		// 1205 lock wait timeout, 1213 deadlock
+		return myErr.Number == 1205 || myErr.Number == 1213
+	}
+	return false
+}
+
+// checksumChunkWithRetry 返回 skipped 表示分块重试后仍然拿不到锁被跳过
+func (r *Checker) checksumChunkWithRetry(ctx context.Context, t *nativeTable, chunk *nativeChunk) (cnt int, elapsed time.Duration, skipped bool, err error) {
+	for i := 1; i <= nativeChunkRetry; i++ {
+		cnt, elapsed, err = r.checksumChunk(ctx, t, chunk)
+		if err == nil {
+			return cnt, elapsed, false, nil
+		}
+		if !isRetryableChunkError(err) {
+			return 0, 0, false, err
+		}
+		slog.Warn(
+			"native checksum chunk retry",
+			slog.String("error", err.Error()),
+			slog.String("table", t.String()),
+			slog.Int("chunk", chunk.Number),
+			slog.Int("retry", i),
+		)
+		time.Sleep(time.Duration(i) * time.Second)
+	}
+	return 0, 0, true, nil
+}
+
+// nextChunkSize 根据实际耗时调整分块大小, 让单个分块耗时接近 chunk_time
+func (r *Checker) nextChunkSize(rate *float64, size int, cnt int, elapsed time.Duration) int {
+	if elapsed <= 0 || cnt <= 0 {
+		return size
+	}
+	thisRate := float64(cnt) / elapsed.Seconds()
+	if *rate == 0 {
+		*rate = thisRate
+	} else {
+		*rate = 0.75*(*rate) + 0.25*thisRate
+	}
+	next := int(*rate * r.Config.NativeChecksum.ChunkTime)
+	if next < 1 {
+		next = 1
+	}
+	return next
+}
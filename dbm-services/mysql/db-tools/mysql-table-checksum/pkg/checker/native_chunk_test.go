@@ -0,0 +1,183 @@
+package checker
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"dbm-services/mysql/db-tools/mysql-table-checksum/pkg/config"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+func TestBoundaryCondition(t *testing.T) {
+	cases := []struct {
+		columns  []string
+		values   []string
+		op       string
+		wantCond string
+		wantArgs []interface{}
+	}{
+		{
+			columns:  []string{"id"},
+			values:   []string{"10"},
+			op:       ">=",
+			wantCond: "((`id` >= ?))",
+			wantArgs: []interface{}{"10"},
+		},
+		{
+			columns:  []string{"a", "b"},
+			values:   []string{"1", "x"},
+			op:       ">=",
+			wantCond: "((`a` > ?) OR (`a` = ? AND `b` >= ?))",
+			wantArgs: []interface{}{"1", "1", "x"},
+		},
+		{
+			columns:  []string{"a", "b", "c"},
+			values:   []string{"1", "2", "3"},
+			op:       "<=",
+			wantCond: "((`a` < ?) OR (`a` = ? AND `b` < ?) OR (`a` = ? AND `b` = ? AND `c` <= ?))",
+			wantArgs: []interface{}{"1", "1", "2", "1", "2", "3"},
+		},
+		{
+			columns:  []string{"a", "b"},
+			values:   []string{"1", "2"},
+			op:       ">",
+			wantCond: "((`a` > ?) OR (`a` = ? AND `b` > ?))",
+			wantArgs: []interface{}{"1", "1", "2"},
+		},
+	}
+	for _, c := range cases {
+		cond, args := boundaryCondition(c.columns, c.values, c.op)
+		if cond != c.wantCond {
+			t.Errorf("boundaryCondition(%v, %v, %s) cond = %s, want %s", c.columns, c.values, c.op, cond, c.wantCond)
+		}
+		if !reflect.DeepEqual(args, c.wantArgs) {
+			t.Errorf("boundaryCondition(%v, %v, %s) args = %v, want %v", c.columns, c.values, c.op, args, c.wantArgs)
+		}
+	}
+}
+
+func TestChunkWhere(t *testing.T) {
+	tbl := &nativeTable{Index: "PRIMARY", IndexColumns: []string{"id"}}
+
+	where, args := (&nativeChunk{}).where(tbl)
+	if where != "1=1" || args != nil {
+		t.Errorf("chunk without boundary got %s %v", where, args)
+	}
+
+	where, args = (&nativeChunk{Lower: []string{"1"}}).where(tbl)
+	if where != "((`id` >= ?))" || !reflect.DeepEqual(args, []interface{}{"1"}) {
+		t.Errorf("last chunk got %s %v", where, args)
+	}
+
+	where, args = (&nativeChunk{Lower: []string{"1"}, Upper: []string{"9"}}).where(tbl)
+	if where != "((`id` >= ?)) AND ((`id` <= ?))" || !reflect.DeepEqual(args, []interface{}{"1", "9"}) {
+		t.Errorf("chunk got %s %v", where, args)
+	}
+
+	where, _ = (&nativeChunk{Lower: []string{"1"}}).where(&nativeTable{})
+	if where != "1=1" {
+		t.Errorf("table without index got %s", where)
+	}
+}
+
+func TestJoinSplitBoundary(t *testing.T) {
+	if b := joinBoundary(nil); b != nil {
+		t.Errorf("joinBoundary(nil) = %v, want nil", b)
+	}
+
+	for _, values := range [][]string{
+		{"1"},
+		{"a,b", "c"},
+		{"", "x\"y", "[z]"},
+	} {
+		b, ok := joinBoundary(values).(string)
+		if !ok {
+			t.Fatalf("joinBoundary(%v) not string", values)
+		}
+		got, exact := splitBoundary(b)
+		if !exact || !reflect.DeepEqual(got, values) {
+			t.Errorf("splitBoundary(%s) = %v %v, want %v", b, got, exact, values)
+		}
+	}
+
+	// pt-table-checksum 的逗号分隔格式
+	for b, want := range map[string][]string{
+		"1,abc": {"1", "abc"},
+		"10":    {"10"},
+		"[1,2":  {"[1", "2"},
+	} {
+		got, exact := splitBoundary(b)
+		if exact || !reflect.DeepEqual(got, want) {
+			t.Errorf("splitBoundary(%s) = %v %v, want %v", b, got, exact, want)
+		}
+	}
+}
+
+func TestCrcExpression(t *testing.T) {
+	tbl := &nativeTable{
+		Columns: []nativeColumn{
+			{Name: "id", DataType: "int", Nullable: "NO"},
+			{Name: "name", DataType: "VARCHAR", Nullable: "YES"},
+			{Name: "c", DataType: "blob", Nullable: "YES"},
+		},
+	}
+	want := "COALESCE(LOWER(CONV(BIT_XOR(CAST(CRC32(CONCAT_WS('#', " +
+		"`id`, CONVERT(`name` USING utf8mb4), `c`, CONCAT(ISNULL(`name`), ISNULL(`c`))" +
+		")) AS UNSIGNED)), 10, 16)), 0)"
+	if got := tbl.crcExpression(); got != want {
+		t.Errorf("crcExpression() = %s, want %s", got, want)
+	}
+}
+
+func TestNextChunkSize(t *testing.T) {
+	r := &Checker{Config: &config.Config{NativeChecksum: config.NativeChecksum{ChunkTime: 1}}}
+
+	var rate float64
+	if got := r.nextChunkSize(&rate, 1000, 0, time.Second); got != 1000 || rate != 0 {
+		t.Errorf("empty chunk got size %d rate %f", got, rate)
+	}
+	if got := r.nextChunkSize(&rate, 1000, 2000, time.Second); got != 2000 {
+		t.Errorf("first chunk got size %d, want 2000", got)
+	}
+	// 0.75*2000 + 0.25*4000
+	if got := r.nextChunkSize(&rate, 2000, 2000, 500*time.Millisecond); got != 2500 {
+		t.Errorf("second chunk got size %d, want 2500", got)
+	}
+	rate = 0.1
+	if got := r.nextChunkSize(&rate, 1, 1, 10*time.Second); got != 1 {
+		t.Errorf("slow chunk got size %d, want 1", got)
+	}
+}
+
+func TestIsRetryableChunkError(t *testing.T) {
+	cases := map[error]bool{
+		&mysql.MySQLError{Number: 1205}:                         true,
+		fmt.Errorf("wrap: %w", &mysql.MySQLError{Number: 1213}): true,
+		&mysql.MySQLError{Number: 1062}:                         false,
+		fmt.Errorf("other"):                                     false,
+	}
+	for err, want := range cases {
+		if got := isRetryableChunkError(err); got != want {
+			t.Errorf("isRetryableChunkError(%v) = %v, want %v", err, got, want)
+		}
+	}
+}
+
+func TestCompareTableName(t *testing.T) {
+	cases := []struct {
+		db1, tbl1, db2, tbl2 string
+		want                 int
+	}{
+		{"a", "t1", "a", "t1", 0},
+		{"a", "t2", "a", "t1", 1},
+		{"a", "t9", "b", "t1", -1},
+	}
+	for _, c := range cases {
+		if got := compareTableName(c.db1, c.tbl1, c.db2, c.tbl2); got != c.want {
+			t.Errorf("compareTableName(%s.%s, %s.%s) = %d, want %d", c.db1, c.tbl1, c.db2, c.tbl2, got, c.want)
+		}
+	}
+}
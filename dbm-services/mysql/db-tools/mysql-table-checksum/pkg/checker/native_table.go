@@ -0,0 +1,231 @@
+package checker
+
+import (
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// 这些库不做校验
+var nativeSystemDBs = []string{"information_schema", "performance_schema", "sys"}
+
+type nativeColumn struct {
+	Name     string `db:"COLUMN_NAME"`
+	DataType string `db:"DATA_TYPE"`
+	Nullable string `db:"IS_NULLABLE"`
+}
+
+// nativeTable 待校验的表
+type nativeTable struct {
+	Schema       string `db:"TABLE_SCHEMA"`
+	Name         string `db:"TABLE_NAME"`
+	RowsEstimate int64  `db:"TABLE_ROWS"`
+	Columns      []nativeColumn
+	// Index 用来分块的主键或唯一索引, 为空表示整表一个分块
+	Index        string
+	IndexColumns []string
+}
+
+func (t *nativeTable) String() string {
+	return fmt.Sprintf("%s.%s", t.Schema, t.Name)
+}
+
+func (t *nativeTable) quotedName() string {
+	return fmt.Sprintf("%s.%s", quoteIdentifier(t.Schema), quoteIdentifier(t.Name))
+}
+
+type nativeFilter struct {
+	databases            []string
+	tables               []string
+	ignoreDatabases      []string
+	ignoreTables         []string
+	databasesRegex       *regexp.Regexp
+	tablesRegex          *regexp.Regexp
+	ignoreDatabasesRegex *regexp.Regexp
+	ignoreTablesRegex    *regexp.Regexp
+}
+
+func (r *Checker) newNativeFilter() (f *nativeFilter, err error) {
+	f = &nativeFilter{
+		databases:       r.Config.Filter.Databases,
+		tables:          r.Config.Filter.Tables,
+		ignoreDatabases: r.Config.Filter.IgnoreDatabases,
+		ignoreTables:    r.Config.Filter.IgnoreTables,
+	}
+
+	for _, e := range []struct {
+		expr string
+		re   **regexp.Regexp
+	}{
+		{r.Config.Filter.DatabasesRegex, &f.databasesRegex},
+		{r.Config.Filter.TablesRegex, &f.tablesRegex},
+		{r.Config.Filter.IgnoreDatabasesRegex, &f.ignoreDatabasesRegex},
+		{r.Config.Filter.IgnoreTablesRegex, &f.ignoreTablesRegex},
+	} {
+		if e.expr == "" {
+			continue
+		}
+		*e.re, err = regexp.Compile(e.expr)
+		if err != nil {
+			slog.Error("compile filter regex", slog.String("error", err.Error()), slog.String("regex", e.expr))
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// match 和 pt-table-checksum 的过滤语义保持一致
+// tables, ignore_tables 可以是 tbl 或者 db.tbl
+func (f *nativeFilter) match(db, tbl string) bool {
+	fullName := fmt.Sprintf("%s.%s", db, tbl)
+
+	if len(f.databases) > 0 && !slices.Contains(f.databases, db) {
+		return false
+	}
+	if slices.Contains(f.ignoreDatabases, db) {
+		return false
+	}
+	if len(f.tables) > 0 && !slices.Contains(f.tables, tbl) && !slices.Contains(f.tables, fullName) {
+		return false
+	}
+	if slices.Contains(f.ignoreTables, tbl) || slices.Contains(f.ignoreTables, fullName) {
+		return false
+	}
+	if f.databasesRegex != nil && !f.databasesRegex.MatchString(db) {
+		return false
+	}
+	if f.tablesRegex != nil && !f.tablesRegex.MatchString(tbl) {
+		return false
+	}
+	if f.ignoreDatabasesRegex != nil && f.ignoreDatabasesRegex.MatchString(db) {
+		return false
+	}
+	if f.ignoreTablesRegex != nil && f.ignoreTablesRegex.MatchString(tbl) {
+		return false
+	}
+	return true
+}
+
+// nativeTables 按库表名排序返回过滤后的表, 断点续跑依赖这个顺序
+func (r *Checker) nativeTables() ([]*nativeTable, error) {
+	filter, err := r.newNativeFilter()
+	if err != nil {
+		return nil, err
+	}
+
+	var all []*nativeTable
+	q, args, err := sqlx.In(
+		`SELECT TABLE_SCHEMA, TABLE_NAME, IFNULL(TABLE_ROWS, 0) AS TABLE_ROWS `+
+			`FROM INFORMATION_SCHEMA.TABLES `+
+			`WHERE TABLE_TYPE = 'BASE TABLE' AND TABLE_SCHEMA NOT IN (?)`,
+		nativeSystemDBs,
+	)
+	if err != nil {
+		return nil, err
+	}
+	err = r.db.Select(&all, q, args...)
+	if err != nil {
+		slog.Error("native list tables", slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	var tables []*nativeTable
+	for _, t := range all {
+		// 结果表自己不参与校验
+		if t.Schema == r.resultDB &&
+			(t.Name == r.resultTbl || t.Name == r.resultHistoryTable || t.Name == "dsns") {
+			continue
+		}
+		if filter.match(t.Schema, t.Name) {
+			tables = append(tables, t)
+		}
+	}
+
+	slices.SortFunc(tables, func(a, b *nativeTable) int {
+		return compareTableName(a.Schema, a.Name, b.Schema, b.Name)
+	})
+	slog.Info("native list tables", slog.Int("count", len(tables)))
+	return tables, nil
+}
+
+func compareTableName(db1, tbl1, db2, tbl2 string) int {
+	if c := strings.Compare(db1, db2); c != 0 {
+		return c
+	}
+	return strings.Compare(tbl1, tbl2)
+}
+
+// loadSchema 读取列定义, 并选出分块索引
+// 优先主键, 其次是所有列都 NOT NULL 的唯一索引
+// 生成列的值由其他列计算, 不参与校验, 也不能写进修复的 REPLACE 语句
+func (r *Checker) loadSchema(t *nativeTable) error {
+	t.Columns = nil
+	err := r.db.Select(
+		&t.Columns,
+		`SELECT COLUMN_NAME, DATA_TYPE, IS_NULLABLE FROM INFORMATION_SCHEMA.COLUMNS `+
+			`WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? AND EXTRA NOT LIKE '%GENERATED%' `+
+			`ORDER BY ORDINAL_POSITION`,
+		t.Schema, t.Name,
+	)
+	if err != nil {
+		slog.Error("native load columns", slog.String("error", err.Error()), slog.String("table", t.String()))
+		return err
+	}
+	if len(t.Columns) == 0 {
+		return fmt.Errorf("table %s not found", t)
+	}
+
+	var indexColumns []struct {
+		IndexName  string `db:"INDEX_NAME"`
+		ColumnName string `db:"COLUMN_NAME"`
+		NonUnique  int    `db:"NON_UNIQUE"`
+		Nullable   string `db:"NULLABLE"`
+		SubPart    *int   `db:"SUB_PART"`
+	}
+	err = r.db.Select(
+		&indexColumns,
+		`SELECT INDEX_NAME, COLUMN_NAME, NON_UNIQUE, NULLABLE, SUB_PART FROM INFORMATION_SCHEMA.STATISTICS `+
+			`WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? AND NON_UNIQUE = 0 `+
+			`ORDER BY INDEX_NAME = 'PRIMARY' DESC, INDEX_NAME, SEQ_IN_INDEX`,
+		t.Schema, t.Name,
+	)
+	if err != nil {
+		slog.Error("native load indexes", slog.String("error", err.Error()), slog.String("table", t.String()))
+		return err
+	}
+
+	t.Index = ""
+	t.IndexColumns = nil
+	var candidate string
+	var candidateColumns []string
+	usable := true
+	for i, ic := range indexColumns {
+		if ic.IndexName != candidate {
+			candidate = ic.IndexName
+			candidateColumns = nil
+			usable = true
+		}
+		// 生成列上的索引不能用来分块
+		if ic.Nullable != "" || ic.SubPart != nil ||
+			!slices.ContainsFunc(t.Columns, func(c nativeColumn) bool { return c.Name == ic.ColumnName }) {
+			usable = false
+		}
+		candidateColumns = append(candidateColumns, ic.ColumnName)
+
+		lastColumn := i == len(indexColumns)-1 || indexColumns[i+1].IndexName != candidate
+		if lastColumn && usable {
+			t.Index = candidate
+			t.IndexColumns = candidateColumns
+			break
+		}
+	}
+	return nil
+}
+
+func quoteIdentifier(name string) string {
+	return fmt.Sprintf("`%s`", strings.ReplaceAll(name, "`", "``"))
+}
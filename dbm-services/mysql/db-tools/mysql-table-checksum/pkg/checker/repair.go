@@ -0,0 +1,555 @@
+package checker
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"dbm-services/mysql/db-tools/mysql-table-checksum/pkg/config"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// RepairOptions 修复参数
+type RepairOptions struct {
+	// Tables 只修复这些表, 格式 db.tbl, 为空修复所有不一致的表
+	Tables []string
+	// OutputFile 修复 sql 文件, 为空时写到 report_path
+	OutputFile string
+	// DryRun 只统计差异行数, 不生成 sql 文件
+	DryRun bool
+	// Execute 在 master 执行修复 sql, 通过复制修复 slave
+	Execute bool
+	// WaitTimeout 等待 slave 追上 master 位点的超时时间, 单位秒
+	WaitTimeout int
+}
+
+// RepairTableReport 单表修复统计
+type RepairTableReport struct {
+	Table         string `json:"table"`
+	Slave         string `json:"slave"`
+	Chunks        int    `json:"chunks"`
+	SkippedChunks int    `json:"skipped_chunks"`
+	ReplaceRows   int    `json:"replace_rows"`
+	DeleteRows    int    `json:"delete_rows"`
+}
+
+// RepairReport 修复结果
+type RepairReport struct {
+	SqlFile    string               `json:"sql_file"`
+	Statements int                  `json:"statements"`
+	Executed   bool                 `json:"executed"`
+	Tables     []*RepairTableReport `json:"tables"`
+	Errors     []string             `json:"errors"`
+}
+
+func (c *RepairReport) String() string {
+	b, _ := json.Marshal(*c)
+	return string(b)
+}
+
+// repairChunk 结果表中记录的不一致分块
+type repairChunk struct {
+	Db            string  `db:"db"`
+	Tbl           string  `db:"tbl"`
+	Chunk         int     `db:"chunk"`
+	ChunkIndex    *string `db:"chunk_index"`
+	LowerBoundary *string `db:"lower_boundary"`
+	UpperBoundary *string `db:"upper_boundary"`
+}
+
+// repairTable 一张表的修复语句, 以唯一键去重, 多个 slave 的差异合并到一起
+type repairTable struct {
+	table   *nativeTable
+	deletes map[string]string
+	// 保持生成顺序, 生成的文件每次都一样
+	deleteKeys  []string
+	replaces    map[string]string
+	replaceKeys []string
+}
+
+// Repairer 根据校验结果, 逐行对比 master 和 slave 的不一致分块, 生成幂等的修复 sql
+type Repairer struct {
+	*Checker
+	Options RepairOptions
+	// 拉取数据的连接不能 parseTime, 否则时间类型会被格式化成 go 的格式
+	fetchDB *sqlx.DB
+	slaves  []*nativeSlave
+	tables  map[string]*repairTable
+	// 表的处理顺序
+	tableNames []string
+	report     *RepairReport
+}
+
+// NewRepairer 新建修复器
+func NewRepairer(opts RepairOptions) (*Repairer, error) {
+	if len(config.ChecksumConfig.Slaves) < 1 {
+		err := fmt.Errorf("repair need at least 1 slave")
+		slog.Error("new repairer", slog.String("error", err.Error()))
+		return nil, err
+	}
+	if opts.WaitTimeout <= 0 {
+		opts.WaitTimeout = 60
+	}
+
+	r := &Repairer{
+		Checker: &Checker{
+			Config: config.ChecksumConfig,
+			Mode:   config.RepairMode,
+		},
+		Options: opts,
+		tables:  make(map[string]*repairTable),
+		report:  &RepairReport{},
+	}
+
+	splitR := strings.Split(r.Config.PtChecksum.Replicate, ".")
+	if len(splitR) != 2 {
+		err := fmt.Errorf("bad replicate table: %s", r.Config.PtChecksum.Replicate)
+		slog.Error("new repairer", slog.String("error", err.Error()))
+		return nil, err
+	}
+	r.resultDB = splitR[0]
+	r.resultTbl = splitR[1]
+	r.resultHistoryTable = fmt.Sprintf("%s_history", splitR[1])
+
+	if err := r.connect(); err != nil {
+		return nil, err
+	}
+
+	var err error
+	r.fetchDB, err = sqlx.Connect("mysql", repairDsn(r.Config.Host))
+	if err != nil {
+		slog.Error("new repairer connect master", slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	for _, slave := range r.Config.Slaves {
+		db, err := sqlx.Connect("mysql", repairDsn(slave))
+		if err != nil {
+			slog.Error("new repairer connect slave", slog.String("error", err.Error()))
+			return nil, err
+		}
+		r.slaves = append(r.slaves, &nativeSlave{Host: slave, db: db})
+	}
+	return r, nil
+}
+
+// repairDsn 固定用 utc 时区读写, master slave 时区不同也不会误判 timestamp
+func repairDsn(h config.Host) string {
+	return fmt.Sprintf(
+		"%s:%s@tcp(%s:%d)/?time_zone=%s",
+		h.User, h.Password, h.Ip, h.Port, url.QueryEscape("'+00:00'"),
+	)
+}
+
+// Close 关闭连接
+func (r *Repairer) Close() {
+	for _, s := range r.slaves {
+		_ = s.db.Close()
+	}
+	if r.fetchDB != nil {
+		_ = r.fetchDB.Close()
+	}
+	if r.conn != nil {
+		_ = r.conn.Close()
+	}
+	if r.db != nil {
+		_ = r.db.Close()
+	}
+}
+
+func (r *Repairer) addError(format string, a ...interface{}) {
+	line := fmt.Sprintf(format, a...)
+	slog.Error("repair", slog.String("error", line))
+	r.report.Errors = append(r.report.Errors, line)
+}
+
+// Repair 执行修复
+func (r *Repairer) Repair() (*RepairReport, error) {
+	ctx := context.Background()
+
+	for _, s := range r.slaves {
+		slaveAddr := fmt.Sprintf("%s:%d", s.Ip, s.Port)
+		chunks, err := r.diffChunks(ctx, s)
+		if err != nil {
+			return nil, err
+		}
+		slog.Info("repair found diff chunks", slog.String("slave", slaveAddr), slog.Int("chunks", len(chunks)))
+
+		var tr *RepairTableReport
+		for _, chunk := range chunks {
+			tableName := fmt.Sprintf("%s.%s", chunk.Db, chunk.Tbl)
+			if tr == nil || tr.Table != tableName {
+				tr = &RepairTableReport{Table: tableName, Slave: slaveAddr}
+				r.report.Tables = append(r.report.Tables, tr)
+			}
+			tr.Chunks += 1
+
+			rt, err := r.repairTable(chunk.Db, chunk.Tbl)
+			if err != nil {
+				r.addError("%s prepare table: %s", tableName, err.Error())
+				tr.SkippedChunks += 1
+				continue
+			}
+
+			replaceRows, deleteRows, err := r.repairChunk(ctx, s, rt, chunk)
+			if err != nil {
+				r.addError("%s chunk %d on slave %s: %s", tableName, chunk.Chunk, slaveAddr, err.Error())
+				tr.SkippedChunks += 1
+				continue
+			}
+			tr.ReplaceRows += replaceRows
+			tr.DeleteRows += deleteRows
+		}
+	}
+
+	statements := r.statements()
+	r.report.Statements = len(statements)
+	if r.Options.DryRun || len(statements) == 0 {
+		return r.report, nil
+	}
+
+	err := r.writeSqlFile(statements)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.Options.Execute {
+		err = r.execute(ctx, statements)
+		if err != nil {
+			return r.report, err
+		}
+		r.report.Executed = true
+	}
+	return r.report, nil
+}
+
+// diffChunks 从 slave 的结果表读出不一致的分块
+func (r *Repairer) diffChunks(ctx context.Context, s *nativeSlave) (chunks []repairChunk, err error) {
+	err = s.db.SelectContext(
+		ctx,
+		&chunks,
+		fmt.Sprintf(
+			"SELECT db, tbl, chunk, chunk_index, lower_boundary, upper_boundary FROM %s.%s "+
+				"WHERE master_ip = ? AND master_port = ? "+
+				"AND (this_cnt <> master_cnt OR this_crc <> master_crc OR ISNULL(master_crc) <> ISNULL(this_crc)) "+
+				"ORDER BY db, tbl, chunk",
+			r.resultDB, r.resultTbl),
+		r.Config.Ip, r.Config.Port,
+	)
+	if err != nil {
+		slog.Error("repair query diff chunks", slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	if len(r.Options.Tables) == 0 {
+		return chunks, nil
+	}
+
+	var res []repairChunk
+	for _, c := range chunks {
+		if slices.Contains(r.Options.Tables, fmt.Sprintf("%s.%s", c.Db, c.Tbl)) {
+			res = append(res, c)
+		}
+	}
+	return res, nil
+}
+
+func (r *Repairer) repairTable(db, tbl string) (*repairTable, error) {
+	name := fmt.Sprintf("%s.%s", db, tbl)
+	if rt, ok := r.tables[name]; ok {
+		return rt, nil
+	}
+
+	t := &nativeTable{Schema: db, Name: tbl}
+	if err := r.loadSchema(t); err != nil {
+		return nil, err
+	}
+	if t.Index == "" {
+		return nil, fmt.Errorf("no primary key or not null unique key to identify rows")
+	}
+
+	rt := &repairTable{
+		table:    t,
+		deletes:  make(map[string]string),
+		replaces: make(map[string]string),
+	}
+	r.tables[name] = rt
+	r.tableNames = append(r.tableNames, name)
+	return rt, nil
+}
+
+// chunkRange 还原分块范围
+// pt-table-checksum 边界外的分块是开区间, 这里统一用闭区间, 多取几行不影响对比结果
+func (r *Repairer) chunkRange(t *nativeTable, chunk repairChunk) (where string, args []interface{}, forceIndex string, err error) {
+	if chunk.ChunkIndex == nil || *chunk.ChunkIndex == "" || (chunk.LowerBoundary == nil && chunk.UpperBoundary == nil) {
+		return "1=1", nil, "", nil
+	}
+
+	var indexColumns []string
+	err = r.db.Select(
+		&indexColumns,
+		`SELECT COLUMN_NAME FROM INFORMATION_SCHEMA.STATISTICS `+
+			`WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? AND INDEX_NAME = ? ORDER BY SEQ_IN_INDEX`,
+		t.Schema, t.Name, *chunk.ChunkIndex,
+	)
+	if err != nil {
+		return "", nil, "", err
+	}
+	if len(indexColumns) == 0 {
+		return "", nil, "", fmt.Errorf("chunk index %s not found", *chunk.ChunkIndex)
+	}
+
+	var conds []string
+	for _, b := range []struct {
+		boundary *string
+		op       string
+	}{
+		{chunk.LowerBoundary, ">="},
+		{chunk.UpperBoundary, "<="},
+	} {
+		if b.boundary == nil {
+			continue
+		}
+		values, _ := splitBoundary(*b.boundary)
+		// pt 的 --chunk-index-columns 只用索引前缀, 值比列多说明边界值里有逗号, 没法还原
+		if len(values) > len(indexColumns) {
+			return "", nil, "", fmt.Errorf("ambiguous boundary %s for index %s", *b.boundary, *chunk.ChunkIndex)
+		}
+		cond, condArgs := boundaryCondition(indexColumns[:len(values)], values, b.op)
+		conds = append(conds, cond)
+		args = append(args, condArgs...)
+	}
+	return strings.Join(conds, " AND "), args, fmt.Sprintf(" FORCE INDEX(%s)", quoteIdentifier(*chunk.ChunkIndex)), nil
+}
+
+// repairChunk 在 master 上锁住分块, 等 slave 回放到同一位点后逐行对比
+func (r *Repairer) repairChunk(ctx context.Context, s *nativeSlave, rt *repairTable, chunk repairChunk) (replaceRows int, deleteRows int, err error) {
+	t := rt.table
+	where, args, forceIndex, err := r.chunkRange(t, chunk)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var columns, orderBy []string
+	for _, c := range t.Columns {
+		columns = append(columns, quoteIdentifier(c.Name))
+	}
+	for _, c := range t.IndexColumns {
+		orderBy = append(orderBy, quoteIdentifier(c))
+	}
+	q := fmt.Sprintf(
+		"SELECT %s FROM %s%s WHERE %s ORDER BY %s",
+		strings.Join(columns, ", "), t.quotedName(), forceIndex, where, strings.Join(orderBy, ", "),
+	)
+
+	tx, err := r.fetchDB.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	// FOR UPDATE 保证对比期间 master 上这个范围不再变化
+	masterRows, masterKeys, err := fetchRepairRows(ctx, tx, t, q+" FOR UPDATE", args)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	err = r.waitSlave(ctx, tx, s)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	slaveRows, slaveKeys, err := fetchRepairRows(ctx, s.db, t, q, args)
+	if err != nil {
+		return 0, 0, err
+	}
+	_ = tx.Rollback()
+
+	for _, k := range slaveKeys {
+		if _, ok := masterRows[k]; !ok {
+			deleteRows += 1
+			if _, ok := rt.deletes[k]; !ok {
+				rt.deletes[k] = deleteStatement(t, slaveRows[k])
+				rt.deleteKeys = append(rt.deleteKeys, k)
+			}
+		}
+	}
+	for _, k := range masterKeys {
+		slaveRow, ok := slaveRows[k]
+		if ok && slaveRow.equal(masterRows[k]) {
+			continue
+		}
+		replaceRows += 1
+		if _, ok := rt.replaces[k]; !ok {
+			rt.replaces[k] = replaceStatement(t, masterRows[k])
+			rt.replaceKeys = append(rt.replaceKeys, k)
+		}
+	}
+
+	slog.Info(
+		"repair chunk",
+		slog.String("table", t.String()),
+		slog.Int("chunk", chunk.Chunk),
+		slog.Int("replace rows", replaceRows),
+		slog.Int("delete rows", deleteRows),
+	)
+	return replaceRows, deleteRows, nil
+}
+
+// waitSlave 用 master 当前位点等待 slave 回放
+func (r *Repairer) waitSlave(ctx context.Context, tx *sqlx.Tx, s *nativeSlave) error {
+	masterStatus := make(map[string]interface{})
+	rows, err := tx.QueryxContext(ctx, `SHOW MASTER STATUS`)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		err = rows.MapScan(masterStatus)
+		if err != nil {
+			_ = rows.Close()
+			return err
+		}
+	}
+	_ = rows.Close()
+
+	file, _ := masterStatus["File"].([]byte)
+	pos, _ := masterStatus["Position"].([]byte)
+	if len(file) == 0 {
+		return fmt.Errorf("master binlog not enabled")
+	}
+
+	var res *int
+	err = s.db.QueryRowContext(
+		ctx, `SELECT MASTER_POS_WAIT(?, ?, ?)`, string(file), string(pos), r.Options.WaitTimeout,
+	).Scan(&res)
+	if err != nil {
+		return err
+	}
+	if res == nil {
+		return fmt.Errorf("slave sql thread not running")
+	}
+	if *res < 0 {
+		return fmt.Errorf("wait slave reach %s:%s timeout", file, pos)
+	}
+	return nil
+}
+
+// fetchRepairRows 返回唯一键到行的映射, 以及按唯一键排序的键列表
+func fetchRepairRows(ctx context.Context, q sqlx.QueryerContext, t *nativeTable, query string, args []interface{}) (map[string]repairRow, []string, error) {
+	var keyPos []int
+	for _, ic := range t.IndexColumns {
+		for i, c := range t.Columns {
+			if c.Name == ic {
+				keyPos = append(keyPos, i)
+				break
+			}
+		}
+	}
+
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	res := make(map[string]repairRow)
+	var keys []string
+	for rows.Next() {
+		row := make(repairRow, len(t.Columns))
+		dest := make([]interface{}, len(row))
+		for i := range row {
+			dest[i] = &row[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, nil, err
+		}
+
+		var keyParts []string
+		for _, p := range keyPos {
+			keyParts = append(keyParts, row[p].String)
+		}
+		key := strings.Join(keyParts, "\x00")
+		res[key] = row
+		keys = append(keys, key)
+	}
+	return res, keys, rows.Err()
+}
+
+// statements 先删后补, 所有语句都可以重复执行
+func (r *Repairer) statements() (res []string) {
+	for _, name := range r.tableNames {
+		rt := r.tables[name]
+		for _, k := range rt.deleteKeys {
+			res = append(res, rt.deletes[k])
+		}
+		for _, k := range rt.replaceKeys {
+			res = append(res, rt.replaces[k])
+		}
+	}
+	return res
+}
+
+func (r *Repairer) writeSqlFile(statements []string) error {
+	sqlFile := r.Options.OutputFile
+	if sqlFile == "" {
+		sqlFile = filepath.Join(
+			r.Config.ReportPath,
+			fmt.Sprintf("repair_%s_%d_%s.sql", r.Config.Ip, r.Config.Port, time.Now().Format("20060102150405")),
+		)
+	}
+	if err := os.MkdirAll(filepath.Dir(sqlFile), 0755); err != nil {
+		slog.Error("repair create sql file dir", slog.String("error", err.Error()))
+		return err
+	}
+
+	f, err := os.Create(sqlFile)
+	if err != nil {
+		slog.Error("repair create sql file", slog.String("error", err.Error()))
+		return err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	w := bufio.NewWriter(f)
+	_, _ = fmt.Fprintf(w, "-- repair for %s:%d, generated at %s\n",
+		r.Config.Ip, r.Config.Port, time.Now().Format(time.RFC3339))
+	_, _ = fmt.Fprintf(w, "-- must run on master, changes replicate to slaves\n")
+	for _, s := range append(repairHeader, statements...) {
+		_, _ = fmt.Fprintf(w, "%s;\n", s)
+	}
+	if err := w.Flush(); err != nil {
+		slog.Error("repair write sql file", slog.String("error", err.Error()))
+		return err
+	}
+
+	r.report.SqlFile = sqlFile
+	slog.Info("repair write sql file", slog.String("file", sqlFile), slog.Int("statements", len(statements)))
+	return nil
+}
+
+// execute 在 master 执行修复语句, binlog 格式必须是 statement, 见 repairHeader
+func (r *Repairer) execute(ctx context.Context, statements []string) error {
+	for _, s := range append(repairHeader, statements...) {
+		_, err := r.conn.ExecContext(ctx, s)
+		if err != nil {
+			slog.Error("repair execute", slog.String("error", err.Error()), slog.String("sql", s))
+			return err
+		}
+	}
+	slog.Info("repair execute finish", slog.Int("statements", len(statements)))
+	return nil
+}
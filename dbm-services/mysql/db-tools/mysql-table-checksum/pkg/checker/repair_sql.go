@@ -0,0 +1,114 @@
+package checker
+
+import (
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+var repairNumericTypes = []string{
+	"tinyint", "smallint", "mediumint", "int", "integer", "bigint",
+	"decimal", "numeric", "float", "double", "real",
+}
+
+// 二进制类型用 hex 字面量, 避免字符集转换
+var repairBinaryTypes = []string{
+	"binary", "varbinary", "tinyblob", "blob", "mediumblob", "longblob", "bit",
+	"geometry", "point", "linestring", "polygon",
+	"multipoint", "multilinestring", "multipolygon", "geometrycollection",
+}
+
+// repairHeader 修复 sql 的会话设置
+// 必须以 statement 格式记录 binlog: master 上数据本来就是对的,
+// row 格式下 REPLACE 相同的行不会产生 binlog, 修复就无法复制到 slave
+var repairHeader = []string{
+	"SET NAMES utf8mb4",
+	"SET SESSION time_zone = '+00:00'",
+	"SET SESSION sql_log_bin = 1",
+	"SET SESSION binlog_format = 'STATEMENT'",
+}
+
+// repairRow 一行数据, 按 nativeTable.Columns 的顺序
+type repairRow []sql.NullString
+
+func (r repairRow) equal(o repairRow) bool {
+	if len(r) != len(o) {
+		return false
+	}
+	for i := range r {
+		if r[i].Valid != o[i].Valid || r[i].String != o[i].String {
+			return false
+		}
+	}
+	return true
+}
+
+func sqlLiteral(c nativeColumn, v sql.NullString) string {
+	if !v.Valid {
+		return "NULL"
+	}
+	dataType := strings.ToLower(c.DataType)
+	if slices.Contains(repairNumericTypes, dataType) {
+		return v.String
+	}
+	if slices.Contains(repairBinaryTypes, dataType) {
+		if v.String == "" {
+			return "''"
+		}
+		return fmt.Sprintf("X'%s'", hex.EncodeToString([]byte(v.String)))
+	}
+	return quoteString(v.String)
+}
+
+// quoteString 按 mysql 字符串字面量规则转义
+func quoteString(s string) string {
+	var b strings.Builder
+	b.WriteByte('\'')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case 0:
+			b.WriteString(`\0`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\'':
+			b.WriteString(`\'`)
+		case 0x1a:
+			b.WriteString(`\Z`)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('\'')
+	return b.String()
+}
+
+func replaceStatement(t *nativeTable, row repairRow) string {
+	var columns, values []string
+	for i, c := range t.Columns {
+		columns = append(columns, quoteIdentifier(c.Name))
+		values = append(values, sqlLiteral(c, row[i]))
+	}
+	return fmt.Sprintf(
+		"REPLACE INTO %s (%s) VALUES (%s)",
+		t.quotedName(), strings.Join(columns, ", "), strings.Join(values, ", "),
+	)
+}
+
+func deleteStatement(t *nativeTable, row repairRow) string {
+	var conds []string
+	for _, ic := range t.IndexColumns {
+		for i, c := range t.Columns {
+			if c.Name == ic {
+				conds = append(conds, fmt.Sprintf("%s = %s", quoteIdentifier(c.Name), sqlLiteral(c, row[i])))
+				break
+			}
+		}
+	}
+	return fmt.Sprintf("DELETE FROM %s WHERE %s", t.quotedName(), strings.Join(conds, " AND "))
+}
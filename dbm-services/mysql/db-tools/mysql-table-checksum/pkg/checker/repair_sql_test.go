@@ -0,0 +1,68 @@
+package checker
+
+import (
+	"database/sql"
+	"testing"
+)
+
+func TestQuoteString(t *testing.T) {
+	cases := map[string]string{
+		"":        `''`,
+		"abc":     `'abc'`,
+		"a'b":     `'a\'b'`,
+		`a\b`:     `'a\\b'`,
+		"a\nb\rc": `'a\nb\rc'`,
+		"a\x00b":  `'a\0b'`,
+		"a\x1ab":  `'a\Zb'`,
+		"中文\"x\"": `'中文"x"'`,
+	}
+	for s, want := range cases {
+		if got := quoteString(s); got != want {
+			t.Errorf("quoteString(%q) = %s, want %s", s, got, want)
+		}
+	}
+}
+
+func TestSqlLiteral(t *testing.T) {
+	cases := []struct {
+		dataType string
+		value    sql.NullString
+		want     string
+	}{
+		{"int", sql.NullString{}, "NULL"},
+		{"varchar", sql.NullString{}, "NULL"},
+		{"int", sql.NullString{String: "-10", Valid: true}, "-10"},
+		{"DECIMAL", sql.NullString{String: "1.50", Valid: true}, "1.50"},
+		{"varbinary", sql.NullString{String: "", Valid: true}, "''"},
+		{"blob", sql.NullString{String: "\x00\xffa", Valid: true}, "X'00ff61'"},
+		{"varchar", sql.NullString{String: "", Valid: true}, "''"},
+		{"varchar", sql.NullString{String: "it's", Valid: true}, `'it\'s'`},
+		{"datetime", sql.NullString{String: "2024-01-01 00:00:00", Valid: true}, "'2024-01-01 00:00:00'"},
+	}
+	for _, c := range cases {
+		if got := sqlLiteral(nativeColumn{Name: "c", DataType: c.dataType}, c.value); got != c.want {
+			t.Errorf("sqlLiteral(%s, %v) = %s, want %s", c.dataType, c.value, got, c.want)
+		}
+	}
+}
+
+func TestReplaceStatement(t *testing.T) {
+	tbl := &nativeTable{
+		Schema: "db1",
+		Name:   "t`1",
+		Columns: []nativeColumn{
+			{Name: "id", DataType: "int"},
+			{Name: "name", DataType: "varchar"},
+			{Name: "data", DataType: "blob"},
+		},
+	}
+	row := repairRow{
+		{String: "1", Valid: true},
+		{String: "a'b", Valid: true},
+		{},
+	}
+	want := "REPLACE INTO `db1`.`t``1` (`id`, `name`, `data`) VALUES (1, 'a\\'b', NULL)"
+	if got := replaceStatement(tbl, row); got != want {
+		t.Errorf("replaceStatement() = %s, want %s", got, want)
+	}
+}
@@ -31,7 +31,7 @@ RunCheckLabel:
 		return err
 	}
 
-	output, err, pterr := r.run()
+	output, err, pterr := r.runEngine()
 	if err != nil {
 		return err
 	}
@@ -167,6 +167,14 @@ func (r *Checker) writeFakeResult(fakeDB string, fakeTbl string) error {
 	return nil
 }
 
+func (r *Checker) runEngine() (output *Output, err error, pterr error) {
+	if r.Config.Engine == config.EngineNative {
+		slog.Info("run with native engine")
+		return r.runNative()
+	}
+	return r.run()
+}
+
 func (r *Checker) run() (output *Output, err error, pterr error) {
 	var stdout, stderr bytes.Buffer
 
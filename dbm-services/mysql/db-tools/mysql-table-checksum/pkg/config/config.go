@@ -8,6 +8,7 @@ import (
 	"path"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v2"
 )
@@ -40,6 +41,24 @@ type PtChecksum struct {
 	Replicate string                   `yaml:"replicate"`
 }
 
+// NativeChecksum 原生校验引擎参数, 不填使用默认值
+type NativeChecksum struct {
+	// ChunkSize 初始分块行数, 之后按 ChunkTime 自适应调整
+	ChunkSize int `yaml:"chunk_size"`
+	// ChunkTime 期望单个分块的校验耗时, 单位秒
+	ChunkTime float64 `yaml:"chunk_time"`
+	// ChunkSizeLimit 没有可用索引的表, 行数不超过 ChunkSize * ChunkSizeLimit 时整表作为一个分块校验
+	ChunkSizeLimit float64 `yaml:"chunk_size_limit"`
+	// MaxThreadsRunning master Threads_running 超过这个值时暂停校验
+	MaxThreadsRunning int `yaml:"max_threads_running"`
+	// MaxLag 单据校验时 slave 延迟超过这个值(秒)暂停校验
+	MaxLag int `yaml:"max_lag"`
+	// LockWaitTimeout 校验会话的 innodb_lock_wait_timeout
+	LockWaitTimeout int `yaml:"lock_wait_timeout"`
+	// RunTime 最长运行时间, 例行校验超时后下次从断点继续
+	RunTime time.Duration `yaml:"run_time"`
+}
+
 type Cluster struct {
 	Id           int    `yaml:"id"`
 	ImmuteDomain string `yaml:"immute_domain"`
@@ -57,19 +76,31 @@ const (
 	RoleSlave InnerRoleEnum = "slave"
 )
 
+// EngineEnum 校验引擎
+type EngineEnum string
+
+const (
+	// EnginePt 调用 pt-table-checksum
+	EnginePt EngineEnum = "pt"
+	// EngineNative 内置的 go 实现
+	EngineNative EngineEnum = "native"
+)
+
 // Config 配置结构
 type Config struct {
-	BkBizId    int `yaml:"bk_biz_id"`
-	Cluster    `yaml:"cluster"`
-	Host       `yaml:",inline"`
-	InnerRole  InnerRoleEnum `yaml:"inner_role"`
-	ReportPath string        `yaml:"report_path"`
-	Slaves     []Host        `yaml:"slaves"`
-	Filter     Filter        `yaml:"filter"`
-	PtChecksum PtChecksum    `yaml:"pt_checksum"`
-	Log        *LogConfig    `yaml:"log"`
-	Schedule   string        `yaml:"schedule"`
-	ApiUrl     string        `yaml:"api_url"`
+	BkBizId        int `yaml:"bk_biz_id"`
+	Cluster        `yaml:"cluster"`
+	Host           `yaml:",inline"`
+	InnerRole      InnerRoleEnum  `yaml:"inner_role"`
+	ReportPath     string         `yaml:"report_path"`
+	Slaves         []Host         `yaml:"slaves"`
+	Filter         Filter         `yaml:"filter"`
+	Engine         EngineEnum     `yaml:"engine"`
+	PtChecksum     PtChecksum     `yaml:"pt_checksum"`
+	NativeChecksum NativeChecksum `yaml:"native_checksum"`
+	Log            *LogConfig     `yaml:"log"`
+	Schedule       string         `yaml:"schedule"`
+	ApiUrl         string         `yaml:"api_url"`
 }
 
 // InitConfig 初始化配置
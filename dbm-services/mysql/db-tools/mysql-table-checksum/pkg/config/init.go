@@ -8,6 +8,8 @@ const (
 	GeneralMode CheckMode = "general"
 	// DemandMode 单据校验
 	DemandMode = "demand"
+	// RepairMode 根据校验结果修复数据
+	RepairMode = "repair"
 )
 
 // String 用于打印